@@ -4,12 +4,17 @@ import (
 	"context"
 	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
+	"net/http"
+	"os"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dgraph-io/badger/v4"
@@ -17,33 +22,62 @@ import (
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 	openuem_nats "github.com/open-uem/nats"
+	"github.com/open-uem/openuem-agent/internal/agent/audit"
 	"github.com/open-uem/openuem-agent/internal/agent/dsc"
+	"github.com/open-uem/openuem-agent/internal/agent/handlers"
+	"github.com/open-uem/openuem-agent/internal/agent/inflight"
+	"github.com/open-uem/openuem-agent/internal/agent/maintenance"
+	"github.com/open-uem/openuem-agent/internal/agent/outbox"
+	"github.com/open-uem/openuem-agent/internal/agent/payload"
+	"github.com/open-uem/openuem-agent/internal/agent/replay"
 	"github.com/open-uem/openuem-agent/internal/agent/rustdesk"
+	"github.com/open-uem/openuem-agent/internal/agent/spool"
+	"github.com/open-uem/openuem-agent/internal/agent/status"
 	"github.com/open-uem/openuem-agent/internal/commands/deploy"
 	"github.com/open-uem/openuem-agent/internal/commands/netbird"
 	"github.com/open-uem/openuem-agent/internal/commands/printers"
 	remotedesktop "github.com/open-uem/openuem-agent/internal/commands/remote-desktop"
 	"github.com/open-uem/openuem-agent/internal/commands/report"
 	"github.com/open-uem/openuem-agent/internal/commands/sftp"
+	"github.com/open-uem/openuem-agent/internal/logger"
+	"github.com/open-uem/openuem-agent/internal/metrics"
 	openuem_utils "github.com/open-uem/utils"
 	"gopkg.in/ini.v1"
 )
 
 type Agent struct {
-	Config                 Config
-	TaskScheduler          gocron.Scheduler
-	ReportJob              gocron.Job
-	NATSConnectJob         gocron.Job
-	NATSConnection         *nats.Conn
-	ServerCertPath         string
-	ServerKeyPath          string
-	CACert                 *x509.Certificate
-	SFTPCert               *x509.Certificate
-	RemoteDesktop          *remotedesktop.RemoteDesktopService
-	BadgerDB               *badger.DB
-	SFTPServer             *sftp.SFTP
-	JetstreamContextCancel context.CancelFunc
-	WingetConfigureJob     gocron.Job
+	Config                  Config
+	TaskScheduler           gocron.Scheduler
+	ReportJob               gocron.Job
+	NATSConnectJob          gocron.Job
+	NATSConnection          *nats.Conn
+	ServerCertPath          string
+	ServerKeyPath           string
+	CACert                  *x509.Certificate
+	SFTPCert                *x509.Certificate
+	RemoteDesktop           *remotedesktop.RemoteDesktopService
+	BadgerDB                *badger.DB
+	SFTPServer              *sftp.SFTP
+	JetstreamContextCancel  context.CancelFunc
+	JetStreamConsumeContext jetstream.ConsumeContext
+	WingetConfigureJob      gocron.Job
+	ReportSpool             *spool.Spool
+	ReportDelta             *report.DeltaTracker
+	Payloads                *payload.Sender
+	Status                  *status.Tracker
+	StatusServer            *http.Server
+	MetricsServer           *http.Server
+	Handlers                *handlers.Registry
+	InFlight                *inflight.Tracker
+	DeployOutbox            *outbox.Outbox
+	AuditLog                *audit.Log
+	ReplayCache             *replay.Cache
+	MaintenanceQueue        *maintenance.Queue
+	natsConnectAttempts     int
+	configWatch             *configWatcher
+	configMu                *sync.Mutex
+	logTails                *atomic.Int32
+	maintenanceWindows      *maintenanceWindows
 }
 
 type JSONActions struct {
@@ -57,74 +91,204 @@ func New() Agent {
 	// Task Scheduler
 	agent.TaskScheduler, err = gocron.NewScheduler()
 	if err != nil {
-		log.Fatalf("[FATAL]: could not create the scheduler: %v", err)
+		logger.Fatal("could not create the scheduler", "error", err)
 	}
 
 	// Read Agent Config from openuem.ini file
-	if err := agent.ReadConfig(); err != nil {
-		log.Fatalf("[FATAL]: could not read agent config: %v", err)
+	agent.Config, err = loadUsableConfig()
+	if err != nil {
+		logger.Fatal("could not read agent config", "error", err)
+	}
+	if agent.Config.IPAddress != "" {
+		slog.Info("IP address has been set from configuration file")
 	}
+	slog.Info("agent has read its settings from the INI file")
 
 	// If it's the initial config, set it and write it
 	if agent.Config.UUID == "" {
 		agent.SetInitialConfig()
 		if err := agent.Config.WriteConfig(); err != nil {
-			log.Fatalf("[FATAL]: could not write agent config: %v", err)
+			logger.Fatal("could not write agent config", "error", err)
 		}
 	}
 
+	// Every record tells which agent it comes from
+	logger.SetLevel(agent.Config.LogLevel, agent.Config.Debug)
+	slog.SetDefault(slog.Default().With("agent_id", agent.Config.UUID))
+
+	// The data folder may be a new one set with DataDir
+	if err := os.MkdirAll(agent.Config.DataDir, 0700); err != nil {
+		logger.Fatal(fmt.Sprintf("could not create the data folder %s", agent.Config.DataDir), "error", err)
+	}
+
+	agent.ReportDelta = report.NewDeltaTracker(agent.Config.FullReportEvery)
+	agent.Payloads = payload.NewSender(agent.Config.Compression)
+	agent.Status = status.NewTracker()
+	agent.InFlight = inflight.NewTracker()
+	agent.configMu = &sync.Mutex{}
+	agent.logTails = &atomic.Int32{}
+	agent.maintenanceWindows = &maintenanceWindows{}
+
+	// A previous renewal may have been interrupted
+	recoverKeyPair("agent certificate", agent.agentKeyPair())
+
 	caCert, err := openuem_utils.ReadPEMCertificate(agent.Config.CACert)
 	if err != nil {
-		log.Fatalf("[FATAL]: could not read CA certificate")
+		logger.Fatal("could not read CA certificate")
 	}
 	agent.CACert = caCert
 
 	agent.SFTPCert, err = openuem_utils.ReadPEMCertificate(agent.Config.SFTPCert)
 	if err != nil {
-		log.Fatalf("[FATAL]: could not read sftp certificate")
+		logger.Fatal("could not read sftp certificate")
 	}
 
 	return agent
 }
 
 func (a *Agent) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(a.Config.ShutdownTimeout)*time.Second)
+	defer cancel()
+
+	a.Shutdown(ctx)
+}
+
+// Shutdown stops receiving new work, waits for the running deployments and profiles
+// until ctx is done and then closes the connections and databases
+func (a *Agent) Shutdown(ctx context.Context) {
+	if a.StatusServer != nil {
+		if err := a.StatusServer.Close(); err != nil {
+			slog.Error("could not close status server", "error", err)
+		}
+	}
+
+	if a.MetricsServer != nil {
+		if err := a.MetricsServer.Close(); err != nil {
+			slog.Error("could not close metrics server", "error", err)
+		}
+	}
+
+	if a.Handlers != nil {
+		a.Handlers.Drain(ctx)
+	}
+
+	if a.JetStreamConsumeContext != nil {
+		a.JetStreamConsumeContext.Drain()
+		select {
+		case <-a.JetStreamConsumeContext.Closed():
+		case <-ctx.Done():
+			a.JetStreamConsumeContext.Stop()
+		}
+	}
+
+	if a.JetstreamContextCancel != nil {
+		a.JetstreamContextCancel()
+	}
+
 	if a.TaskScheduler != nil {
 		if err := a.TaskScheduler.Shutdown(); err != nil {
-			log.Printf("[ERROR]: could not close NATS connection, reason: %s\n", err.Error())
+			slog.Error("could not close NATS connection", "error", err)
+		}
+	}
+
+	if a.InFlight != nil {
+		if count := a.InFlight.Count(); count > 0 {
+			slog.Info(fmt.Sprintf("waiting for %d deployments or profiles to finish", count))
 		}
+		deploys, profiles := a.InFlight.Stop(ctx)
+		a.savePartialResults(deploys, profiles)
 	}
 
 	if a.NATSConnection != nil {
 		a.NATSConnection.Close()
 	}
 
-	if a.SFTPServer != nil {
-		if err := a.SFTPServer.Server.Close(); err != nil {
-			log.Printf("[ERROR]: could not close SFTP server, reason: %s\n", err.Error())
-		}
-	}
+	a.StopSFTPServer()
 
 	if a.BadgerDB != nil {
 		if err := a.BadgerDB.Close(); err != nil {
-			log.Printf("[ERROR]: could not close BadgerDB connection, reason: %s\n", err.Error())
+			slog.Error("could not close BadgerDB connection", "error", err)
+		}
+	}
+
+	if a.ReportSpool != nil {
+		if err := a.ReportSpool.Close(); err != nil {
+			slog.Error("could not close report spool", "error", err)
+		}
+	}
+
+	if a.DeployOutbox != nil {
+		if err := a.DeployOutbox.Close(); err != nil {
+			slog.Error("could not close deployment results outbox", "error", err)
+		}
+	}
+
+	if a.ReplayCache != nil {
+		if err := a.ReplayCache.Close(); err != nil {
+			slog.Error("could not close the nonce cache", "error", err)
+		}
+	}
+
+	if a.MaintenanceQueue != nil {
+		if err := a.MaintenanceQueue.Close(); err != nil {
+			slog.Error("could not close the maintenance queue", "error", err)
+		}
+	}
+	slog.Info("agent has been stopped!")
+}
+
+// savePartialResults keeps the tasks that were interrupted so they're reported when the agent starts again
+func (a *Agent) savePartialResults(deploys []openuem_nats.DeployAction, profiles []openuem_nats.ProfileReport) {
+	for _, action := range deploys {
+		slog.Warn("the agent was stopped while the package was being deployed", "package_id", action.PackageId)
+		action.When = time.Now()
+		action.Failed = true
+		action.Info = "the agent was stopped before the deployment finished, its result is unknown"
+		if a.DeployOutbox == nil {
+			continue
+		}
+		if err := a.DeployOutbox.Add(action); err != nil {
+			slog.Error("could not save interrupted deployment to the outbox", "error", err)
+		}
+	}
+
+	for _, report := range profiles {
+		slog.Warn("the agent was stopped while the profile was being applied", "profile_id", report.ProfileID)
+		report.Success = false
+		if report.Error == "" {
+			report.Error = "the agent was stopped before the profile finished"
+		}
+		if err := a.SaveProfileReportNotACK(report); err != nil {
+			slog.Error("could not save interrupted profile report to pending ack file", "error", err)
 		}
 	}
-	log.Println("[INFO]: agent has been stopped!")
+}
+
+// CollectReport runs all the report collectors with the settings in c
+func CollectReport(c Config) (*report.Report, error) {
+	r, err := report.RunReport(c.UUID, c.Enabled, c.Debug, c.VNCProxyPort, c.SFTPPort, c.IPAddress, c.SFTPDisabled, c.RemoteAssistanceDisabled, c.TenantID, c.SiteID, c.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	r.Certificates = CertificateExpiries(c)
+	r.Maintenance = MaintenanceStatus(c, nil)
+	return r, nil
 }
 
 func (a *Agent) RunReport() *report.Report {
 	start := time.Now()
 
-	log.Println(">>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>")
+	slog.Info(">>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>")
 
-	log.Println("[INFO]: agent is running a report...")
-	r, err := report.RunReport(a.Config.UUID, a.Config.Enabled, a.Config.Debug, a.Config.VNCProxyPort, a.Config.SFTPPort, a.Config.IPAddress, a.Config.SFTPDisabled, a.Config.RemoteAssistanceDisabled, a.Config.TenantID, a.Config.SiteID)
+	slog.Info("agent is running a report...")
+	r, err := CollectReport(a.Config)
 	if err != nil {
 		return nil
 	}
+	r.Maintenance = MaintenanceStatus(a.Config, a.MaintenanceQueue)
 
 	if r.IP == "" {
-		log.Println("[WARN]: agent has no IP address, report won't be sent and we're flagging this so the watchdog can restart the service")
+		slog.Warn("agent has no IP address, report won't be sent and we're flagging this so the watchdog can restart the service")
 
 		// Get conf file
 		configFile := openuem_utils.GetAgentConfigFile()
@@ -132,37 +296,106 @@ func (a *Agent) RunReport() *report.Report {
 		// Open ini file
 		cfg, err := ini.Load(configFile)
 		if err != nil {
-			log.Println("[ERROR]: could not read config file")
+			slog.Error("could not read config file")
 			return nil
 		}
 
 		cfg.Section("Agent").Key("RestartRequired").SetValue("true")
 		if err := cfg.SaveTo(configFile); err != nil {
-			log.Println("[ERROR]: could not save RestartRequired flag to config file")
+			slog.Error("could not save RestartRequired flag to config file")
 			return nil
 		}
 
-		log.Println("[WARN]: the flag to restart the service by the watchdog has been raised")
+		slog.Warn("the flag to restart the service by the watchdog has been raised")
 		return nil
 	}
 
-	log.Printf("[INFO]: agent report run took %v\n", time.Since(start))
+	slog.Info(fmt.Sprintf("agent report run took %v", time.Since(start)))
 
-	log.Println("<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<")
+	slog.Info("<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<")
 
 	return r
 }
 
 func (a *Agent) SendReport(r *report.Report) error {
+	err := a.sendReport(r)
+	a.Status.ReportSent(err)
+	return err
+}
+
+func (a *Agent) sendReport(r *report.Report) error {
+	sections, manifest, err := r.Sections()
+	if err != nil {
+		return err
+	}
+
+	if a.Config.DeltaReports {
+		if delta := a.ReportDelta.Delta(r, sections, manifest); delta != nil {
+			err := a.sendDeltaReport(delta, manifest)
+			if err == nil {
+				return nil
+			}
+
+			if !errors.Is(err, nats.ErrNoResponders) {
+				return err
+			}
+			slog.Warn("no worker is accepting delta reports, a full report will be sent")
+		}
+	}
+
 	data, err := json.Marshal(r)
 	if err != nil {
 		return err
 	}
 
+	if err := a.sendReportData(data); err != nil {
+		return err
+	}
+
+	a.ReportDelta.Sent(manifest, true)
+	return nil
+}
+
+func (a *Agent) sendDeltaReport(delta *report.DeltaReport, manifest map[string]string) error {
+	if a.NATSConnection == nil {
+		return fmt.Errorf("NATS connection is not ready")
+	}
+
+	data, err := json.Marshal(delta)
+	if err != nil {
+		return err
+	}
+
+	msg, err := a.Payloads.Request(a.NATSConnection, "report.delta", data, 4*time.Minute)
+	if err != nil {
+		return err
+	}
+	a.ReportDelta.Sent(manifest, false)
+
+	// The worker may ask for a full report if it's not in sync with the agent
+	if len(msg.Data) > 0 {
+		response := report.DeltaResponse{}
+		if err := json.Unmarshal(msg.Data, &response); err != nil {
+			slog.Error("could not unmarshal delta report response", "error", err)
+			return nil
+		}
+
+		if response.FullReportRequired {
+			slog.Info("worker has requested a full report")
+			a.ReportDelta.Reset()
+		}
+	}
+
+	slog.Debug(fmt.Sprintf("%s report sent with %d changed sections", delta.Type, len(delta.Sections)))
+
+	return nil
+}
+
+func (a *Agent) sendReportData(data []byte) error {
 	if a.NATSConnection == nil {
 		return fmt.Errorf("NATS connection is not ready")
 	}
-	_, err = a.NATSConnection.Request("report", data, 4*time.Minute)
+	_, err := a.Payloads.Request(a.NATSConnection, "report", data, 4*time.Minute)
 	if err != nil {
 		return err
 	}
@@ -181,12 +414,13 @@ func (a *Agent) startReportJob() error {
 			time.Duration(a.Config.ExecuteTaskEveryXMinutes)*time.Minute,
 		),
 		gocron.NewTask(a.ReportTask),
+		gocron.WithName("report"),
 	)
 	if err != nil {
-		log.Fatalf("[FATAL]: could not start the agent job: %v", err)
+		logger.Fatal("could not start the agent job", "error", err)
 		return err
 	}
-	log.Printf("[INFO]: new agent job has been scheduled every %d minutes", a.Config.ExecuteTaskEveryXMinutes)
+	slog.Info(fmt.Sprintf("new agent job has been scheduled every %d minutes", a.Config.ExecuteTaskEveryXMinutes))
 	return nil
 }
 
@@ -198,12 +432,13 @@ func (a *Agent) startPendingACKJob() error {
 			SCHEDULETIME_5MIN*time.Minute,
 		),
 		gocron.NewTask(a.PendingACKTask),
+		gocron.WithName("pending-ack"),
 	)
 	if err != nil {
-		log.Fatalf("[FATAL]: could not start the pending ACK job: %v", err)
+		logger.Fatal("could not start the pending ACK job", "error", err)
 		return err
 	}
-	log.Printf("[INFO]: new pending ACK job has been scheduled every %d minutes", SCHEDULETIME_5MIN)
+	slog.Info(fmt.Sprintf("new pending ACK job has been scheduled every %d minutes", SCHEDULETIME_5MIN))
 	return nil
 }
 
@@ -212,53 +447,51 @@ func (a *Agent) ReportTask() {
 	if r == nil {
 		return
 	}
-	if err := a.SendReport(r); err != nil {
+	if err := a.SendOrSpoolReport(r); err != nil {
+		metrics.ReportSendFailures.Inc()
 		a.Config.ExecuteTaskEveryXMinutes = SCHEDULETIME_5MIN
 		if err := a.Config.WriteConfig(); err != nil {
-			log.Fatalf("[FATAL]: could not write agent config: %v", err)
+			logger.Fatal("could not write agent config", "error", err)
 		}
 		a.RescheduleReportRunTask()
-		log.Printf("[ERROR]: report could not be send to NATS server!, reason: %s\n", err.Error())
+		slog.Error("report could not be send to NATS server!", "error", err)
 		return
 	}
 
 	// Get remote config
 	if err := a.GetRemoteConfig(); err != nil {
-		log.Printf("[ERROR]: could not get remote config %v", err)
+		slog.Error("could not get remote config", "error", err)
 	}
 
 	// Report run and sent! Use default frequency
 	a.Config.ExecuteTaskEveryXMinutes = a.Config.DefaultFrequency
 	if err := a.Config.WriteConfig(); err != nil {
-		log.Fatalf("[FATAL]: could not write agent config: %v", err)
+		logger.Fatal("could not write agent config", "error", err)
 	}
 	a.RescheduleReportRunTask()
 }
 
 func (a *Agent) PendingACKTask() {
-	actions, err := ReadDeploymentNotACK()
+	a.FlushDeployOutbox()
+
+	reports, err := a.ReadProfileReportsNotACK()
 	if err != nil {
-		log.Printf("[ERROR]: could not read pending deployment ack, reason: %s\n", err.Error())
+		slog.Error("could not read pending profile reports ack", "error", err)
 		return
 	}
 
-	j := 0
-	for i := 0; i < len(actions); i++ {
-		if err := a.SendDeployResult(&actions[i]); err != nil {
-			log.Printf("[ERROR]: sending deployment result from task failed!, reason: %s\n", err.Error())
-			j = j + 1
-		} else {
-			actions = slices.Delete(actions, j, j+1)
+	pending := []openuem_nats.ProfileReport{}
+	for _, r := range reports {
+		if err := a.SendProfileReport(&r); err != nil {
+			slog.Error("sending profile report from task failed!", "error", err)
+			pending = append(pending, r)
 		}
 	}
 
-	if err := SaveDeploymentsNotACK(actions); err != nil {
-		log.Printf("[ERROR]: could not save pending deployments ack, reason: %s\n", err.Error())
-		return
-	}
-
-	if len(actions) > 0 {
-		log.Println("[INFO]: updated pending deployment ack in pending_acks.json file")
+	if len(pending) != len(reports) {
+		if err := a.SaveProfileReportsNotACK(pending); err != nil {
+			slog.Error("could not save pending profile reports ack", "error", err)
+		}
 	}
 }
 
@@ -267,28 +500,20 @@ func (a *Agent) RescheduleReportRunTask() {
 	a.startReportJob()
 }
 
-func (a *Agent) EnableAgentHandler(msg jetstream.Msg) {
-	if err := a.ReadConfig(); err != nil {
-		log.Printf("[ERROR]: could not read config, reason: %v", err)
+func (a *Agent) EnableAgentHandler(msg jetstream.Msg) error {
+	defer ackMessage(msg)
 
-		if err := msg.Ack(); err != nil {
-			log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-		}
-		return
+	if err := a.ReadConfig(); err != nil {
+		return fmt.Errorf("could not read config, reason: %v", err)
 	}
 
 	if !a.Config.Enabled {
 		// Save property to file
 		a.Config.Enabled = true
 		if err := a.Config.WriteConfig(); err != nil {
-			log.Printf("[ERROR]: could not write agent config: %v", err)
-
-			if err := msg.Ack(); err != nil {
-				log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-			}
-			return
+			return fmt.Errorf("could not write agent config, reason: %v", err)
 		}
-		log.Println("[INFO]: agent has been enabled!")
+		slog.Info("agent has been enabled!")
 
 		// Run report async
 		go func() {
@@ -299,7 +524,7 @@ func (a *Agent) EnableAgentHandler(msg jetstream.Msg) {
 
 			// Send report to NATS
 			if err := a.SendReport(r); err != nil {
-				log.Printf("[ERROR]: report could not be send to NATS server!, reason: %s\n", err.Error())
+				slog.Error("report could not be send to NATS server!", "error", err)
 				a.Config.ExecuteTaskEveryXMinutes = SCHEDULETIME_5MIN
 			} else {
 				// Use default frequency
@@ -310,292 +535,212 @@ func (a *Agent) EnableAgentHandler(msg jetstream.Msg) {
 			a.startReportJob()
 		}()
 	}
-
-	if err := msg.Ack(); err != nil {
-		log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-	}
+	return nil
 }
 
-func (a *Agent) DisableAgentHandler(msg jetstream.Msg) {
-	if err := a.ReadConfig(); err != nil {
-		log.Printf("[ERROR]: could not read config, reason: %v", err)
+func (a *Agent) DisableAgentHandler(msg jetstream.Msg) error {
+	defer ackMessage(msg)
 
-		if err := msg.Ack(); err != nil {
-			log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-		}
-		return
+	if err := a.ReadConfig(); err != nil {
+		return fmt.Errorf("could not read config, reason: %v", err)
 	}
 
 	if a.Config.Enabled {
-		log.Println("[INFO]: agent has been disabled!")
+		slog.Info("agent has been disabled!")
 
 		// Stop reporting job
 		if err := a.TaskScheduler.RemoveJob(a.ReportJob.ID()); err != nil {
-			log.Printf("[INFO]: could not stop report task, reason: %v\n", err)
+			slog.Info("could not stop report task", "error", err)
 		} else {
-			log.Printf("[INFO]: report task has been removed\n")
+			slog.Info("report task has been removed")
 		}
 
 		// Save property to file
 		a.Config.Enabled = false
 		if err := a.Config.WriteConfig(); err != nil {
-			log.Printf("[ERROR]: could not write agent config: %v", err)
-
-			if err := msg.Ack(); err != nil {
-				log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-			}
-			return
+			return fmt.Errorf("could not write agent config, reason: %v", err)
 		}
 	}
-
-	if err := msg.Ack(); err != nil {
-		log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-	}
+	return nil
 }
 
-func (a *Agent) RunReportHandler(msg jetstream.Msg) {
+func (a *Agent) RunReportHandler(msg jetstream.Msg) error {
+	defer ackMessage(msg)
+
 	a.ReadConfig()
+
+	// A report requested from the console is always a full report
+	a.ReportDelta.Reset()
+
 	r := a.RunReport()
 	if r == nil {
-		log.Println("[ERROR]: report could not be generated, report has nil value")
-		if err := msg.Ack(); err != nil {
-			log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-		}
-		return
+		return errors.New("report could not be generated, report has nil value")
 	}
 
 	if err := a.SendReport(r); err != nil {
-		log.Printf("[ERROR]: report could not be send to NATS server!, reason: %v\n", err)
-		if err := msg.Ack(); err != nil {
-			log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-		}
-		return
+		return fmt.Errorf("report could not be send to NATS server, reason: %v", err)
 	}
+	return nil
+}
 
+// ackMessage acknowledges JetStream commands whatever their result, so they're not delivered again
+func ackMessage(msg jetstream.Msg) {
 	if err := msg.Ack(); err != nil {
-		log.Printf("[ERROR]: could not ACK message, reason: %v", err)
+		slog.Error("could not ACK message", "error", err)
 	}
 }
 
-func (a *Agent) StopRemoteDesktopSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.stopvnc."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-		if err := msg.Respond([]byte("Remote Desktop service stopped!")); err != nil {
-			log.Printf("[ERROR]: could not respond to agent stop remote desktop message, reason: %v\n", err)
-		}
-
-		if a.RemoteDesktop != nil {
-			a.RemoteDesktop.Stop()
-			a.RemoteDesktop = nil
-		}
-	})
+func (a *Agent) StopRemoteDesktopHandler(msg *nats.Msg) error {
+	if err := msg.Respond([]byte("Remote Desktop service stopped!")); err != nil {
+		slog.Error("could not respond to agent stop remote desktop message", "error", err)
+	}
 
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent stop remote desktop, reason: %v", err)
+	if a.RemoteDesktop != nil {
+		a.RemoteDesktop.Stop()
+		a.RemoteDesktop = nil
 	}
 	return nil
 }
 
-func (a *Agent) InstallPackageSubscribe() error {
-	_, err := a.NATSConnection.Subscribe("agent.installpackage."+a.Config.UUID, func(msg *nats.Msg) {
-
-		action := openuem_nats.DeployAction{}
-		err := json.Unmarshal(msg.Data, &action)
-		if err != nil {
-			log.Printf("[ERROR]: could not get the package id to install, reason: %v\n", err)
-			return
-		}
-
-		if _, stderr, err := deploy.InstallPackage(action, false, a.Config.Debug); err != nil {
-			log.Printf("[ERROR]: could not deploy package using package manager, reason: %v\n", err)
-			action.Failed = true
-			action.Info = stderr
-			if err := a.SendDeployResult(&action); err != nil {
-				log.Printf("[ERROR]: could not send deploy result to worker, reason: %v\n", err)
-				if err := SaveDeploymentNotACK(action); err != nil {
-					log.Println("[ERROR]: could not save deployment pending ack to JSON file", err)
-				}
-			}
-			return
-		}
+func (a *Agent) InstallPackageHandler(msg *nats.Msg, action openuem_nats.DeployAction) error {
+	task, err := a.InFlight.StartDeploy(action)
+	if err != nil {
+		return err
+	}
+	defer task.Done()
 
-		// Send deploy result if succesful
-		action.When = time.Now()
-		action.Failed = false
-		if err := a.SendDeployResult(&action); err != nil {
-			log.Printf("[ERROR]: could not send deploy result to worker, reason: %v\n", err)
-			if err := SaveDeploymentNotACK(action); err != nil {
-				log.Println("[ERROR]: could not save deployment pending ack to JSON file", err)
-			}
-		}
+	if _, stderr, err := deploy.InstallPackage(action, false, a.Config.Debug); err != nil {
+		slog.Error("could not deploy package using package manager", "error", err)
+		metrics.DeploymentResult("install", true)
+		action.Failed = true
+		action.Info = stderr
+		a.DeliverDeployResult(action)
+		return nil
+	}
 
-		// Send a report to update the installed apps
-		r := a.RunReport()
-		if r == nil {
-			return
-		}
-		if err := a.SendReport(r); err != nil {
-			log.Printf("[ERROR]: report could not be send to NATS server!, reason: %s\n", err.Error())
-		}
-	})
+	// Send deploy result if succesful
+	metrics.DeploymentResult("install", false)
+	action.When = time.Now()
+	action.Failed = false
+	a.DeliverDeployResult(action)
 
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent install package, reason: %v", err)
+	// Send a report to update the installed apps
+	r := a.RunReport()
+	if r == nil {
+		return nil
+	}
+	if err := a.SendReport(r); err != nil {
+		slog.Error("report could not be send to NATS server!", "error", err)
 	}
 	return nil
 }
 
-func (a *Agent) UpdatePackageSubscribe() error {
-	_, err := a.NATSConnection.Subscribe("agent.updatepackage."+a.Config.UUID, func(msg *nats.Msg) {
-
-		action := openuem_nats.DeployAction{}
-		err := json.Unmarshal(msg.Data, &action)
-		if err != nil {
-			log.Printf("[ERROR]: could not get the package id to update, reason: %v\n", err)
-			return
-		}
-
-		if _, stderr, err := deploy.UpdatePackage(action); err != nil {
-			if strings.Contains(err.Error(), strings.ToLower("0x8A15002B")) {
-				log.Println("[INFO]: could not update package using package manager, no updates found", err)
-			} else {
-				log.Printf("[ERROR]: could not update package using package manager, reason: %v\n", err)
-				action.Failed = true
-				action.Info = stderr
-				if err := a.SendDeployResult(&action); err != nil {
-					log.Printf("[ERROR]: could not send deploy result to worker, reason: %v\n", err)
-				}
-			}
-			return
-		}
+func (a *Agent) UpdatePackageHandler(msg *nats.Msg, action openuem_nats.DeployAction) error {
+	task, err := a.InFlight.StartDeploy(action)
+	if err != nil {
+		return err
+	}
+	defer task.Done()
 
-		// Send deploy result if succesful
-		action.When = time.Now()
-		action.Failed = false
-		if err := a.SendDeployResult(&action); err != nil {
-			log.Printf("[ERROR]: could not send deploy result to worker, reason: %v\n", err)
-			if err := SaveDeploymentNotACK(action); err != nil {
-				log.Println("[ERROR]: could not save deployment pending ack to JSON file", err)
-			}
+	if _, stderr, err := deploy.UpdatePackage(action); err != nil {
+		if strings.Contains(err.Error(), strings.ToLower("0x8A15002B")) {
+			slog.Info("could not update package using package manager, no updates found", "error", err)
+		} else {
+			slog.Error("could not update package using package manager", "error", err)
+			metrics.DeploymentResult("update", true)
+			action.Failed = true
+			action.Info = stderr
+			a.DeliverDeployResult(action)
 		}
+		return nil
+	}
 
-		// Send a report to update the installed apps
-		r := a.RunReport()
-		if r == nil {
-			return
-		}
+	// Send deploy result if succesful
+	metrics.DeploymentResult("update", false)
+	action.When = time.Now()
+	action.Failed = false
+	a.DeliverDeployResult(action)
 
-		if err := a.SendReport(r); err != nil {
-			log.Printf("[ERROR]: report could not be send to NATS server!, reason: %s\n", err.Error())
-		}
-	})
+	// Send a report to update the installed apps
+	r := a.RunReport()
+	if r == nil {
+		return nil
+	}
 
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent install package, reason: %v", err)
+	if err := a.SendReport(r); err != nil {
+		slog.Error("report could not be send to NATS server!", "error", err)
 	}
 	return nil
 }
 
-func (a *Agent) UninstallPackageSubscribe() error {
-	_, err := a.NATSConnection.Subscribe("agent.uninstallpackage."+a.Config.UUID, func(msg *nats.Msg) {
-
-		action := openuem_nats.DeployAction{}
-		err := json.Unmarshal(msg.Data, &action)
-		if err != nil {
-			log.Printf("[ERROR]: could not get the package id to uninstall, reason: %v\n", err)
-			return
-		}
-
-		if _, stderr, err := deploy.UninstallPackage(action); err != nil {
-			log.Printf("[ERROR]: could not uninstall package, reason: %v\n", err)
-			action.Failed = false
-			action.Info = stderr
-			if err := a.SendDeployResult(&action); err != nil {
-				log.Printf("[ERROR]: could not send deploy result to worker, reason: %v\n", err)
-			}
-			return
-		}
+func (a *Agent) UninstallPackageHandler(msg *nats.Msg, action openuem_nats.DeployAction) error {
+	task, err := a.InFlight.StartDeploy(action)
+	if err != nil {
+		return err
+	}
+	defer task.Done()
 
-		// Send deploy result if succesful
-		action.When = time.Now()
-		if err := a.SendDeployResult(&action); err != nil {
-			log.Printf("[ERROR]: could not send deploy result to worker, reason: %v\n", err)
-			if err := SaveDeploymentNotACK(action); err != nil {
-				log.Println("[ERROR]: could not save deployment pending ack to JSON file", err)
-			}
-		}
+	if _, stderr, err := deploy.UninstallPackage(action); err != nil {
+		slog.Error("could not uninstall package", "error", err)
+		metrics.DeploymentResult("uninstall", true)
+		action.Failed = false
+		action.Info = stderr
+		a.DeliverDeployResult(action)
+		return nil
+	}
 
-		// Send a report to update the installed apps
-		r := a.RunReport()
-		if r == nil {
-			return
-		}
+	// Send deploy result if succesful
+	metrics.DeploymentResult("uninstall", false)
+	action.When = time.Now()
+	a.DeliverDeployResult(action)
 
-		if err := a.SendReport(r); err != nil {
-			log.Printf("[ERROR]: report could not be send to NATS server!, reason: %s\n", err.Error())
-		}
-	})
+	// Send a report to update the installed apps
+	r := a.RunReport()
+	if r == nil {
+		return nil
+	}
 
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent uninstall package, reason: %v", err)
+	if err := a.SendReport(r); err != nil {
+		slog.Error("report could not be send to NATS server!", "error", err)
 	}
 	return nil
 }
 
-func (a *Agent) AgentSettingsSubscribe() error {
-	_, err := a.NATSConnection.Subscribe("agent.settings."+a.Config.UUID, func(msg *nats.Msg) {
+func (a *Agent) AgentSettingsHandler(msg *nats.Msg, data openuem_nats.AgentSetting) error {
+	c := a.Config
+	c.Debug = data.DebugMode
+	c.SFTPDisabled = !data.SFTPService
+	c.RemoteAssistanceDisabled = !data.RemoteAssistance
 
-		data := openuem_nats.AgentSetting{}
-		err := json.Unmarshal(msg.Data, &data)
+	if data.SFTPPort != "" {
+		port, err := strconv.Atoi(data.SFTPPort)
 		if err != nil {
-			log.Printf("[ERROR]: could not get the agent's settings sent from the console, reason: %v\n", err)
-			return
+			return errors.New("the SFTP port is not a valid number")
 		}
 
-		a.Config.Debug = data.DebugMode
-		a.Config.SFTPDisabled = !data.SFTPService
-		a.Config.RemoteAssistanceDisabled = !data.RemoteAssistance
-
-		if data.SFTPPort != "" {
-			port, err := strconv.Atoi(data.SFTPPort)
-			if err != nil {
-				log.Println("[ERROR]: the SFTP port is not a valid number")
-				return
-			}
-
-			if port < 0 || port > 65535 {
-				log.Println("[ERROR]: the SFTP port is not a valid port")
-				return
-			}
+		if port < 0 || port > 65535 {
+			return errors.New("the SFTP port is not a valid port")
 		}
-		a.Config.SFTPPort = data.SFTPPort
-
-		if data.VNCProxyPort != "" {
-			port, err := strconv.Atoi(data.VNCProxyPort)
-			if err != nil {
-				log.Println("[ERROR]: the VNC proxy port is not a valid number")
-				return
-			}
+	}
+	c.SFTPPort = data.SFTPPort
 
-			if port < 0 || port > 65535 {
-				log.Println("[ERROR]: the VNC proxy port is not a valid port")
-				return
-			}
+	if data.VNCProxyPort != "" {
+		port, err := strconv.Atoi(data.VNCProxyPort)
+		if err != nil {
+			return errors.New("the VNC proxy port is not a valid number")
 		}
-		a.Config.VNCProxyPort = data.VNCProxyPort
 
-		if err := a.Config.WriteConfig(); err != nil {
-			log.Printf("[ERROR]: could not save the agent's settings, reason: %v\n", err)
-			return
+		if port < 0 || port > 65535 {
+			return errors.New("the VNC proxy port is not a valid port")
 		}
+	}
+	c.VNCProxyPort = data.VNCProxyPort
 
-		if err := a.Config.SetRestartRequiredFlag(); err != nil {
-			log.Printf("[ERROR]: could not set the restart required flag, you may have to restart the agent from the console, reason: %v\n", err)
-			return
-		}
-	})
+	// All these settings can be applied without restarting the agent
+	a.ApplyConfig(c)
 
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent's settings port, reason: %v", err)
+	if err := a.Config.WriteConfig(); err != nil {
+		return fmt.Errorf("could not save the agent's settings, reason: %v", err)
 	}
 	return nil
 }
@@ -606,7 +751,7 @@ func (a *Agent) SendDeployResult(r *openuem_nats.DeployAction) error {
 		return err
 	}
 
-	response, err := a.NATSConnection.Request("deployresult", data, 2*time.Minute)
+	response, err := a.Payloads.Request(a.NATSConnection, "deployresult", data, 2*time.Minute)
 	if err != nil {
 		return err
 	}
@@ -626,124 +771,68 @@ func (a *Agent) SubscribeToNATSSubjects() {
 		a.CreateAgentJetStreamConsumer()
 	}()
 
-	// Subscribe to Remote Desktop
-	err := a.StartRemoteDesktopSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
-
-	err = a.StopRemoteDesktopSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
-
-	// Subscribe to RustDesk subjects
-	err = a.StartRustDeskSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
-
-	err = a.StopRustDeskSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
-
-	err = a.InstallPackageSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
-
-	err = a.UpdatePackageSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
-
-	err = a.UninstallPackageSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
-
-	err = a.NewConfigSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
-
-	err = a.PowerOffSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
-
-	err = a.RebootSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
-
-	err = a.AgentSettingsSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
-
-	err = a.SetDefaultPrinter()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
-
-	err = a.RemovePrinter()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
-
-	err = a.InstallNetBirdSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
-
-	err = a.RegisterNetBirdSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
-
-	err = a.UninstallNetBirdSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
+	if a.Handlers == nil {
+		a.Handlers = handlers.NewRegistry(a.Config.UUID,
+			handlers.Logging,
+			handlers.Audit(a.recordAudit),
+			handlers.Timing,
+			handlers.ErrorResponse,
+			handlers.Verify(a.verifyHandlerCommand),
+			handlers.Defer(a.deferHandlerCommand),
+			handlers.Recover,
+		)
+		a.RegisterHandlers()
 	}
 
-	err = a.SwitchProfileNetBirdSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
+	if err := a.Handlers.Subscribe(a.NATSConnection); err != nil {
+		slog.Error(err.Error())
 	}
 
-	err = a.NetBirdUpSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
-
-	err = a.NetBirdDownSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
+	slog.Info("Subscribed to NATS subjects!")
+}
 
-	err = a.RefreshNetBirdSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
+// RegisterHandlers declares the NATS subjects the agent answers to
+func (a *Agent) RegisterHandlers() {
+	q := handlers.MANAGEMENT_QUEUE
+
+	a.Handlers.Register(
+		handlers.New("agent.stopvnc", q, a.StopRemoteDesktopHandler).WithAudit(),
+		handlers.New("agent.rustdesk.start", q, a.StartRustDeskHandler).WithPayload("rustdesk settings").WithErrorResponse(rustdesk.ErrorResponse).WithAudit(),
+		handlers.New("agent.rustdesk.stop", q, a.StopRustDeskHandler).WithErrorResponse(rustdesk.ErrorResponse).WithAudit(),
+		handlers.JSON("agent.installpackage", "", a.InstallPackageHandler).WithAudit().WithDeferral(),
+		handlers.JSON("agent.updatepackage", "", a.UpdatePackageHandler).WithAudit().WithDeferral(),
+		handlers.JSON("agent.uninstallpackage", "", a.UninstallPackageHandler).WithAudit().WithDeferral(),
+		handlers.JSON("agent.settings", "", a.AgentSettingsHandler).WithAudit(),
+		handlers.New("agent.defaultprinter", q, a.SetDefaultPrinterHandler).WithPayload("printer name").WithAudit(),
+		handlers.New("agent.removeprinter", q, a.RemovePrinterHandler).WithPayload("printer name").WithAudit(),
+		handlers.New("agent.netbird.install", q, a.InstallNetBirdHandler).WithErrorResponse(netbird.ErrorResponse).WithAudit(),
+		handlers.New("agent.netbird.register", q, a.RegisterNetBirdHandler).WithPayload("netbird registration").WithErrorResponse(netbird.ErrorResponse).WithAudit(),
+		handlers.New("agent.netbird.uninstall", q, a.UninstallNetBirdHandler).WithErrorResponse(netbird.ErrorResponse).WithAudit(),
+		handlers.JSON("agent.netbird.switchprofile", q, a.SwitchProfileNetBirdHandler).WithErrorResponse(netbird.ErrorResponse).WithAudit(),
+		handlers.New("agent.netbird.up", q, a.NetBirdUpHandler).WithErrorResponse(netbird.ErrorResponse).WithAudit(),
+		handlers.New("agent.netbird.down", q, a.NetBirdDownHandler).WithErrorResponse(netbird.ErrorResponse).WithAudit(),
+		handlers.New("agent.netbird.refresh", q, a.RefreshNetBirdHandler).WithErrorResponse(netbird.ErrorResponse),
+		handlers.New("agent.ping", q, a.PingHandler),
+		handlers.New("agent.handlers", q, a.ListHandlersHandler),
+		handlers.New("agent.doctor", q, a.DoctorHandler),
+		handlers.New("agent.logs", q, a.LogsHandler).WithPayload("logger.Query"),
+		handlers.New("agent.audit", q, a.AuditHandler).WithPayload("audit.Query"),
+		handlers.JSON("agent.logs.tail", q, a.LogTailHandler),
+	)
 
-	err = a.PingSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
-	}
+	a.Handlers.Register(a.PlatformHandlers()...)
+}
 
-	err = a.AgentRunTaskSubscribe()
+func (a *Agent) ListHandlersHandler(msg *nats.Msg) error {
+	data, err := json.Marshal(a.Handlers.List())
 	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
+		return err
 	}
 
-	err = a.RunProfileSubscribe()
-	if err != nil {
-		log.Printf("[ERROR]: %v\n", err)
+	if err := msg.Respond(data); err != nil {
+		return handlers.Responded(err)
 	}
-
-	log.Println("[INFO]: Subscribed to NATS subjects!")
+	return nil
 }
 
 func (a *Agent) CreateAgentJetStreamConsumer() {
@@ -751,15 +840,19 @@ func (a *Agent) CreateAgentJetStreamConsumer() {
 
 	js, err := jetstream.New(a.NATSConnection)
 	if err != nil {
-		log.Printf("[ERROR]: could not intantiate JetStream: %v", err)
+		slog.Error("could not intantiate JetStream", "error", err)
 		return
 	}
 
+	// The context of the previous consumer is released when it's recreated after a reconnection
+	if a.JetstreamContextCancel != nil {
+		a.JetstreamContextCancel()
+	}
 	ctx, a.JetstreamContextCancel = context.WithTimeout(context.Background(), SCHEDULETIME_5MIN*time.Minute)
 	s, err := js.Stream(ctx, "AGENTS_STREAM")
 
 	if err != nil {
-		log.Printf("[ERROR]: could not get stream AGENTS_STREAM: %v\n", err)
+		slog.Error("could not get stream AGENTS_STREAM", "error", err)
 		return
 	}
 
@@ -778,19 +871,23 @@ func (a *Agent) CreateAgentJetStreamConsumer() {
 
 	c1, err := s.CreateOrUpdateConsumer(ctx, consumerConfig)
 	if err != nil {
-		log.Printf("[ERROR]: could not create Jetstream consumer: %v", err)
+		slog.Error("could not create Jetstream consumer", "error", err)
 		return
 	}
 
-	// TODO stop consume context ()
-	_, err = c1.Consume(a.JetStreamAgentHandler, jetstream.ConsumeErrHandler(func(consumeCtx jetstream.ConsumeContext, err error) {
-		log.Printf("[ERROR]: consumer error: %v", err)
+	// Stop the previous consumer if we're recreating it after a reconnection
+	if a.JetStreamConsumeContext != nil {
+		a.JetStreamConsumeContext.Stop()
+	}
+
+	a.JetStreamConsumeContext, err = c1.Consume(a.JetStreamAgentHandler, jetstream.ConsumeErrHandler(func(consumeCtx jetstream.ConsumeContext, err error) {
+		slog.Error("consumer error", "error", err)
 	}))
 	if err != nil {
-		log.Printf("[ERROR]: could not start Agent consumer: %v", err)
+		slog.Error("could not start Agent consumer", "error", err)
 		return
 	}
-	log.Println("[INFO]: Agent consumer is ready to serve")
+	slog.Info("Agent consumer is ready to serve")
 
 }
 
@@ -826,91 +923,112 @@ func (a *Agent) GetRemoteConfig() error {
 	}
 
 	if config.Ok {
-		a.Config.DefaultFrequency = config.AgentFrequency
-		a.Config.WingetConfigureFrequency = config.WinGetFrequency
-		a.Config.SFTPDisabled = config.SFTPDisabled
-		a.Config.RemoteAssistanceDisabled = config.RemoteAssistanceDisabled
+		c := a.currentConfig()
+		c.DefaultFrequency = config.AgentFrequency
+		c.WingetConfigureFrequency = config.WinGetFrequency
+		c.SFTPDisabled = config.SFTPDisabled
+		c.RemoteAssistanceDisabled = config.RemoteAssistanceDisabled
+
+		// The SFTP server is started or stopped if the console changed it
+		a.ApplyConfig(c)
+
 		if err := a.Config.WriteConfig(); err != nil {
-			log.Fatalf("[FATAL]: could not write agent config: %v", err)
+			logger.Fatal("could not write agent config", "error", err)
 		}
 
-		if a.Config.Debug {
-			log.Printf("[DEBUG]: new default frequency is %d", a.Config.DefaultFrequency)
-		}
+		slog.Debug(fmt.Sprintf("new default frequency is %d", a.Config.DefaultFrequency))
 	}
 	return nil
 }
 
 func (a *Agent) JetStreamAgentHandler(msg jetstream.Msg) {
-	if msg.Subject() == "agent.enable."+a.Config.UUID {
-		a.EnableAgentHandler(msg)
+	c := command{
+		Name:       strings.TrimSuffix(msg.Subject(), "."+a.Config.UUID),
+		Subject:    msg.Subject(),
+		Header:     msg.Headers(),
+		Data:       msg.Data(),
+		Privileged: msg.Subject() != "agent.report."+a.Config.UUID,
 	}
-
-	if msg.Subject() == "agent.disable."+a.Config.UUID {
-		a.DisableAgentHandler(msg)
-	}
-
-	if msg.Subject() == "agent.report."+a.Config.UUID {
-		a.RunReportHandler(msg)
+	if c.Header == nil {
+		c.Header = nats.Header{}
 	}
-
-	if msg.Subject() == "agent.certificate."+a.Config.UUID {
-		a.AgentCertificateHandler(msg)
+	if meta, err := msg.Metadata(); err == nil {
+		c.Received = meta.Timestamp
+		c.Redelivered = meta.NumDelivered > 1
 	}
-}
 
-func (a *Agent) SetDefaultPrinter() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.defaultprinter."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-		printerName := string(msg.Data)
-		if printerName == "" {
-			log.Println("[ERROR]: printer name cannot be empty")
-			return
-		}
-		log.Printf("[INFO]: set %s printer as default request received", printerName)
-
-		if err := printers.SetDefaultPrinter(printerName); err != nil {
-			log.Printf("[ERROR]: could not set printer %s as default, reason: %v\n", printerName, err)
-			if err := msg.Respond([]byte(err.Error())); err != nil {
-				log.Printf("[ERROR]: could not respond to agent.removeprinter message, reason: %v\n", err)
+	// Rejected commands are terminated so they're not delivered again, unless the
+	// nonce cache couldn't be used and they're delivered again later
+	start := time.Now()
+	if err := a.verifyCommand(c); err != nil {
+		if errors.Is(err, errNonceCacheUnavailable) {
+			slog.Warn("command will be delivered again, the nonce cache is not available", "subject", c.Subject)
+			if err := msg.NakWithDelay(NONCE_CACHE_RETRY_DELAY); err != nil {
+				slog.Error("could not NAK the message", "subject", c.Subject, "error", err)
 			}
 			return
 		}
 
-		if err := msg.Respond(nil); err != nil {
-			log.Printf("[ERROR]: could not respond to agent.removeprinter message, reason: %v\n", err)
+		slog.Error("command has been rejected", "subject", c.Subject, "error", err)
+		a.auditCommand(c, start, err)
+		if err := msg.Term(); err != nil {
+			slog.Error("could not terminate the rejected message", "subject", c.Subject, "error", err)
 		}
-	})
-
+		return
+	}
+	var err error
+	switch msg.Subject() {
+	case "agent.enable." + a.Config.UUID:
+		err = a.EnableAgentHandler(msg)
+	case "agent.disable." + a.Config.UUID:
+		err = a.DisableAgentHandler(msg)
+	case "agent.report." + a.Config.UUID:
+		err = a.RunReportHandler(msg)
+	case "agent.certificate." + a.Config.UUID:
+		err = a.AgentCertificateHandler(msg)
+	}
 	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to default printer message, reason: %v", err)
+		slog.Error("command could not be run", "subject", c.Subject, "error", err)
+	}
+
+	if c.Privileged {
+		a.auditCommand(c, start, err)
 	}
-	return nil
 }
 
-func (a *Agent) RemovePrinter() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.removeprinter."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-		printerName := string(msg.Data)
-		if printerName == "" {
-			log.Println("[ERROR]: printer name cannot be empty")
-			return
-		}
-		log.Printf("[INFO]: remove %s printer request received", printerName)
+func (a *Agent) SetDefaultPrinterHandler(msg *nats.Msg) error {
+	printerName := string(msg.Data)
+	if printerName == "" {
+		return errors.New("printer name cannot be empty")
+	}
+	slog.Info(fmt.Sprintf("set %s printer as default request received", printerName))
 
-		if err := printers.RemovePrinter(printerName); err != nil {
-			log.Printf("[ERROR]: could not remove %s printer, reason: %v\n", printerName, err)
-			if err := msg.Respond(nil); err != nil {
-				log.Printf("[ERROR]: could not respond to agent.removeprinter message, reason: %v\n", err)
-			}
-			return
-		}
+	if err := printers.SetDefaultPrinter(printerName); err != nil {
+		return fmt.Errorf("could not set printer %s as default, reason: %v", printerName, err)
+	}
+
+	if err := msg.Respond(nil); err != nil {
+		slog.Error("could not respond to agent.defaultprinter message", "error", err)
+	}
+	return nil
+}
+
+func (a *Agent) RemovePrinterHandler(msg *nats.Msg) error {
+	printerName := string(msg.Data)
+	if printerName == "" {
+		return errors.New("printer name cannot be empty")
+	}
+	slog.Info(fmt.Sprintf("remove %s printer request received", printerName))
 
+	if err := printers.RemovePrinter(printerName); err != nil {
 		if err := msg.Respond(nil); err != nil {
-			log.Printf("[ERROR]: could not respond to agent.removeprinter message, reason: %v\n", err)
+			slog.Error("could not respond to agent.removeprinter message", "error", err)
 		}
-	})
+		return handlers.Responded(fmt.Errorf("could not remove %s printer, reason: %v", printerName, err))
+	}
 
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to remove printer message, reason: %v", err)
+	if err := msg.Respond(nil); err != nil {
+		slog.Error("could not respond to agent.removeprinter message", "error", err)
 	}
 	return nil
 }
@@ -922,216 +1040,133 @@ func (a *Agent) SendProfileReport(report *openuem_nats.ProfileReport) error {
 		return err
 	}
 
-	if _, err := a.NATSConnection.Request("wingetcfg.report", data, 2*time.Minute); err != nil {
+	if _, err := a.Payloads.Request(a.NATSConnection, "wingetcfg.report", data, 2*time.Minute); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (a *Agent) StartRustDeskSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.rustdesk.start."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-
-		rd := rustdesk.New()
-
-		if err := rd.GetInstallationInfo(); err != nil {
-			rustdesk.RustDeskRespond(msg, "", err.Error())
-			return
-		}
-
-		id, err := rd.GetRustDeskID()
-		if err != nil {
-			rustdesk.RustDeskRespond(msg, "", err.Error())
-			return
-		}
-
-		if err := rd.SetRustDeskPassword(msg.Data); err != nil {
-			rustdesk.RustDeskRespond(msg, "", err.Error())
-			return
-		}
-
-		if err := rd.Configure(msg.Data); err != nil {
-			rustdesk.RustDeskRespond(msg, "", err.Error())
-			return
-		}
+func (a *Agent) StartRustDeskHandler(msg *nats.Msg) error {
+	rd := rustdesk.New()
 
-		// Send ID to the console
-		rustdesk.RustDeskRespond(msg, id, "")
-	})
+	if err := rd.GetInstallationInfo(); err != nil {
+		return err
+	}
 
+	id, err := rd.GetRustDeskID()
 	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to rustdesk start subject, reason: %v", err)
+		return err
 	}
-	return nil
-}
 
-func (a *Agent) StopRustDeskSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.rustdesk.stop."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-		rd := rustdesk.New()
-
-		if err := rd.GetInstallationInfo(); err != nil {
-			rustdesk.RustDeskRespond(msg, "", err.Error())
-			return
-		}
-		if err := rd.KillRustDeskProcess(); err != nil {
-			rustdesk.RustDeskRespond(msg, "", err.Error())
-			return
-		}
-
-		if err := rd.ConfigRollBack(); err != nil {
-			rustdesk.RustDeskRespond(msg, "", err.Error())
-			return
-		}
-
-		rustdesk.RustDeskRespond(msg, "", "")
-	})
+	if err := rd.SetRustDeskPassword(msg.Data); err != nil {
+		return err
+	}
 
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to rustdesk stop subject, reason: %v", err)
+	if err := rd.Configure(msg.Data); err != nil {
+		return err
 	}
+
+	// Send ID to the console
+	rustdesk.RustDeskRespond(msg, id, "")
 	return nil
 }
 
-func (a *Agent) InstallNetBirdSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.netbird.install."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
+func (a *Agent) StopRustDeskHandler(msg *nats.Msg) error {
+	rd := rustdesk.New()
 
-		data, err := netbird.Install()
-		if err != nil {
-			netbird.Respond(msg, &openuem_nats.Netbird{Error: err.Error()})
-			return
-		}
-
-		//NetBird has been installed
-		log.Println("[INFO]: the NetBird agent binary has been installed")
-		netbird.Respond(msg, data)
-	})
+	if err := rd.GetInstallationInfo(); err != nil {
+		return err
+	}
+	if err := rd.KillRustDeskProcess(); err != nil {
+		return err
+	}
 
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to netbird install subject, reason: %v", err)
+	if err := rd.ConfigRollBack(); err != nil {
+		return err
 	}
+
+	rustdesk.RustDeskRespond(msg, "", "")
 	return nil
 }
 
-func (a *Agent) RegisterNetBirdSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.netbird.register."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-
-		data, err := netbird.Register(msg.Data)
-		if err != nil {
-			netbird.Respond(msg, &openuem_nats.Netbird{Error: err.Error()})
-			return
-		}
-
-		//NetBird has been registered
-		log.Println("[INFO]: the NetBird agent binary has been registered")
-		netbird.Respond(msg, data)
-	})
-
+func (a *Agent) InstallNetBirdHandler(msg *nats.Msg) error {
+	data, err := netbird.Install()
 	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to netbird install subject, reason: %v", err)
+		return err
 	}
+
+	//NetBird has been installed
+	slog.Info("the NetBird agent binary has been installed")
+	netbird.Respond(msg, data)
 	return nil
 }
 
-func (a *Agent) UninstallNetBirdSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.netbird.uninstall."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-
-		if err := netbird.Uninstall(); err != nil {
-			netbird.Respond(msg, &openuem_nats.Netbird{Error: err.Error()})
-			return
-		}
-
-		//NetBird has been uninstalled
-		log.Println("[INFO]: the NetBird agent binary has been uninstalled")
-		netbird.Respond(msg, &openuem_nats.Netbird{})
-	})
-
+func (a *Agent) RegisterNetBirdHandler(msg *nats.Msg) error {
+	data, err := netbird.Register(msg.Data)
 	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to netbird uninstall subject, reason: %v", err)
+		return err
 	}
+
+	//NetBird has been registered
+	slog.Info("the NetBird agent binary has been registered")
+	netbird.Respond(msg, data)
 	return nil
 }
 
-func (a *Agent) SwitchProfileNetBirdSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.netbird.switchprofile."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-
-		request := openuem_nats.NetbirdSettings{}
-		if err := json.Unmarshal(msg.Data, &request); err != nil {
-			log.Printf("[ERROR]: could not unmarshal the NetBird switch profile request, reason: %v", err)
-			return
-		}
-
-		data, err := netbird.SwitchProfile(request)
-		if err != nil {
-			netbird.Respond(msg, &openuem_nats.Netbird{Error: err.Error()})
-			return
-		}
+func (a *Agent) UninstallNetBirdHandler(msg *nats.Msg) error {
+	if err := netbird.Uninstall(); err != nil {
+		return err
+	}
 
-		//NetBird profile has been switched
-		log.Println("[INFO]: the NetBird profile has been switched")
-		netbird.Respond(msg, data)
-	})
+	//NetBird has been uninstalled
+	slog.Info("the NetBird agent binary has been uninstalled")
+	netbird.Respond(msg, &openuem_nats.Netbird{})
+	return nil
+}
 
+func (a *Agent) SwitchProfileNetBirdHandler(msg *nats.Msg, request openuem_nats.NetbirdSettings) error {
+	data, err := netbird.SwitchProfile(request)
 	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to netbird switch profile subject, reason: %v", err)
+		return err
 	}
+
+	//NetBird profile has been switched
+	slog.Info("the NetBird profile has been switched")
+	netbird.Respond(msg, data)
 	return nil
 }
 
-func (a *Agent) NetBirdUpSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.netbird.up."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-
-		data, err := netbird.NetbirdUp(msg.Data)
-		if err != nil {
-			netbird.Respond(msg, &openuem_nats.Netbird{Error: err.Error()})
-			return
-		}
-
-		log.Println("[INFO]: the NetBird up has been executed")
-		netbird.Respond(msg, data)
-	})
-
+func (a *Agent) NetBirdUpHandler(msg *nats.Msg) error {
+	data, err := netbird.NetbirdUp(msg.Data)
 	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to netbird up subject, reason: %v", err)
+		return err
 	}
+
+	slog.Info("the NetBird up has been executed")
+	netbird.Respond(msg, data)
 	return nil
 }
 
-func (a *Agent) NetBirdDownSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.netbird.down."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-
-		data, err := netbird.NetbirdDown(msg.Data)
-		if err != nil {
-			netbird.Respond(msg, &openuem_nats.Netbird{Error: err.Error()})
-			return
-		}
-
-		log.Println("[INFO]: the NetBird down has been executed")
-		netbird.Respond(msg, data)
-	})
-
+func (a *Agent) NetBirdDownHandler(msg *nats.Msg) error {
+	data, err := netbird.NetbirdDown(msg.Data)
 	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to netbird up subject, reason: %v", err)
+		return err
 	}
+
+	slog.Info("the NetBird down has been executed")
+	netbird.Respond(msg, data)
 	return nil
 }
 
-func (a *Agent) RefreshNetBirdSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.netbird.refresh."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-
-		data, err := netbird.RefreshInfo(msg.Data)
-		if err != nil {
-			netbird.Respond(msg, &openuem_nats.Netbird{Error: err.Error()})
-			return
-		}
-
-		//NetBird profile has been switched
-		log.Println("[INFO]: the NetBird info has been refreshed")
-		netbird.Respond(msg, data)
-	})
-
+func (a *Agent) RefreshNetBirdHandler(msg *nats.Msg) error {
+	data, err := netbird.RefreshInfo(msg.Data)
 	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to netbird refresh subject, reason: %v", err)
+		return err
 	}
+
+	slog.Info("the NetBird info has been refreshed")
+	netbird.Respond(msg, data)
 	return nil
 }
 
@@ -1154,9 +1189,9 @@ func (a *Agent) ApplyNetBirdConfiguration(p openuem_nats.ProfileConfig, taskCont
 					taskReport.Failed = true
 					taskReport.StdErr = err.Error()
 				} else {
-					log.Println("[INFO]: the NetBird agent binary has been installed")
+					slog.Info("the NetBird agent binary has been installed")
 					if err := dsc.SetTaskAsSuccessfull(t.ID, taskControlPath, taskControl); err != nil {
-						log.Printf("[ERROR]: could not save the task as successfull, reason: %v", err)
+						slog.Error("could not save the task as successfull", "error", err)
 					}
 					success = true
 				}
@@ -1171,9 +1206,9 @@ func (a *Agent) ApplyNetBirdConfiguration(p openuem_nats.ProfileConfig, taskCont
 					taskReport.Failed = true
 					taskReport.StdErr = err.Error()
 				} else {
-					log.Println("[INFO]: the NetBird agent binary has been uninstalled")
+					slog.Info("the NetBird agent binary has been uninstalled")
 					if err := dsc.SetTaskAsSuccessfull(t.ID, taskControlPath, taskControl); err != nil {
-						log.Printf("[ERROR]: could not save the task as successfull, reason: %v", err)
+						slog.Error("could not save the task as successfull", "error", err)
 					}
 					success = true
 				}
@@ -1193,9 +1228,9 @@ func (a *Agent) ApplyNetBirdConfiguration(p openuem_nats.ProfileConfig, taskCont
 					taskReport.Failed = true
 					taskReport.StdErr = err.Error()
 				} else {
-					log.Println("[INFO]: the NetBird agent has been registered")
+					slog.Info("the NetBird agent has been registered")
 					if err := dsc.SetTaskAsSuccessfull(t.ID, taskControlPath, taskControl); err != nil {
-						log.Printf("[ERROR]: could not save the task as successfull, reason: %v", err)
+						slog.Error("could not save the task as successfull", "error", err)
 					}
 					success = true
 				}
@@ -1212,15 +1247,18 @@ func (a *Agent) ApplyNetBirdConfiguration(p openuem_nats.ProfileConfig, taskCont
 	return taskReports, nil
 }
 
-func (a *Agent) PingSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.ping."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-		if err := msg.Respond(nil); err != nil {
-			log.Printf("[ERROR]: could not respond to ping message, reason: %v", err)
-		}
-	})
-
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent ping subject, reason: %v", err)
+func (a *Agent) PingHandler(msg *nats.Msg) error {
+	if err := msg.Respond(nil); err != nil {
+		return handlers.Responded(fmt.Errorf("could not respond to ping message, reason: %v", err))
 	}
 	return nil
 }
+
+// profileErrorResponse answers a failed profile task request with a profile report
+func (a *Agent) profileErrorResponse(err error) []byte {
+	data, mErr := json.Marshal(openuem_nats.ProfileReport{AgentID: a.Config.UUID, Error: err.Error()})
+	if mErr != nil {
+		slog.Error("could not marshal profile report response", "error", mErr)
+	}
+	return data
+}
@@ -5,12 +5,11 @@ package agent
 import (
 	"bytes"
 	"context"
-	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"os/user"
@@ -27,85 +26,83 @@ import (
 	"github.com/apenella/go-ansible/v2/pkg/execute/workflow"
 	galaxy "github.com/apenella/go-ansible/v2/pkg/galaxy/collection/install"
 	"github.com/apenella/go-ansible/v2/pkg/playbook"
-	"github.com/dgraph-io/badger/v4"
 	"github.com/go-co-op/gocron/v2"
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 	openuem_nats "github.com/open-uem/nats"
 	"github.com/open-uem/openuem-agent/internal/agent/dsc"
+	"github.com/open-uem/openuem-agent/internal/agent/handlers"
 	rd "github.com/open-uem/openuem-agent/internal/commands/remote-desktop"
 	openuem_runtime "github.com/open-uem/openuem-agent/internal/commands/runtime"
-	"github.com/open-uem/openuem-agent/internal/commands/sftp"
+	"github.com/open-uem/openuem-agent/internal/logger"
+	"github.com/open-uem/openuem-agent/internal/metrics"
 	ansiblecfg "github.com/open-uem/openuem-ansible-config/ansible"
-	openuem_utils "github.com/open-uem/utils"
 	"gopkg.in/yaml.v3"
 )
 
 func (a *Agent) Start() {
 
-	log.Println("[INFO]: agent has been started!")
+	slog.Info("agent has been started!")
 
 	// Log agent associated user
 	currentUser, err := user.Current()
 	if err != nil {
-		log.Printf("[ERROR]: %v", err)
+		slog.Error(err.Error())
 	}
-	log.Printf("[INFO]: agent is run as %s", currentUser.Username)
+	slog.Info(fmt.Sprintf("agent is run as %s", currentUser.Username))
 
 	a.Config.ExecuteTaskEveryXMinutes = SCHEDULETIME_5MIN
 	if err := a.Config.WriteConfig(); err != nil {
-		log.Fatalf("[FATAL]: could not write agent config: %v", err)
+		logger.Fatal("could not write agent config", "error", err)
 	}
 
 	// Agent started so reset restart required flag
 	if err := a.Config.ResetRestartRequiredFlag(); err != nil {
-		log.Fatalf("[FATAL]: could not reset restart required flag, reason: %v", err)
+		logger.Fatal("could not reset restart required flag", "error", err)
 	}
 
 	// Start task scheduler
 	a.TaskScheduler.Start()
-	log.Println("[INFO]: task scheduler has started!")
+	slog.Info("task scheduler has started!")
 
-	// Start BadgerDB KV and SFTP server only if port is set
-	if a.Config.SFTPPort != "" && !a.Config.SFTPDisabled {
-		cwd, err := Getwd()
-		if err != nil {
-			log.Println("[ERROR]: could not get working directory")
-			return
-		}
+	// Start local status server
+	a.StartStatusServer()
 
-		badgerPath := filepath.Join(cwd, "badgerdb")
-		if err := os.RemoveAll(badgerPath); err != nil {
-			log.Println("[ERROR]: could not remove badgerdb directory")
-			return
-		}
+	// Start metrics server if enabled
+	a.StartMetricsServer()
 
-		if err := os.MkdirAll(badgerPath, 0660); err != nil {
-			log.Println("[ERROR]: could not recreate badgerdb directory")
-			return
-		}
+	// Watch the config file so changes are applied without a restart
+	a.startConfigWatchJob()
 
-		a.BadgerDB, err = badger.Open(badger.DefaultOptions(filepath.Join(cwd, "badgerdb")))
-		if err != nil {
-			log.Printf("[ERROR]: %v", err)
-		}
+	// Start BadgerDB KV and SFTP server only if port is set
+	a.StartSFTPServer()
 
-		go func() {
-			a.SFTPServer = sftp.New()
-			err = a.SFTPServer.Serve(":"+a.Config.SFTPPort, a.SFTPCert, a.CACert, a.BadgerDB)
-			if err != nil {
-				log.Printf("[ERROR]: %v", err)
-			}
-			log.Println("[INFO]: SFTP server has started!")
-		}()
-	} else {
-		log.Println("[INFO]: SFTP port is not set so SFTP server is not started!")
-	}
+	// Open the spool where reports are kept while the NATS server is not reachable
+	a.OpenReportSpool(a.Config.DataDir)
+
+	// Open the outbox where deployment results are kept until the worker acknowledges them
+	a.OpenDeployOutbox()
+	a.OpenAuditLog()
+	a.OpenReplayCache()
+	a.OpenMaintenanceQueue()
+	a.startMaintenanceJob()
+
+	// Certificates are checked even if the agent starts offline or can't send its first report
+	a.startCertificateExpiryJob()
 
 	// Try to connect to NATS server and start a reconnect job if failed
-	a.NATSConnection, err = openuem_nats.ConnectWithNATS(a.Config.NATSServers, a.Config.AgentCert, a.Config.AgentKey, a.Config.CACert, a.Config.WebSocketPort)
+	a.NATSConnection, err = a.ConnectToNATS()
 	if err != nil {
-		log.Printf("[ERROR]: %v", err)
+		slog.Error(err.Error())
+
+		// While the agent is offline reports are kept in the spool
+		if a.Config.Enabled {
+			if r := a.RunReport(); r != nil {
+				metrics.ReportSendFailures.Inc()
+				a.SpoolReport(r)
+			}
+			a.startReportJob()
+		}
 		a.startNATSConnectJob()
 		return
 	}
@@ -113,28 +110,26 @@ func (a *Agent) Start() {
 
 	// Run report for the first time after start if agent is enabled
 	if a.Config.Enabled {
-		r := a.RunReport()
-		if r == nil {
-			return
-		}
+		if r := a.RunReport(); r != nil {
+			// Send first report to NATS
+			if err := a.SendOrSpoolReport(r); err != nil {
+				metrics.ReportSendFailures.Inc()
+				a.Config.ExecuteTaskEveryXMinutes = SCHEDULETIME_5MIN // Try to send it again in 5 minutes
+				slog.Error("report could not be send to NATS server!", "error", err)
+			} else {
+				// Get remote config
+				if err := a.GetRemoteConfig(); err != nil {
+					slog.Error("could not get remote config", "error", err)
+				}
+				slog.Info("remote config requested")
 
-		// Send first report to NATS
-		if err := a.SendReport(r); err != nil {
-			a.Config.ExecuteTaskEveryXMinutes = SCHEDULETIME_5MIN // Try to send it again in 5 minutes
-			log.Printf("[ERROR]: report could not be send to NATS server!, reason: %s\n", err.Error())
-		} else {
-			// Get remote config
-			if err := a.GetRemoteConfig(); err != nil {
-				log.Printf("[ERROR]: could not get remote config %v", err)
+				// Start scheduled report job with default frequency
+				a.Config.ExecuteTaskEveryXMinutes = a.Config.DefaultFrequency
 			}
-			log.Println("[INFO]: remote config requested")
-
-			// Start scheduled report job with default frequency
-			a.Config.ExecuteTaskEveryXMinutes = a.Config.DefaultFrequency
 		}
 
 		if err := a.Config.WriteConfig(); err != nil {
-			log.Fatalf("[FATAL]: could not write agent config: %v", err)
+			logger.Fatal("could not write agent config", "error", err)
 		}
 
 		a.startReportJob()
@@ -145,140 +140,84 @@ func (a *Agent) Start() {
 	a.startCheckForAnsibleProfilesJob()
 }
 
-func (a *Agent) startNATSConnectJob() error {
-	var err error
-
-	if a.Config.ExecuteTaskEveryXMinutes == 0 {
-		a.Config.ExecuteTaskEveryXMinutes = SCHEDULETIME_5MIN
+func (a *Agent) startJobsAfterNATSConnect() {
+	// The report job may have been started while the agent was offline
+	if a.ReportJob == nil {
+		a.startReportJob()
 	}
+	a.startPendingACKJob()
+	a.startCheckForAnsibleProfilesJob()
+}
 
-	// Create task for running the agent
-	a.NATSConnectJob, err = a.TaskScheduler.NewJob(
-		gocron.DurationJob(
-			time.Duration(time.Duration(a.Config.ExecuteTaskEveryXMinutes)*time.Minute),
-		),
-		gocron.NewTask(
-			func() {
-				a.NATSConnection, err = openuem_nats.ConnectWithNATS(a.Config.NATSServers, a.Config.AgentCert, a.Config.AgentKey, a.Config.CACert, a.Config.WebSocketPort)
-				if err != nil {
-					return
-				}
-
-				// We have connected
-				a.TaskScheduler.RemoveJob(a.NATSConnectJob.ID())
-				a.SubscribeToNATSSubjects()
+func (a *Agent) PlatformHandlers() []*handlers.Handler {
+	q := handlers.MANAGEMENT_QUEUE
 
-				// Start the rest of tasks
-				a.startReportJob()
-				a.startPendingACKJob()
-				a.startCheckForAnsibleProfilesJob()
-			},
-		),
-	)
-	if err != nil {
-		log.Fatalf("[FATAL]: could not start the NATS connect job: %v", err)
-		return err
+	return []*handlers.Handler{
+		handlers.JSON("agent.startvnc", q, a.StartRemoteDesktopHandler).WithAudit(),
+		handlers.JSON("agent.reboot", q, a.RebootHandler).WithAudit().WithDeferral(),
+		handlers.JSON("agent.poweroff", q, a.PowerOffHandler).WithAudit().WithDeferral(),
+		handlers.JSON("agent.newconfig", "", a.NewConfigHandler).AsBroadcast().WithAudit(),
+		handlers.New("agent.ansible", q, a.AgentRunTaskHandler).WithPayload("nats.ProfileConfig (YAML)").WithErrorResponse(a.profileErrorResponse).WithAudit().WithDeferral(),
+		handlers.New("agent.runprofile", q, a.RunProfileHandler).WithAudit().WithDeferral(),
 	}
-	log.Printf("[INFO]: new NATS connect job has been scheduled every %d minutes", a.Config.ExecuteTaskEveryXMinutes)
-	return nil
 }
 
-func (a *Agent) StartRemoteDesktopSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.startvnc."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-
-		// Instantiate new vnc server, but first try to check if certificates are there
-		a.GetServerCertificate()
-		if a.ServerCertPath == "" || a.ServerKeyPath == "" {
-			log.Println("[ERROR]: Remote Desktop service requires a server certificate that it's not ready")
-			return
-		}
-
-		v, err := rd.New(a.ServerCertPath, a.ServerKeyPath, "", a.Config.VNCProxyPort)
-		if err != nil {
-			log.Println("[ERROR]: could not get a Remote Desktop service")
-			return
-		}
-
-		// Unmarshal data
-		var rdConn openuem_nats.VNCConnection
-		if err := json.Unmarshal(msg.Data, &rdConn); err != nil {
-			log.Println("[ERROR]: could not unmarshall Remote Desktop connection")
-			return
-		}
+func (a *Agent) StartRemoteDesktopHandler(msg *nats.Msg, rdConn openuem_nats.VNCConnection) error {
+	// Instantiate new vnc server, but first try to check if certificates are there
+	a.GetServerCertificate()
+	if a.ServerCertPath == "" || a.ServerKeyPath == "" {
+		return errors.New("Remote Desktop service requires a server certificate that it's not ready")
+	}
 
-		// Start Remote Desktop service
-		a.RemoteDesktop = v
-		v.Start(rdConn.PIN, rdConn.NotifyUser)
+	v, err := rd.New(a.ServerCertPath, a.ServerKeyPath, "", a.Config.VNCProxyPort)
+	if err != nil {
+		return fmt.Errorf("could not get a Remote Desktop service, reason: %v", err)
+	}
 
-		if err := msg.Respond([]byte("Remote Desktop service started!")); err != nil {
-			log.Printf("[ERROR]: could not respond to agent start vnc message, reason: %v\n", err)
-		}
-	})
+	// Start Remote Desktop service
+	a.RemoteDesktop = v
+	v.Start(rdConn.PIN, rdConn.NotifyUser)
+	metrics.VNCSessions.Inc()
 
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent start vnc, reason: %v", err)
+	if err := msg.Respond([]byte("Remote Desktop service started!")); err != nil {
+		slog.Error("could not respond to agent start vnc message", "error", err)
 	}
 	return nil
 }
-func (a *Agent) RebootSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.reboot."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-		log.Println("[INFO]: reboot request received")
-		if err := msg.Respond([]byte("Reboot!")); err != nil {
-			log.Printf("[ERROR]: could not respond to agent reboot message, reason: %v\n", err)
-		}
+func (a *Agent) RebootHandler(msg *nats.Msg, action openuem_nats.RebootOrRestart) error {
+	slog.Info("reboot request received")
+	if err := msg.Respond([]byte("Reboot!")); err != nil {
+		slog.Error("could not respond to agent reboot message", "error", err)
+	}
 
-		action := openuem_nats.RebootOrRestart{}
-		if err := json.Unmarshal(msg.Data, &action); err != nil {
-			log.Printf("[ERROR]: could not unmarshal to agent reboot message, reason: %v\n", err)
-			return
+	when := int(time.Until(action.Date).Minutes())
+	if when > 0 {
+		if err := exec.Command("shutdown", "-r", strconv.Itoa(when)).Run(); err != nil {
+			return handlers.Responded(fmt.Errorf("could not initiate power off, reason: %v", err))
 		}
-
-		when := int(time.Until(action.Date).Minutes())
-		if when > 0 {
-			if err := exec.Command("shutdown", "-r", strconv.Itoa(when)).Run(); err != nil {
-				log.Printf("[ERROR]: could not initiate power off, reason: %v", err)
-			}
-		} else {
-			if err := exec.Command("shutdown", "-r", "now").Run(); err != nil {
-				log.Printf("[ERROR]: could not initiate shutdown, reason: %v", err)
-			}
+	} else {
+		if err := exec.Command("shutdown", "-r", "now").Run(); err != nil {
+			return handlers.Responded(fmt.Errorf("could not initiate shutdown, reason: %v", err))
 		}
-	})
-
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent reboot, reason: %v", err)
 	}
 	return nil
 }
 
-func (a *Agent) PowerOffSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.poweroff."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-		log.Println("[INFO]: power off request received")
-		if err := msg.Respond([]byte("Power Off!")); err != nil {
-			log.Printf("[ERROR]: could not respond to agent power off message, reason: %v\n", err)
-			return
-		}
+func (a *Agent) PowerOffHandler(msg *nats.Msg, action openuem_nats.RebootOrRestart) error {
+	slog.Info("power off request received")
+	if err := msg.Respond([]byte("Power Off!")); err != nil {
+		return handlers.Responded(fmt.Errorf("could not respond to agent power off message, reason: %v", err))
+	}
 
-		action := openuem_nats.RebootOrRestart{}
-		if err := json.Unmarshal(msg.Data, &action); err != nil {
-			log.Printf("[ERROR]: could not unmarshal to agent power off message, reason: %v\n", err)
-			return
+	when := int(time.Until(action.Date).Minutes())
+	if when > 0 {
+		if err := exec.Command("shutdown", "-h", strconv.Itoa(when)).Run(); err != nil {
+			return handlers.Responded(fmt.Errorf("could not initiate power off, reason: %v", err))
 		}
-
-		when := int(time.Until(action.Date).Minutes())
-		if when > 0 {
-			if err := exec.Command("shutdown", "-h", strconv.Itoa(when)).Run(); err != nil {
-				log.Printf("[ERROR]: could not initiate power off, reason: %v", err)
-			}
-		} else {
-			if err := exec.Command("shutdown", "-h", "now").Run(); err != nil {
-				log.Printf("[ERROR]: could not initiate shutdown, reason: %v", err)
-			}
+	} else {
+		if err := exec.Command("shutdown", "-h", "now").Run(); err != nil {
+			return handlers.Responded(fmt.Errorf("could not initiate shutdown, reason: %v", err))
 		}
-	})
-
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent power off, reason: %v", err)
 	}
 	return nil
 }
@@ -288,115 +227,50 @@ func (a *Agent) RescheduleAnsibleConfigureTask() {
 	a.startCheckForAnsibleProfilesJob()
 }
 
-func (a *Agent) NewConfigSubscribe() error {
-	_, err := a.NATSConnection.Subscribe("agent.newconfig", func(msg *nats.Msg) {
-
-		config := openuem_nats.Config{}
-		err := json.Unmarshal(msg.Data, &config)
-		if err != nil {
-			log.Printf("[ERROR]: could not get new config to apply, reason: %v\n", err)
-			return
-		}
-
-		a.Config.DefaultFrequency = config.AgentFrequency
-		a.Config.SFTPDisabled = config.SFTPDisabled
-		a.Config.RemoteAssistanceDisabled = config.RemoteAssistanceDisabled
-
-		// Should we re-schedule agent report?
-		if a.Config.ExecuteTaskEveryXMinutes != SCHEDULETIME_5MIN {
-			a.Config.ExecuteTaskEveryXMinutes = a.Config.DefaultFrequency
-			a.RescheduleReportRunTask()
-		}
-
-		// Should we re-schedule ansible configure task?
-		if config.WinGetFrequency != 0 {
-			a.Config.WingetConfigureFrequency = config.WinGetFrequency
-			a.RescheduleAnsibleConfigureTask()
-		}
-
-		if err := a.Config.WriteConfig(); err != nil {
-			log.Fatalf("[FATAL]: could not write agent config: %v", err)
-		}
+// RescheduleProfilesTask restarts the profiles job so it uses the new frequency
+func (a *Agent) RescheduleProfilesTask() {
+	a.RescheduleAnsibleConfigureTask()
+}
 
-		if err := a.Config.SetRestartRequiredFlag(); err != nil {
-			log.Printf("[ERROR]: could not set restart required flag, reason: %v\n", err)
-			return
-		}
+func (a *Agent) NewConfigHandler(msg *nats.Msg, config NewConfigRequest) error {
+	c := a.Config
+	c.DefaultFrequency = config.AgentFrequency
+	c.SFTPDisabled = config.SFTPDisabled
+	c.RemoteAssistanceDisabled = config.RemoteAssistanceDisabled
+	c.WingetConfigureFrequency = config.WinGetFrequency
+	if err := applyMaintenanceWindows(&c, config.MaintenanceWindows); err != nil {
+		return err
+	}
 
-		log.Println("[INFO]: new config has been set from console")
-	})
+	// Reports and profiles are rescheduled and the SFTP server is started or stopped if needed
+	a.ApplyConfig(c)
 
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent uninstall package, reason: %v", err)
+	if err := a.Config.WriteConfig(); err != nil {
+		logger.Fatal("could not write agent config", "error", err)
 	}
+
+	slog.Info("new config has been set from console")
 	return nil
 }
 
-func (a *Agent) AgentCertificateHandler(msg jetstream.Msg) {
-
+func (a *Agent) AgentCertificateHandler(msg jetstream.Msg) error {
 	data := openuem_nats.AgentCertificateData{}
 
 	if err := json.Unmarshal(msg.Data(), &data); err != nil {
-		log.Printf("[ERROR]: could not unmarshal agent certificate data, reason: %v\n", err)
-		if err := msg.Ack(); err != nil {
-			log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-			return
-		}
-
-		return
+		ackMessage(msg)
+		return fmt.Errorf("could not unmarshal agent certificate data, reason: %v", err)
 	}
 
-	wd := "/etc/openuem-agent"
-
-	if err := os.MkdirAll(filepath.Join(wd, "certificates"), 0660); err != nil {
-		log.Printf("[ERROR]: could not create certificates folder, reason: %v\n", err)
-		if err := msg.Ack(); err != nil {
-			log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-		}
-		return
+	if err := a.InstallServerCertificate(data); err != nil {
+		ackMessage(msg)
+		return fmt.Errorf("could not install the server certificate, reason: %v", err)
 	}
 
-	keyPath := filepath.Join(wd, "certificates", "server.key")
-
-	privateKey, err := x509.ParsePKCS1PrivateKey(data.PrivateKeyBytes)
-	if err != nil {
-		log.Printf("[ERROR]: could not get private key, reason: %v\n", err)
-		if err := msg.Ack(); err != nil {
-			log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-		}
-		return
-	}
-
-	err = openuem_utils.SavePrivateKey(privateKey, keyPath)
-	if err != nil {
-		log.Printf("[ERROR]: could not save agent private key, reason: %v\n", err)
-		if err := msg.Ack(); err != nil {
-			log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-		}
-		return
-	}
-	log.Printf("[INFO]: Agent private key saved in %s", keyPath)
-
-	certPath := filepath.Join(wd, "certificates", "server.cer")
-	err = openuem_utils.SaveCertificate(data.CertBytes, certPath)
-	if err != nil {
-		log.Printf("[ERROR]: could not save agent certificate, reason: %v\n", err)
-		if err := msg.Ack(); err != nil {
-			log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-		}
-		return
-	}
-	log.Printf("[INFO]: Agent certificate saved in %s", certPath)
-
-	if err := msg.Ack(); err != nil {
-		log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-	}
+	ackMessage(msg)
 
 	// Finally run a new report to inform that the certificate is ready
-	r := a.RunReport()
-	if r == nil {
-		return
-	}
+	a.RunReport()
+	return nil
 }
 
 func (a *Agent) startCheckForAnsibleProfilesJob() error {
@@ -412,63 +286,47 @@ func (a *Agent) startCheckForAnsibleProfilesJob() error {
 			time.Duration(a.Config.WingetConfigureFrequency)*time.Minute,
 		),
 		gocron.NewTask(a.GetUnixConfigureProfiles),
+		gocron.WithName("ansible-profiles"),
 	)
 	if err != nil {
-		log.Fatalf("[FATAL]: could not start the check for Ansible profiles job, reason: %v", err)
+		logger.Fatal("could not start the check for Ansible profiles job", "error", err)
 		return err
 	}
-	log.Printf("[INFO]: new check for Ansible profiles job has been scheduled every %d minutes", a.Config.WingetConfigureFrequency)
+	slog.Info(fmt.Sprintf("new check for Ansible profiles job has been scheduled every %d minutes", a.Config.WingetConfigureFrequency))
 	return nil
 }
 
-func (a *Agent) GetServerCertificate() {
-
-	cwd := "/etc/openuem-agent"
-
-	serverCertPath := filepath.Join(cwd, "certificates", "server.cer")
-	_, err := openuem_utils.ReadPEMCertificate(serverCertPath)
-	if err != nil {
-		log.Printf("[ERROR]: could not read server certificate")
-	} else {
-		a.ServerCertPath = serverCertPath
-	}
-
-	serverKeyPath := filepath.Join(cwd, "certificates", "server.key")
-	_, err = openuem_utils.ReadPEMPrivateKey(serverKeyPath)
-	if err != nil {
-		log.Printf("[ERROR]: could not read server private key")
-	} else {
-		a.ServerKeyPath = serverKeyPath
-	}
+// runScheduledProfiles applies the profiles deferred to the maintenance window
+func (a *Agent) runScheduledProfiles() {
+	a.GetUnixConfigureProfiles()
 }
 
 func (a *Agent) GetUnixConfigureProfiles() {
-	if a.Config.Debug {
-		log.Println("[DEBUG]: running task Ansible profiles job")
+	slog.Debug("running task Ansible profiles job")
+
+	// Outside the maintenance windows the profiles are applied when the next one opens
+	if a.deferScheduledProfiles() {
+		return
 	}
 
 	profileRequest := openuem_nats.CfgProfiles{
 		AgentID: a.Config.UUID,
 	}
 
-	if a.Config.Debug {
-		log.Println("[DEBUG]: going to send a ansible.profile request")
-	}
+	slog.Debug("going to send a ansible.profile request")
 
 	data, err := json.Marshal(profileRequest)
 	if err != nil {
-		log.Printf("[ERROR]: could not marshal profile request, reason: %v", err)
+		slog.Error("could not marshal profile request", "error", err)
 	}
 
-	if a.Config.Debug {
-		log.Println("[DEBUG]: ansiblecfg.profile sending request")
-	}
+	slog.Debug("ansiblecfg.profile sending request")
 
 	msg, err := a.NATSConnection.Request("ansiblecfg.profiles", data, 5*time.Minute)
 	if err != nil {
-		log.Printf("[ERROR]: could not send request to agent worker, reason: %v", err)
+		slog.Error("could not send request to agent worker", "error", err)
 		if err := a.Config.SetRestartRequiredFlag(); err != nil {
-			log.Printf("[ERROR]: could not set restart required flag, reason: %v\n", err)
+			slog.Error("could not set restart required flag", "error", err)
 			return
 		}
 	}
@@ -480,29 +338,27 @@ func (a *Agent) ProcessProfileResponse(msg *nats.Msg, force bool) {
 	profiles := []openuem_nats.ProfileConfig{}
 
 	if a.Config.Debug {
-		log.Println("[DEBUG]: ansiblecfg.profile request sent")
+		slog.Debug("ansiblecfg.profile request sent")
 		if msg.Data != nil {
-			log.Println("[DEBUG]: received ansiblecfg.profile response")
+			slog.Debug("received ansiblecfg.profile response")
 		}
 	}
 
 	if err := yaml.Unmarshal(msg.Data, &profiles); err != nil {
-		log.Printf("[ERROR]: could not unmarshal profiles response from agent worker, reason: %v", err)
+		slog.Error("could not unmarshal profiles response from agent worker", "error", err)
 	}
 
-	if a.Config.Debug {
-		log.Println("[DEBUG]: ansiblecfg.profile response unmarshalled")
-	}
+	slog.Debug("ansiblecfg.profile response unmarshalled")
 
 	if len(profiles) > 0 {
 		if err := a.InstallCommunityGeneralCollection(); err != nil {
-			log.Printf("[ERROR]: could not install ansible community general collection, reason: %v", err)
+			slog.Error("could not install ansible community general collection", "error", err)
 		}
 	}
 
-	ansibleFolder, err := CreatePlaybooksFolder()
+	ansibleFolder, err := a.CreatePlaybooksFolder()
 	if err != nil {
-		log.Printf("[ERROR]: could not create playbooks folder %v", err)
+		slog.Error("could not create playbooks folder", "error", err)
 		return
 	}
 
@@ -510,7 +366,7 @@ func (a *Agent) ProcessProfileResponse(msg *nats.Msg, force bool) {
 	taskControl, err := dsc.ReadTaskControlFile(taskControlPath)
 
 	if err != nil {
-		log.Printf("[ERROR]: tasks control file is not available, reason %v", err)
+		slog.Error("tasks control file is not available", "error", err)
 		return
 	}
 
@@ -520,27 +376,31 @@ func (a *Agent) ProcessProfileResponse(msg *nats.Msg, force bool) {
 			ProfileID: p.ProfileID,
 			Success:   true,
 		}
-		// Ansible tasks
-		if a.Config.Debug {
-			log.Println("[DEBUG]: ansiblecfg.profile to be unmarshalled")
+
+		task, err := a.InFlight.StartProfile(profileReport)
+		if err != nil {
+			slog.Warn("profile has not been applied", "profile_id", p.ProfileID, "error", err)
+			break
 		}
 
+		// Ansible tasks
+		slog.Debug("ansiblecfg.profile to be unmarshalled")
+
 		errData := ""
 
 		if len(p.AnsibleConfig) > 0 {
 			cfg, err := yaml.Marshal(p.AnsibleConfig)
 			if err != nil {
-				log.Printf("[ERROR]: could not marshal YAML file with Ansible configuration, reason: %v", err)
+				slog.Error("could not marshal YAML file with Ansible configuration", "error", err)
+				task.Done()
 				continue
 			}
 
-			if a.Config.Debug {
-				log.Println("[DEBUG]: we're going to apply the configuration")
-			}
+			slog.Debug("we're going to apply the configuration")
 
 			tasks, err := a.ApplyConfiguration(p.ProfileID, cfg, taskControl, taskControlPath)
 			if err != nil {
-				log.Println("[ERROR]: could not apply YAML configuration file with Ansible")
+				slog.Error("could not apply YAML configuration file with Ansible")
 				profileReport.Error = err.Error()
 			}
 
@@ -554,11 +414,13 @@ func (a *Agent) ProcessProfileResponse(msg *nats.Msg, force bool) {
 			profileReport.Tasks = tasks
 		}
 
+		task.UpdateProfile(profileReport)
+
 		// Netbird tasks
 		if len(p.NetBirdConfig) > 0 {
 			tasks, err := a.ApplyNetBirdConfiguration(p, taskControl, taskControlPath)
 			if err != nil {
-				log.Println("[ERROR]: could not apply Netbird configuration file")
+				slog.Error("could not apply Netbird configuration file")
 
 				if errData != "" {
 					errData = strings.Join([]string{errData, err.Error()}, ",")
@@ -578,10 +440,17 @@ func (a *Agent) ProcessProfileResponse(msg *nats.Msg, force bool) {
 			profileReport.Tasks = append(profileReport.Tasks, tasks...)
 		}
 
+		for _, t := range profileReport.Tasks {
+			if t.Failed {
+				metrics.ProfileTaskFailures.Inc()
+			}
+		}
+
 		// Report if application was successful or not
 		if err := a.SendProfileReport(&profileReport); err != nil {
-			log.Println("[ERROR]: could not report if profile was applied succesfully or no")
+			slog.Error("could not report if profile was applied succesfully or no")
 		}
+		task.Done()
 	}
 }
 
@@ -593,13 +462,13 @@ func (a *Agent) ApplyConfiguration(profileID int, config []byte, taskControl *ds
 	tasks := []openuem_nats.TaskReport{}
 
 	if err := yaml.Unmarshal(config, &cfg); err != nil {
-		log.Printf("[ERROR]: could not unmarshall Ansible playbook folder %v", err)
+		slog.Error("could not unmarshall Ansible playbook folder", "error", err)
 		return nil, err
 	}
 
-	ansibleFolder, err := CreatePlaybooksFolder()
+	ansibleFolder, err := a.CreatePlaybooksFolder()
 	if err != nil {
-		log.Printf("[ERROR]: could not create playbooks folder %v", err)
+		slog.Error("could not create playbooks folder", "error", err)
 		return nil, err
 	}
 
@@ -615,66 +484,66 @@ func (a *Agent) ApplyConfiguration(profileID int, config []byte, taskControl *ds
 		} else {
 			// Clear stalled profile for more than 24 hours
 			if time.Now().After(when.Add(24 * time.Hour)) {
-				log.Printf("[INFO]: found previous task %s that hasn't be re-run for more than 24 hours", ID)
+				slog.Info("found previous task that hasn't be re-run for more than 24 hours", "task_id", ID)
 				taskControl.ProfilesRunning[ID] = time.Now()
 			} else {
-				log.Printf("[INFO]: previous profile %s is marked as running, not relaunching, ", ID)
+				slog.Info("previous profile is marked as running, not relaunching", "profile_id", ID)
 				return nil, nil
 			}
 		}
 	}
 	if err := dsc.SaveTaskControl(taskControlPath, taskControl); err != nil {
-		log.Printf("[ERROR]: could not save new profile %s running, reason: %v", ID, err)
+		slog.Error("could not save new profile running", "profile_id", ID, "error", err)
 		return nil, err
 	}
 
 	defer func() {
 		delete(taskControl.ProfilesRunning, ID)
 		if err := dsc.SaveTaskControl(taskControlPath, taskControl); err != nil {
-			log.Printf("[ERROR]: could not remove profile %s from running, reason: %v", ID, err)
+			slog.Error("could not remove profile from running", "profile_id", ID, "error", err)
 			return
 		}
 	}()
 
 	pbFile, err := os.CreateTemp(ansibleFolder, "*.yml")
 	if err != nil {
-		log.Printf("[ERROR]: could not create playbook file %v", err)
+		slog.Error("could not create playbook file", "error", err)
 		return nil, err
 	}
 
 	_, err = pbFile.WriteString("---\n\n")
 	if err != nil {
-		log.Printf("[ERROR]: could not write start of playbook to file %v", err)
+		slog.Error("could not write start of playbook to file", "error", err)
 		return nil, err
 	}
 
 	// Get current user for brew commands
 	username, err := openuem_runtime.GetLoggedInUser()
 	if err != nil {
-		log.Printf("[ERROR]: could not find the logged in user, reason %v", err)
+		slog.Error("could not find the logged in user", "error", err)
 		return nil, err
 	}
 
 	_, err = pbFile.WriteString(strings.ReplaceAll(string(config), "some_user", username))
 	if err != nil {
-		log.Printf("[ERROR]: could not write playbook file %v", err)
+		slog.Error("could not write playbook file", "error", err)
 		return nil, err
 	}
 
 	if err := pbFile.Close(); err != nil {
-		log.Printf("[ERROR]: could not close playbook file %v", err)
+		slog.Error("could not close playbook file", "error", err)
 		return nil, err
 	}
 
 	if !a.Config.Debug {
 		defer func() {
 			if err := os.Remove(pbFile.Name()); err != nil {
-				log.Printf("[ERROR]: could not delete playbook file %v", err)
+				slog.Error("could not delete playbook file", "error", err)
 			}
 		}()
 	}
 
-	log.Printf("[INFO]: received a request to apply profile %d", profileID)
+	slog.Info("received a request to apply profile", "profile_id", profileID)
 
 	buff := new(bytes.Buffer)
 
@@ -739,25 +608,19 @@ func (a *Agent) ApplyConfiguration(profileID int, config []byte, taskControl *ds
 		}
 	} else {
 		if executeErr != nil {
-			log.Printf("[INFO]: an error was found executing the Ansible playbook, reason: %v", err)
+			slog.Info("an error was found executing the Ansible playbook", "error", err)
 			return nil, err
 		}
 	}
 
-	log.Printf("[INFO]: an Ansible playbook was run for profile %d", profileID)
+	slog.Info("an Ansible playbook was run for profile", "profile_id", profileID)
 
 	return tasks, nil
 }
 
-func CreatePlaybooksFolder() (string, error) {
-	cwd, err := Getwd()
-	if err != nil {
-		log.Println("[ERROR]: could not get working directory")
-		return "", errors.New("could not get working directory")
-	}
-
-	folder := filepath.Join(cwd, "ansible")
-	return folder, os.MkdirAll(folder, 0660)
+func (a *Agent) CreatePlaybooksFolder() (string, error) {
+	folder := filepath.Join(a.Config.DataDir, "ansible")
+	return folder, os.MkdirAll(folder, 0700)
 }
 
 func (a *Agent) InstallCommunityGeneralCollection() error {
@@ -773,46 +636,46 @@ func (a *Agent) InstallCommunityGeneralCollection() error {
 	cmd := exec.Command(galaxyCommand, "collection", "list", "community.general")
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		log.Printf("[ERROR]: could not check if community.general collection is available, reason: %v", err)
+		slog.Error("could not check if community.general collection is available", "error", err)
 		return err
 	}
 
 	if string(out) == "" {
 		var galaxyInstallCollectionCmd *galaxy.AnsibleGalaxyCollectionInstallCmd
 
-		ansibleFolder, err := CreatePlaybooksFolder()
+		ansibleFolder, err := a.CreatePlaybooksFolder()
 		if err != nil {
-			log.Printf("[ERROR]: could not create playbooks folder %v", err)
+			slog.Error("could not create playbooks folder", "error", err)
 			return err
 		}
 
 		pbFile, err := os.CreateTemp(ansibleFolder, "*.yml")
 		if err != nil {
-			log.Printf("[ERROR]: could not create playbook file %v", err)
+			slog.Error("could not create playbook file", "error", err)
 			return err
 		}
 
 		_, err = pbFile.WriteString("---\n\ncollections:\n- name: community.general")
 		if err != nil {
-			log.Printf("[ERROR]: could not write start of playbook to file %v", err)
+			slog.Error("could not write start of playbook to file", "error", err)
 			return err
 		}
 
 		if err := pbFile.Close(); err != nil {
-			log.Printf("[ERROR]: could not close playbook file %v", err)
+			slog.Error("could not close playbook file", "error", err)
 			return err
 		}
 
 		defer func() {
 			if err := os.Remove(pbFile.Name()); err != nil {
-				log.Printf("[INFO]: could not remove playbook to install the general collection")
+				slog.Info("could not remove playbook to install the general collection")
 			}
 		}()
 
 		if !a.Config.Debug {
 			defer func() {
 				if err := os.Remove(pbFile.Name()); err != nil {
-					log.Printf("[ERROR]: could not delete playbook file %v", err)
+					slog.Error("could not delete playbook file", "error", err)
 				}
 			}()
 		}
@@ -847,244 +710,97 @@ func (a *Agent) InstallCommunityGeneralCollection() error {
 	return nil
 }
 
-func ReadDeploymentNotACK() ([]openuem_nats.DeployAction, error) {
-	cwd, err := Getwd()
-	if err != nil {
-		return nil, err
-	}
+// defaultDataFolder is where the agent keeps its certificates, databases and pending results unless DataDir is set
+func defaultDataFolder() string {
+	return "/etc/openuem-agent"
+}
 
-	filename := filepath.Join(cwd, "pending_acks.json")
-	jsonFile, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
-	if err != nil {
-		return nil, err
-	}
-	defer jsonFile.Close()
+func defaultLogFolder() string {
+	return "/var/log/openuem-agent"
+}
 
-	byteValue, err := io.ReadAll(jsonFile)
-	if err != nil {
-		return nil, err
-	}
+func (a *Agent) AgentRunTaskHandler(msg *nats.Msg) error {
+	var profileConfig openuem_nats.ProfileConfig
 
-	jActions := JSONActions{}
-	if len(byteValue) > 0 {
-		err = json.Unmarshal(byteValue, &jActions)
-		if err != nil {
-			return nil, err
-		}
-		return jActions.Actions, nil
+	// Unmarshal data
+	profileReport := openuem_nats.ProfileReport{
+		AgentID: a.Config.UUID,
 	}
 
-	return []openuem_nats.DeployAction{}, nil
-}
-
-func SaveDeploymentsNotACK(actions []openuem_nats.DeployAction) error {
-	cwd, err := Getwd()
-	if err != nil {
-		return err
+	if err := yaml.Unmarshal(msg.Data, &profileConfig); err != nil {
+		return fmt.Errorf("could not unmarshall playbook %v", err)
 	}
 
-	filename := filepath.Join(cwd, "pending_acks.json")
-	jsonFile, err := os.Create(filename)
+	// Run playbook
+	ansibleFolder, err := a.CreatePlaybooksFolder()
 	if err != nil {
-		return err
+		return fmt.Errorf("could not create playbooks folder %v", err)
 	}
-	defer jsonFile.Close()
 
-	jActions := JSONActions{}
-	jActions.Actions = actions
+	taskControlPath := filepath.Join(ansibleFolder, "tasks.json")
+	taskControl, err := dsc.ReadTaskControlFile(taskControlPath)
+	profileReport.ProfileID = profileConfig.ProfileID
 
-	byteValue, err := json.MarshalIndent(jActions, "", " ")
-	if err != nil {
-		return err
+	if len(profileConfig.AnsibleConfig) == 0 {
+		return errors.New("no ansible playbook was found in the request")
 	}
 
-	_, err = jsonFile.Write(byteValue)
+	cfg, err := yaml.Marshal(profileConfig.AnsibleConfig)
 	if err != nil {
-		return err
+		return fmt.Errorf("could not marshal YAML file with Ansible configuration, reason: %v", err)
 	}
 
-	return nil
-}
-
-func SaveDeploymentNotACK(action openuem_nats.DeployAction) error {
-	var actions []openuem_nats.DeployAction
-	cwd, err := Getwd()
+	task, err := a.InFlight.StartProfile(profileReport)
 	if err != nil {
 		return err
 	}
+	defer task.Done()
 
-	filename := filepath.Join(cwd, "pending_acks.json")
-	jsonFile, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
-	if err != nil {
-		return err
+	// All is fine, we can execute the task and we'll report later
+	if err := msg.Respond(nil); err != nil {
+		slog.Error("could not send the response to agent.ansible message")
 	}
-	defer jsonFile.Close()
 
-	byteValue, err := io.ReadAll(jsonFile)
+	tasks, err := a.ApplyConfiguration(profileConfig.ProfileID, cfg, taskControl, taskControlPath)
 	if err != nil {
-		return err
-	}
+		slog.Error("could not apply YAML configuration file with Ansible")
+		profileReport.Error = err.Error()
 
-	jActions := JSONActions{}
-
-	if len(byteValue) > 0 {
-		err = json.Unmarshal(byteValue, &jActions)
-		if err != nil {
-			return err
+		// Report if application was successful or not
+		if err := a.SendProfileReport(&profileReport); err != nil {
+			slog.Error("could not report if profile was applied succesfully or no")
 		}
-		actions = jActions.Actions
-	}
-
-	actions = append(actions, action)
-
-	if err := SaveDeploymentsNotACK(actions); err != nil {
-		return err
+		return nil
 	}
 
-	return nil
-}
-
-func (a *Agent) AgentRunTaskSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.ansible."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-		var profileConfig openuem_nats.ProfileConfig
-
-		// Unmarshal data
-		profileReport := openuem_nats.ProfileReport{
-			AgentID: a.Config.UUID,
-		}
-
-		if err := yaml.Unmarshal(msg.Data, &profileConfig); err != nil {
-			log.Println("[ERROR]: could not unmarshall playbook")
-			profileReport.Error = fmt.Sprintf("could not unmarshall playbook %v", err)
-
-			response, err := yaml.Marshal(profileReport)
-			if err != nil {
-				log.Printf("[ERROR]: could not marshal response to agent.ansible request, reason: %v", err)
-				return
-			}
-
-			if err := msg.Respond(response); err != nil {
-				log.Printf("[ERROR]: could not send response to agent.ansible request, reason: %v", err)
-				return
-			}
-			return
-		}
-
-		// Run playbook
-		ansibleFolder, err := CreatePlaybooksFolder()
-		if err != nil {
-			log.Printf("[ERROR]: could not create playbooks folder %v", err)
-			profileReport.Error = fmt.Sprintf("could not create playbooks folder %v", err)
-
-			response, err := json.Marshal(profileReport)
-			if err != nil {
-				log.Printf("[ERROR]: could not marshal response to agent.ansible request, reason: %v", err)
-				return
-			}
-
-			if err := msg.Respond(response); err != nil {
-				log.Printf("[ERROR]: could not send response to agent.ansible request, reason: %v", err)
-				return
-			}
-			return
+	profileReport.Tasks = tasks
+	profileReport.Success = true
+	for _, t := range tasks {
+		if t.Failed {
+			profileReport.Success = false
 		}
+	}
 
-		taskControlPath := filepath.Join(ansibleFolder, "tasks.json")
-		taskControl, err := dsc.ReadTaskControlFile(taskControlPath)
-		profileReport.ProfileID = profileConfig.ProfileID
-
-		if len(profileConfig.AnsibleConfig) == 0 {
-			log.Println("[ERROR]: no ansible playbook was found in the request")
-			profileReport.Error = "no ansible playbook was found in the request"
-
-			response, err := json.Marshal(profileReport)
-			if err != nil {
-				log.Printf("[ERROR]: could not marshal response to agent.ansible request, reason: %v", err)
-				return
-			}
-
-			if err := msg.Respond(response); err != nil {
-				log.Printf("[ERROR]: could not send response to agent.ansible request, reason: %v", err)
-				return
-			}
-			return
-		}
-
-		cfg, err := yaml.Marshal(profileConfig.AnsibleConfig)
-		if err != nil {
-			log.Printf("[ERROR]: could not marshal YAML file with Ansible configuration, reason: %v", err)
-
-			response, err := json.Marshal(profileReport)
-			if err != nil {
-				log.Printf("[ERROR]: could not marshal response to agent.ansible request, reason: %v", err)
-				return
-			}
-
-			if err := msg.Respond(response); err != nil {
-				log.Printf("[ERROR]: could not send response to agent.ansible request, reason: %v", err)
-				return
-			}
-			return
-		}
-
-		// All is fine, we can execute the task and we'll report later
-		if err := msg.Respond(nil); err != nil {
-			log.Printf("[ERROR]: could not send the response to agent.ansible message")
-		}
-
-		tasks, err := a.ApplyConfiguration(profileConfig.ProfileID, cfg, taskControl, taskControlPath)
-		if err != nil {
-			log.Println("[ERROR]: could not apply YAML configuration file with Ansible")
-			profileReport.Error = err.Error()
-
-			// Report if application was successful or not
-			if err := a.SendProfileReport(&profileReport); err != nil {
-				log.Println("[ERROR]: could not report if profile was applied succesfully or no")
-			}
-			return
-		}
-
-		profileReport.Tasks = tasks
-		profileReport.Success = true
-		for _, t := range tasks {
-			if t.Failed {
-				profileReport.Success = false
-			}
-		}
-
-		// Report as the task has finished
-		if err := a.SendProfileReport(&profileReport); err != nil {
-			log.Println("[ERROR]: could not report if profile was applied succesfully or no")
-		}
-
-	})
-
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent ansible task execution, reason: %v", err)
+	// Report as the task has finished
+	if err := a.SendProfileReport(&profileReport); err != nil {
+		slog.Error("could not report if profile was applied succesfully or no")
 	}
 	return nil
 }
 
-func (a *Agent) RunProfileSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.runprofile."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-		if err := msg.Respond(nil); err != nil {
-			log.Printf("[ERROR]: could not respond to console request to run a profile, reason: %v", err)
-		}
-
-		msg, err := a.NATSConnection.Request("ansiblecfg.profiles", msg.Data, 5*time.Minute)
-		if err != nil {
-			log.Printf("[ERROR]: could not send request to agent worker, reason: %v", err)
-			if err := a.Config.SetRestartRequiredFlag(); err != nil {
-				log.Printf("[ERROR]: could not set restart required flag, reason: %v\n", err)
-				return
-			}
-		}
-
-		a.ProcessProfileResponse(msg, true)
-	})
+func (a *Agent) RunProfileHandler(msg *nats.Msg) error {
+	if err := msg.Respond(nil); err != nil {
+		slog.Error("could not respond to console request to run a profile", "error", err)
+	}
 
+	msg, err := a.NATSConnection.Request("ansiblecfg.profiles", msg.Data, 5*time.Minute)
 	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent run profile subject, reason: %v", err)
+		slog.Error("could not send request to agent worker", "error", err)
+		if err := a.Config.SetRestartRequiredFlag(); err != nil {
+			return handlers.Responded(fmt.Errorf("could not set restart required flag, reason: %v", err))
+		}
 	}
+
+	a.ProcessProfileResponse(msg, true)
 	return nil
 }
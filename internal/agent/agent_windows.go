@@ -4,12 +4,10 @@ package agent
 
 import (
 	"bytes"
-	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"os/user"
@@ -19,16 +17,17 @@ import (
 	"strings"
 	"time"
 
-	"github.com/dgraph-io/badger/v4"
 	"github.com/go-co-op/gocron/v2"
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 	openuem_nats "github.com/open-uem/nats"
 	"github.com/open-uem/openuem-agent/internal/agent/dsc"
+	"github.com/open-uem/openuem-agent/internal/agent/handlers"
 	"github.com/open-uem/openuem-agent/internal/commands/deploy"
 	rd "github.com/open-uem/openuem-agent/internal/commands/remote-desktop"
 	"github.com/open-uem/openuem-agent/internal/commands/report"
-	"github.com/open-uem/openuem-agent/internal/commands/sftp"
+	"github.com/open-uem/openuem-agent/internal/logger"
+	"github.com/open-uem/openuem-agent/internal/metrics"
 	openuem_utils "github.com/open-uem/utils"
 	"github.com/open-uem/wingetcfg/wingetcfg"
 	"gopkg.in/yaml.v3"
@@ -36,69 +35,67 @@ import (
 
 func (a *Agent) Start() {
 
-	log.Println("[INFO]: agent has been started!")
+	slog.Info("agent has been started!")
 
 	// Log agent associated user
 	currentUser, err := user.Current()
 	if err != nil {
-		log.Printf("[ERROR]: %v", err)
+		slog.Error(err.Error())
 	}
-	log.Printf("[INFO]: agent is run as %s", currentUser.Username)
+	slog.Info(fmt.Sprintf("agent is run as %s", currentUser.Username))
 
 	a.Config.ExecuteTaskEveryXMinutes = SCHEDULETIME_5MIN
 	if err := a.Config.WriteConfig(); err != nil {
-		log.Fatalf("[FATAL]: could not write agent config: %v", err)
+		logger.Fatal("could not write agent config", "error", err)
 	}
 
 	// Agent started so reset restart required flag
 	if err := a.Config.ResetRestartRequiredFlag(); err != nil {
-		log.Fatalf("[FATAL]: could not reset restart required flag, reason: %v", err)
+		logger.Fatal("could not reset restart required flag", "error", err)
 	}
 
 	// Start task scheduler
 	a.TaskScheduler.Start()
-	log.Println("[INFO]: task scheduler has started!")
+	slog.Info("task scheduler has started!")
 
-	// Start BadgerDB KV and SFTP server only if port is set
-	if a.Config.SFTPPort != "" && !a.Config.SFTPDisabled {
-		cwd, err := Getwd()
-		if err != nil {
-			log.Println("[ERROR]: could not get working directory")
-			return
-		}
+	// Start local status server
+	a.StartStatusServer()
 
-		badgerPath := filepath.Join(cwd, "badgerdb")
-		if err := os.RemoveAll(badgerPath); err != nil {
-			log.Println("[ERROR]: could not remove badgerdb directory")
-			return
-		}
+	// Start metrics server if enabled
+	a.StartMetricsServer()
 
-		if err := os.MkdirAll(badgerPath, 0660); err != nil {
-			log.Println("[ERROR]: could not recreate badgerdb directory")
-			return
-		}
+	// Watch the config file so changes are applied without a restart
+	a.startConfigWatchJob()
 
-		a.BadgerDB, err = badger.Open(badger.DefaultOptions(filepath.Join(cwd, "badgerdb")))
-		if err != nil {
-			log.Printf("[ERROR]: %v", err)
-		}
+	// Start BadgerDB KV and SFTP server only if port is set
+	a.StartSFTPServer()
 
-		go func() {
-			a.SFTPServer = sftp.New()
-			err = a.SFTPServer.Serve(":"+a.Config.SFTPPort, a.SFTPCert, a.CACert, a.BadgerDB)
-			if err != nil {
-				log.Printf("[ERROR]: %v", err)
-			}
-			log.Println("[INFO]: SFTP server has started!")
-		}()
-	} else {
-		log.Println("[INFO]: SFTP port is not set so SFTP server is not started!")
-	}
+	// Open the spool where reports are kept while the NATS server is not reachable
+	a.OpenReportSpool(a.Config.DataDir)
+
+	// Open the outbox where deployment results are kept until the worker acknowledges them
+	a.OpenDeployOutbox()
+	a.OpenAuditLog()
+	a.OpenReplayCache()
+	a.OpenMaintenanceQueue()
+	a.startMaintenanceJob()
+
+	// Certificates are checked even if the agent starts offline or can't send its first report
+	a.startCertificateExpiryJob()
 
 	// Try to connect to NATS server and start a reconnect job if failed
-	a.NATSConnection, err = openuem_nats.ConnectWithNATS(a.Config.NATSServers, a.Config.AgentCert, a.Config.AgentKey, a.Config.CACert, a.Config.WebSocketPort)
+	a.NATSConnection, err = a.ConnectToNATS()
 	if err != nil {
-		log.Printf("[ERROR]: %v", err)
+		slog.Error(err.Error())
+
+		// While the agent is offline reports are kept in the spool
+		if a.Config.Enabled {
+			if r := a.RunReport(); r != nil {
+				metrics.ReportSendFailures.Inc()
+				a.SpoolReport(r)
+			}
+			a.startReportJob()
+		}
 		a.startNATSConnectJob()
 		return
 	}
@@ -106,28 +103,26 @@ func (a *Agent) Start() {
 
 	// Run report for the first time after start if agent is enabled
 	if a.Config.Enabled {
-		r := a.RunReport()
-		if r == nil {
-			return
-		}
+		if r := a.RunReport(); r != nil {
+			// Send first report to NATS
+			if err := a.SendOrSpoolReport(r); err != nil {
+				metrics.ReportSendFailures.Inc()
+				a.Config.ExecuteTaskEveryXMinutes = SCHEDULETIME_5MIN // Try to send it again in 5 minutes
+				slog.Error("report could not be send to NATS server!", "error", err)
+			} else {
+				// Get remote config
+				if err := a.GetRemoteConfig(); err != nil {
+					slog.Error("could not get remote config", "error", err)
+				}
+				slog.Info("remote config requested")
 
-		// Send first report to NATS
-		if err := a.SendReport(r); err != nil {
-			a.Config.ExecuteTaskEveryXMinutes = SCHEDULETIME_5MIN // Try to send it again in 5 minutes
-			log.Printf("[ERROR]: report could not be send to NATS server!, reason: %s\n", err.Error())
-		} else {
-			// Get remote config
-			if err := a.GetRemoteConfig(); err != nil {
-				log.Printf("[ERROR]: could not get remote config %v", err)
+				// Start scheduled report job with default frequency
+				a.Config.ExecuteTaskEveryXMinutes = a.Config.DefaultFrequency
 			}
-			log.Println("[INFO]: remote config requested")
-
-			// Start scheduled report job with default frequency
-			a.Config.ExecuteTaskEveryXMinutes = a.Config.DefaultFrequency
 		}
 
 		if err := a.Config.WriteConfig(); err != nil {
-			log.Fatalf("[FATAL]: could not write agent config: %v", err)
+			logger.Fatal("could not write agent config", "error", err)
 		}
 
 		a.startReportJob()
@@ -138,153 +133,95 @@ func (a *Agent) Start() {
 	a.startCheckForWinGetProfilesJob()
 }
 
-func (a *Agent) startNATSConnectJob() error {
-	var err error
-
-	if a.Config.ExecuteTaskEveryXMinutes == 0 {
-		a.Config.ExecuteTaskEveryXMinutes = SCHEDULETIME_5MIN
+func (a *Agent) startJobsAfterNATSConnect() {
+	// The report job may have been started while the agent was offline
+	if a.ReportJob == nil {
+		a.startReportJob()
 	}
+	a.startPendingACKJob()
+	a.startCheckForWinGetProfilesJob()
+}
 
-	// Create task for running the agent
-	a.NATSConnectJob, err = a.TaskScheduler.NewJob(
-		gocron.DurationJob(
-			time.Duration(time.Duration(a.Config.ExecuteTaskEveryXMinutes)*time.Minute),
-		),
-		gocron.NewTask(
-			func() {
-				a.NATSConnection, err = openuem_nats.ConnectWithNATS(a.Config.NATSServers, a.Config.AgentCert, a.Config.AgentKey, a.Config.CACert, a.Config.WebSocketPort)
-				if err != nil {
-					return
-				}
-
-				// We have connected
-				a.TaskScheduler.RemoveJob(a.NATSConnectJob.ID())
-				a.SubscribeToNATSSubjects()
+func (a *Agent) PlatformHandlers() []*handlers.Handler {
+	q := handlers.MANAGEMENT_QUEUE
 
-				// Start the rest of tasks
-				a.startReportJob()
-				a.startPendingACKJob()
-				a.startCheckForWinGetProfilesJob()
-			},
-		),
-	)
-	if err != nil {
-		log.Fatalf("[FATAL]: could not start the NATS connect job: %v", err)
-		return err
+	return []*handlers.Handler{
+		handlers.JSON("agent.startvnc", q, a.StartRemoteDesktopHandler).WithAudit(),
+		handlers.JSON("agent.reboot", q, a.RebootHandler).WithAudit().WithDeferral(),
+		handlers.JSON("agent.poweroff", q, a.PowerOffHandler).WithAudit().WithDeferral(),
+		handlers.JSON("agent.newconfig", "", a.NewConfigHandler).AsBroadcast().WithAudit(),
+		handlers.New("agent.windowstask", q, a.AgentRunTaskHandler).WithPayload("nats.ProfileConfig (YAML)").WithErrorResponse(a.profileErrorResponse).WithAudit().WithDeferral(),
+		handlers.New("agent.runprofile", q, a.RunProfileHandler).WithAudit().WithDeferral(),
 	}
-	log.Printf("[INFO]: new NATS connect job has been scheduled every %d minutes", a.Config.ExecuteTaskEveryXMinutes)
-	return nil
 }
 
-func (a *Agent) StartRemoteDesktopSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.startvnc."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-
-		loggedOnUser, err := report.GetLoggedOnUsername()
-		if err != nil {
-			log.Println("[ERROR]: could not get logged on username")
-			return
-		}
-
-		sid, err := report.GetSID(loggedOnUser)
-		if err != nil {
-			log.Println("[ERROR]: could not get SID for logged on user")
-			return
-		}
-
-		// Instantiate new remote desktop service, but first try to check if certificates are there
-		a.GetServerCertificate()
-		if a.ServerCertPath == "" || a.ServerKeyPath == "" {
-			log.Println("[ERROR]: Remote Desktop requires a server certificate that it's not ready")
-			return
-		}
+func (a *Agent) StartRemoteDesktopHandler(msg *nats.Msg, rdConn openuem_nats.VNCConnection) error {
+	loggedOnUser, err := report.GetLoggedOnUsername()
+	if err != nil {
+		return fmt.Errorf("could not get logged on username, reason: %v", err)
+	}
 
-		v, err := rd.New(a.ServerCertPath, a.ServerKeyPath, sid, a.Config.VNCProxyPort)
-		if err != nil {
-			log.Println("[ERROR]: could not get a Remote Desktop service")
-			return
-		}
+	sid, err := report.GetSID(loggedOnUser)
+	if err != nil {
+		return fmt.Errorf("could not get SID for logged on user, reason: %v", err)
+	}
 
-		// Unmarshal data
-		var rdConn openuem_nats.VNCConnection
-		if err := json.Unmarshal(msg.Data, &rdConn); err != nil {
-			log.Println("[ERROR]: could not unmarshall Remote Desktop connection")
-			return
-		}
+	// Instantiate new remote desktop service, but first try to check if certificates are there
+	a.GetServerCertificate()
+	if a.ServerCertPath == "" || a.ServerKeyPath == "" {
+		return errors.New("Remote Desktop requires a server certificate that it's not ready")
+	}
 
-		// Start Remote Desktop server
-		a.RemoteDesktop = v
-		v.Start(rdConn.PIN, rdConn.NotifyUser)
+	v, err := rd.New(a.ServerCertPath, a.ServerKeyPath, sid, a.Config.VNCProxyPort)
+	if err != nil {
+		return fmt.Errorf("could not get a Remote Desktop service, reason: %v", err)
+	}
 
-		if err := msg.Respond([]byte("Remote Desktop service started!")); err != nil {
-			log.Printf("[ERROR]: could not respond to agent start remote desktop message, reason: %v\n", err)
-		}
-	})
+	// Start Remote Desktop server
+	a.RemoteDesktop = v
+	v.Start(rdConn.PIN, rdConn.NotifyUser)
+	metrics.VNCSessions.Inc()
 
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent start remote desktop, reason: %v", err)
+	if err := msg.Respond([]byte("Remote Desktop service started!")); err != nil {
+		slog.Error("could not respond to agent start remote desktop message", "error", err)
 	}
 	return nil
 }
 
-func (a *Agent) RebootSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.reboot."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-		log.Println("[INFO]: reboot request received")
-		if err := msg.Respond([]byte("Reboot!")); err != nil {
-			log.Printf("[ERROR]: could not respond to agent reboot message, reason: %v\n", err)
-		}
+func (a *Agent) RebootHandler(msg *nats.Msg, action openuem_nats.RebootOrRestart) error {
+	slog.Info("reboot request received")
+	if err := msg.Respond([]byte("Reboot!")); err != nil {
+		slog.Error("could not respond to agent reboot message", "error", err)
+	}
 
-		action := openuem_nats.RebootOrRestart{}
-		if err := json.Unmarshal(msg.Data, &action); err != nil {
-			log.Printf("[ERROR]: could not unmarshal to agent reboot message, reason: %v\n", err)
-			return
+	when := int(time.Until(action.Date).Seconds())
+	if when > 0 {
+		if err := exec.Command("cmd", "/C", "shutdown", "/r", "/t", strconv.Itoa(when)).Run(); err != nil {
+			return handlers.Responded(fmt.Errorf("could not initiate power off, reason: %v", err))
 		}
-
-		when := int(time.Until(action.Date).Seconds())
-		if when > 0 {
-			if err := exec.Command("cmd", "/C", "shutdown", "/r", "/t", strconv.Itoa(when)).Run(); err != nil {
-				fmt.Printf("[ERROR]: could not initiate power off, reason: %v", err)
-			}
-		} else {
-			if err := exec.Command("cmd", "/C", "shutdown", "/r").Run(); err != nil {
-				fmt.Printf("[ERROR]: could not initiate power off, reason: %v", err)
-			}
+	} else {
+		if err := exec.Command("cmd", "/C", "shutdown", "/r").Run(); err != nil {
+			return handlers.Responded(fmt.Errorf("could not initiate power off, reason: %v", err))
 		}
-	})
-
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent reboot, reason: %v", err)
 	}
 	return nil
 }
 
-func (a *Agent) PowerOffSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.poweroff."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-		log.Println("[INFO]: power off request received")
-		if err := msg.Respond([]byte("Power Off!")); err != nil {
-			log.Printf("[ERROR]: could not respond to agent power off message, reason: %v\n", err)
-			return
-		}
+func (a *Agent) PowerOffHandler(msg *nats.Msg, action openuem_nats.RebootOrRestart) error {
+	slog.Info("power off request received")
+	if err := msg.Respond([]byte("Power Off!")); err != nil {
+		return handlers.Responded(fmt.Errorf("could not respond to agent power off message, reason: %v", err))
+	}
 
-		action := openuem_nats.RebootOrRestart{}
-		if err := json.Unmarshal(msg.Data, &action); err != nil {
-			log.Printf("[ERROR]: could not unmarshal to agent power off message, reason: %v\n", err)
-			return
+	when := int(time.Until(action.Date).Seconds())
+	if when > 0 {
+		if err := exec.Command("cmd", "/C", "shutdown", "/s", "/t", strconv.Itoa(when)).Run(); err != nil {
+			return handlers.Responded(fmt.Errorf("could not initiate power off, reason: %v", err))
 		}
-
-		when := int(time.Until(action.Date).Seconds())
-		if when > 0 {
-			if err := exec.Command("cmd", "/C", "shutdown", "/s", "/t", strconv.Itoa(when)).Run(); err != nil {
-				log.Printf("[ERROR]: could not initiate power off, reason: %v", err)
-			}
-		} else {
-			if err := exec.Command("cmd", "/C", "shutdown", "/s").Run(); err != nil {
-				log.Printf("[ERROR]: could not initiate shutdown, reason: %v", err)
-			}
+	} else {
+		if err := exec.Command("cmd", "/C", "shutdown", "/s").Run(); err != nil {
+			return handlers.Responded(fmt.Errorf("could not initiate shutdown, reason: %v", err))
 		}
-	})
-
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent power off, reason: %v", err)
 	}
 	return nil
 }
@@ -302,42 +239,47 @@ func (a *Agent) startCheckForWinGetProfilesJob() error {
 			time.Duration(a.Config.WingetConfigureFrequency)*time.Minute,
 		),
 		gocron.NewTask(a.GetWingetConfigureProfiles),
+		gocron.WithName("winget-profiles"),
 	)
 	if err != nil {
-		log.Fatalf("[FATAL]: could not start the check for WinGet profiles job, reason: %v", err)
+		logger.Fatal("could not start the check for WinGet profiles job", "error", err)
 		return err
 	}
-	log.Printf("[INFO]: new check for WinGet profiles job has been scheduled every %d minutes", a.Config.WingetConfigureFrequency)
+	slog.Info(fmt.Sprintf("new check for WinGet profiles job has been scheduled every %d minutes", a.Config.WingetConfigureFrequency))
 	return nil
 }
 
+// runScheduledProfiles applies the profiles deferred to the maintenance window
+func (a *Agent) runScheduledProfiles() {
+	a.GetWingetConfigureProfiles()
+}
+
 func (a *Agent) GetWingetConfigureProfiles() {
-	if a.Config.Debug {
-		log.Println("[DEBUG]: running task WinGet profiles job")
+	slog.Debug("running task WinGet profiles job")
+
+	// Outside the maintenance windows the profiles are applied when the next one opens
+	if a.deferScheduledProfiles() {
+		return
 	}
 
 	profileRequest := openuem_nats.CfgProfiles{
 		AgentID: a.Config.UUID,
 	}
 
-	if a.Config.Debug {
-		log.Println("[DEBUG]: going to send a wingetcfg.profile request")
-	}
+	slog.Debug("going to send a wingetcfg.profile request")
 
 	data, err := json.Marshal(profileRequest)
 	if err != nil {
-		log.Printf("[ERROR]: could not marshal profile request, reason: %v", err)
+		slog.Error("could not marshal profile request", "error", err)
 	}
 
-	if a.Config.Debug {
-		log.Println("[DEBUG]: wingetcfg.profile sending request")
-	}
+	slog.Debug("wingetcfg.profile sending request")
 
 	msg, err := a.NATSConnection.Request("wingetcfg.profiles", data, 5*time.Minute)
 	if err != nil {
-		log.Printf("[ERROR]: could not send request to agent worker, reason: %v", err)
+		slog.Error("could not send request to agent worker", "error", err)
 		if err := a.Config.SetRestartRequiredFlag(); err != nil {
-			log.Printf("[ERROR]: could not set restart required flag, reason: %v\n", err)
+			slog.Error("could not set restart required flag", "error", err)
 			return
 		}
 	}
@@ -349,19 +291,17 @@ func (a *Agent) ProcessProfileResponse(msg *nats.Msg, force bool) {
 	profiles := []openuem_nats.ProfileConfig{}
 
 	if a.Config.Debug {
-		log.Println("[DEBUG]: wingetcfg.profile request sent")
+		slog.Debug("wingetcfg.profile request sent")
 		if msg.Data != nil {
-			log.Println("[DEBUG]: received wingetcfg.profile response")
+			slog.Debug("received wingetcfg.profile response")
 		}
 	}
 
 	if err := yaml.Unmarshal(msg.Data, &profiles); err != nil {
-		log.Printf("[ERROR]: could not unmarshal profiles response from agent worker, reason: %v", err)
+		slog.Error("could not unmarshal profiles response from agent worker", "error", err)
 	}
 
-	if a.Config.Debug {
-		log.Println("[DEBUG]: wingetcfg.profile response unmarshalled")
-	}
+	slog.Debug("wingetcfg.profile response unmarshalled")
 
 	for _, p := range profiles {
 		profileReport := openuem_nats.ProfileReport{
@@ -370,31 +310,36 @@ func (a *Agent) ProcessProfileResponse(msg *nats.Msg, force bool) {
 			Success:   true,
 		}
 
-		if a.Config.Debug {
-			log.Println("[DEBUG]: wingetcfg.profile to be unmarshalled")
+		task, err := a.InFlight.StartProfile(profileReport)
+		if err != nil {
+			slog.Warn("profile has not been applied", "profile_id", p.ProfileID, "error", err)
+			break
 		}
 
+		slog.Debug("wingetcfg.profile to be unmarshalled")
+
 		cfg, err := yaml.Marshal(p.WinGetConfig)
 		if err != nil {
-			log.Printf("[ERROR]: could not marshal YAML file with winget configuration, reason: %v", err)
+			slog.Error("could not marshal YAML file with winget configuration", "error", err)
+			task.Done()
 			continue
 		}
 
-		if a.Config.Debug {
-			log.Println("[DEBUG]: we're going to apply the configuration")
-		}
+		slog.Debug("we're going to apply the configuration")
 
 		// Read task control file
 		cwd, err := openuem_utils.GetWd()
 		if err != nil {
-			log.Printf("[ERROR]: could not get working directory, reason %v", err)
+			slog.Error("could not get working directory", "error", err)
+			task.Done()
 			return
 		}
 		taskControlPath := filepath.Join(cwd, "powershell", "tasks.json")
 		taskControl, err := dsc.ReadTaskControlFile(taskControlPath)
 
 		if err != nil {
-			log.Printf("[ERROR]: tasks control file is not available, reason %v", err)
+			slog.Error("tasks control file is not available", "error", err)
+			task.Done()
 			return
 		}
 
@@ -402,7 +347,7 @@ func (a *Agent) ProcessProfileResponse(msg *nats.Msg, force bool) {
 
 		taskReports, err := a.ApplyConfiguration(p.ProfileID, cfg, p.Exclusions, p.Deployments, taskControl, taskControlPath, force)
 		if err != nil {
-			log.Println("[ERROR]: could not apply profile")
+			slog.Error("could not apply profile")
 			profileReport.Error = err.Error()
 		}
 
@@ -414,11 +359,13 @@ func (a *Agent) ProcessProfileResponse(msg *nats.Msg, force bool) {
 			}
 		}
 
+		task.UpdateProfile(profileReport)
+
 		// Netbird tasks
 		if len(p.NetBirdConfig) > 0 {
 			tasks, err := a.ApplyNetBirdConfiguration(p, taskControl, taskControlPath)
 			if err != nil {
-				log.Println("[ERROR]: could not apply Netbird configuration file")
+				slog.Error("could not apply Netbird configuration file")
 
 				if errData != "" {
 					errData = strings.Join([]string{errData, err.Error()}, ",")
@@ -438,10 +385,17 @@ func (a *Agent) ProcessProfileResponse(msg *nats.Msg, force bool) {
 			profileReport.Tasks = append(profileReport.Tasks, tasks...)
 		}
 
+		for _, t := range profileReport.Tasks {
+			if t.Failed {
+				metrics.ProfileTaskFailures.Inc()
+			}
+		}
+
 		// Report if application was successful or not
 		if err := a.SendProfileReport(&profileReport); err != nil {
-			log.Println("[ERROR]: could not report if profile was applied succesfully or no")
+			slog.Error("could not report if profile was applied succesfully or no")
 		}
+		task.Done()
 	}
 
 }
@@ -466,23 +420,23 @@ func (a *Agent) ApplyConfiguration(profileID int, config []byte, exclusions, dep
 		} else {
 			// Clear stalled profile for more than 24 hours
 			if time.Now().After(when.Add(24 * time.Hour)) {
-				log.Printf("[INFO]: found previous task %s that hasn't be re-run for more than 24 hours", ID)
+				slog.Info("found previous task that hasn't be re-run for more than 24 hours", "task_id", ID)
 				taskControl.ProfilesRunning[ID] = time.Now()
 			} else {
-				log.Printf("[INFO]: previous profile %s is marked as running, not relaunching, ", ID)
+				slog.Info("previous profile is marked as running, not relaunching", "profile_id", ID)
 				return nil, nil
 			}
 		}
 	}
 	if err := dsc.SaveTaskControl(taskControlPath, taskControl); err != nil {
-		log.Printf("[ERROR]: could not save new profile %s running, reason: %v", ID, err)
+		slog.Error("could not save new profile running", "profile_id", ID, "error", err)
 		return nil, err
 	}
 
 	defer func() {
 		delete(taskControl.ProfilesRunning, ID)
 		if err := dsc.SaveTaskControl(taskControlPath, taskControl); err != nil {
-			log.Printf("[ERROR]: could not remove profile %s from running, reason: %v", ID, err)
+			slog.Error("could not remove profile from running", "profile_id", ID, "error", err)
 			return
 		}
 	}()
@@ -499,14 +453,14 @@ func (a *Agent) CheckIfCfgPackagesInstalled(cfg wingetcfg.WinGetCfg, installed s
 			if r.Settings["Ensure"].(string) == "Present" {
 				if strings.Contains(installed, packageID) {
 					if err := a.SendWinGetCfgDeploymentReport(packageID, packageName, "install"); err != nil {
-						log.Printf("[ERROR]: could not send WinGetCfg deployment report, reason: %v", err)
+						slog.Error("could not send WinGetCfg deployment report", "error", err)
 						continue
 					}
 				}
 			} else {
 				if !strings.Contains(installed, packageID) {
 					if err := a.SendWinGetCfgDeploymentReport(packageID, packageName, "uninstall"); err != nil {
-						log.Printf("[ERROR]: could not send WinGetCfg deployment report, reason: %v", err)
+						slog.Error("could not send WinGetCfg deployment report", "error", err)
 						continue
 					}
 				}
@@ -546,12 +500,12 @@ func (a *Agent) SendWinGetCfgExcludedPackage(packageIDs []string) {
 
 		data, err := json.Marshal(deployment)
 		if err != nil {
-			log.Printf("[ERROR]: could not marshal package exclude for package %s and agent %s", id, a.Config.UUID)
+			slog.Error(fmt.Sprintf("could not marshal package exclude for package and agent %s", a.Config.UUID), "package_id", id)
 			return
 		}
 
 		if _, err := a.NATSConnection.Request("wingetcfg.exclude", data, 2*time.Minute); err != nil {
-			log.Printf("[ERROR]: could not send package exclude for package %s and agent %s", id, a.Config.UUID)
+			slog.Error(fmt.Sprintf("could not send package exclude for package and agent %s", a.Config.UUID), "package_id", id)
 		}
 	}
 }
@@ -561,145 +515,50 @@ func (a *Agent) RescheduleWingetConfigureTask() {
 	a.startCheckForWinGetProfilesJob()
 }
 
-func (a *Agent) NewConfigSubscribe() error {
-	_, err := a.NATSConnection.Subscribe("agent.newconfig", func(msg *nats.Msg) {
-
-		config := openuem_nats.Config{}
-		err := json.Unmarshal(msg.Data, &config)
-		if err != nil {
-			log.Printf("[ERROR]: could not get new config to apply, reason: %v\n", err)
-			return
-		}
-
-		a.Config.DefaultFrequency = config.AgentFrequency
-
-		if a.Config.SFTPDisabled != config.SFTPDisabled {
-			if err := a.Config.SetRestartRequiredFlag(); err != nil {
-				log.Printf("[ERROR]: could not set restart required flag, reason: %v\n", err)
-				return
-			}
-		}
-		a.Config.SFTPDisabled = config.SFTPDisabled
-
-		a.Config.RemoteAssistanceDisabled = config.RemoteAssistanceDisabled
-
-		// Should we re-schedule agent report?
-		if a.Config.ExecuteTaskEveryXMinutes != SCHEDULETIME_5MIN {
-			a.Config.ExecuteTaskEveryXMinutes = a.Config.DefaultFrequency
-			a.RescheduleReportRunTask()
-		}
+// RescheduleProfilesTask restarts the profiles job so it uses the new frequency
+func (a *Agent) RescheduleProfilesTask() {
+	a.RescheduleWingetConfigureTask()
+}
 
-		// Should we re-schedule winget configure task?
-		if config.WinGetFrequency != 0 {
-			a.Config.WingetConfigureFrequency = config.WinGetFrequency
-			a.RescheduleWingetConfigureTask()
-		}
+func (a *Agent) NewConfigHandler(msg *nats.Msg, config NewConfigRequest) error {
+	c := a.Config
+	c.DefaultFrequency = config.AgentFrequency
+	c.SFTPDisabled = config.SFTPDisabled
+	c.RemoteAssistanceDisabled = config.RemoteAssistanceDisabled
+	c.WingetConfigureFrequency = config.WinGetFrequency
+	if err := applyMaintenanceWindows(&c, config.MaintenanceWindows); err != nil {
+		return err
+	}
 
-		if err := a.Config.WriteConfig(); err != nil {
-			log.Fatalf("[FATAL]: could not write agent config: %v", err)
-		}
-		log.Println("[INFO]: new config has been set from console")
-	})
+	// Reports and profiles are rescheduled and the SFTP server is started or stopped if needed
+	a.ApplyConfig(c)
 
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent uninstall package, reason: %v", err)
+	if err := a.Config.WriteConfig(); err != nil {
+		logger.Fatal("could not write agent config", "error", err)
 	}
+
+	slog.Info("new config has been set from console")
 	return nil
 }
 
-func (a *Agent) AgentCertificateHandler(msg jetstream.Msg) {
-
+func (a *Agent) AgentCertificateHandler(msg jetstream.Msg) error {
 	data := openuem_nats.AgentCertificateData{}
 
 	if err := json.Unmarshal(msg.Data(), &data); err != nil {
-		log.Printf("[ERROR]: could not unmarshal agent certificate data, reason: %v\n", err)
-		if err := msg.Ack(); err != nil {
-			log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-		}
-		return
-	}
-
-	wd, err := openuem_utils.GetWd()
-	if err != nil {
-		log.Printf("[ERROR]: could not get working directory, reason: %v\n", err)
-		if err := msg.Ack(); err != nil {
-			log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-		}
-		return
+		ackMessage(msg)
+		return fmt.Errorf("could not unmarshal agent certificate data, reason: %v", err)
 	}
 
-	if err := os.MkdirAll(filepath.Join(wd, "certificates"), 0660); err != nil {
-		log.Printf("[ERROR]: could not create certificates folder, reason: %v\n", err)
-		if err := msg.Ack(); err != nil {
-			log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-		}
-		return
+	if err := a.InstallServerCertificate(data); err != nil {
+		ackMessage(msg)
+		return fmt.Errorf("could not install the server certificate, reason: %v", err)
 	}
 
-	keyPath := filepath.Join(wd, "certificates", "server.key")
-
-	privateKey, err := x509.ParsePKCS1PrivateKey(data.PrivateKeyBytes)
-	if err != nil {
-		log.Printf("[ERROR]: could not get private key, reason: %v\n", err)
-		if err := msg.Ack(); err != nil {
-			log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-		}
-		return
-	}
-
-	err = openuem_utils.SavePrivateKey(privateKey, keyPath)
-	if err != nil {
-		log.Printf("[ERROR]: could not save agent private key, reason: %v\n", err)
-		if err := msg.Ack(); err != nil {
-			log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-		}
-		return
-	}
-	log.Printf("[INFO]: Agent private key saved in %s", keyPath)
-
-	certPath := filepath.Join(wd, "certificates", "server.cer")
-	err = openuem_utils.SaveCertificate(data.CertBytes, certPath)
-	if err != nil {
-		log.Printf("[ERROR]: could not save agent certificate, reason: %v\n", err)
-		if err := msg.Ack(); err != nil {
-			log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-		}
-		return
-	}
-	log.Printf("[INFO]: Agent certificate saved in %s", keyPath)
-
-	if err := msg.Ack(); err != nil {
-		log.Printf("[ERROR]: could not ACK message, reason: %v", err)
-	}
+	ackMessage(msg)
 
 	// Finally run a new report to inform that the certificate is ready
-	r := a.RunReport()
-	if r == nil {
-		return
-	}
-}
-
-func (a *Agent) GetServerCertificate() {
-
-	cwd, err := openuem_utils.GetWd()
-	if err != nil {
-		log.Println("[ERROR]: could not get current working directory")
-	}
-	serverCertPath := filepath.Join(cwd, "certificates", "server.cer")
-	_, err = openuem_utils.ReadPEMCertificate(serverCertPath)
-	if err != nil {
-		log.Printf("[ERROR]: could not read server certificate")
-	} else {
-		a.ServerCertPath = serverCertPath
-	}
-
-	serverKeyPath := filepath.Join(cwd, "certificates", "server.key")
-	_, err = openuem_utils.ReadPEMPrivateKey(serverKeyPath)
-	if err != nil {
-		log.Printf("[ERROR]: could not read server private key")
-	} else {
-		a.ServerKeyPath = serverKeyPath
-	}
+	a.RunReport()
+	return nil
 }
 
 func (a *Agent) ExecutePowerShellScript(script string) (string, string, error) {
@@ -709,7 +568,7 @@ func (a *Agent) ExecutePowerShellScript(script string) (string, string, error) {
 	if script != "" {
 		file, err := os.CreateTemp(os.TempDir(), "*.ps1")
 		if err != nil {
-			log.Printf("[ERROR]: could not create temp ps1 file, reason: %v", err)
+			slog.Error("could not create temp ps1 file", "error", err)
 			return "", "", fmt.Errorf("could not create temp ps1 file, reason: %v", err)
 		} else {
 			defer func() {
@@ -718,18 +577,18 @@ func (a *Agent) ExecutePowerShellScript(script string) (string, string, error) {
 				}
 			}()
 			if _, err := file.Write([]byte(script)); err != nil {
-				log.Printf("[ERROR]: could not execute write on temp ps1 file, reason: %v", err)
+				slog.Error("could not execute write on temp ps1 file", "error", err)
 				return "", "", fmt.Errorf("could not execute write on temp ps1 file, reason: %v", err)
 			}
 			if err := file.Close(); err != nil {
-				log.Printf("[ERROR]: could not close temp ps1 file, reason: %v", err)
+				slog.Error("could not close temp ps1 file", "error", err)
 				return "", "", fmt.Errorf("could not close temp ps1 file, reason: %v", err)
 			}
 
 			// Get current Execution-Policy
 			out, err := exec.Command("PowerShell", "-command", "Get-ExecutionPolicy -Scope CurrentUser").CombinedOutput()
 			if err != nil {
-				log.Printf("[ERROR]: could not get current Powershell execution policy, reason: %v, %s", err, string(out))
+				slog.Error("could not get current Powershell execution policy", "error", err, "output", string(out))
 				return "", "", fmt.Errorf("could not get current Powershell execution policy, reason: %v, %s", err, string(out))
 			}
 			currentExecutionPolicy := strings.TrimSpace(string(out))
@@ -737,14 +596,14 @@ func (a *Agent) ExecutePowerShellScript(script string) (string, string, error) {
 			// Set ExecutionPolicy temporarily to RemoteSigned
 			out, err = exec.Command("PowerShell", "-command", "Set-ExecutionPolicy RemoteSigned -Scope CurrentUser").CombinedOutput()
 			if err != nil {
-				log.Printf("[ERROR]: could not set Powershell execution policy to RemoteSigned temporarily, reason: %v, %s", err, string(out))
+				slog.Error("could not set Powershell execution policy to RemoteSigned temporarily", "error", err, "output", string(out))
 				return "", "", fmt.Errorf("could not set Powershell execution policy to RemoteSigned temporarily, reason: %v, %s", err, string(out))
 			}
 			defer func() {
 				// Revert back to previous ExecutionPolicy
 				out, err = exec.Command("PowerShell", "-command", fmt.Sprintf("Set-ExecutionPolicy %s -Scope CurrentUser", currentExecutionPolicy)).CombinedOutput()
 				if err != nil {
-					log.Printf("[ERROR]: could not revert the Powershell execution policy to RemoteSigned temporarily, reason: %v, %s", err, string(out))
+					slog.Error("could not revert the Powershell execution policy to RemoteSigned temporarily", "error", err, "output", string(out))
 				}
 			}()
 
@@ -753,16 +612,14 @@ func (a *Agent) ExecutePowerShellScript(script string) (string, string, error) {
 			cmd.Stderr = &stderr
 			cmd.Stdout = &stdout
 			if err := cmd.Run(); err != nil {
-				log.Printf("[ERROR]: could not execute powershell script, reason: %v, %s", err, string(out))
+				slog.Error("could not execute powershell script", "error", err, "output", string(out))
 				return "", "", fmt.Errorf("could not execute powershell script, reason: %v, %s", err, string(out))
 			}
 
-			if a.Config.Debug {
-				log.Printf("[DEBUG]: a script should have run: PowerShell -File %s", file.Name())
-			}
+			slog.Debug(fmt.Sprintf("a script should have run: PowerShell -File %s", file.Name()))
 
 			if err := os.Remove(file.Name()); err != nil {
-				log.Printf("[ERROR]: could not remove temp ps1 file, reason: %v", err)
+				slog.Error("could not remove temp ps1 file", "error", err)
 			}
 
 			return stdout.String(), stderr.String(), nil
@@ -888,14 +745,14 @@ func (a *Agent) PackageManagementTask(r *wingetcfg.WinGetResource, taskControlPa
 			}
 
 			if err := a.SendWinGetCfgDeploymentReport(packageID, packageName, "install"); err != nil {
-				log.Printf("[ERROR]: could not send WinGetCfg deployment report, reason: %v", err)
+				slog.Error("could not send WinGetCfg deployment report", "error", err)
 				return nil, err
 			}
 
 			// if package must not be kept updated, mark the task as successful
 			if !keepUpdated && stderr == "" {
 				if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-					log.Printf("[INFO]: could not set task to install %s as successful in JSON control file", packageName)
+					slog.Info(fmt.Sprintf("could not set task to install %s as successful in JSON control file", packageName))
 				}
 			}
 
@@ -918,13 +775,13 @@ func (a *Agent) PackageManagementTask(r *wingetcfg.WinGetResource, taskControlPa
 				return nil, err
 			}
 			if err := a.SendWinGetCfgDeploymentReport(packageID, packageName, "uninstall"); err != nil {
-				log.Printf("[ERROR]: could not send WinGetCfg deployment report, reason: %v", err)
+				slog.Error("could not send WinGetCfg deployment report", "error", err)
 				return nil, err
 			}
 
 			if stderr == "" {
 				if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-					log.Printf("[INFO]: could not set task to uninstall %s as successful in JSON control file", packageName)
+					slog.Info(fmt.Sprintf("could not set task to uninstall %s as successful in JSON control file", packageName))
 				}
 			}
 
@@ -988,10 +845,10 @@ func (a *Agent) RegistryTask(r *wingetcfg.WinGetResource, taskControlPath string
 				if valueData != "" {
 					stdout, stderr, err := dsc.UpdateRegistryKeyDefaultValue(key, valueData)
 					if err != nil {
-						log.Printf("[ERROR]: could not update registry %s default value, reason: %v", key, err)
+						slog.Error(fmt.Sprintf("could not update registry %s default value", key), "error", err)
 						return nil, fmt.Errorf("could not update registry %s default value, reason: %v", key, err)
 					}
-					log.Printf("[INFO]: registry key default value %s has been updated", key)
+					slog.Info(fmt.Sprintf("registry key default value %s has been updated", key))
 
 					taskReport.Name = r.ID
 					taskReport.StdOut = stdout
@@ -1001,16 +858,16 @@ func (a *Agent) RegistryTask(r *wingetcfg.WinGetResource, taskControlPath string
 
 					if stderr == "" {
 						if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-							log.Printf("[INFO]: could not set task %s as successful in JSON control file", r.ID)
+							slog.Info("could not set task as successful in JSON control file", "task_id", r.ID)
 						}
 					}
 				} else {
 					stdout, stderr, err := dsc.AddRegistryKey(key, forceRegistry)
 					if err != nil {
-						log.Printf("[ERROR]: could not add registry key %s, reason: %v", key, err)
+						slog.Error(fmt.Sprintf("could not add registry key %s", key), "error", err)
 						return nil, fmt.Errorf("could not add registry key %s, reason: %v", key, err)
 					}
-					log.Printf("[INFO]: registry key %s has been added", key)
+					slog.Info(fmt.Sprintf("registry key %s has been added", key))
 
 					taskReport.Name = r.ID
 					taskReport.StdOut = stdout
@@ -1020,7 +877,7 @@ func (a *Agent) RegistryTask(r *wingetcfg.WinGetResource, taskControlPath string
 
 					if stderr == "" {
 						if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-							log.Printf("[INFO]: could not set task %s as successful in JSON control file", r.ID)
+							slog.Info("could not set task as successful in JSON control file", "task_id", r.ID)
 						}
 					}
 				}
@@ -1032,7 +889,7 @@ func (a *Agent) RegistryTask(r *wingetcfg.WinGetResource, taskControlPath string
 
 				stdout, stderr, err := dsc.AddOrEditRegistryValue(key, valueName, propertyType, valueData, hex, force)
 				if err != nil {
-					log.Printf("[ERROR]: could not add registry value key %s, reason: %v", valueName, err)
+					slog.Error(fmt.Sprintf("could not add registry value key %s", valueName), "error", err)
 					return nil, fmt.Errorf("could not add registry value key %s, reason: %v", valueName, err)
 				}
 
@@ -1044,11 +901,11 @@ func (a *Agent) RegistryTask(r *wingetcfg.WinGetResource, taskControlPath string
 
 				if stderr == "" {
 					if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-						log.Printf("[INFO]: could not set task %s as successful in JSON control file", r.ID)
+						slog.Info("could not set task as successful in JSON control file", "task_id", r.ID)
 					}
 				}
 
-				log.Printf("[INFO]: registry key value %s has been added", valueName)
+				slog.Info(fmt.Sprintf("registry key value %s has been added", valueName))
 			}
 
 		} else {
@@ -1065,10 +922,10 @@ func (a *Agent) RegistryTask(r *wingetcfg.WinGetResource, taskControlPath string
 			if valueName == "" {
 				stdout, stderr, err := dsc.RemoveRegistryKey(key, force)
 				if err != nil {
-					log.Printf("[ERROR]: could not remove registry key %s, reason: %v", key, err)
+					slog.Error(fmt.Sprintf("could not remove registry key %s", key), "error", err)
 					return nil, fmt.Errorf("could not remove registry key %s, reason: %v", key, err)
 				}
-				log.Printf("[INFO]: registry key %s has been removed", key)
+				slog.Info(fmt.Sprintf("registry key %s has been removed", key))
 
 				taskReport.Name = r.ID
 				taskReport.StdOut = stdout
@@ -1078,16 +935,16 @@ func (a *Agent) RegistryTask(r *wingetcfg.WinGetResource, taskControlPath string
 
 				if stderr == "" {
 					if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-						log.Printf("[INFO]: could not set task %s as successful in JSON control file", r.ID)
+						slog.Info("could not set task as successful in JSON control file", "task_id", r.ID)
 					}
 				}
 			} else {
 				stdout, stderr, err := dsc.RemoveRegistryKeyValue(key, valueName)
 				if err != nil {
-					log.Printf("[ERROR]: could not remove registry key value %s, reason: %v", valueName, err)
+					slog.Error(fmt.Sprintf("could not remove registry key value %s", valueName), "error", err)
 					return nil, fmt.Errorf("could not remove registry key value %s, reason: %v", valueName, err)
 				}
-				log.Printf("[INFO]: registry key value %s has been removed", valueName)
+				slog.Info(fmt.Sprintf("registry key value %s has been removed", valueName))
 
 				taskReport.Name = r.ID
 				taskReport.StdOut = stdout
@@ -1097,7 +954,7 @@ func (a *Agent) RegistryTask(r *wingetcfg.WinGetResource, taskControlPath string
 
 				if stderr == "" {
 					if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-						log.Printf("[INFO]: could not set task %s as successful in JSON control file", r.ID)
+						slog.Info("could not set task as successful in JSON control file", "task_id", r.ID)
 					}
 				}
 			}
@@ -1173,7 +1030,7 @@ func (a *Agent) LocalUserTask(r *wingetcfg.WinGetResource, taskControlPath strin
 
 			stdout, stderr, err := dsc.CreateLocalUser(username, password, comment, fullName, disabled, passwordChangeNotAllowed, passwordNeverExpires, changePasswordAtLogon)
 			if err != nil {
-				log.Printf("[ERROR]: could not create the local user, reason: %v", err)
+				slog.Error("could not create the local user", "error", err)
 				return nil, fmt.Errorf("could not create the local user, reason: %v", err)
 			}
 
@@ -1183,18 +1040,18 @@ func (a *Agent) LocalUserTask(r *wingetcfg.WinGetResource, taskControlPath strin
 			taskReport.Failed = stderr != ""
 			taskReport.EndTime = time.Now().Local().String()
 
-			log.Printf("[INFO]: the local user %s has been added", username)
+			slog.Info(fmt.Sprintf("the local user %s has been added", username))
 
 			if stderr == "" {
 				if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-					log.Printf("[INFO]: could not set task %s as successful in JSON control file", r.ID)
+					slog.Info("could not set task as successful in JSON control file", "task_id", r.ID)
 				}
 			}
 
 		} else {
 			stdout, stderr, err := dsc.DeleteLocalUser(username)
 			if err != nil {
-				log.Printf("[ERROR]: could not remove the local user, reason: %v", err)
+				slog.Error("could not remove the local user", "error", err)
 				return nil, fmt.Errorf("could not remove the local user, reason: %v", err)
 			}
 
@@ -1204,11 +1061,11 @@ func (a *Agent) LocalUserTask(r *wingetcfg.WinGetResource, taskControlPath strin
 			taskReport.Failed = stderr != ""
 			taskReport.EndTime = time.Now().Local().String()
 
-			log.Printf("[INFO]: the local user %s has been deleted", username)
+			slog.Info(fmt.Sprintf("the local user %s has been deleted", username))
 
 			if stderr == "" {
 				if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-					log.Printf("[INFO]: could not set task %s as successful in JSON control file", r.ID)
+					slog.Info("could not set task as successful in JSON control file", "task_id", r.ID)
 				}
 			}
 		}
@@ -1260,7 +1117,7 @@ func (a *Agent) LocalGroupTask(r *wingetcfg.WinGetResource, taskControlPath stri
 				stdout, stderr, err := dsc.CreateLocalGroup(groupName, description)
 
 				if err != nil {
-					log.Printf("[ERROR]: could not create local group, reason: %v", err)
+					slog.Error("could not create local group", "error", err)
 					return nil, fmt.Errorf("could not create local group, reason: %v", err)
 				}
 
@@ -1272,11 +1129,11 @@ func (a *Agent) LocalGroupTask(r *wingetcfg.WinGetResource, taskControlPath stri
 					EndTime: time.Now().Local().Format(time.RFC3339Nano),
 				}
 
-				log.Printf("[INFO]: the local group %s has been added", groupName)
+				slog.Info(fmt.Sprintf("the local group %s has been added", groupName))
 
 				if stderr == "" {
 					if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-						log.Printf("[INFO]: could not set task %s as successful in JSON control file", r.ID)
+						slog.Info("could not set task as successful in JSON control file", "task_id", r.ID)
 					}
 				}
 
@@ -1287,7 +1144,7 @@ func (a *Agent) LocalGroupTask(r *wingetcfg.WinGetResource, taskControlPath stri
 				if !dsc.ExistsGroup(groupName) {
 					stdout, stderr, err := dsc.CreateLocalGroup(groupName, description)
 					if err != nil {
-						log.Printf("[ERROR]: could not create local group, reason: %v", err)
+						slog.Error("could not create local group", "error", err)
 						return nil, fmt.Errorf("could not create local group, reason: %v", err)
 					}
 
@@ -1302,15 +1159,15 @@ func (a *Agent) LocalGroupTask(r *wingetcfg.WinGetResource, taskControlPath stri
 					if stderr != "" {
 						return &taskReport, nil
 					}
-					log.Printf("[INFO]: the local group %s has been added", groupName)
+					slog.Info(fmt.Sprintf("the local group %s has been added", groupName))
 				}
 
 				stdout, stderr, err := dsc.AddMembersToLocalGroup(groupName, members)
 				if err != nil {
-					log.Printf("[ERROR]: could not add members to local group, reason: %v", err)
+					slog.Error("could not add members to local group", "error", err)
 					return nil, fmt.Errorf("could not add members to local group, reason: %v", err)
 				}
-				log.Printf("[INFO]: members have been added to local group %s", groupName)
+				slog.Info(fmt.Sprintf("members have been added to local group %s", groupName))
 
 				taskReport := openuem_nats.TaskReport{
 					Name:    r.ID,
@@ -1322,7 +1179,7 @@ func (a *Agent) LocalGroupTask(r *wingetcfg.WinGetResource, taskControlPath stri
 
 				if stderr == "" {
 					if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-						log.Printf("[INFO]: could not set task %s as successful in JSON control file", r.ID)
+						slog.Info("could not set task as successful in JSON control file", "task_id", r.ID)
 					}
 				}
 
@@ -1333,8 +1190,8 @@ func (a *Agent) LocalGroupTask(r *wingetcfg.WinGetResource, taskControlPath stri
 
 				stdout, stderr, err := dsc.AddMembersToLocalGroup(groupName, membersToInclude)
 				if err != nil {
-					log.Println("stad", stdout, stderr)
-					log.Printf("[ERROR]: could not add members to local group, reason: %v", err)
+					slog.Info(fmt.Sprintf("stad %v", stdout), "error", stderr)
+					slog.Error("could not add members to local group", "error", err)
 					return nil, fmt.Errorf("could not add members to local group, reason: %v", err)
 				}
 
@@ -1350,11 +1207,11 @@ func (a *Agent) LocalGroupTask(r *wingetcfg.WinGetResource, taskControlPath stri
 					return &taskReport, nil
 				}
 
-				log.Printf("[INFO]: members have been added to local group %s", groupName)
+				slog.Info(fmt.Sprintf("members have been added to local group %s", groupName))
 
 				if stderr == "" {
 					if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-						log.Printf("[INFO]: could not set task %s as successful in JSON control file", r.ID)
+						slog.Info("could not set task as successful in JSON control file", "task_id", r.ID)
 					}
 				}
 
@@ -1365,10 +1222,10 @@ func (a *Agent) LocalGroupTask(r *wingetcfg.WinGetResource, taskControlPath stri
 
 				stdout, stderr, err := dsc.RemoveMembersFromLocalGroup(groupName, membersToExclude)
 				if err != nil {
-					log.Printf("[ERROR]: could not exclude members from local group, reason: %v", err)
+					slog.Error("could not exclude members from local group", "error", err)
 					return nil, fmt.Errorf("could not exclude members from local group, reason: %v", err)
 				}
-				log.Printf("[INFO]: members have been removed from local group %s", groupName)
+				slog.Info(fmt.Sprintf("members have been removed from local group %s", groupName))
 
 				taskReport := openuem_nats.TaskReport{
 					Name:    r.ID,
@@ -1384,7 +1241,7 @@ func (a *Agent) LocalGroupTask(r *wingetcfg.WinGetResource, taskControlPath stri
 
 				if stderr == "" {
 					if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-						log.Printf("[INFO]: could not set task %s as successful in JSON control file", r.ID)
+						slog.Info("could not set task as successful in JSON control file", "task_id", r.ID)
 					}
 				}
 
@@ -1394,7 +1251,7 @@ func (a *Agent) LocalGroupTask(r *wingetcfg.WinGetResource, taskControlPath stri
 		} else {
 			stdout, stderr, err := dsc.RemoveLocalGroup(groupName)
 			if err != nil {
-				log.Printf("[ERROR]: could not delete local group, reason: %v", err)
+				slog.Error("could not delete local group", "error", err)
 				return nil, fmt.Errorf("could not delete local group, reason: %v", err)
 			}
 
@@ -1406,11 +1263,11 @@ func (a *Agent) LocalGroupTask(r *wingetcfg.WinGetResource, taskControlPath stri
 				EndTime: time.Now().Local().Format(time.RFC3339Nano),
 			}
 
-			log.Printf("[INFO]: the local group %s has been deleted", groupName)
+			slog.Info(fmt.Sprintf("the local group %s has been deleted", groupName))
 
 			if stderr == "" {
 				if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-					log.Printf("[INFO]: could not set task %s as successful in JSON control file", r.ID)
+					slog.Info("could not set task as successful in JSON control file", "task_id", r.ID)
 				}
 			}
 
@@ -1449,12 +1306,12 @@ func (a *Agent) MSIPackageTask(r *wingetcfg.WinGetResource, taskControlPath stri
 		if ensure == "Present" {
 			stdout, stderr, err := dsc.InstallMSIPackage(path, arguments, logPath)
 			if err != nil {
-				log.Printf("[ERROR]: could not install MSI package reason: %v", err)
+				slog.Error("could not install MSI package", "error", err)
 				return nil, fmt.Errorf("could not install MSI package reason: %v", err)
 			}
 
 			if stderr == "" {
-				log.Printf("[INFO]: MSI package has been installed from %s", path)
+				slog.Info(fmt.Sprintf("MSI package has been installed from %s", path))
 			}
 
 			taskReport := openuem_nats.TaskReport{
@@ -1467,7 +1324,7 @@ func (a *Agent) MSIPackageTask(r *wingetcfg.WinGetResource, taskControlPath stri
 
 			if stderr == "" {
 				if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-					log.Printf("[INFO]: could not set task %s as successful in JSON control file", r.ID)
+					slog.Info("could not set task as successful in JSON control file", "task_id", r.ID)
 				}
 			}
 
@@ -1476,12 +1333,12 @@ func (a *Agent) MSIPackageTask(r *wingetcfg.WinGetResource, taskControlPath stri
 		} else {
 			stdout, stderr, err := dsc.UninstallMSIPackage(path, arguments, logPath)
 			if err != nil {
-				log.Printf("[ERROR]: could not uninstall MSI package reason: %v", err)
+				slog.Error("could not uninstall MSI package", "error", err)
 				return nil, fmt.Errorf("could not uninstall MSI package reason: %v", err)
 			}
 
 			if stderr == "" {
-				log.Printf("[INFO]: MSI package %s has been uninstalled", path)
+				slog.Info("MSI package has been uninstalled", "package_id", path)
 			}
 
 			taskReport := openuem_nats.TaskReport{
@@ -1494,7 +1351,7 @@ func (a *Agent) MSIPackageTask(r *wingetcfg.WinGetResource, taskControlPath stri
 
 			if stderr == "" {
 				if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-					log.Printf("[INFO]: could not set task %s as successful in JSON control file", r.ID)
+					slog.Info("could not set task as successful in JSON control file", "task_id", r.ID)
 				}
 			}
 
@@ -1533,15 +1390,15 @@ func (a *Agent) PowershellTask(r *wingetcfg.WinGetResource, taskControlPath stri
 					task.RunConfig = "once"
 				}
 			} else {
-				log.Println("[ERROR]: could not find ID key in task's settings")
+				slog.Error("could not find ID key in task's settings")
 				return nil, errors.New("could not find ID key in task's settings")
 			}
 		} else {
-			log.Println("[ERROR]: could not find Name key in task's settings")
+			slog.Error("could not find Name key in task's settings")
 			return nil, errors.New("could not find Name key in task's settings")
 		}
 	} else {
-		log.Println("[ERROR]: could not find Script key in task's settings")
+		slog.Error("could not find Script key in task's settings")
 		return nil, errors.New("could not find script key in task's settings")
 	}
 
@@ -1555,7 +1412,7 @@ func (a *Agent) PowershellTask(r *wingetcfg.WinGetResource, taskControlPath stri
 					return nil, err
 				}
 
-				log.Printf("[INFO]: powershell script %s run successfully", task.Name)
+				slog.Info(fmt.Sprintf("powershell script %s run successfully", task.Name))
 
 				taskReport := openuem_nats.TaskReport{
 					Name:    task.ID,
@@ -1567,7 +1424,7 @@ func (a *Agent) PowershellTask(r *wingetcfg.WinGetResource, taskControlPath stri
 
 				if stderr == "" {
 					if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-						log.Printf("[INFO]: could not set task %s as successful in JSON control file", r.ID)
+						slog.Info("could not set task as successful in JSON control file", "task_id", r.ID)
 					}
 				}
 
@@ -1580,7 +1437,7 @@ func (a *Agent) PowershellTask(r *wingetcfg.WinGetResource, taskControlPath stri
 			return nil, err
 		}
 
-		log.Printf("[INFO]: powershell script %s run successfully", task.ID)
+		slog.Info("powershell script run successfully", "task_id", task.ID)
 
 		taskReport := openuem_nats.TaskReport{
 			Name:    task.ID,
@@ -1592,7 +1449,7 @@ func (a *Agent) PowershellTask(r *wingetcfg.WinGetResource, taskControlPath stri
 
 		if stderr == "" {
 			if err := dsc.SetTaskAsSuccessfull(r.ID, taskControlPath, t); err != nil {
-				log.Printf("[INFO]: could not set task %s as successful in JSON control file", r.ID)
+				slog.Info("could not set task as successful in JSON control file", "task_id", r.ID)
 			}
 		}
 
@@ -1668,219 +1525,100 @@ func getCommaSeparatedStringKey(r *wingetcfg.WinGetResource, key string, require
 	return strings.Join(csValues, ", "), nil
 }
 
-func ReadDeploymentNotACK() ([]openuem_nats.DeployAction, error) {
+// defaultDataFolder is where the agent keeps its certificates, databases and pending results unless DataDir is set
+func defaultDataFolder() string {
 	cwd, err := Getwd()
 	if err != nil {
-		return nil, err
+		return "."
 	}
+	return cwd
+}
 
-	filename := filepath.Join(cwd, "pending_acks.json")
-	jsonFile, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
-	if err != nil {
-		return nil, err
-	}
-	defer jsonFile.Close()
+func defaultLogFolder() string {
+	return filepath.Join(defaultDataFolder(), "logs")
+}
 
-	byteValue, err := io.ReadAll(jsonFile)
-	if err != nil {
-		return nil, err
-	}
+func (a *Agent) AgentRunTaskHandler(msg *nats.Msg) error {
+	var profileConfig openuem_nats.ProfileConfig
 
-	jActions := JSONActions{}
-	if len(byteValue) > 0 {
-		err = json.Unmarshal(byteValue, &jActions)
-		if err != nil {
-			return nil, err
-		}
-		return jActions.Actions, nil
+	// Unmarshal data
+	profileReport := openuem_nats.ProfileReport{
+		AgentID: a.Config.UUID,
 	}
 
-	return []openuem_nats.DeployAction{}, nil
-}
-
-func SaveDeploymentsNotACK(actions []openuem_nats.DeployAction) error {
-	cwd, err := Getwd()
-	if err != nil {
-		return err
+	if err := yaml.Unmarshal(msg.Data, &profileConfig); err != nil {
+		return fmt.Errorf("could not unmarshall profile config %v", err)
 	}
 
-	filename := filepath.Join(cwd, "pending_acks.json")
-	jsonFile, err := os.Create(filename)
+	// Read task control file
+	cwd, err := openuem_utils.GetWd()
 	if err != nil {
-		return err
+		return fmt.Errorf("could not get working directory, reason %v", err)
 	}
-	defer jsonFile.Close()
-
-	jActions := JSONActions{}
-	jActions.Actions = actions
+	taskControlPath := filepath.Join(cwd, "powershell", "tasks.json")
+	taskControl, err := dsc.ReadTaskControlFile(taskControlPath)
+	profileReport.ProfileID = profileConfig.ProfileID
 
-	byteValue, err := json.MarshalIndent(jActions, "", " ")
 	if err != nil {
-		return err
+		return fmt.Errorf("tasks control file is not available, reason %v", err)
 	}
 
-	_, err = jsonFile.Write(byteValue)
+	cfg, err := yaml.Marshal(profileConfig.WinGetConfig)
 	if err != nil {
-		return err
+		return fmt.Errorf("could not marshal YAML file with Windows task configuration, reason: %v", err)
 	}
 
-	return nil
-}
-
-func SaveDeploymentNotACK(action openuem_nats.DeployAction) error {
-	var actions []openuem_nats.DeployAction
-	cwd, err := Getwd()
+	task, err := a.InFlight.StartProfile(profileReport)
 	if err != nil {
 		return err
 	}
+	defer task.Done()
 
-	filename := filepath.Join(cwd, "pending_acks.json")
-	jsonFile, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
-	if err != nil {
-		return err
+	// All is fine, we can execute the task and we'll report later
+	if err := msg.Respond(nil); err != nil {
+		slog.Error("could not send the response to agent.windowstask message")
 	}
-	defer jsonFile.Close()
 
-	byteValue, err := io.ReadAll(jsonFile)
+	tasks, err := a.ApplyConfiguration(profileConfig.ProfileID, cfg, profileConfig.Exclusions, profileConfig.Exclusions, taskControl, taskControlPath, true)
 	if err != nil {
-		return err
-	}
+		slog.Error("could not apply YAML Windows task file")
+		profileReport.Error = err.Error()
 
-	jActions := JSONActions{}
-
-	if len(byteValue) > 0 {
-		err = json.Unmarshal(byteValue, &jActions)
-		if err != nil {
-			return err
+		// Report if application was successful or not
+		if err := a.SendProfileReport(&profileReport); err != nil {
+			slog.Error("could not report if profile was applied succesfully or no")
 		}
-		actions = jActions.Actions
+		return nil
 	}
 
-	actions = append(actions, action)
-
-	if err := SaveDeploymentsNotACK(actions); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (a *Agent) AgentRunTaskSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.windowstask."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-		var profileConfig openuem_nats.ProfileConfig
-
-		// Unmarshal data
-		profileReport := openuem_nats.ProfileReport{
-			AgentID: a.Config.UUID,
-		}
-
-		if err := yaml.Unmarshal(msg.Data, &profileConfig); err != nil {
-			log.Println("[ERROR]: could not unmarshall profile config")
-			profileReport.Error = fmt.Sprintf("could not unmarshall profile config %v", err)
-
-			response, err := yaml.Marshal(profileReport)
-			if err != nil {
-				log.Printf("[ERROR]: could not marshal response to agent.windowstask request, reason: %v", err)
-				return
-			}
-
-			if err := msg.Respond(response); err != nil {
-				log.Printf("[ERROR]: could not send response to agent.windowstask request, reason: %v", err)
-				return
-			}
-			return
-		}
-
-		// Read task control file
-		cwd, err := openuem_utils.GetWd()
-		if err != nil {
-			log.Printf("[ERROR]: could not get working directory, reason %v", err)
-			return
-		}
-		taskControlPath := filepath.Join(cwd, "powershell", "tasks.json")
-		taskControl, err := dsc.ReadTaskControlFile(taskControlPath)
-		profileReport.ProfileID = profileConfig.ProfileID
-
-		if err != nil {
-			log.Printf("[ERROR]: tasks control file is not available, reason %v", err)
-			return
-		}
-
-		cfg, err := yaml.Marshal(profileConfig.WinGetConfig)
-		if err != nil {
-			log.Printf("[ERROR]: could not marshal YAML file with Windows task configuration, reason: %v", err)
-
-			response, err := json.Marshal(profileReport)
-			if err != nil {
-				log.Printf("[ERROR]: could not marshal response to agent.windowstask request, reason: %v", err)
-				return
-			}
-
-			if err := msg.Respond(response); err != nil {
-				log.Printf("[ERROR]: could not send response to agent.windowstask request, reason: %v", err)
-				return
-			}
-			return
-		}
-
-		// All is fine, we can execute the task and we'll report later
-		if err := msg.Respond(nil); err != nil {
-			log.Printf("[ERROR]: could not send the response to agent.windowstask message")
-		}
-
-		tasks, err := a.ApplyConfiguration(profileConfig.ProfileID, cfg, profileConfig.Exclusions, profileConfig.Exclusions, taskControl, taskControlPath, true)
-		if err != nil {
-			log.Println("[ERROR]: could not apply YAML Windows task file")
-			profileReport.Error = err.Error()
-
-			// Report if application was successful or not
-			if err := a.SendProfileReport(&profileReport); err != nil {
-				log.Println("[ERROR]: could not report if profile was applied succesfully or no")
-			}
-			return
-		}
-
-		profileReport.Tasks = tasks
-		profileReport.Success = true
-		for _, t := range tasks {
-			if t.Failed {
-				profileReport.Success = false
-			}
+	profileReport.Tasks = tasks
+	profileReport.Success = true
+	for _, t := range tasks {
+		if t.Failed {
+			profileReport.Success = false
 		}
+	}
 
-		// Report as the task has finished
-		if err := a.SendProfileReport(&profileReport); err != nil {
-			log.Println("[ERROR]: could not report if profile was applied succesfully or no")
-		}
-
-	})
-
-	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent windows task execution, reason: %v", err)
+	// Report as the task has finished
+	if err := a.SendProfileReport(&profileReport); err != nil {
+		slog.Error("could not report if profile was applied succesfully or no")
 	}
 	return nil
 }
 
-func (a *Agent) RunProfileSubscribe() error {
-	_, err := a.NATSConnection.QueueSubscribe("agent.runprofile."+a.Config.UUID, "openuem-agent-management", func(msg *nats.Msg) {
-		if err := msg.Respond(nil); err != nil {
-			log.Printf("[ERROR]: could not respond to console request to run a profile, reason: %v", err)
-		}
-
-		msg, err := a.NATSConnection.Request("wingetcfg.profiles", msg.Data, 5*time.Minute)
-		if err != nil {
-			log.Printf("[ERROR]: could not send request to agent worker, reason: %v", err)
-			if err := a.Config.SetRestartRequiredFlag(); err != nil {
-				log.Printf("[ERROR]: could not set restart required flag, reason: %v\n", err)
-				return
-			}
-		}
-
-		a.ProcessProfileResponse(msg, true)
-	})
+func (a *Agent) RunProfileHandler(msg *nats.Msg) error {
+	if err := msg.Respond(nil); err != nil {
+		slog.Error("could not respond to console request to run a profile", "error", err)
+	}
 
+	msg, err := a.NATSConnection.Request("wingetcfg.profiles", msg.Data, 5*time.Minute)
 	if err != nil {
-		return fmt.Errorf("[ERROR]: could not subscribe to agent run profile subject, reason: %v", err)
+		slog.Error("could not send request to agent worker", "error", err)
+		if err := a.Config.SetRestartRequiredFlag(); err != nil {
+			return handlers.Responded(fmt.Errorf("could not set restart required flag, reason: %v", err))
+		}
 	}
+
+	a.ProcessProfileResponse(msg, true)
 	return nil
 }
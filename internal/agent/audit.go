@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/open-uem/openuem-agent/internal/agent/audit"
+	"github.com/open-uem/openuem-agent/internal/agent/handlers"
+	"github.com/open-uem/openuem-agent/internal/agent/keys"
+	"github.com/open-uem/openuem-agent/internal/agent/signing"
+)
+
+type AuditResponse struct {
+	Entries      []audit.Entry      `json:"entries"`
+	Verification audit.Verification `json:"verification"`
+	Truncated    bool               `json:"truncated,omitempty"`
+}
+
+func AuditLogPath(dataDir string) string {
+	return filepath.Join(dataDir, audit.FILENAME)
+}
+
+// AuditKey derives the key of the audit log from agent.key, so the entries can only
+// be written by someone who can read the private key of the agent
+func AuditKey(c Config) ([]byte, error) {
+	key, err := keys.ReadPrivateKey(c.AgentKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the agent private key, reason: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode the agent private key, reason: %v", err)
+	}
+
+	mac := hmac.New(sha256.New, der)
+	mac.Write([]byte("openuem-agent audit log"))
+	return mac.Sum(nil), nil
+}
+
+// VerifyAuditLog checks the chain of the audit log with the current agent key
+func VerifyAuditLog(c Config) audit.Verification {
+	key, err := AuditKey(c)
+	if err != nil {
+		return audit.Verification{Error: err.Error()}
+	}
+	return audit.Verify(AuditLogPath(c.DataDir), key)
+}
+
+func (a *Agent) OpenAuditLog() {
+	key, err := AuditKey(a.Config)
+	if err == nil {
+		a.AuditLog, err = audit.Open(AuditLogPath(a.Config.DataDir), key)
+	}
+	if err != nil {
+		slog.Error("could not open the audit log, remote actions won't be audited", "error", err)
+	}
+}
+
+// rekeyAuditLog signs the next entries with the key derived from the renewed agent.key
+func (a *Agent) rekeyAuditLog() {
+	if a.AuditLog == nil {
+		return
+	}
+
+	key, err := AuditKey(a.Config)
+	if err != nil {
+		slog.Error("could not change the key of the audit log", "error", err)
+		return
+	}
+	a.AuditLog.SetKey(key)
+}
+
+// recordAudit appends the result of an audited handler to the audit log
+func (a *Agent) recordAudit(h *handlers.Handler, msg *nats.Msg, start time.Time, err error) {
+	a.auditCommand(command{Name: h.Name, Subject: msg.Subject, Header: msg.Header, Data: msg.Data}, start, err)
+}
+
+func (a *Agent) auditCommand(c command, start time.Time, err error) {
+	if a.AuditLog == nil {
+		slog.Warn("remote action could not be audited, the audit log is not open", "handler", c.Name, "subject", c.Subject)
+		return
+	}
+
+	e := audit.Entry{
+		Time:       start,
+		Handler:    c.Name,
+		Subject:    c.Subject,
+		Requester:  audit.Headers(requesterHeaders(c.Header)),
+		Parameters: audit.Parameters(c.Data),
+		Result:     audit.RESULT_SUCCESS,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		e.Result = audit.RESULT_ERROR
+		e.Error = err.Error()
+	}
+
+	if _, err := a.AuditLog.Append(e); err != nil {
+		slog.Error("could not write to the audit log", "handler", c.Name, "subject", c.Subject, "error", err)
+	}
+}
+
+// requesterHeaders leaves out the signature, its status header tells who signed the command
+func requesterHeaders(header nats.Header) nats.Header {
+	r := nats.Header{}
+	for k, v := range header {
+		if k != signing.HEADER {
+			r[k] = v
+		}
+	}
+	return r
+}
+
+// AuditHandler answers with the last entries of the audit log and whether its chain is intact,
+// an empty payload returns the last DEFAULT_QUERY_LIMIT entries
+func (a *Agent) AuditHandler(msg *nats.Msg) error {
+	q := audit.Query{}
+	if len(msg.Data) > 0 {
+		if err := json.Unmarshal(msg.Data, &q); err != nil {
+			return fmt.Errorf("could not decode audit.Query payload, reason: %v", err)
+		}
+	}
+
+	path := AuditLogPath(a.Config.DataDir)
+	entries, err := audit.Read(path, q)
+	if err != nil {
+		return fmt.Errorf("could not read the audit log, reason: %v", err)
+	}
+
+	r := AuditResponse{Entries: entries, Verification: VerifyAuditLog(a.Config)}
+	maxPayload := int(a.NATSConnection.MaxPayload())
+	for {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+
+		// The oldest entries are left out until the response fits in a message
+		if len(data) > maxPayload && len(r.Entries) > 0 {
+			r.Entries = r.Entries[max(1, len(r.Entries)/4):]
+			r.Truncated = true
+			continue
+		}
+
+		if err := msg.Respond(data); err != nil {
+			return handlers.Responded(err)
+		}
+		return nil
+	}
+}
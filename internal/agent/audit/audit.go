@@ -0,0 +1,378 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const FILENAME = "audit.log"
+
+const RESULT_SUCCESS = "success"
+const RESULT_ERROR = "error"
+
+// GENESIS_HASH is the previous hash of the first entry
+const GENESIS_HASH = "0000000000000000000000000000000000000000000000000000000000000000"
+
+const DEFAULT_QUERY_LIMIT = 100
+
+const DEFAULT_MAX_SIZE_MB = 10
+const DEFAULT_MAX_BACKUPS = 5
+
+// Entry is a remote action carried out by the agent. Hash is an HMAC of the rest of
+// the fields, PrevHash included, so changing or removing an entry breaks the chain
+// and the entries can't be forged without the key. KeyID tells which key was used
+type Entry struct {
+	Seq        uint64            `json:"seq"`
+	Time       time.Time         `json:"time"`
+	Handler    string            `json:"handler"`
+	Subject    string            `json:"subject"`
+	Requester  map[string]string `json:"requester,omitempty"`
+	Parameters json.RawMessage   `json:"parameters,omitempty"`
+	Result     string            `json:"result"`
+	Error      string            `json:"error,omitempty"`
+	DurationMs int64             `json:"duration_ms"`
+	KeyID      string            `json:"key_id,omitempty"`
+	PrevHash   string            `json:"prev_hash"`
+	Hash       string            `json:"hash"`
+}
+
+func (e Entry) computeHash(key []byte) (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// KeyID identifies a key without revealing it
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Log is an append-only file with one JSON entry per line. The file is moved to a backup
+// once it grows beyond MaxSize and only the newest MaxBackups backups are kept. The chain
+// goes on in the new file, so its first entry points to the last entry of the backup
+type Log struct {
+	mu         sync.Mutex
+	Path       string
+	MaxSize    int64
+	MaxBackups int
+	key        []byte
+	seq        uint64
+	lastHash   string
+}
+
+// Open continues the chain of the entries already in the file or, if it has just been
+// rotated, of the entries in the newest backup. New entries are signed with key
+func Open(path string, key []byte) (*Log, error) {
+	if len(key) == 0 {
+		return nil, errors.New("the audit log needs a key")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	l := &Log{
+		Path:       path,
+		MaxSize:    DEFAULT_MAX_SIZE_MB * 1024 * 1024,
+		MaxBackups: DEFAULT_MAX_BACKUPS,
+		key:        key,
+		lastHash:   GENESIS_HASH,
+	}
+	last := func(e Entry) bool {
+		l.seq = e.Seq
+		l.lastHash = e.Hash
+		return true
+	}
+
+	err := scan(path, last)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if l.seq > 0 {
+		return l, nil
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) > 0 {
+		if err := scan(backups[len(backups)-1], last); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Backups returns the rotated files of the audit log, oldest first
+func Backups(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// SetKey signs the next entries with a new key, the chain goes on from the last entry
+func (l *Log) SetKey(key []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.key = key
+}
+
+// Append sets the sequence and the hashes of the entry and writes it to disk
+func (l *Log) Append(e Entry) (Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Seq = l.seq + 1
+	e.KeyID = KeyID(l.key)
+	e.PrevHash = l.lastHash
+	hash, err := e.computeHash(l.key)
+	if err != nil {
+		return e, err
+	}
+	e.Hash = hash
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return e, err
+	}
+
+	if err := l.rotate(int64(len(data) + 1)); err != nil {
+		// Losing the rotation is better than losing the entry
+		fmt.Fprintf(os.Stderr, "could not rotate the audit log, reason: %v\n", err)
+	}
+
+	f, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return e, err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return e, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return e, err
+	}
+	if err := f.Close(); err != nil {
+		return e, err
+	}
+
+	l.seq = e.Seq
+	l.lastHash = e.Hash
+	return e, nil
+}
+
+// rotate moves the file to a backup if writing size bytes would make it bigger than MaxSize
+func (l *Log) rotate(size int64) error {
+	info, err := os.Stat(l.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if l.MaxSize <= 0 || info.Size() == 0 || info.Size()+size <= l.MaxSize {
+		return nil
+	}
+
+	// Backups are named after their last entry, padded so they sort in order
+	backup := fmt.Sprintf("%s.%020d", l.Path, l.seq)
+	if err := os.Rename(l.Path, backup); err != nil {
+		return err
+	}
+
+	backups, err := Backups(l.Path)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(backups)-l.MaxBackups; i++ {
+		if err := os.Remove(backups[i]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Query selects the last Limit entries, optionally those after Since whose handler starts with Handler
+type Query struct {
+	Limit   int       `json:"limit,omitempty"`
+	Since   time.Time `json:"since,omitempty"`
+	Handler string    `json:"handler,omitempty"`
+}
+
+// Read returns the entries that match the query, oldest first
+func Read(path string, q Query) ([]Entry, error) {
+	if q.Limit <= 0 {
+		q.Limit = DEFAULT_QUERY_LIMIT
+	}
+
+	entries := []Entry{}
+	err := scanAll(path, func(e Entry) bool {
+		if !q.Since.IsZero() && e.Time.Before(q.Since) {
+			return true
+		}
+		if q.Handler != "" && !strings.HasPrefix(e.Handler, q.Handler) {
+			return true
+		}
+		entries = append(entries, e)
+		if len(entries) > q.Limit {
+			entries = entries[1:]
+		}
+		return true
+	})
+	return entries, err
+}
+
+// Verification is the result of checking the chain of the file and its backups. Unverified
+// counts the entries signed with a previous key, only their place in the chain is checked.
+// Discarded counts the entries of the backups removed by the rotation
+type Verification struct {
+	Entries    int    `json:"entries"`
+	Valid      bool   `json:"valid"`
+	Unverified int    `json:"unverified,omitempty"`
+	Discarded  uint64 `json:"discarded,omitempty"`
+	BrokenAt   uint64 `json:"broken_at,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Verify recomputes the HMAC of every entry signed with key and checks that each entry
+// points to the previous one, across the backups too. The key changes when the agent
+// certificate is renewed, so the entries written before can't be checked anymore. Removing
+// the last entries of the file can't be detected either, the number of entries is shown to
+// the console to compare it. If the oldest backups have been removed by the rotation, the
+// chain starts at the first entry of the oldest backup left
+func Verify(path string, key []byte) Verification {
+	v := Verification{Valid: true}
+	prev := GENESIS_HASH
+	seq := uint64(0)
+	keyID := KeyID(key)
+	current := false
+
+	fail := func(at uint64, format string, args ...any) bool {
+		v.Valid = false
+		v.BrokenAt = at
+		v.Error = fmt.Sprintf(format, args...)
+		return false
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		fail(1, "could not find the backups of the audit log, reason: %v", err)
+		return v
+	}
+
+	err = scanAll(path, func(e Entry) bool {
+		v.Entries++
+		if v.Entries == 1 && len(backups) > 0 && e.Seq > 1 {
+			v.Discarded = e.Seq - 1
+			seq = e.Seq - 1
+			prev = e.PrevHash
+		}
+		if e.Seq != seq+1 {
+			return fail(seq+1, "entry %d is missing, found entry %d", seq+1, e.Seq)
+		}
+		if e.PrevHash != prev {
+			return fail(e.Seq, "entry %d doesn't point to the previous entry", e.Seq)
+		}
+		if e.KeyID != keyID {
+			// Keys are only replaced, an older key can't be found after the current one
+			if current {
+				return fail(e.Seq, "entry %d has been modified", e.Seq)
+			}
+			v.Unverified++
+			seq = e.Seq
+			prev = e.Hash
+			return true
+		}
+		hash, err := e.computeHash(key)
+		if err != nil {
+			return fail(e.Seq, "could not compute the hash of entry %d, reason: %v", e.Seq, err)
+		}
+		if !hmac.Equal([]byte(hash), []byte(e.Hash)) {
+			return fail(e.Seq, "entry %d has been modified", e.Seq)
+		}
+		current = true
+		seq = e.Seq
+		prev = e.Hash
+		return true
+	})
+	if err != nil && v.Valid {
+		fail(seq+1, "%v", err)
+	}
+	return v
+}
+
+// scanAll calls fn for every entry in the backups and then in the file until fn returns false
+func scanAll(path string, fn func(e Entry) bool) error {
+	files, err := Backups(path)
+	if err != nil {
+		return err
+	}
+
+	stopped := false
+	next := func(e Entry) bool {
+		stopped = !fn(e)
+		return !stopped
+	}
+	for _, file := range append(files, path) {
+		if err := scan(file, next); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("%s: %v", filepath.Base(file), err)
+		}
+		if stopped {
+			return nil
+		}
+	}
+	return nil
+}
+
+// scan calls fn for every entry in the file until fn returns false
+func scan(path string, fn func(e Entry) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		e := Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("line %d is not a valid entry, reason: %v", line, err)
+		}
+		if !fn(e) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
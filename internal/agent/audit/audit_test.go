@@ -0,0 +1,305 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newLog(t *testing.T, key []byte, entries int) *Log {
+	t.Helper()
+
+	l, err := Open(filepath.Join(t.TempDir(), FILENAME), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < entries; i++ {
+		if _, err := l.Append(Entry{Time: time.Now(), Handler: "agent.reboot", Subject: "agent.reboot.test", Result: RESULT_SUCCESS}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return l
+}
+
+// editLines applies fn to the lines of the log file and writes them back
+func editLines(t *testing.T, path string, fn func(lines [][]byte) [][]byte) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := fn(bytes.Split(bytes.TrimSpace(data), []byte("\n")))
+	if err := os.WriteFile(path, append(bytes.Join(lines, []byte("\n")), '\n'), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	key := []byte("agent key")
+
+	tests := []struct {
+		name     string
+		key      []byte
+		edit     func(lines [][]byte) [][]byte
+		valid    bool
+		brokenAt uint64
+		err      string
+	}{
+		{name: "intact", key: key, valid: true},
+		{name: "modified entry", key: key, edit: func(lines [][]byte) [][]byte {
+			lines[1] = bytes.Replace(lines[1], []byte("agent.reboot.test"), []byte("agent.poweroff.test"), 1)
+			return lines
+		}, brokenAt: 2, err: "has been modified"},
+		{name: "removed entry", key: key, edit: func(lines [][]byte) [][]byte {
+			return append(lines[:1], lines[2:]...)
+		}, brokenAt: 2, err: "is missing"},
+		{name: "reordered entries", key: key, edit: func(lines [][]byte) [][]byte {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		}, brokenAt: 2, err: "is missing"},
+		{name: "not a JSON entry", key: key, edit: func(lines [][]byte) [][]byte {
+			return append(lines, []byte("not json"))
+		}, brokenAt: 4, err: "not a valid entry"},
+		{name: "other key", key: []byte("other key"), edit: nil, valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLog(t, key, 3)
+			if tt.edit != nil {
+				editLines(t, l.Path, tt.edit)
+			}
+
+			v := Verify(l.Path, tt.key)
+			if v.Valid != tt.valid || v.BrokenAt != tt.brokenAt || !strings.Contains(v.Error, tt.err) {
+				t.Fatalf("Verify() = %+v, want valid %v broken at %d with %q", v, tt.valid, tt.brokenAt, tt.err)
+			}
+		})
+	}
+}
+
+func TestVerifyForgedEntry(t *testing.T) {
+	l := newLog(t, []byte("agent key"), 2)
+
+	// Whoever can write the file but not read the key can't add valid entries
+	forger, err := Open(l.Path, []byte("guessed key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := forger.Append(Entry{Handler: "agent.reboot", Result: RESULT_SUCCESS}); err != nil {
+		t.Fatal(err)
+	}
+
+	v := Verify(l.Path, []byte("agent key"))
+	if v.Valid || v.BrokenAt != 3 {
+		t.Fatalf("Verify() = %+v, want broken at 3", v)
+	}
+}
+
+func TestVerifyAfterRekey(t *testing.T) {
+	l := newLog(t, []byte("old key"), 2)
+
+	l.SetKey([]byte("new key"))
+	for i := 0; i < 2; i++ {
+		if _, err := l.Append(Entry{Handler: "agent.reboot", Result: RESULT_SUCCESS}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v := Verify(l.Path, []byte("new key"))
+	if !v.Valid || v.Entries != 4 || v.Unverified != 2 {
+		t.Fatalf("Verify() = %+v, want 4 valid entries with 2 unverified", v)
+	}
+
+	// Entries with an older key can't come after the current key
+	editLines(t, l.Path, func(lines [][]byte) [][]byte {
+		lines[3] = bytes.Replace(lines[3], []byte(KeyID([]byte("new key"))), []byte(KeyID([]byte("old key"))), 1)
+		return lines
+	})
+	if v := Verify(l.Path, []byte("new key")); v.Valid {
+		t.Fatalf("Verify() = %+v, want an invalid chain", v)
+	}
+}
+
+func TestOpenContinuesChain(t *testing.T) {
+	key := []byte("agent key")
+	l := newLog(t, key, 2)
+
+	reopened, err := Open(l.Path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := reopened.Append(Entry{Handler: "agent.reboot", Result: RESULT_SUCCESS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Seq != 3 {
+		t.Errorf("entry has sequence %d, want 3", e.Seq)
+	}
+	if v := Verify(l.Path, key); !v.Valid || v.Entries != 3 {
+		t.Errorf("Verify() = %+v, want 3 valid entries", v)
+	}
+}
+
+func TestRead(t *testing.T) {
+	l := newLog(t, []byte("agent key"), 5)
+	if _, err := l.Append(Entry{Time: time.Now(), Handler: "agent.netbird.register", Result: RESULT_SUCCESS}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		q    Query
+		want int
+	}{
+		{q: Query{}, want: 6},
+		{q: Query{Limit: 2}, want: 2},
+		{q: Query{Handler: "agent.netbird"}, want: 1},
+		{q: Query{Since: time.Now().Add(time.Hour)}, want: 0},
+	}
+
+	for _, tt := range tests {
+		entries, err := Read(l.Path, tt.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != tt.want {
+			t.Errorf("Read(%+v) = %d entries, want %d", tt.q, len(entries), tt.want)
+		}
+	}
+}
+
+func TestOpenCreatesDataDir(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "openuem", "data")
+	l, err := Open(filepath.Join(dataDir, FILENAME), []byte("agent key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Append(Entry{Handler: "agent.reboot", Result: RESULT_SUCCESS}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A folder without the execute bit can't be entered by a non-root agent
+	info, err := os.Stat(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0700 {
+		t.Errorf("data folder has permissions %v, want 0700", info.Mode().Perm())
+	}
+}
+
+// newRotatingLog returns a log with one entry whose files have room for two entries
+func newRotatingLog(t *testing.T, key []byte) *Log {
+	t.Helper()
+
+	l := newLog(t, key, 0)
+	if _, err := l.Append(Entry{Handler: "agent.reboot", Subject: "agent.reboot.test", Result: RESULT_SUCCESS}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(l.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Some room is left for the entries with a longer sequence
+	l.MaxSize = 2*info.Size() + 10
+	l.MaxBackups = 2
+	return l
+}
+
+func TestRotate(t *testing.T) {
+	key := []byte("agent key")
+	l := newRotatingLog(t, key)
+
+	for i := 1; i < 4; i++ {
+		if _, err := l.Append(Entry{Handler: "agent.reboot", Subject: "agent.reboot.test", Result: RESULT_SUCCESS}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := Backups(l.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("got %d backups, want 1", len(backups))
+	}
+
+	// The first entry of the new file points to the last entry of the backup
+	if v := Verify(l.Path, key); !v.Valid || v.Entries != 4 || v.Discarded != 0 {
+		t.Fatalf("Verify() = %+v, want 4 valid entries", v)
+	}
+	entries, err := Read(l.Path, Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 || entries[0].Seq != 1 || entries[3].Seq != 4 {
+		t.Errorf("Read() = %d entries, want the 4 entries in order", len(entries))
+	}
+
+	// Entries in a backup can't be modified either
+	editLines(t, backups[0], func(lines [][]byte) [][]byte {
+		lines[1] = bytes.Replace(lines[1], []byte("agent.reboot.test"), []byte("agent.poweroff.test"), 1)
+		return lines
+	})
+	if v := Verify(l.Path, key); v.Valid || v.BrokenAt != 2 {
+		t.Errorf("Verify() = %+v, want broken at 2", v)
+	}
+}
+
+func TestRotateRemovesOldBackups(t *testing.T) {
+	key := []byte("agent key")
+	l := newRotatingLog(t, key)
+
+	for i := 1; i < 8; i++ {
+		if _, err := l.Append(Entry{Handler: "agent.reboot", Subject: "agent.reboot.test", Result: RESULT_SUCCESS}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := Backups(l.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("got %d backups, want 2", len(backups))
+	}
+
+	// The chain starts at the oldest backup left
+	if v := Verify(l.Path, key); !v.Valid || v.Entries != 6 || v.Discarded != 2 {
+		t.Errorf("Verify() = %+v, want 6 valid entries and 2 discarded", v)
+	}
+}
+
+func TestOpenContinuesChainAfterRotation(t *testing.T) {
+	key := []byte("agent key")
+	l := newLog(t, key, 2)
+	l.MaxSize = 1
+
+	// The entry is written to a new file, which is removed to leave only the backup
+	if _, err := l.Append(Entry{Handler: "agent.reboot", Result: RESULT_SUCCESS}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(l.Path, l.Path+".99999999999999999999"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Open(l.Path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := reopened.Append(Entry{Handler: "agent.reboot", Result: RESULT_SUCCESS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Seq != 4 {
+		t.Errorf("entry has sequence %d, want 4", e.Seq)
+	}
+	if v := Verify(l.Path, key); !v.Valid || v.Entries != 4 {
+		t.Errorf("Verify() = %+v, want 4 valid entries", v)
+	}
+}
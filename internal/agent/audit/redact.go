@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+const REDACTED = "[REDACTED]"
+
+// MAX_PARAMETERS_SIZE keeps big payloads, like Ansible profiles, from filling the audit log
+const MAX_PARAMETERS_SIZE = 16 * 1024
+
+var secretKey = regexp.MustCompile(`(?i)(pass|secret|token|credential|private|^pin$|^key$|[_-]key$|apikey|setupkey|authorization)`)
+
+// secretLine matches "name: value" and "name=value" lines whose name looks like a secret
+var secretLine = regexp.MustCompile(`(?im)^(\s*-?\s*["']?[\w.-]*(pass|secret|token|credential|private|pin|key)[\w.-]*["']?\s*[:=]\s*)(\S.*)$`)
+
+// Parameters returns the payload with the values of secret looking fields replaced,
+// payloads that are not JSON are stored as a string
+func Parameters(data []byte) json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+
+	text := ""
+	var v any
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&v); err == nil && !d.More() {
+		redacted, err := json.Marshal(redact(v))
+		if err == nil && len(redacted) <= MAX_PARAMETERS_SIZE {
+			return redacted
+		}
+		if err == nil {
+			text = string(redacted)
+		}
+	}
+
+	if text == "" {
+		text = secretLine.ReplaceAllString(string(data), "${1}"+REDACTED)
+	}
+	if len(text) > MAX_PARAMETERS_SIZE {
+		text = text[:MAX_PARAMETERS_SIZE] + "... (truncated)"
+	}
+	s, _ := json.Marshal(text)
+	return s
+}
+
+// Headers returns the message headers with the secret looking ones redacted
+func Headers(headers map[string][]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	r := map[string]string{}
+	for k, values := range headers {
+		if secretKey.MatchString(k) {
+			r[k] = REDACTED
+			continue
+		}
+		r[k] = strings.Join(values, ", ")
+	}
+	return r
+}
+
+func redact(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, value := range t {
+			if secretKey.MatchString(k) {
+				t[k] = REDACTED
+				continue
+			}
+			t[k] = redact(value)
+		}
+		return t
+	case []any:
+		for i := range t {
+			t[i] = redact(t[i])
+		}
+		return t
+	case string:
+		// Some handlers get a JSON string with YAML or INI content inside
+		if strings.Contains(t, "\n") {
+			return secretLine.ReplaceAllString(t, "${1}"+REDACTED)
+		}
+		return t
+	default:
+		return t
+	}
+}
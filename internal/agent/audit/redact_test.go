@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		hidden []string
+		kept   []string
+	}{
+		{
+			name:   "certificate",
+			data:   `{"cert_bytes":"MIIB","private_key_bytes":"MIIEvQ"}`,
+			hidden: []string{"MIIEvQ"},
+			kept:   []string{"MIIB"},
+		},
+		{
+			name:   "rustdesk",
+			data:   `{"permanentPassword":"s3cret","id":"123456"}`,
+			hidden: []string{"s3cret"},
+			kept:   []string{"123456"},
+		},
+		{
+			name:   "netbird setup key",
+			data:   `{"key":"ABCD-1234","management_url":"https://netbird.example.com"}`,
+			hidden: []string{"ABCD-1234"},
+			kept:   []string{"https://netbird.example.com"},
+		},
+		{
+			name:   "nested pin",
+			data:   `{"settings":[{"pin":"4321","name":"printer"}]}`,
+			hidden: []string{"4321"},
+			kept:   []string{"printer"},
+		},
+		{
+			name:   "YAML inside JSON",
+			data:   `{"profile":"- name: wifi\n  password: hunter2\n"}`,
+			hidden: []string{"hunter2"},
+			kept:   []string{"wifi"},
+		},
+		{
+			name:   "not JSON",
+			data:   "user=admin\napi_key=xyz\n",
+			hidden: []string{"xyz"},
+			kept:   []string{"admin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(Parameters([]byte(tt.data)))
+			for _, s := range tt.hidden {
+				if strings.Contains(got, s) {
+					t.Errorf("Parameters() = %s, %q must be redacted", got, s)
+				}
+			}
+			for _, s := range tt.kept {
+				if !strings.Contains(got, s) {
+					t.Errorf("Parameters() = %s, %q must be kept", got, s)
+				}
+			}
+			if !strings.Contains(got, REDACTED) {
+				t.Errorf("Parameters() = %s, want %s", got, REDACTED)
+			}
+		})
+	}
+}
+
+func TestParametersTruncated(t *testing.T) {
+	got := Parameters([]byte(strings.Repeat("a", MAX_PARAMETERS_SIZE*2)))
+	if len(got) > MAX_PARAMETERS_SIZE+32 || !strings.Contains(string(got), "(truncated)") {
+		t.Errorf("Parameters() of %d bytes is not truncated", len(got))
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	got := Headers(map[string][]string{
+		"Openuem-User":  {"admin"},
+		"Authorization": {"Bearer abc"},
+		"Key":           {"ABCD-1234"},
+		"Pin":           {"4321"},
+	})
+
+	if got["Openuem-User"] != "admin" {
+		t.Errorf("Openuem-User header is %q, want admin", got["Openuem-User"])
+	}
+	for _, k := range []string{"Authorization", "Key", "Pin"} {
+		if got[k] != REDACTED {
+			t.Errorf("%s header is %q, want %s", k, got[k], REDACTED)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/nats-io/nats.go"
+	openuem_nats "github.com/open-uem/nats"
+	"github.com/open-uem/openuem-agent/internal/agent/keys"
+	"github.com/open-uem/openuem-agent/internal/commands/report"
+	"github.com/open-uem/openuem-agent/internal/metrics"
+	openuem_utils "github.com/open-uem/utils"
+)
+
+const CERTIFICATE_CHECK_INTERVAL = 24 * time.Hour
+
+// DEFAULT_CERTIFICATE_RENEWAL_WINDOW is how many days before expiring a certificate is renewed
+const DEFAULT_CERTIFICATE_RENEWAL_WINDOW = 30
+
+const AGENT_CERTIFICATE = "agent.cer"
+const SERVER_CERTIFICATE = "server.cer"
+
+// CertificateRenewalRequest asks the worker for a new certificate, it answers with
+// a nats.AgentCertificateData or with no data if the certificate will be sent later
+type CertificateRenewalRequest struct {
+	AgentID     string    `json:"agent_id"`
+	Certificate string    `json:"certificate"`
+	DNSName     string    `json:"dns_name,omitempty"`
+	NotAfter    time.Time `json:"not_after"`
+}
+
+// CertificateExpiries reads when the certificates used for NATS and for remote assistance expire
+func CertificateExpiries(c Config) []report.CertificateExpiry {
+	expiries := []report.CertificateExpiry{}
+	for _, name := range []string{AGENT_CERTIFICATE, SERVER_CERTIFICATE} {
+		e := report.CertificateExpiry{Name: name}
+		cert, err := openuem_utils.ReadPEMCertificate(certificatePath(c, name))
+		if err != nil {
+			e.Error = "could not read certificate"
+		} else {
+			e.NotAfter = cert.NotAfter
+		}
+		expiries = append(expiries, e)
+	}
+	return expiries
+}
+
+func certificatePath(c Config, name string) string {
+	if name == AGENT_CERTIFICATE {
+		return c.AgentCert
+	}
+	return serverCertificatePath(c.DataDir)
+}
+
+func (a *Agent) startCertificateExpiryJob() error {
+	_, err := a.TaskScheduler.NewJob(
+		gocron.DurationJob(CERTIFICATE_CHECK_INTERVAL),
+		gocron.NewTask(a.CertificateExpiryTask),
+		gocron.WithName("certificate-expiry"),
+		gocron.WithStartAt(gocron.WithStartImmediately()),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		slog.Error("could not start the certificate expiry job", "error", err)
+		return err
+	}
+	slog.Info(fmt.Sprintf("certificates expiry will be checked every %s", CERTIFICATE_CHECK_INTERVAL))
+	return nil
+}
+
+// CertificateExpiryTask warns about the certificates that are close to expiry and asks for new ones
+func (a *Agent) CertificateExpiryTask() {
+	window := time.Duration(a.Config.CertificateRenewalWindow) * 24 * time.Hour
+
+	for _, e := range CertificateExpiries(a.Config) {
+		if e.Error != "" {
+			// The server certificate is sent by the console after the first report
+			if e.Name != SERVER_CERTIFICATE {
+				slog.Error(fmt.Sprintf("could not check when %s expires", e.Name), "error", e.Error)
+			}
+			continue
+		}
+		metrics.CertificateExpiry.WithLabelValues(e.Name).Set(float64(e.NotAfter.Unix()))
+
+		left := time.Until(e.NotAfter)
+		if left > window {
+			slog.Debug(fmt.Sprintf("%s expires on %s", e.Name, e.NotAfter.Local().Format(time.RFC1123)))
+			continue
+		}
+
+		if left <= 0 {
+			slog.Warn(fmt.Sprintf("%s expired on %s", e.Name, e.NotAfter.Local().Format(time.RFC1123)))
+		} else {
+			slog.Warn(fmt.Sprintf("%s expires in %d days, on %s", e.Name, int(left.Hours()/24), e.NotAfter.Local().Format(time.RFC1123)))
+		}
+
+		if err := a.RequestCertificateRenewal(e); err != nil {
+			slog.Error(fmt.Sprintf("could not renew %s", e.Name), "error", err)
+		}
+	}
+}
+
+func (a *Agent) RequestCertificateRenewal(e report.CertificateExpiry) error {
+	if a.NATSConnection == nil || !a.NATSConnection.IsConnected() {
+		return errors.New("NATS connection is not ready")
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		slog.Error("could not get the hostname for the certificate request", "error", err)
+	}
+
+	data, err := json.Marshal(CertificateRenewalRequest{AgentID: a.Config.UUID, Certificate: e.Name, DNSName: hostname, NotAfter: e.NotAfter})
+	if err != nil {
+		return err
+	}
+
+	msg, err := a.NATSConnection.Request("certificates.renew", data, 2*time.Minute)
+	if err != nil {
+		if errors.Is(err, nats.ErrNoResponders) {
+			return errors.New("no worker answers certificate renewal requests, it will be requested again on the next check")
+		}
+		return err
+	}
+
+	if len(msg.Data) == 0 {
+		slog.Info(fmt.Sprintf("renewal of %s has been requested, the worker will send the new certificate", e.Name))
+		return nil
+	}
+
+	certData := openuem_nats.AgentCertificateData{}
+	if err := json.Unmarshal(msg.Data, &certData); err != nil {
+		return fmt.Errorf("could not unmarshal the new certificate, reason: %v", err)
+	}
+
+	return a.InstallRenewedCertificate(e.Name, certData)
+}
+
+// InstallRenewedCertificate checks the new certificate and key and starts using them without restarting the agent
+func (a *Agent) InstallRenewedCertificate(name string, data openuem_nats.AgentCertificateData) error {
+	cert, err := x509.ParseCertificate(data.CertBytes)
+	if err != nil {
+		return fmt.Errorf("could not parse the new certificate, reason: %v", err)
+	}
+
+	privateKey, err := keys.ParsePrivateKey(data.PrivateKeyBytes)
+	if err != nil {
+		return fmt.Errorf("could not parse the new private key, reason: %v", err)
+	}
+
+	pair := a.agentKeyPair()
+	if name == SERVER_CERTIFICATE {
+		pair = a.serverKeyPair()
+	}
+
+	if err := pair.Install(data.CertBytes, privateKey, a.CACert); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("%s has been renewed, it expires on %s", name, cert.NotAfter.Local().Format(time.RFC1123)))
+	metrics.CertificateExpiry.WithLabelValues(name).Set(float64(cert.NotAfter.Unix()))
+
+	if name == AGENT_CERTIFICATE {
+		a.rekeyAuditLog()
+	}
+
+	a.reloadCertificates(name)
+	return nil
+}
+
+// reloadCertificates reconnects to NATS so the client certificate is read again and restarts the
+// listeners with the certificates found on disk. The VNC proxy reads the server certificate each
+// time a session is started
+func (a *Agent) reloadCertificates(name string) {
+	if name == AGENT_CERTIFICATE && a.NATSConnection != nil {
+		if err := a.NATSConnection.ForceReconnect(); err != nil {
+			slog.Error("could not reconnect to NATS with the new certificate", "error", err)
+		}
+	}
+
+	if name == SERVER_CERTIFICATE {
+		a.GetServerCertificate()
+	}
+
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+
+	if caCert, err := openuem_utils.ReadPEMCertificate(a.Config.CACert); err == nil {
+		a.CACert = caCert
+	}
+	if sftpCert, err := openuem_utils.ReadPEMCertificate(a.Config.SFTPCert); err == nil {
+		a.SFTPCert = sftpCert
+	}
+
+	a.StopSFTPServer()
+	a.StartSFTPServer()
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	openuem_nats "github.com/open-uem/nats"
+	"github.com/open-uem/openuem-agent/internal/agent/keys"
+	openuem_utils "github.com/open-uem/utils"
+)
+
+// ServerCertificatePath is where the certificate used by the VNC proxy and remote assistance is stored
+func (a *Agent) ServerCertificatePath() string {
+	return serverCertificatePath(a.Config.DataDir)
+}
+
+func (a *Agent) ServerKeyFilePath() string {
+	return filepath.Join(a.Config.DataDir, "certificates", "server.key")
+}
+
+func serverCertificatePath(dataDir string) string {
+	return filepath.Join(dataDir, "certificates", "server.cer")
+}
+
+func (a *Agent) serverKeyPair() keys.KeyPair {
+	return keys.KeyPair{CertPath: a.ServerCertificatePath(), KeyPath: a.ServerKeyFilePath()}
+}
+
+func (a *Agent) agentKeyPair() keys.KeyPair {
+	return keys.KeyPair{CertPath: a.Config.AgentCert, KeyPath: a.Config.AgentKey}
+}
+
+func (a *Agent) GetServerCertificate() {
+	// A previous install may have been interrupted
+	recoverKeyPair("server certificate", a.serverKeyPair())
+
+	serverCertPath := a.ServerCertificatePath()
+	_, err := openuem_utils.ReadPEMCertificate(serverCertPath)
+	if err != nil {
+		slog.Error("could not read server certificate")
+	} else {
+		a.ServerCertPath = serverCertPath
+	}
+
+	serverKeyPath := a.ServerKeyFilePath()
+	_, err = keys.ReadPrivateKey(serverKeyPath)
+	if err != nil {
+		slog.Error("could not read server private key", "error", err)
+	} else {
+		a.ServerKeyPath = serverKeyPath
+	}
+}
+
+// InstallServerCertificate replaces server.cer and server.key, if anything fails the previous pair is kept
+func (a *Agent) InstallServerCertificate(data openuem_nats.AgentCertificateData) error {
+	privateKey, err := keys.ParsePrivateKey(data.PrivateKeyBytes)
+	if err != nil {
+		return fmt.Errorf("could not get private key, reason: %v", err)
+	}
+
+	pair := a.serverKeyPair()
+	if err := pair.Install(data.CertBytes, privateKey, a.CACert); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("server certificate and private key saved in %s", filepath.Dir(pair.CertPath)))
+
+	a.GetServerCertificate()
+	return nil
+}
+
+func recoverKeyPair(name string, pair keys.KeyPair) {
+	restored, err := pair.Recover()
+	if err != nil {
+		slog.Error(fmt.Sprintf("%s could not be loaded and its backup could not be restored", name), "error", err)
+		return
+	}
+	if restored {
+		slog.Warn(fmt.Sprintf("%s could not be loaded so its backup has been restored", name))
+	}
+}
@@ -1,12 +1,13 @@
 package agent
 
 import (
-	"log"
-	"os"
-	"path/filepath"
+	"errors"
+	"fmt"
+	"log/slog"
 	"strconv"
 
 	"github.com/google/uuid"
+	"github.com/open-uem/openuem-agent/internal/logger"
 	openuem_utils "github.com/open-uem/utils"
 	"gopkg.in/ini.v1"
 )
@@ -35,239 +36,205 @@ type Config struct {
 	TenantID                 string
 	ScriptsRun               string
 	WebSocketPort            string
+	ReportSpoolMaxSize       int
+	ReportSpoolMaxAge        int
+	DeltaReports             bool
+	FullReportEvery          int
+	Compression              string
+	MetricsListenAddress     string
+	ShutdownTimeout          int
+	OutboxMaxAttempts        int
+	OutboxMaxAge             int
+	ReconnectInitialDelay    int
+	ReconnectMultiplier      float64
+	ReconnectMaxDelay        int
+	DataDir                  string
+	LogDir                   string
+	CertificateRenewalWindow int
+	LogFormat                string
+	LogLevel                 string
+	LogMaxSize               int
+	LogMaxAge                int
+	LogMaxBackups            int
+	SignaturePolicy          string
+	SignaturePolicies        string
+	SignerFingerprints       string
+	ReplayPolicy             string
+	ReplayPolicies           string
+	ClockSkew                int
+	MaintenanceWindows       string
 }
 
-func (a *Agent) ReadConfig() error {
-	// Get conf file
-	configFile := openuem_utils.GetAgentConfigFile()
-
-	f, err := os.Open(configFile)
-	if err != nil {
-		log.Println("[ERROR]: could not open INI file")
-		return err
-	}
-	if err := f.Close(); err != nil {
-		log.Println("[ERROR]: could not close INI file")
-		return err
-	}
-
-	// Open ini file
-	cfg, err := ini.Load(configFile)
-	if err != nil {
-		log.Println("[ERROR]: could not read INI file")
-		return err
-	}
+// LoadConfig reads the settings from the INI file without applying them
+func LoadConfig() (Config, error) {
+	return LoadConfigFile(openuem_utils.GetAgentConfigFile())
+}
 
-	key, err := cfg.Section("Agent").GetKey("UUID")
-	if err != nil {
-		log.Println("[ERROR]: could not get UUID")
-		return err
-	}
-	a.Config.UUID = key.String()
+// LoadConfigFile reads every setting declared in the schema. If some values can't be
+// used, a ConfigErrors with all the problems is returned. Invalid settings that are
+// not critical are warnings and have their default value in the returned Config
+func LoadConfigFile(configFile string) (Config, error) {
+	c, _, err := loadConfigFile(configFile)
+	return c, err
+}
 
-	key, err = cfg.Section("Agent").GetKey("Enabled")
-	if err != nil {
-		log.Println("[ERROR]: could not get Enabled")
-		return err
-	}
-	a.Config.Enabled, err = key.Bool()
-	if err != nil {
-		log.Println("[ERROR]: could not parse Enabled")
-		return err
-	}
+// EffectiveConfig returns the value used for each setting and where it comes from,
+// environment variables and flags take precedence over the config file
+func EffectiveConfig(configFile string) ([]ConfigValue, error) {
+	_, values, err := loadConfigFile(configFile)
+	return values, err
+}
 
-	key, err = cfg.Section("Agent").GetKey("ExecuteTaskEveryXMinutes")
-	if err != nil {
-		log.Println("[ERROR]: could not get ExecuteTaskEveryXMinutes")
-		return err
-	}
-	a.Config.ExecuteTaskEveryXMinutes, err = key.Int()
-	if err != nil {
-		log.Println("[ERROR]: could not parse ExecuteTaskEveryXMinutes")
-		return err
-	}
+func loadConfigFile(configFile string) (Config, []ConfigValue, error) {
+	c := Config{}
 
-	key, err = cfg.Section("NATS").GetKey("NATSServers")
+	cfg, err := ini.Load(configFile)
 	if err != nil {
-		log.Println("[ERROR]: could not get NATSServers")
-		return err
-	}
-	a.Config.NATSServers = key.String()
-
-	key, err = cfg.Section("NATS").GetKey("WebSocketPort")
-	if err == nil {
-		if _, err := strconv.Atoi(key.String()); err != nil {
-			log.Println("[ERROR]: the WebSocket port is not valid")
-			return err
+		return c, nil, fmt.Errorf("could not read INI file, reason: %v", err)
+	}
+
+	values := []ConfigValue{}
+	problems := ConfigErrors{}
+	for _, s := range ConfigSchema() {
+		value, source, found := s.lookup(cfg)
+		if !found {
+			if s.Critical {
+				problems = append(problems, ConfigProblem{Section: s.Section, Key: s.Key, Message: "is required"})
+				continue
+			}
+			s.SetDefault(&c)
+			values = append(values, ConfigValue{Section: s.Section, Key: s.Key, Value: s.Default, Source: SOURCE_DEFAULT})
+			problems = append(problems, ConfigProblem{Section: s.Section, Key: s.Key, Message: "is required, the default value is used", Warning: true})
+			continue
+		}
+		values = append(values, ConfigValue{Section: s.Section, Key: s.Key, Value: value, Source: source})
+
+		if err := s.Set(&c, value); err != nil {
+			message := err.Error()
+			switch source {
+			case SOURCE_ENV:
+				message += " (set by " + s.EnvVar() + ")"
+			case SOURCE_FLAG:
+				message += " (set by --" + s.Flag() + ")"
+			}
+			if s.Critical {
+				problems = append(problems, ConfigProblem{Section: s.Section, Key: s.Key, Message: message})
+				continue
+			}
+
+			s.SetDefault(&c)
+			values[len(values)-1] = ConfigValue{Section: s.Section, Key: s.Key, Value: s.Default, Source: SOURCE_DEFAULT}
+			problems = append(problems, ConfigProblem{Section: s.Section, Key: s.Key, Message: message + ", the default value is used", Warning: true})
 		}
-		a.Config.WebSocketPort = key.String()
-	}
-
-	key, err = cfg.Section("Agent").GetKey("Debug")
-	if err != nil {
-		log.Println("[ERROR]: could not get Debug")
-		return err
-	}
-	a.Config.Debug, err = key.Bool()
-	if err != nil {
-		log.Println("[ERROR]: could not parse Debug")
-		return err
-	}
-
-	key, err = cfg.Section("Agent").GetKey("DefaultFrequency")
-	if err != nil {
-		log.Println("[ERROR]: could not get DefaultFrequency")
-		return err
-	}
-	a.Config.DefaultFrequency, err = key.Int()
-	if err != nil {
-		log.Println("[ERROR]: could not parse DefaultFrequency")
-		return err
-	}
-
-	key, err = cfg.Section("Agent").GetKey("SFTPPort")
-	if err != nil {
-		log.Println("[ERROR]: could not get SFTPPort")
-		return err
-	}
-	a.Config.SFTPPort = key.String()
-	val, err := strconv.Atoi(a.Config.SFTPPort)
-	if err != nil || (val < 0) || (val > 65535) {
-		a.Config.SFTPPort = ""
-	}
-
-	key, err = cfg.Section("Agent").GetKey("VNCProxyPort")
-	if err != nil {
-		log.Println("[ERROR]: could not get VNCProxyPort")
-		return err
-	}
-	a.Config.VNCProxyPort = key.String()
-	val, err = strconv.Atoi(a.Config.VNCProxyPort)
-	if err != nil || (val < 0) || (val > 65535) {
-		a.Config.VNCProxyPort = ""
 	}
 
-	// Read required certificates and private key
-	cwd, err := Getwd()
-	if err != nil {
-		log.Fatalf("[FATAL]: could not get current working directory")
+	problems = append(problems, validateConfig(c)...)
+	if c.ReconnectMaxDelay < c.ReconnectInitialDelay {
+		c.ReconnectMaxDelay = c.ReconnectInitialDelay
 	}
-
-	key, err = cfg.Section("Certificates").GetKey("AgentCert")
-	if err != nil {
-		log.Println("[ERROR]: could not get agent certificate from config file")
-		a.Config.AgentCert = filepath.Join(cwd, "certificates", "agent.cer")
-	} else {
-		a.Config.AgentCert = key.String()
-	}
-
-	_, err = openuem_utils.ReadPEMCertificate(a.Config.AgentCert)
-	if err != nil {
-		log.Fatalf("[FATAL]: could not read agent certificate")
+	if len(problems) > 0 {
+		return c, values, problems
 	}
+	return c, values, nil
+}
 
-	key, err = cfg.Section("Certificates").GetKey("AgentKey")
-	if err != nil {
-		log.Println("[ERROR]: could not get agent private key from config file")
-		a.Config.AgentKey = filepath.Join(cwd, "certificates", "agent.key")
-	} else {
-		a.Config.AgentKey = key.String()
+// loadUsableConfig reads the config file for the agent, the settings that are not valid
+// but have a default are logged and don't stop the agent
+func loadUsableConfig() (Config, error) {
+	c, err := LoadConfig()
+	problems := ConfigErrors{}
+	if !errors.As(err, &problems) {
+		return c, err
 	}
 
-	_, err = openuem_utils.ReadPEMPrivateKey(a.Config.AgentKey)
-	if err != nil {
-		log.Fatalf("[FATAL]: could not read agent private key")
-	}
-
-	key, err = cfg.Section("Certificates").GetKey("CACert")
-	if err != nil {
-		log.Println("[ERROR]: could not get CA certificate from config file")
-		a.Config.CACert = filepath.Join(cwd, "certificates", "ca.cer")
-	} else {
-		a.Config.CACert = key.String()
+	for _, p := range problems {
+		if p.Warning {
+			slog.Warn("the config file has a setting that can't be used", "setting", p.Section+"."+p.Key, "problem", p.Message)
+		}
 	}
+	return c, problems.Fatal()
+}
 
-	_, err = openuem_utils.ReadPEMCertificate(a.Config.CACert)
+// ResolveLogSettings finds where and how the logs are written before the logger is created,
+// the rest of the settings are read later by the agent
+func ResolveLogSettings() logger.Options {
+	cfg, err := ini.Load(openuem_utils.GetAgentConfigFile())
 	if err != nil {
-		log.Fatalf("[FATAL]: could not read CA certificate")
+		cfg = ini.Empty()
 	}
 
-	key, err = cfg.Section("Certificates").GetKey("SFTPCert")
-	if err != nil {
-		log.Println("[ERROR]: could not get SFTP certificate from config file")
-		a.Config.SFTPCert = filepath.Join(cwd, "certificates", "sftp.cer")
-	} else {
-		a.Config.SFTPCert = key.String()
-	}
-	_, err = openuem_utils.ReadPEMCertificate(a.Config.SFTPCert)
-	if err != nil {
-		log.Println("[ERROR]: could not read sftp certificate")
-		a.Config.SFTPCert = ""
+	o := logger.Options{
+		Dir:        defaultLogFolder(),
+		Format:     logger.FORMAT_TEXT,
+		MaxSize:    logger.DEFAULT_MAX_SIZE_MB,
+		MaxAge:     logger.DEFAULT_MAX_AGE_DAYS,
+		MaxBackups: logger.DEFAULT_MAX_BACKUPS,
 	}
-
-	key, err = cfg.Section("Agent").GetKey("WingetConfigureFrequency")
-	if err != nil {
-		a.Config.WingetConfigureFrequency = SCHEDULETIME_30MIN
-	} else {
-		a.Config.WingetConfigureFrequency, err = key.Int()
-		if err != nil {
-			log.Println("[ERROR]: could not parse WingetConfigureFrequency")
-			return err
+	for _, s := range ConfigSchema() {
+		value, _, found := s.lookup(cfg)
+		if !found || value == "" {
+			continue
 		}
-	}
-
-	key, err = cfg.Section("Agent").GetKey("IPAddress")
-	if err == nil {
-		ip := key.String()
-		if ip != "" {
-			a.Config.IPAddress = ip
-			log.Println("[INFO]: IP address has been set from configuration file")
+		switch s.Key {
+		case "LogDir":
+			o.Dir = value
+		case "LogFormat":
+			o.Format = value
+		case "LogMaxSize":
+			o.MaxSize = atoiOr(value, o.MaxSize)
+		case "LogMaxAge":
+			o.MaxAge = atoiOr(value, o.MaxAge)
+		case "LogMaxBackups":
+			o.MaxBackups = atoiOr(value, o.MaxBackups)
 		}
 	}
+	return o
+}
 
-	key, err = cfg.Section("Agent").GetKey("SFTPDisabled")
+func atoiOr(value string, def int) int {
+	n, err := strconv.Atoi(value)
 	if err != nil {
-		log.Println("[ERROR]: could not get SFTPDisabled")
-		a.Config.SFTPDisabled = false
-	} else {
-		a.Config.SFTPDisabled, err = key.Bool()
-		if err != nil {
-			log.Println("[ERROR]: could not parse SFTPDisabled")
-			return err
-		}
+		return def
 	}
+	return n
+}
 
-	key, err = cfg.Section("Agent").GetKey("RemoteAssistanceDisabled")
+// ReadConfig loads the config file again. The settings that can be changed while the
+// agent is running are applied as the config watcher does, so their changes take effect
+func (a *Agent) ReadConfig() error {
+	c, err := loadUsableConfig()
 	if err != nil {
-		log.Println("[ERROR]: could not get RemoteAssistanceDisabled")
-		a.Config.RemoteAssistanceDisabled = false
-	} else {
-		a.Config.RemoteAssistanceDisabled, err = key.Bool()
-		if err != nil {
-			log.Println("[ERROR]: could not parse RemoteAssistanceDisabled")
-			return err
-		}
+		return err
 	}
 
-	key, err = cfg.Section("Agent").GetKey("TenantID")
-	if err == nil {
-		a.Config.TenantID = key.String()
-	}
+	a.configMu.Lock()
+	old := a.Config
+	a.Config = c
+	a.Config.Debug, a.Config.LogLevel = old.Debug, old.LogLevel
+	a.Config.DefaultFrequency = old.DefaultFrequency
+	a.Config.WingetConfigureFrequency = old.WingetConfigureFrequency
+	a.Config.SFTPPort, a.Config.SFTPDisabled = old.SFTPPort, old.SFTPDisabled
+	a.configMu.Unlock()
 
-	key, err = cfg.Section("Agent").GetKey("SiteID")
-	if err == nil {
-		a.Config.SiteID = key.String()
-	}
+	a.ApplyConfig(c)
 
-	key, err = cfg.Section("Agent").GetKey("ScriptsRun")
-	if err == nil {
-		a.Config.ScriptsRun = key.String()
+	if c.IPAddress != "" {
+		slog.Info("IP address has been set from configuration file")
 	}
-
-	log.Println("[INFO]: agent has read its settings from the INI file")
+	slog.Info("agent has read its settings from the INI file")
 	return nil
 }
 
+// currentConfig returns a copy of the settings that is safe to read while they're being reloaded
+func (a *Agent) currentConfig() Config {
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+
+	return a.Config
+}
+
+// WriteConfig saves the settings that are already in the file or differ from their defaults.
+// Settings overridden by environment variables or flags are left as they are in the file
 func (c *Config) WriteConfig() error {
 	// Get conf file
 	configFile := openuem_utils.GetAgentConfigFile()
@@ -278,22 +245,23 @@ func (c *Config) WriteConfig() error {
 		return err
 	}
 
-	cfg.Section("Agent").Key("UUID").SetValue(c.UUID)
-	cfg.Section("Agent").Key("Enabled").SetValue(strconv.FormatBool(c.Enabled))
-	cfg.Section("Agent").Key("DefaultFrequency").SetValue(strconv.Itoa(c.DefaultFrequency))
-	cfg.Section("Agent").Key("ExecuteTaskEveryXMinutes").SetValue(strconv.Itoa(c.ExecuteTaskEveryXMinutes))
-	cfg.Section("Agent").Key("WingetConfigureFrequency").SetValue(strconv.Itoa(c.WingetConfigureFrequency))
-	cfg.Section("Agent").Key("Debug").SetValue(strconv.FormatBool(c.Debug))
-	cfg.Section("Agent").Key("SFTPPort").SetValue(c.SFTPPort)
-	cfg.Section("Agent").Key("VNCProxyPort").SetValue(c.VNCProxyPort)
-	cfg.Section("Agent").Key("SFTPDisabled").SetValue(strconv.FormatBool(c.SFTPDisabled))
-	cfg.Section("Agent").Key("RemoteAssistanceDisabled").SetValue(strconv.FormatBool(c.RemoteAssistanceDisabled))
-	cfg.Section("Agent").Key("ScriptsRun").SetValue(c.ScriptsRun)
+	for _, s := range ConfigSchema() {
+		if s.Overridden() {
+			continue
+		}
+
+		value := s.Value(c)
+		if !cfg.Section(s.Section).HasKey(s.Key) && value == s.Default {
+			continue
+		}
+		cfg.Section(s.Section).Key(s.Key).SetValue(value)
+	}
 
 	if err := cfg.SaveTo(configFile); err != nil {
-		log.Fatalf("[FATAL]: could not save config file, reason: %v", err)
+		return fmt.Errorf("could not save config file, reason: %v", err)
 	}
-	log.Printf("[INFO]: config has been saved to %s", configFile)
+	recordConfigWrite(configFile)
+	slog.Info(fmt.Sprintf("config has been saved to %s", configFile))
 	return nil
 }
 
@@ -308,7 +276,11 @@ func (c *Config) ResetRestartRequiredFlag() error {
 	}
 
 	cfg.Section("Agent").Key("RestartRequired").SetValue("false")
-	return cfg.SaveTo(configFile)
+	if err := cfg.SaveTo(configFile); err != nil {
+		return err
+	}
+	recordConfigWrite(configFile)
+	return nil
 }
 
 func (c *Config) SetRestartRequiredFlag() error {
@@ -322,7 +294,11 @@ func (c *Config) SetRestartRequiredFlag() error {
 	}
 
 	cfg.Section("Agent").Key("RestartRequired").SetValue("true")
-	return cfg.SaveTo(configFile)
+	if err := cfg.SaveTo(configFile); err != nil {
+		return err
+	}
+	recordConfigWrite(configFile)
+	return nil
 }
 
 func (a *Agent) SetInitialConfig() {
@@ -331,6 +307,6 @@ func (a *Agent) SetInitialConfig() {
 	a.Config.Enabled = true
 	a.Config.ExecuteTaskEveryXMinutes = 5
 	if err := a.Config.WriteConfig(); err != nil {
-		log.Fatalf("[FATAL]: could not write agent config: %v", err)
+		logger.Fatal("could not write agent config", "error", err)
 	}
 }
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"gopkg.in/ini.v1"
+)
+
+const (
+	SOURCE_DEFAULT = "default"
+	SOURCE_FILE    = "file"
+	SOURCE_ENV     = "env"
+	SOURCE_FLAG    = "flag"
+)
+
+// flagOverrides has the settings given as command-line flags, keyed by the setting's key
+var flagOverrides = map[string]string{}
+
+// ConfigValue is the effective value of a setting and where it comes from
+type ConfigValue struct {
+	Section string `json:"section"`
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Source  string `json:"source"`
+}
+
+// EnvVar is the environment variable that overrides the setting, e.g. OPENUEM_NATS_SERVERS
+func (s Setting) EnvVar() string {
+	return "OPENUEM_" + strings.ToUpper(strings.Join(keyWords(s.Key), "_"))
+}
+
+// Flag is the command-line flag that overrides the setting, e.g. --nats-servers
+func (s Setting) Flag() string {
+	return strings.ToLower(strings.Join(keyWords(s.Key), "-"))
+}
+
+// Overridden reports if the setting has been set by an environment variable or a flag,
+// these values are never written to the config file
+func (s Setting) Overridden() bool {
+	if _, ok := flagOverrides[s.Key]; ok {
+		return true
+	}
+	_, ok := os.LookupEnv(s.EnvVar())
+	return ok
+}
+
+// lookup finds the value with the highest precedence: flag, environment, config file and default
+func (s Setting) lookup(cfg *ini.File) (value string, source string, found bool) {
+	if v, ok := flagOverrides[s.Key]; ok {
+		return v, SOURCE_FLAG, true
+	}
+
+	if v, ok := os.LookupEnv(s.EnvVar()); ok {
+		return v, SOURCE_ENV, true
+	}
+
+	if cfg.Section(s.Section).HasKey(s.Key) {
+		if v := cfg.Section(s.Section).Key(s.Key).String(); v != "" || s.Required {
+			return v, SOURCE_FILE, true
+		}
+	}
+
+	return s.Default, SOURCE_DEFAULT, !s.Required
+}
+
+// ParseFlagOverrides reads the settings given as flags before the command, e.g.
+// openuem-agent --debug --nats-servers=nats.example.com:4433, and returns the remaining arguments
+func ParseFlagOverrides(args []string) ([]string, error) {
+	fs := flag.NewFlagSet("openuem-agent", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	for _, s := range ConfigSchema() {
+		_, isBool := s.Field(&Config{}).(*bool)
+		fs.Var(&overrideFlag{key: s.Key, isBool: isBool}, s.Flag(), s.EnvVar())
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return []string{"help"}, nil
+		}
+		return nil, err
+	}
+
+	return fs.Args(), nil
+}
+
+type overrideFlag struct {
+	key    string
+	isBool bool
+}
+
+func (f *overrideFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return flagOverrides[f.key]
+}
+
+func (f *overrideFlag) Set(value string) error {
+	flagOverrides[f.key] = value
+	return nil
+}
+
+func (f *overrideFlag) IsBoolFlag() bool {
+	return f.isBool
+}
+
+// keyWords splits an INI key in words, e.g. NATSServers is NATS and Servers
+func keyWords(key string) []string {
+	runes := []rune(key)
+	words := []string{}
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		lowerBefore := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+		acronymEnds := unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if unicode.IsUpper(runes[i]) && (lowerBefore || acronymEnds) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(words, string(runes[start:]))
+}
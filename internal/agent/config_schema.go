@@ -0,0 +1,332 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/open-uem/openuem-agent/internal/agent/keys"
+	"github.com/open-uem/openuem-agent/internal/agent/maintenance"
+	"github.com/open-uem/openuem-agent/internal/agent/outbox"
+	"github.com/open-uem/openuem-agent/internal/agent/payload"
+	"github.com/open-uem/openuem-agent/internal/agent/replay"
+	"github.com/open-uem/openuem-agent/internal/agent/signing"
+	"github.com/open-uem/openuem-agent/internal/agent/spool"
+	"github.com/open-uem/openuem-agent/internal/commands/report"
+	"github.com/open-uem/openuem-agent/internal/logger"
+	openuem_utils "github.com/open-uem/utils"
+)
+
+// Setting declares a key of openuem.ini and the Config field it's read into.
+// Field must return a pointer to a string, int, bool or float64 field. The agent
+// can't start with an invalid Critical setting, other invalid settings use their default
+type Setting struct {
+	Section  string
+	Key      string
+	Default  string
+	Required bool
+	Critical bool
+	Min      float64
+	Max      float64
+	Check    func(value string) error
+	Field    func(c *Config) any
+}
+
+// ConfigProblem is a setting whose value could not be used. Warning is set when the
+// default value has been used instead
+type ConfigProblem struct {
+	Section string `json:"section"`
+	Key     string `json:"key"`
+	Message string `json:"message"`
+	Warning bool   `json:"warning,omitempty"`
+}
+
+func (p ConfigProblem) String() string {
+	if p.Warning {
+		return fmt.Sprintf("[%s] %s: %s (warning)", p.Section, p.Key, p.Message)
+	}
+	return fmt.Sprintf("[%s] %s: %s", p.Section, p.Key, p.Message)
+}
+
+// ConfigErrors has all the problems found in the config file so they can be fixed at once
+type ConfigErrors []ConfigProblem
+
+func (e ConfigErrors) Error() string {
+	problems := []string{}
+	for _, p := range e {
+		problems = append(problems, p.String())
+	}
+	return strings.Join(problems, "; ")
+}
+
+// Fatal returns the problems the agent can't start with, or nil if there are only warnings
+func (e ConfigErrors) Fatal() error {
+	fatal := ConfigErrors{}
+	for _, p := range e {
+		if !p.Warning {
+			fatal = append(fatal, p)
+		}
+	}
+	if len(fatal) == 0 {
+		return nil
+	}
+	return fatal
+}
+
+// ConfigSchema lists every setting of the agent, certificates default to the certificates folder
+func ConfigSchema() []Setting {
+	cwd, err := Getwd()
+	if err != nil {
+		cwd = "."
+	}
+	certificate := func(file string) string {
+		return filepath.Join(cwd, "certificates", file)
+	}
+
+	return []Setting{
+		// A new agent has no UUID yet, it's generated the first time the agent starts
+		{Section: "Agent", Key: "UUID", Field: func(c *Config) any { return &c.UUID }},
+		{Section: "Agent", Key: "Enabled", Required: true, Critical: true, Field: func(c *Config) any { return &c.Enabled }},
+		{Section: "Agent", Key: "ExecuteTaskEveryXMinutes", Required: true, Critical: true, Field: func(c *Config) any { return &c.ExecuteTaskEveryXMinutes }},
+		{Section: "Agent", Key: "DefaultFrequency", Required: true, Critical: true, Field: func(c *Config) any { return &c.DefaultFrequency }},
+		{Section: "Agent", Key: "WingetConfigureFrequency", Default: strconv.Itoa(SCHEDULETIME_30MIN), Field: func(c *Config) any { return &c.WingetConfigureFrequency }},
+		{Section: "Agent", Key: "Debug", Required: true, Field: func(c *Config) any { return &c.Debug }},
+		{Section: "Agent", Key: "SFTPPort", Required: true, Check: validPort, Field: func(c *Config) any { return &c.SFTPPort }},
+		{Section: "Agent", Key: "VNCProxyPort", Required: true, Check: validPort, Field: func(c *Config) any { return &c.VNCProxyPort }},
+		{Section: "Agent", Key: "SFTPDisabled", Default: "false", Field: func(c *Config) any { return &c.SFTPDisabled }},
+		{Section: "Agent", Key: "RemoteAssistanceDisabled", Default: "false", Field: func(c *Config) any { return &c.RemoteAssistanceDisabled }},
+		{Section: "Agent", Key: "IPAddress", Check: validIP, Field: func(c *Config) any { return &c.IPAddress }},
+		{Section: "Agent", Key: "TenantID", Field: func(c *Config) any { return &c.TenantID }},
+		{Section: "Agent", Key: "SiteID", Field: func(c *Config) any { return &c.SiteID }},
+		{Section: "Agent", Key: "ScriptsRun", Field: func(c *Config) any { return &c.ScriptsRun }},
+		{Section: "Agent", Key: "ReportSpoolMaxSize", Default: strconv.Itoa(spool.DEFAULT_MAX_SIZE_MB), Min: 1, Field: func(c *Config) any { return &c.ReportSpoolMaxSize }},
+		{Section: "Agent", Key: "ReportSpoolMaxAge", Default: strconv.Itoa(spool.DEFAULT_MAX_AGE_HOURS), Min: 1, Field: func(c *Config) any { return &c.ReportSpoolMaxAge }},
+		{Section: "Agent", Key: "DeltaReports", Default: "false", Field: func(c *Config) any { return &c.DeltaReports }},
+		{Section: "Agent", Key: "FullReportEvery", Default: strconv.Itoa(report.DEFAULT_FULL_REPORT_EVERY), Min: 1, Field: func(c *Config) any { return &c.FullReportEvery }},
+		{Section: "Agent", Key: "MetricsListenAddress", Check: validAddress, Field: func(c *Config) any { return &c.MetricsListenAddress }},
+		{Section: "Agent", Key: "ShutdownTimeout", Default: "30", Min: 1, Max: 3600, Field: func(c *Config) any { return &c.ShutdownTimeout }},
+		{Section: "Agent", Key: "OutboxMaxAttempts", Default: strconv.Itoa(outbox.DEFAULT_MAX_ATTEMPTS), Min: 1, Field: func(c *Config) any { return &c.OutboxMaxAttempts }},
+		{Section: "Agent", Key: "OutboxMaxAge", Default: strconv.Itoa(outbox.DEFAULT_MAX_AGE_HOURS), Min: 1, Field: func(c *Config) any { return &c.OutboxMaxAge }},
+		{Section: "Agent", Key: "DataDir", Default: defaultDataFolder(), Check: notEmpty, Field: func(c *Config) any { return &c.DataDir }},
+		{Section: "Agent", Key: "LogDir", Default: defaultLogFolder(), Check: notEmpty, Field: func(c *Config) any { return &c.LogDir }},
+		{Section: "Agent", Key: "LogFormat", Default: logger.FORMAT_TEXT, Check: validLogFormat, Field: func(c *Config) any { return &c.LogFormat }},
+		{Section: "Agent", Key: "LogLevel", Default: "info", Check: validLogLevel, Field: func(c *Config) any { return &c.LogLevel }},
+		{Section: "Agent", Key: "LogMaxSize", Default: strconv.Itoa(logger.DEFAULT_MAX_SIZE_MB), Min: 1, Field: func(c *Config) any { return &c.LogMaxSize }},
+		{Section: "Agent", Key: "LogMaxAge", Default: strconv.Itoa(logger.DEFAULT_MAX_AGE_DAYS), Min: 1, Field: func(c *Config) any { return &c.LogMaxAge }},
+		{Section: "Agent", Key: "LogMaxBackups", Default: strconv.Itoa(logger.DEFAULT_MAX_BACKUPS), Field: func(c *Config) any { return &c.LogMaxBackups }},
+
+		{Section: "NATS", Key: "NATSServers", Required: true, Critical: true, Check: notEmpty, Field: func(c *Config) any { return &c.NATSServers }},
+		{Section: "NATS", Key: "WebSocketPort", Check: validPort, Field: func(c *Config) any { return &c.WebSocketPort }},
+		{Section: "NATS", Key: "Compression", Default: payload.ENCODING_NONE, Check: validCompression, Field: func(c *Config) any { return &c.Compression }},
+		{Section: "NATS", Key: "ReconnectInitialDelay", Default: strconv.Itoa(DEFAULT_RECONNECT_INITIAL_DELAY), Min: 1, Field: func(c *Config) any { return &c.ReconnectInitialDelay }},
+		{Section: "NATS", Key: "ReconnectMultiplier", Default: strconv.FormatFloat(DEFAULT_RECONNECT_MULTIPLIER, 'f', -1, 64), Min: 1, Max: 10, Field: func(c *Config) any { return &c.ReconnectMultiplier }},
+		{Section: "NATS", Key: "ReconnectMaxDelay", Default: strconv.Itoa(DEFAULT_RECONNECT_MAX_DELAY), Min: 1, Field: func(c *Config) any { return &c.ReconnectMaxDelay }},
+
+		{Section: "Certificates", Key: "CACert", Default: certificate("ca.cer"), Critical: true, Check: readableCertificate, Field: func(c *Config) any { return &c.CACert }},
+		{Section: "Certificates", Key: "AgentCert", Default: certificate("agent.cer"), Critical: true, Check: readableCertificate, Field: func(c *Config) any { return &c.AgentCert }},
+		{Section: "Certificates", Key: "AgentKey", Default: certificate("agent.key"), Critical: true, Check: readablePrivateKey, Field: func(c *Config) any { return &c.AgentKey }},
+		{Section: "Certificates", Key: "SFTPCert", Default: certificate("sftp.cer"), Check: readableCertificate, Field: func(c *Config) any { return &c.SFTPCert }},
+		{Section: "Certificates", Key: "CertificateRenewalWindow", Default: strconv.Itoa(DEFAULT_CERTIFICATE_RENEWAL_WINDOW), Min: 1, Field: func(c *Config) any { return &c.CertificateRenewalWindow }},
+
+		{Section: "Security", Key: "SignaturePolicy", Default: signing.POLICY_OFF, Check: signing.ValidPolicy, Field: func(c *Config) any { return &c.SignaturePolicy }},
+		{Section: "Security", Key: "SignaturePolicies", Check: validSignaturePolicies, Field: func(c *Config) any { return &c.SignaturePolicies }},
+		{Section: "Security", Key: "SignerFingerprints", Check: validFingerprints, Field: func(c *Config) any { return &c.SignerFingerprints }},
+		{Section: "Security", Key: "ReplayPolicy", Default: signing.POLICY_OFF, Check: signing.ValidPolicy, Field: func(c *Config) any { return &c.ReplayPolicy }},
+		{Section: "Security", Key: "ReplayPolicies", Check: validSignaturePolicies, Field: func(c *Config) any { return &c.ReplayPolicies }},
+		{Section: "Security", Key: "ClockSkew", Default: strconv.Itoa(replay.DEFAULT_CLOCK_SKEW_SECONDS), Min: 1, Max: 86400, Field: func(c *Config) any { return &c.ClockSkew }},
+
+		{Section: "Maintenance", Key: "Windows", Check: validMaintenanceWindows, Field: func(c *Config) any { return &c.MaintenanceWindows }},
+	}
+}
+
+// DefaultConfig has the default value of every setting, the checks are skipped
+// so the paths are set even if the files don't exist
+func DefaultConfig() Config {
+	c := Config{}
+	for _, s := range ConfigSchema() {
+		s.SetDefault(&c)
+	}
+	return c
+}
+
+// SetDefault stores the default value without checking it, settings without a default are left empty
+func (s Setting) SetDefault(c *Config) {
+	if field, ok := s.Field(c).(*string); ok {
+		*field = s.Default
+		return
+	}
+	if s.Default != "" {
+		_ = s.Set(c, s.Default)
+	}
+}
+
+// Set parses value and stores it in the setting's field
+func (s Setting) Set(c *Config, value string) error {
+	switch field := s.Field(c).(type) {
+	case *string:
+		if s.Check != nil {
+			if err := s.Check(value); err != nil {
+				return err
+			}
+		}
+		*field = value
+	case *int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%q is not a valid number", value)
+		}
+		if err := s.checkRange(float64(n)); err != nil {
+			return err
+		}
+		*field = n
+	case *float64:
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a valid number", value)
+		}
+		if err := s.checkRange(n); err != nil {
+			return err
+		}
+		*field = n
+	case *bool:
+		b, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		*field = b
+	default:
+		return fmt.Errorf("unsupported field type %T", field)
+	}
+	return nil
+}
+
+// Value formats the setting's field as it's written to the INI file
+func (s Setting) Value(c *Config) string {
+	switch field := s.Field(c).(type) {
+	case *string:
+		return *field
+	case *int:
+		return strconv.Itoa(*field)
+	case *float64:
+		return strconv.FormatFloat(*field, 'f', -1, 64)
+	case *bool:
+		return strconv.FormatBool(*field)
+	}
+	return ""
+}
+
+func (s Setting) checkRange(n float64) error {
+	if n < s.Min {
+		return fmt.Errorf("must be at least %v", s.Min)
+	}
+	if s.Max > 0 && n > s.Max {
+		return fmt.Errorf("must be at most %v", s.Max)
+	}
+	return nil
+}
+
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "t", "true", "y", "yes", "on":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not a valid boolean", value)
+}
+
+// validateConfig checks the rules that involve more than one setting
+func validateConfig(c Config) ConfigErrors {
+	problems := ConfigErrors{}
+	if c.ReconnectMaxDelay < c.ReconnectInitialDelay {
+		problems = append(problems, ConfigProblem{Section: "NATS", Key: "ReconnectMaxDelay", Message: "must not be lower than ReconnectInitialDelay, ReconnectInitialDelay is used", Warning: true})
+	}
+	return problems
+}
+
+func validPort(value string) error {
+	if value == "" {
+		return nil
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 0 || port > math.MaxUint16 {
+		return fmt.Errorf("%q is not a valid port", value)
+	}
+	return nil
+}
+
+func validIP(value string) error {
+	if value != "" && net.ParseIP(value) == nil {
+		return fmt.Errorf("%q is not a valid IP address", value)
+	}
+	return nil
+}
+
+func validAddress(value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, port, err := net.SplitHostPort(value); err != nil || validPort(port) != nil {
+		return fmt.Errorf("%q is not a valid host:port address", value)
+	}
+	return nil
+}
+
+func validCompression(value string) error {
+	if !payload.IsValidEncoding(value) {
+		return fmt.Errorf("%q is not a valid compression", value)
+	}
+	return nil
+}
+
+func validLogFormat(value string) error {
+	if value != logger.FORMAT_TEXT && value != logger.FORMAT_JSON {
+		return fmt.Errorf("%q is not a valid log format, use %s or %s", value, logger.FORMAT_TEXT, logger.FORMAT_JSON)
+	}
+	return nil
+}
+
+func validLogLevel(value string) error {
+	_, err := logger.ParseLevel(value)
+	return err
+}
+
+func validSignaturePolicies(value string) error {
+	_, err := signing.ParsePolicies(value)
+	return err
+}
+
+func validFingerprints(value string) error {
+	_, err := signing.ParseFingerprints(value)
+	return err
+}
+
+func validMaintenanceWindows(value string) error {
+	_, err := maintenance.ParseSchedule(value)
+	return err
+}
+
+func notEmpty(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return errors.New("must not be empty")
+	}
+	return nil
+}
+
+func readableCertificate(value string) error {
+	if _, err := openuem_utils.ReadPEMCertificate(value); err != nil {
+		return fmt.Errorf("the certificate could not be read, reason: %v", err)
+	}
+	return nil
+}
+
+func readablePrivateKey(value string) error {
+	if _, err := keys.ReadPrivateKey(value); err != nil {
+		return fmt.Errorf("the private key could not be read, reason: %v", err)
+	}
+	return nil
+}
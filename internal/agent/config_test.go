@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes a valid config file with its certificates in a temporary folder,
+// the extra lines are appended to the [Agent] section
+func writeConfigFile(t *testing.T, agentLines ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "agent"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	for _, name := range []string{"ca.cer", "agent.cer", "sftp.cer"} {
+		if err := os.WriteFile(filepath.Join(dir, name), cert, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "agent.key"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := []string{
+		"[Agent]",
+		"Enabled = true",
+		"Debug = false",
+		"ExecuteTaskEveryXMinutes = 5",
+		"DefaultFrequency = 60",
+		"VNCProxyPort = 1443",
+		"DataDir = " + filepath.Join(dir, "data"),
+		"LogDir = " + filepath.Join(dir, "logs"),
+	}
+	lines = append(lines, agentLines...)
+	lines = append(lines,
+		"[NATS]",
+		"NATSServers = nats.example.com:4433",
+		"[Certificates]",
+		"CACert = "+filepath.Join(dir, "ca.cer"),
+		"AgentCert = "+filepath.Join(dir, "agent.cer"),
+		"AgentKey = "+filepath.Join(dir, "agent.key"),
+		"SFTPCert = "+filepath.Join(dir, "sftp.cer"),
+	)
+
+	file := filepath.Join(dir, "openuem.ini")
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		warnings []string
+		fatal    []string
+		check    func(t *testing.T, c Config)
+	}{
+		{
+			name:  "valid",
+			lines: []string{"UUID = 2b3c9a4e-5f6d-4e7a-8b9c-0d1e2f3a4b5c", "SFTPPort = 2022"},
+			check: func(t *testing.T, c Config) {
+				if c.UUID != "2b3c9a4e-5f6d-4e7a-8b9c-0d1e2f3a4b5c" || c.SFTPPort != "2022" {
+					t.Errorf("got UUID %q and SFTPPort %q", c.UUID, c.SFTPPort)
+				}
+			},
+		},
+		{
+			// A fresh install has no UUID yet, the agent generates it with SetInitialConfig
+			name:  "fresh install without UUID",
+			lines: []string{"SFTPPort = 2022"},
+			check: func(t *testing.T, c Config) {
+				if c.UUID != "" {
+					t.Errorf("expected an empty UUID, got %q", c.UUID)
+				}
+			},
+		},
+		{
+			name:     "invalid SFTPPort uses the default",
+			lines:    []string{"SFTPPort = not-a-port"},
+			warnings: []string{"SFTPPort"},
+			check: func(t *testing.T, c Config) {
+				if c.SFTPPort != "" {
+					t.Errorf("expected the default SFTPPort, got %q", c.SFTPPort)
+				}
+			},
+		},
+		{
+			name:     "missing SFTPPort uses the default",
+			warnings: []string{"SFTPPort"},
+		},
+		{
+			name:     "invalid log level uses the default",
+			lines:    []string{"SFTPPort = 2022", "LogLevel = loud"},
+			warnings: []string{"LogLevel"},
+			check: func(t *testing.T, c Config) {
+				if c.LogLevel != "info" {
+					t.Errorf("expected the default log level, got %q", c.LogLevel)
+				}
+			},
+		},
+		{
+			name:  "invalid critical setting is fatal",
+			lines: []string{"SFTPPort = 2022", "ExecuteTaskEveryXMinutes = often"},
+			fatal: []string{"ExecuteTaskEveryXMinutes"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := LoadConfigFile(writeConfigFile(t, tt.lines...))
+
+			problems := ConfigErrors{}
+			if err != nil && !errors.As(err, &problems) {
+				t.Fatalf("could not load the config: %v", err)
+			}
+
+			warnings, fatal := []string{}, []string{}
+			for _, p := range problems {
+				if p.Warning {
+					warnings = append(warnings, p.Key)
+				} else {
+					fatal = append(fatal, p.Key)
+				}
+			}
+			if strings.Join(warnings, ",") != strings.Join(tt.warnings, ",") {
+				t.Errorf("expected warnings for %v, got %v", tt.warnings, problems)
+			}
+			if strings.Join(fatal, ",") != strings.Join(tt.fatal, ",") {
+				t.Errorf("expected fatal problems for %v, got %v", tt.fatal, problems)
+			}
+			if (problems.Fatal() != nil) != (len(tt.fatal) > 0) {
+				t.Errorf("Fatal() returned %v", problems.Fatal())
+			}
+			if tt.check != nil {
+				tt.check(t, c)
+			}
+		})
+	}
+}
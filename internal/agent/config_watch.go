@@ -0,0 +1,213 @@
+package agent
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/open-uem/openuem-agent/internal/logger"
+	openuem_utils "github.com/open-uem/utils"
+)
+
+const CONFIG_WATCH_INTERVAL = 10 * time.Second
+
+// configWatcher remembers the last version of the config file that was loaded
+type configWatcher struct {
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	loaded  Config
+}
+
+// ownWrite is the state of the config file after the agent saved it, the watcher
+// doesn't load those changes again
+var ownWrite struct {
+	sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+func recordConfigWrite(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	ownWrite.Lock()
+	defer ownWrite.Unlock()
+	ownWrite.modTime = info.ModTime()
+	ownWrite.size = info.Size()
+}
+
+func isOwnWrite(info os.FileInfo) bool {
+	ownWrite.Lock()
+	defer ownWrite.Unlock()
+
+	return info.ModTime().Equal(ownWrite.modTime) && info.Size() == ownWrite.size
+}
+
+// startConfigWatchJob checks the config file periodically and applies its changes,
+// settings that can't be changed while running set the RestartRequired flag
+func (a *Agent) startConfigWatchJob() error {
+	a.configWatch = &configWatcher{loaded: a.currentConfig()}
+	if info, err := os.Stat(openuem_utils.GetAgentConfigFile()); err == nil {
+		a.configWatch.modTime = info.ModTime()
+		a.configWatch.size = info.Size()
+	}
+
+	_, err := a.TaskScheduler.NewJob(
+		gocron.DurationJob(CONFIG_WATCH_INTERVAL),
+		gocron.NewTask(a.ConfigWatchTask),
+		gocron.WithName("config-watch"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		slog.Error("could not start the config watch job", "error", err)
+		return err
+	}
+	slog.Info(fmt.Sprintf("config file will be checked for changes every %s", CONFIG_WATCH_INTERVAL))
+	return nil
+}
+
+func (a *Agent) ConfigWatchTask() {
+	info, err := os.Stat(openuem_utils.GetAgentConfigFile())
+	if err != nil {
+		slog.Error("could not check the config file", "error", err)
+		return
+	}
+
+	w := a.configWatch
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return
+	}
+
+	// The settings saved by the agent are already in use
+	if isOwnWrite(info) {
+		w.modTime = info.ModTime()
+		w.size = info.Size()
+		return
+	}
+
+	// The file may be half written, we'll try again on the next run
+	c, err := loadUsableConfig()
+	if err != nil {
+		slog.Error("the config file has changed but it could not be loaded", "error", err)
+		return
+	}
+	w.modTime = info.ModTime()
+	w.size = info.Size()
+
+	if changes := restartRequiredChanges(w.loaded, c); len(changes) > 0 {
+		slog.Info(fmt.Sprintf("the agent must be restarted to apply the changes in %s", strings.Join(changes, ", ")))
+		if err := a.Config.SetRestartRequiredFlag(); err != nil {
+			slog.Error("could not set restart required flag", "error", err)
+		}
+	}
+	w.loaded = c
+
+	a.ApplyConfig(c)
+}
+
+// ApplyConfig applies the settings that can be changed while the agent is running
+func (a *Agent) ApplyConfig(c Config) {
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+
+	if a.Config.Debug != c.Debug || a.Config.LogLevel != c.LogLevel {
+		a.Config.Debug = c.Debug
+		a.Config.LogLevel = c.LogLevel
+		logger.SetLevel(c.LogLevel, c.Debug)
+		slog.Info("log level has been changed", "level", logger.Level.Level(), "debug", c.Debug)
+	}
+
+	a.Config.IPAddress = c.IPAddress
+	a.Config.TenantID = c.TenantID
+	a.Config.SiteID = c.SiteID
+	a.Config.ScriptsRun = c.ScriptsRun
+	a.Config.DeltaReports = c.DeltaReports
+	a.Config.ShutdownTimeout = c.ShutdownTimeout
+	a.Config.RemoteAssistanceDisabled = c.RemoteAssistanceDisabled
+	a.Config.CertificateRenewalWindow = c.CertificateRenewalWindow
+	a.Config.SignaturePolicy = c.SignaturePolicy
+	a.Config.SignaturePolicies = c.SignaturePolicies
+	a.Config.SignerFingerprints = c.SignerFingerprints
+	a.Config.ReplayPolicy = c.ReplayPolicy
+	a.Config.ReplayPolicies = c.ReplayPolicies
+	a.Config.MaintenanceWindows = c.MaintenanceWindows
+
+	// The proxy listens on the new port the next time a remote desktop session is started
+	if a.Config.VNCProxyPort != c.VNCProxyPort {
+		a.Config.VNCProxyPort = c.VNCProxyPort
+		slog.Info(fmt.Sprintf("VNC proxy port has been set to %s", c.VNCProxyPort))
+	}
+
+	if a.Config.DefaultFrequency != c.DefaultFrequency {
+		a.Config.DefaultFrequency = c.DefaultFrequency
+
+		// While the report can't be sent it keeps being retried every 5 minutes
+		if a.Config.ExecuteTaskEveryXMinutes != SCHEDULETIME_5MIN {
+			a.Config.ExecuteTaskEveryXMinutes = c.DefaultFrequency
+			if a.Config.Enabled && a.ReportJob != nil {
+				a.RescheduleReportRunTask()
+			}
+		}
+	}
+
+	if c.WingetConfigureFrequency != 0 && a.Config.WingetConfigureFrequency != c.WingetConfigureFrequency {
+		a.Config.WingetConfigureFrequency = c.WingetConfigureFrequency
+		if a.WingetConfigureJob != nil {
+			a.RescheduleProfilesTask()
+		}
+	}
+
+	if a.Config.SFTPPort != c.SFTPPort || a.Config.SFTPDisabled != c.SFTPDisabled {
+		a.StopSFTPServer()
+		a.Config.SFTPPort = c.SFTPPort
+		a.Config.SFTPDisabled = c.SFTPDisabled
+		a.StartSFTPServer()
+	}
+}
+
+// restartRequiredChanges lists the settings that are only read when the agent starts
+func restartRequiredChanges(old, c Config) []string {
+	changes := []string{}
+	check := func(name string, changed bool) {
+		if changed {
+			changes = append(changes, name)
+		}
+	}
+
+	check("UUID", old.UUID != c.UUID)
+	check("NATSServers", old.NATSServers != c.NATSServers)
+	check("WebSocketPort", old.WebSocketPort != c.WebSocketPort)
+	check("Compression", old.Compression != c.Compression)
+	check("ReconnectInitialDelay", old.ReconnectInitialDelay != c.ReconnectInitialDelay)
+	check("ReconnectMultiplier", old.ReconnectMultiplier != c.ReconnectMultiplier)
+	check("ReconnectMaxDelay", old.ReconnectMaxDelay != c.ReconnectMaxDelay)
+	check("CACert", old.CACert != c.CACert)
+	check("AgentCert", old.AgentCert != c.AgentCert)
+	check("AgentKey", old.AgentKey != c.AgentKey)
+	check("SFTPCert", old.SFTPCert != c.SFTPCert)
+	check("MetricsListenAddress", old.MetricsListenAddress != c.MetricsListenAddress)
+	check("ReportSpoolMaxSize", old.ReportSpoolMaxSize != c.ReportSpoolMaxSize)
+	check("ReportSpoolMaxAge", old.ReportSpoolMaxAge != c.ReportSpoolMaxAge)
+	check("FullReportEvery", old.FullReportEvery != c.FullReportEvery)
+	check("OutboxMaxAttempts", old.OutboxMaxAttempts != c.OutboxMaxAttempts)
+	check("OutboxMaxAge", old.OutboxMaxAge != c.OutboxMaxAge)
+	check("DataDir", old.DataDir != c.DataDir)
+	check("LogDir", old.LogDir != c.LogDir)
+	check("LogFormat", old.LogFormat != c.LogFormat)
+	check("LogMaxSize", old.LogMaxSize != c.LogMaxSize)
+	check("LogMaxAge", old.LogMaxAge != c.LogMaxAge)
+	check("LogMaxBackups", old.LogMaxBackups != c.LogMaxBackups)
+	check("ClockSkew", old.ClockSkew != c.ClockSkew)
+
+	return changes
+}
@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	openuem_nats "github.com/open-uem/nats"
+	"github.com/open-uem/openuem-agent/internal/agent/outbox"
+	"github.com/open-uem/openuem-agent/internal/metrics"
+)
+
+func (a *Agent) OpenDeployOutbox() {
+	var err error
+	folder := a.Config.DataDir
+
+	a.DeployOutbox, err = outbox.New(filepath.Join(folder, "outbox"), a.Config.OutboxMaxAttempts, a.Config.OutboxMaxAge)
+	if err != nil {
+		slog.Error("could not open the deployment results outbox", "error", err)
+		return
+	}
+
+	a.importPendingACKFile(filepath.Join(folder, "pending_acks.json"))
+	a.updatePendingACKMetric()
+}
+
+// importPendingACKFile moves the results saved by previous versions of the agent to the outbox
+func (a *Agent) importPendingACKFile(filename string) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			slog.Error("could not read pending_acks.json file", "error", err)
+		}
+		return
+	}
+
+	jActions := JSONActions{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &jActions); err != nil {
+			slog.Error("could not decode pending_acks.json file", "error", err)
+			return
+		}
+	}
+
+	for _, action := range jActions.Actions {
+		if err := a.DeployOutbox.Add(action); err != nil {
+			slog.Error("could not import pending deployment ack to the outbox", "error", err)
+			return
+		}
+	}
+
+	if err := os.Remove(filename); err != nil {
+		slog.Error("could not remove pending_acks.json file", "error", err)
+		return
+	}
+
+	if len(jActions.Actions) > 0 {
+		slog.Info(fmt.Sprintf("%d pending deployment results have been moved to the outbox", len(jActions.Actions)))
+	}
+}
+
+// DeliverDeployResult stores the result in the outbox and then tries to send it
+// with any other result that is due
+func (a *Agent) DeliverDeployResult(action openuem_nats.DeployAction) {
+	if a.DeployOutbox == nil {
+		if err := a.SendDeployResult(&action); err != nil {
+			slog.Error("could not send deploy result to worker", "error", err)
+		}
+		return
+	}
+
+	if err := a.DeployOutbox.Add(action); err != nil {
+		slog.Error("could not save deploy result in the outbox", "error", err)
+		if err := a.SendDeployResult(&action); err != nil {
+			slog.Error("could not send deploy result to worker", "error", err)
+		}
+		return
+	}
+
+	// Sending may take minutes if the worker is not reachable, the handler doesn't wait
+	go a.FlushDeployOutbox()
+}
+
+func (a *Agent) FlushDeployOutbox() {
+	if a.DeployOutbox == nil {
+		return
+	}
+
+	sent, err := a.DeployOutbox.Flush(a.SendDeployResult)
+	if err != nil {
+		slog.Error("some deploy results could not be sent to worker, they'll be retried later", "error", err)
+	}
+	if sent > 0 {
+		slog.Debug(fmt.Sprintf("%d deploy results have been sent from the outbox", sent))
+	}
+
+	a.updatePendingACKMetric()
+}
+
+func (a *Agent) updatePendingACKMetric() {
+	if entries, err := a.DeployOutbox.Pending(); err == nil {
+		metrics.PendingACKs.Set(float64(len(entries)))
+	}
+}
+
+// RegisterOutboxRoutes lets the CLI list and retry the dead letters through the status socket
+func (a *Agent) RegisterOutboxRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /outbox/dead", func(w http.ResponseWriter, r *http.Request) {
+		if a.DeployOutbox == nil {
+			http.Error(w, "the outbox is not available", http.StatusServiceUnavailable)
+			return
+		}
+
+		entries, err := a.DeployOutbox.DeadLetters()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(entries); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	mux.HandleFunc("POST /outbox/retry", func(w http.ResponseWriter, r *http.Request) {
+		if a.DeployOutbox == nil {
+			http.Error(w, "the outbox is not available", http.StatusServiceUnavailable)
+			return
+		}
+
+		retried, err := a.DeployOutbox.Retry(r.URL.Query().Get("id"))
+		if err != nil {
+			if errors.Is(err, outbox.ErrNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		slog.Info(fmt.Sprintf("%d dead letters have been queued again from the CLI", retried))
+
+		// Send them now if we can
+		go a.FlushDeployOutbox()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]int{"retried": retried}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
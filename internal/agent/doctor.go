@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/nats-io/nats.go"
+	"github.com/open-uem/openuem-agent/internal/agent/doctor"
+	"github.com/open-uem/openuem-agent/internal/agent/handlers"
+	openuem_utils "github.com/open-uem/utils"
+)
+
+// DoctorOptions tells the diagnostics checks where the agent's files and servers are,
+// err is the error returned when the config was loaded
+func DoctorOptions(c Config, err error) doctor.Options {
+	o := doctor.Options{
+		ConfigFile:   openuem_utils.GetAgentConfigFile(),
+		CACert:       c.CACert,
+		AgentCert:    c.AgentCert,
+		AgentKey:     c.AgentKey,
+		SFTPCert:     c.SFTPCert,
+		NATSServers:  c.NATSServers,
+		SFTPPort:     c.SFTPPort,
+		SFTPDisabled: c.SFTPDisabled,
+		VNCProxyPort: c.VNCProxyPort,
+		VNCDisabled:  c.RemoteAssistanceDisabled,
+		DataDir:      c.DataDir,
+		LogDir:       c.LogDir,
+	}
+
+	problems := ConfigErrors{}
+	if errors.As(err, &problems) {
+		for _, p := range problems {
+			o.ConfigProblems = append(o.ConfigProblems, p.String())
+		}
+	} else if err != nil {
+		o.ConfigProblems = []string{err.Error()}
+	}
+
+	return o
+}
+
+func (a *Agent) RunDoctor() *doctor.Result {
+	// The file is read again so the result shows the problems of its current content
+	_, err := LoadConfig()
+	o := DoctorOptions(a.Config, err)
+	o.SFTPListening = a.SFTPServer != nil
+	o.VNCListening = a.RemoteDesktop != nil && a.RemoteDesktop.RequiresVNCProxy
+	return doctor.Run(o)
+}
+
+func (a *Agent) DoctorHandler(msg *nats.Msg) error {
+	data, err := json.Marshal(a.RunDoctor())
+	if err != nil {
+		return err
+	}
+
+	if err := msg.Respond(data); err != nil {
+		return handlers.Responded(err)
+	}
+	return nil
+}
@@ -0,0 +1,393 @@
+package doctor
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	openuem_utils "github.com/open-uem/utils"
+)
+
+const (
+	PASS = "pass"
+	WARN = "warn"
+	FAIL = "fail"
+)
+
+const DIAL_TIMEOUT = 5 * time.Second
+
+// EXPIRY_WARNING is how long before a certificate expires we start warning about it
+const EXPIRY_WARNING = 30 * 24 * time.Hour
+
+// Options has what the checks need to know about the agent, it's filled from the agent's config
+type Options struct {
+	ConfigFile     string
+	ConfigProblems []string
+	CACert         string
+	AgentCert      string
+	AgentKey       string
+	SFTPCert       string
+	NATSServers    string
+	SFTPPort       string
+	SFTPDisabled   bool
+	VNCProxyPort   string
+	VNCDisabled    bool
+	DataDir        string
+	LogDir         string
+	SFTPListening  bool
+	VNCListening   bool
+}
+
+type Check struct {
+	Group   string `json:"group"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type Result struct {
+	Time   time.Time `json:"time"`
+	Checks []Check   `json:"checks"`
+	Passed int       `json:"passed"`
+	Warned int       `json:"warned"`
+	Failed int       `json:"failed"`
+}
+
+type Tool struct {
+	Name     string
+	Purpose  string
+	Optional bool
+}
+
+// Run runs all the checks, none of them stops the others
+func Run(o Options) *Result {
+	r := Result{Time: time.Now(), Checks: []Check{}}
+
+	r.add(checkConfig(o)...)
+	r.add(checkCertificates(o)...)
+	r.add(checkNATSServers(o)...)
+	r.add(checkPorts(o)...)
+	r.add(checkTools(Tools())...)
+	r.add(checkFolders(o)...)
+
+	return &r
+}
+
+func (r *Result) add(checks ...Check) {
+	for _, c := range checks {
+		switch c.Status {
+		case PASS:
+			r.Passed++
+		case WARN:
+			r.Warned++
+		case FAIL:
+			r.Failed++
+		}
+		r.Checks = append(r.Checks, c)
+	}
+}
+
+func (r *Result) Print() {
+	group := ""
+	for _, c := range r.Checks {
+		if c.Group != group {
+			group = c.Group
+			fmt.Printf("\n** %s %s\n", group, strings.Repeat("*", 110-len(group)))
+		}
+		fmt.Printf("%-40s |  %-4s  %s\n", c.Name, strings.ToUpper(c.Status), c.Message)
+	}
+
+	fmt.Printf("\n%d passed, %d warnings, %d failed\n", r.Passed, r.Warned, r.Failed)
+}
+
+func checkConfig(o Options) []Check {
+	if len(o.ConfigProblems) == 0 {
+		return []Check{{Group: "Config", Name: "openuem.ini", Status: PASS, Message: fmt.Sprintf("%s has no problems", o.ConfigFile)}}
+	}
+
+	checks := []Check{}
+	for _, p := range o.ConfigProblems {
+		checks = append(checks, Check{Group: "Config", Name: "openuem.ini", Status: FAIL, Message: p})
+	}
+	return checks
+}
+
+func checkCertificates(o Options) []Check {
+	checks := []Check{}
+
+	ca, err := openuem_utils.ReadPEMCertificate(o.CACert)
+	if err != nil {
+		checks = append(checks, Check{Group: "Certificates", Name: "ca.cer", Status: FAIL, Message: fmt.Sprintf("could not read %s, reason: %v", o.CACert, err)})
+	} else {
+		checks = append(checks, certificateExpiry("ca.cer", ca, ""))
+	}
+
+	for _, c := range []struct{ name, path string }{{"agent.cer", o.AgentCert}, {"sftp.cer", o.SFTPCert}} {
+		cert, err := openuem_utils.ReadPEMCertificate(c.path)
+		if err != nil {
+			checks = append(checks, Check{Group: "Certificates", Name: c.name, Status: FAIL, Message: fmt.Sprintf("could not read %s, reason: %v", c.path, err)})
+			continue
+		}
+
+		if ca == nil {
+			checks = append(checks, certificateExpiry(c.name, cert, "could not be checked against ca.cer"))
+			continue
+		}
+
+		roots := x509.NewCertPool()
+		roots.AddCert(ca)
+		if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, CurrentTime: cert.NotBefore, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+			checks = append(checks, Check{Group: "Certificates", Name: c.name, Status: FAIL, Message: fmt.Sprintf("is not signed by ca.cer, reason: %v", err)})
+			continue
+		}
+		checks = append(checks, certificateExpiry(c.name, cert, "signed by ca.cer"))
+	}
+
+	if _, err := tls.LoadX509KeyPair(o.AgentCert, o.AgentKey); err != nil {
+		checks = append(checks, Check{Group: "Certificates", Name: "agent.key", Status: FAIL, Message: fmt.Sprintf("could not be used with agent.cer, reason: %v", err)})
+	} else {
+		checks = append(checks, Check{Group: "Certificates", Name: "agent.key", Status: PASS, Message: "matches agent.cer"})
+	}
+
+	return checks
+}
+
+func certificateExpiry(name string, cert *x509.Certificate, note string) Check {
+	c := Check{Group: "Certificates", Name: name}
+	now := time.Now()
+	switch {
+	case now.After(cert.NotAfter):
+		c.Status = FAIL
+		c.Message = fmt.Sprintf("expired on %s", cert.NotAfter.Local().Format(time.RFC1123))
+	case now.Before(cert.NotBefore):
+		c.Status = FAIL
+		c.Message = fmt.Sprintf("is not valid until %s", cert.NotBefore.Local().Format(time.RFC1123))
+	case cert.NotAfter.Sub(now) < EXPIRY_WARNING:
+		c.Status = WARN
+		c.Message = fmt.Sprintf("expires soon, on %s", cert.NotAfter.Local().Format(time.RFC1123))
+	default:
+		c.Status = PASS
+		c.Message = fmt.Sprintf("expires %s", cert.NotAfter.Local().Format(time.RFC1123))
+	}
+
+	if note != "" {
+		c.Message = note + ", " + c.Message
+	}
+	return c
+}
+
+// checkNATSServers checks each server step by step so the first failing step tells what's wrong
+func checkNATSServers(o Options) []Check {
+	checks := []Check{}
+
+	servers := []string{}
+	for s := range strings.SplitSeq(o.NATSServers, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			servers = append(servers, s)
+		}
+	}
+	if len(servers) == 0 {
+		return []Check{{Group: "NATS", Name: "NATSServers", Status: FAIL, Message: "no NATS servers have been set"}}
+	}
+
+	tlsConfig, err := natsTLSConfig(o)
+	if err != nil {
+		checks = append(checks, Check{Group: "NATS", Name: "TLS config", Status: FAIL, Message: err.Error()})
+	}
+
+	for _, server := range servers {
+		checks = append(checks, checkNATSServer(server, tlsConfig, o))
+	}
+	return checks
+}
+
+func checkNATSServer(server string, tlsConfig *tls.Config, o Options) Check {
+	c := Check{Group: "NATS", Name: server, Status: FAIL}
+
+	address := server
+	if u, err := url.Parse(server); err == nil && u.Host != "" {
+		address = u.Host
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = address, "4222"
+		address = net.JoinHostPort(host, port)
+	}
+
+	if _, err := net.LookupHost(host); err != nil {
+		c.Message = fmt.Sprintf("DNS lookup failed, reason: %v", err)
+		return c
+	}
+
+	conn, err := net.DialTimeout("tcp", address, DIAL_TIMEOUT)
+	if err != nil {
+		c.Message = fmt.Sprintf("TCP connection failed, reason: %v", err)
+		return c
+	}
+	defer conn.Close()
+
+	if tlsConfig == nil {
+		c.Status = WARN
+		c.Message = "TCP connection works, TLS could not be checked"
+		return c
+	}
+
+	// NATS servers send INFO before the TLS handshake unless they're set to handshake first
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			c.Message = fmt.Sprintf("could not read server INFO, reason: %v", err)
+			return c
+		}
+	}
+	conn.SetDeadline(time.Now().Add(DIAL_TIMEOUT))
+
+	serverTLS := tlsConfig.Clone()
+	serverTLS.ServerName = host
+	if err := tls.Client(conn, serverTLS).Handshake(); err != nil {
+		c.Message = fmt.Sprintf("TLS handshake failed, reason: %v", err)
+		return c
+	}
+
+	nc, err := nats.Connect(server,
+		nats.RootCAs(o.CACert),
+		nats.ClientCert(o.AgentCert, o.AgentKey),
+		nats.Timeout(DIAL_TIMEOUT),
+		nats.NoReconnect(),
+	)
+	if err != nil {
+		c.Message = fmt.Sprintf("authentication failed, reason: %v", err)
+		return c
+	}
+	defer nc.Close()
+
+	rtt, err := nc.RTT()
+	if err != nil {
+		c.Status = WARN
+		c.Message = fmt.Sprintf("connected but the server didn't answer, reason: %v", err)
+		return c
+	}
+
+	c.Status = PASS
+	c.Message = fmt.Sprintf("DNS, TCP, TLS and authentication work, round trip %s", rtt.Round(time.Millisecond))
+	return c
+}
+
+func natsTLSConfig(o Options) (*tls.Config, error) {
+	ca, err := openuem_utils.ReadPEMCertificate(o.CACert)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA certificate, reason: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	cert, err := tls.LoadX509KeyPair(o.AgentCert, o.AgentKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not load agent certificate, reason: %v", err)
+	}
+
+	return &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+func checkPorts(o Options) []Check {
+	return []Check{
+		checkPort("SFTP port", o.SFTPPort, o.SFTPDisabled, o.SFTPListening, "the agent's SFTP server"),
+		checkPort("VNC proxy port", o.VNCProxyPort, o.VNCDisabled, o.VNCListening, "the agent's VNC proxy"),
+	}
+}
+
+func checkPort(name string, port string, disabled bool, listening bool, owner string) Check {
+	c := Check{Group: "Ports", Name: name}
+
+	switch {
+	case disabled:
+		c.Status = PASS
+		c.Message = "disabled"
+		return c
+	case port == "":
+		c.Status = WARN
+		c.Message = "no port has been set"
+		return c
+	case listening:
+		c.Status = PASS
+		c.Message = fmt.Sprintf("%s is used by %s", port, owner)
+		return c
+	}
+
+	l, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		c.Status = FAIL
+		c.Message = fmt.Sprintf("%s is not free, reason: %v", port, err)
+		return c
+	}
+	l.Close()
+
+	c.Status = PASS
+	c.Message = fmt.Sprintf("%s is free", port)
+	return c
+}
+
+func checkTools(tools []Tool) []Check {
+	checks := []Check{}
+	for _, t := range tools {
+		c := Check{Group: "Tools", Name: t.Name}
+		path, err := exec.LookPath(t.Name)
+		switch {
+		case err == nil:
+			c.Status = PASS
+			c.Message = path
+		case t.Optional:
+			c.Status = WARN
+			c.Message = fmt.Sprintf("not found, it's needed for %s", t.Purpose)
+		default:
+			c.Status = FAIL
+			c.Message = fmt.Sprintf("not found, it's required for %s", t.Purpose)
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+func checkFolders(o Options) []Check {
+	checks := []Check{}
+	for _, f := range []struct{ name, path string }{
+		{"DataDir", o.DataDir},
+		{"Certificates", filepath.Join(o.DataDir, "certificates")},
+		{"LogDir", o.LogDir},
+	} {
+		c := Check{Group: "Folders", Name: f.name, Status: FAIL}
+		if err := canWrite(f.path); err != nil {
+			c.Message = fmt.Sprintf("%s is not writable, reason: %v", f.path, err)
+		} else {
+			c.Status = PASS
+			c.Message = fmt.Sprintf("%s is writable", f.path)
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+func canWrite(dir string) error {
+	if dir == "" {
+		return errors.New("the folder has not been set")
+	}
+
+	f, err := os.CreateTemp(dir, ".openuem-doctor-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
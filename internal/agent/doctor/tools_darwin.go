@@ -0,0 +1,13 @@
+//go:build darwin
+
+package doctor
+
+// Tools lists the external programs the agent runs on macOS
+func Tools() []Tool {
+	return []Tool{
+		{Name: "system_profiler", Purpose: "the report"},
+		{Name: "softwareupdate", Purpose: "the system updates report"},
+		{Name: "ansible-playbook", Purpose: "running profiles", Optional: true},
+		{Name: "brew", Purpose: "installing packages", Optional: true},
+	}
+}
@@ -0,0 +1,15 @@
+//go:build linux
+
+package doctor
+
+// Tools lists the external programs the agent runs on Linux
+func Tools() []Tool {
+	return []Tool{
+		{Name: "hwinfo", Purpose: "the computer and monitors report"},
+		{Name: "dmidecode", Purpose: "the computer report"},
+		{Name: "lsblk", Purpose: "the disks report"},
+		{Name: "ansible-playbook", Purpose: "running profiles", Optional: true},
+		{Name: "flatpak", Purpose: "installing packages", Optional: true},
+		{Name: "x11vnc", Purpose: "remote assistance on X11 desktops", Optional: true},
+	}
+}
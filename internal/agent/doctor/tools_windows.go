@@ -0,0 +1,8 @@
+//go:build windows
+
+package doctor
+
+// Tools is empty on Windows, the report uses WMI and the APIs of the system
+func Tools() []Tool {
+	return []Tool{}
+}
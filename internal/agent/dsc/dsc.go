@@ -2,7 +2,7 @@ package dsc
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"os"
 	"slices"
 	"time"
@@ -19,32 +19,32 @@ func ReadTaskControlFile(taskControl string) (*TaskControl, error) {
 		f, err := os.Create(taskControl)
 		defer func() {
 			if err := f.Close(); err != nil {
-				log.Printf("[ERROR]: could not close the task control file, reason: %v", err)
+				slog.Error("could not close the task control file", "error", err)
 			}
 		}()
 		if err != nil {
-			log.Printf("[ERROR]: could not create the task control file, reason: %v", err)
+			slog.Error("could not create the task control file", "error", err)
 			return nil, err
 		}
 
 		t := TaskControl{}
 		data, err := json.Marshal(t)
 		if err != nil {
-			log.Printf("[ERROR]: could not marshall initial task control, reason: %v", err)
+			slog.Error("could not marshall initial task control", "error", err)
 		}
 		if _, err := f.Write(data); err != nil {
-			log.Printf("[ERROR]: could not write initial data to task control file, reason: %v", err)
+			slog.Error("could not write initial data to task control file", "error", err)
 		}
 		return &t, nil
 	} else {
 		data, err := os.ReadFile(taskControl)
 		if err != nil {
-			log.Printf("[ERROR]: could not read the task control file, reason: %v", err)
+			slog.Error("could not read the task control file", "error", err)
 			return nil, err
 		}
 		t := TaskControl{}
 		if err := json.Unmarshal(data, &t); err != nil {
-			log.Printf("[ERROR]: could not unmarshall JSON data from the task control file, reason: %v", err)
+			slog.Error("could not unmarshall JSON data from the task control file", "error", err)
 			return nil, err
 		}
 
@@ -63,12 +63,12 @@ func SetTaskAsSuccessfull(taskID string, taskControlPath string, t *TaskControl)
 
 	out, err := json.Marshal(t)
 	if err != nil {
-		log.Printf("[ERROR]: could not marshal JSON data for the task control file, reason: %v", err)
+		slog.Error("could not marshal JSON data for the task control file", "error", err)
 		return err
 	}
 
 	if err := os.WriteFile(taskControlPath, out, 0660); err != nil {
-		log.Printf("[ERROR]: could not write JSON data to the task control file, reason: %v", err)
+		slog.Error("could not write JSON data to the task control file", "error", err)
 		return err
 	}
 
@@ -78,12 +78,12 @@ func SetTaskAsSuccessfull(taskID string, taskControlPath string, t *TaskControl)
 func SaveTaskControl(taskControlPath string, t *TaskControl) error {
 	out, err := json.Marshal(t)
 	if err != nil {
-		log.Printf("[ERROR]: could not marshal JSON data for the task control file, reason: %v", err)
+		slog.Error("could not marshal JSON data for the task control file", "error", err)
 		return err
 	}
 
 	if err := os.WriteFile(taskControlPath, out, 0660); err != nil {
-		log.Printf("[ERROR]: could not write executed task as JSON data to the task control file, reason: %v", err)
+		slog.Error("could not write executed task as JSON data to the task control file", "error", err)
 		return err
 	}
 
@@ -93,12 +93,12 @@ func SaveTaskControl(taskControlPath string, t *TaskControl) error {
 func SetProfileAsRunning(taskControlPath string, t *TaskControl) error {
 	out, err := json.Marshal(t)
 	if err != nil {
-		log.Printf("[ERROR]: could not marshal JSON data for the task control file, reason: %v", err)
+		slog.Error("could not marshal JSON data for the task control file", "error", err)
 		return err
 	}
 
 	if err := os.WriteFile(taskControlPath, out, 0660); err != nil {
-		log.Printf("[ERROR]: could not write executed task as JSON data to the task control file, reason: %v", err)
+		slog.Error("could not write executed task as JSON data to the task control file", "error", err)
 		return err
 	}
 
@@ -4,7 +4,7 @@ package dsc
 
 import (
 	"bytes"
-	"log"
+	"log/slog"
 	"os/exec"
 
 	"github.com/open-uem/openuem-agent/internal/commands/runtime"
@@ -26,7 +26,7 @@ func RunTaskWithLowPriority(command string) (string, string, error) {
 
 	err = runtime.SetPriorityWindows(cmd.Process.Pid, windows.IDLE_PRIORITY_CLASS)
 	if err != nil {
-		log.Println("[ERROR]: could not change process priority")
+		slog.Error("could not change process priority")
 		return "", "", err
 	}
 
@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/open-uem/openuem-agent/internal/metrics"
+)
+
+const MANAGEMENT_QUEUE = "openuem-agent-management"
+
+type HandlerFunc func(msg *nats.Msg) error
+
+type Middleware func(h *Handler, next HandlerFunc) HandlerFunc
+
+// Handler declares a NATS subject the agent listens to and the payload it expects
+type Handler struct {
+	Name          string
+	Queue         string
+	Payload       string
+	Broadcast     bool
+	Audited       bool
+	Deferrable    bool
+	Handle        HandlerFunc
+	ErrorResponse func(err error) []byte
+}
+
+type Info struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Queue   string `json:"queue,omitempty"`
+	Payload string `json:"payload,omitempty"`
+}
+
+// New declares a handler for agent.<name>.<uuid> that gets the raw message
+func New(name string, queue string, fn HandlerFunc) *Handler {
+	return &Handler{Name: name, Queue: queue, Handle: fn}
+}
+
+// JSON declares a handler whose payload is decoded from JSON before calling fn
+func JSON[T any](name string, queue string, fn func(msg *nats.Msg, data T) error) *Handler {
+	h := New(name, queue, func(msg *nats.Msg) error {
+		var data T
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			return fmt.Errorf("could not decode %s payload, reason: %v", reflect.TypeFor[T]().String(), err)
+		}
+		return fn(msg, data)
+	})
+	h.Payload = reflect.TypeFor[T]().String()
+	return h
+}
+
+// WithErrorResponse sets how errors are encoded for subjects whose callers expect a specific format
+func (h *Handler) WithErrorResponse(fn func(err error) []byte) *Handler {
+	h.ErrorResponse = fn
+	return h
+}
+
+// WithPayload documents the payload for handlers that decode it themselves
+func (h *Handler) WithPayload(payload string) *Handler {
+	h.Payload = payload
+	return h
+}
+
+// WithAudit records every message handled in the audit log
+func (h *Handler) WithAudit() *Handler {
+	h.Audited = true
+	return h
+}
+
+// WithDeferral lets the agent keep the message until the next maintenance window
+func (h *Handler) WithDeferral() *Handler {
+	h.Deferrable = true
+	return h
+}
+
+// AsBroadcast declares a subject shared by all agents, without the agent's uuid
+func (h *Handler) AsBroadcast() *Handler {
+	h.Broadcast = true
+	return h
+}
+
+func (h *Handler) Subject(agentID string) string {
+	if h.Broadcast {
+		return h.Name
+	}
+	return h.Name + "." + agentID
+}
+
+type respondedError struct {
+	err error
+}
+
+func (e *respondedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *respondedError) Unwrap() error {
+	return e.err
+}
+
+// Responded marks an error that happened after the handler had already replied,
+// so it is logged but no error response is sent
+func Responded(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &respondedError{err: err}
+}
+
+type Registry struct {
+	mu            sync.Mutex
+	agentID       string
+	handlers      []*Handler
+	middlewares   []Middleware
+	subscriptions []*nats.Subscription
+}
+
+// NewRegistry creates a registry, the first middleware is the outermost one
+func NewRegistry(agentID string, middlewares ...Middleware) *Registry {
+	return &Registry{agentID: agentID, middlewares: middlewares}
+}
+
+func (r *Registry) Register(handlers ...*Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers = append(r.handlers, handlers...)
+}
+
+func (r *Registry) List() []Info {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	infos := []Info{}
+	for _, h := range r.handlers {
+		infos = append(infos, Info{Name: h.Name, Subject: h.Subject(r.agentID), Queue: h.Queue, Payload: h.Payload})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Subject < infos[j].Subject })
+	return infos
+}
+
+// Lookup returns the registered handler with that name
+func (r *Registry) Lookup(name string) (*Handler, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, h := range r.handlers {
+		if h.Name == name {
+			return h, true
+		}
+	}
+	return nil, false
+}
+
+// Subscribe subscribes all the registered handlers, replacing previous subscriptions
+func (r *Registry) Subscribe(nc *nats.Conn) error {
+	r.Unsubscribe()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+	for _, h := range r.handlers {
+		handle := r.chain(h)
+		cb := func(msg *nats.Msg) {
+			_ = handle(msg)
+		}
+
+		var err error
+		var s *nats.Subscription
+		if h.Queue != "" {
+			s, err = nc.QueueSubscribe(h.Subject(r.agentID), h.Queue, cb)
+		} else {
+			s, err = nc.Subscribe(h.Subject(r.agentID), cb)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not subscribe to %s, reason: %v", h.Subject(r.agentID), err))
+			continue
+		}
+		r.subscriptions = append(r.subscriptions, s)
+	}
+
+	return errors.Join(errs...)
+}
+
+// chain wraps the handler with the middlewares. A panic in a middleware, e.g. while verifying
+// a signature, is recovered too so it can't stop the agent
+func (r *Registry) chain(h *Handler) HandlerFunc {
+	next := h.Handle
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		next = r.middlewares[i](h, next)
+	}
+	return Recover(h, next)
+}
+
+func (r *Registry) Unsubscribe() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range r.subscriptions {
+		if err := s.Unsubscribe(); err != nil && !errors.Is(err, nats.ErrConnectionClosed) && !errors.Is(err, nats.ErrBadSubscription) {
+			slog.Error("could not unsubscribe from", "subject", s.Subject, "error", err)
+		}
+	}
+	r.subscriptions = nil
+}
+
+// Drain stops receiving new messages and waits until the pending ones have been handled or ctx is done
+func (r *Registry) Drain(ctx context.Context) {
+	r.mu.Lock()
+	subscriptions := r.subscriptions
+	r.subscriptions = nil
+	r.mu.Unlock()
+
+	for _, s := range subscriptions {
+		if err := s.Drain(); err != nil && !errors.Is(err, nats.ErrConnectionClosed) && !errors.Is(err, nats.ErrBadSubscription) {
+			slog.Error("could not drain subscription to", "subject", s.Subject, "error", err)
+		}
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for _, s := range subscriptions {
+		for s.IsValid() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// Recover turns a panic in a handler into an error so one bad message can't stop the agent.
+// As the innermost middleware the error is still audited and answered, every chain is also
+// wrapped with it so panics in other middlewares are recovered as well
+func Recover(h *Handler, next HandlerFunc) HandlerFunc {
+	return func(msg *nats.Msg) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				slog.Error(fmt.Sprintf("panic while handling: %v", p), "handler", h.Name, "subject", msg.Subject, "stack", string(debug.Stack()))
+				err = fmt.Errorf("internal error while handling %s", h.Name)
+			}
+		}()
+		return next(msg)
+	}
+}
+
+// Timing records how long each handler takes
+func Timing(h *Handler, next HandlerFunc) HandlerFunc {
+	return func(msg *nats.Msg) error {
+		start := time.Now()
+		err := next(msg)
+		metrics.ObserveHandler(h.Name, start, err)
+		return err
+	}
+}
+
+// ErrorResponse replies to requests whose handler failed before responding
+func ErrorResponse(h *Handler, next HandlerFunc) HandlerFunc {
+	return func(msg *nats.Msg) error {
+		err := next(msg)
+		if err == nil || msg.Reply == "" {
+			return err
+		}
+
+		var responded *respondedError
+		if errors.As(err, &responded) {
+			return err
+		}
+
+		data := []byte(err.Error())
+		if h.ErrorResponse != nil {
+			data = h.ErrorResponse(err)
+		}
+		if err := msg.Respond(data); err != nil {
+			slog.Error("could not respond to message", "subject", msg.Subject, "error", err)
+		}
+		return err
+	}
+}
+
+// Audit calls record after handling the messages of audited handlers
+func Audit(record func(h *Handler, msg *nats.Msg, start time.Time, err error)) Middleware {
+	return func(h *Handler, next HandlerFunc) HandlerFunc {
+		if !h.Audited {
+			return next
+		}
+		return func(msg *nats.Msg) error {
+			start := time.Now()
+			err := next(msg)
+			record(h, msg, start, err)
+			return err
+		}
+	}
+}
+
+// Verify rejects the message with the error returned by check before it reaches the handler
+func Verify(check func(h *Handler, msg *nats.Msg) error) Middleware {
+	return func(h *Handler, next HandlerFunc) HandlerFunc {
+		return func(msg *nats.Msg) error {
+			if err := check(h, msg); err != nil {
+				return err
+			}
+			return next(msg)
+		}
+	}
+}
+
+// Defer stops the messages of deferrable handlers that queue keeps to be handled later
+func Defer(queue func(h *Handler, msg *nats.Msg) (bool, error)) Middleware {
+	return func(h *Handler, next HandlerFunc) HandlerFunc {
+		if !h.Deferrable {
+			return next
+		}
+		return func(msg *nats.Msg) error {
+			deferred, err := queue(h, msg)
+			if err != nil {
+				return err
+			}
+			if deferred {
+				return nil
+			}
+			return next(msg)
+		}
+	}
+}
+
+// Logging logs failed messages and, at debug level, every message handled
+func Logging(h *Handler, next HandlerFunc) HandlerFunc {
+	return func(msg *nats.Msg) error {
+		start := time.Now()
+		err := next(msg)
+		log := slog.With("handler", h.Name, "subject", msg.Subject, "payload", h.Payload, "duration", time.Since(start))
+		if err != nil {
+			log.Error("message could not be handled", "error", err)
+			return err
+		}
+		log.Debug("message handled")
+		return nil
+	}
+}
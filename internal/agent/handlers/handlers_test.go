@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func panics(h *Handler, next HandlerFunc) HandlerFunc {
+	return func(msg *nats.Msg) error {
+		panic("bad signature")
+	}
+}
+
+func TestChainRecoversPanics(t *testing.T) {
+	handled := func(msg *nats.Msg) error { return nil }
+	panicking := func(msg *nats.Msg) error { panic("nil map") }
+
+	tests := []struct {
+		name        string
+		middlewares []Middleware
+		handle      HandlerFunc
+		err         string
+	}{
+		{name: "no panic", middlewares: []Middleware{Logging, Recover}, handle: handled},
+		{name: "panic in the handler", middlewares: []Middleware{Logging, Recover}, handle: panicking, err: "internal error"},
+		{name: "panic in a middleware", middlewares: []Middleware{Logging, panics, Recover}, handle: handled, err: "internal error"},
+		{name: "panic in the outermost middleware", middlewares: []Middleware{panics, Logging}, handle: handled, err: "internal error"},
+		{name: "panic in verify", middlewares: []Middleware{Verify(func(h *Handler, msg *nats.Msg) error { panic("bad x509") }), Recover}, handle: handled, err: "internal error"},
+		{name: "panic in defer", middlewares: []Middleware{Defer(func(h *Handler, msg *nats.Msg) (bool, error) { panic("queue closed") }), Recover}, handle: handled, err: "internal error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry("agent", tt.middlewares...)
+			h := New("agent.reboot", "", tt.handle).WithDeferral()
+
+			err := r.chain(h)(&nats.Msg{Subject: "agent.reboot.agent"})
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error is %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAuditRecordsRecoveredPanic(t *testing.T) {
+	var recorded error
+	audit := Audit(func(h *Handler, msg *nats.Msg, start time.Time, err error) { recorded = err })
+
+	r := NewRegistry("agent", audit, Recover)
+	h := New("agent.reboot", "", func(msg *nats.Msg) error { panic("nil map") }).WithAudit()
+	r.chain(h)(&nats.Msg{Subject: "agent.reboot.agent"})
+
+	if recorded == nil {
+		t.Error("the panic was not audited as an error")
+	}
+}
+
+func TestVerifyStopsMessage(t *testing.T) {
+	errRejected := errors.New("rejected")
+	called := false
+	r := NewRegistry("agent", Verify(func(h *Handler, msg *nats.Msg) error { return errRejected }))
+	h := New("agent.reboot", "", func(msg *nats.Msg) error { called = true; return nil })
+
+	if err := r.chain(h)(&nats.Msg{}); !errors.Is(err, errRejected) || called {
+		t.Errorf("error = %v, handler called = %v, want rejected before the handler", err, called)
+	}
+}
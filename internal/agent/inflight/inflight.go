@@ -0,0 +1,132 @@
+package inflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	openuem_nats "github.com/open-uem/nats"
+)
+
+var ErrShuttingDown = errors.New("the agent is shutting down, the task has not been started")
+
+// Tracker keeps the deployments and profiles that are running so the agent
+// can wait for them and save what they've done if it has to stop
+type Tracker struct {
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	stopping bool
+	nextID   int
+	deploys  map[int]openuem_nats.DeployAction
+	profiles map[int]openuem_nats.ProfileReport
+}
+
+type Task struct {
+	tracker *Tracker
+	id      int
+	once    sync.Once
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		deploys:  map[int]openuem_nats.DeployAction{},
+		profiles: map[int]openuem_nats.ProfileReport{},
+	}
+}
+
+func (t *Tracker) start() (*Task, error) {
+	if t.stopping {
+		return nil, ErrShuttingDown
+	}
+	t.nextID++
+	t.wg.Add(1)
+	return &Task{tracker: t, id: t.nextID}, nil
+}
+
+func (t *Tracker) StartDeploy(action openuem_nats.DeployAction) (*Task, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	task, err := t.start()
+	if err != nil {
+		return nil, err
+	}
+	t.deploys[task.id] = action
+	return task, nil
+}
+
+func (t *Tracker) StartProfile(report openuem_nats.ProfileReport) (*Task, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	task, err := t.start()
+	if err != nil {
+		return nil, err
+	}
+	t.profiles[task.id] = report
+	return task, nil
+}
+
+// UpdateProfile stores the tasks of a profile that have already finished
+func (task *Task) UpdateProfile(report openuem_nats.ProfileReport) {
+	t := task.tracker
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.profiles[task.id]; ok {
+		t.profiles[task.id] = report
+	}
+}
+
+func (task *Task) Done() {
+	task.once.Do(func() {
+		t := task.tracker
+		t.mu.Lock()
+		delete(t.deploys, task.id)
+		delete(t.profiles, task.id)
+		t.mu.Unlock()
+		t.wg.Done()
+	})
+}
+
+func (t *Tracker) Count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.deploys) + len(t.profiles)
+}
+
+// Stop refuses new tasks and waits for the running ones until ctx is done.
+// The tasks that couldn't finish in time are returned with their partial results
+func (t *Tracker) Stop(ctx context.Context) ([]openuem_nats.DeployAction, []openuem_nats.ProfileReport) {
+	t.mu.Lock()
+	t.stopping = true
+	t.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil, nil
+	case <-ctx.Done():
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	deploys := []openuem_nats.DeployAction{}
+	for _, d := range t.deploys {
+		deploys = append(deploys, d)
+	}
+
+	profiles := []openuem_nats.ProfileReport{}
+	for _, p := range t.profiles {
+		profiles = append(profiles, p)
+	}
+
+	return deploys, profiles
+}
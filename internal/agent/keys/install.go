@@ -0,0 +1,168 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const BACKUP_SUFFIX = ".bak"
+
+// KeyPair is where a certificate and its private key are stored
+type KeyPair struct {
+	CertPath string
+	KeyPath  string
+}
+
+func (p KeyPair) Load() (tls.Certificate, error) {
+	return tls.LoadX509KeyPair(p.CertPath, p.KeyPath)
+}
+
+func (p KeyPair) backup() KeyPair {
+	return KeyPair{CertPath: p.CertPath + BACKUP_SUFFIX, KeyPath: p.KeyPath + BACKUP_SUFFIX}
+}
+
+// Install checks that the key matches the certificate and that the certificate chains to ca,
+// stages both files next to the current ones and renames them in place. The current pair is
+// kept as a backup and restored if the new one can't be loaded
+func (p KeyPair) Install(certDER []byte, key crypto.Signer, ca *x509.Certificate) error {
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return fmt.Errorf("could not parse the certificate, reason: %v", err)
+	}
+
+	if err := MatchesCertificate(cert, key); err != nil {
+		return err
+	}
+
+	if ca != nil {
+		roots := x509.NewCertPool()
+		roots.AddCert(ca)
+		if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+			return fmt.Errorf("the certificate is not valid, reason: %v", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.CertPath), 0700); err != nil {
+		return fmt.Errorf("could not create certificates folder, reason: %v", err)
+	}
+
+	stagingDir, err := os.MkdirTemp(filepath.Dir(p.CertPath), ".staging-")
+	if err != nil {
+		return fmt.Errorf("could not create staging folder, reason: %v", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	staged := KeyPair{CertPath: filepath.Join(stagingDir, filepath.Base(p.CertPath)), KeyPath: filepath.Join(stagingDir, filepath.Base(p.KeyPath))}
+	if err := SavePrivateKey(key, staged.KeyPath); err != nil {
+		return fmt.Errorf("could not stage the private key, reason: %v", err)
+	}
+	if err := writeFile(staged.CertPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0644); err != nil {
+		return fmt.Errorf("could not stage the certificate, reason: %v", err)
+	}
+	if _, err := staged.Load(); err != nil {
+		return fmt.Errorf("the staged certificate could not be loaded, reason: %v", err)
+	}
+
+	hasBackup := false
+	if _, err := p.Load(); err == nil {
+		if err := p.saveBackup(); err != nil {
+			return fmt.Errorf("could not back up the current certificate, reason: %v", err)
+		}
+		hasBackup = true
+	}
+
+	swapErr := os.Rename(staged.KeyPath, p.KeyPath)
+	if swapErr == nil {
+		swapErr = os.Rename(staged.CertPath, p.CertPath)
+	}
+	if swapErr == nil {
+		_, swapErr = p.Load()
+	}
+	if swapErr == nil {
+		return nil
+	}
+
+	if !hasBackup {
+		return fmt.Errorf("could not install the certificate, reason: %v", swapErr)
+	}
+	if err := p.Restore(); err != nil {
+		return fmt.Errorf("could not install the certificate, reason: %v, and the previous one could not be restored, reason: %v", swapErr, err)
+	}
+	return fmt.Errorf("could not install the certificate so the previous one has been restored, reason: %v", swapErr)
+}
+
+// Restore puts back the pair that was in use before the last Install
+func (p KeyPair) Restore() error {
+	b := p.backup()
+	if _, err := b.Load(); err != nil {
+		return fmt.Errorf("the backup could not be loaded, reason: %v", err)
+	}
+	if err := copyFile(b.KeyPath, p.KeyPath, 0600); err != nil {
+		return err
+	}
+	return copyFile(b.CertPath, p.CertPath, 0644)
+}
+
+// Recover restores the backup if the pair can't be loaded, e.g. when the agent stopped in the
+// middle of an Install. It returns true if the backup has been restored
+func (p KeyPair) Recover() (bool, error) {
+	if _, err := p.Load(); err == nil {
+		return false, nil
+	}
+
+	if _, err := os.Stat(p.backup().CertPath); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+
+	if err := p.Restore(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (p KeyPair) saveBackup() error {
+	b := p.backup()
+	if err := copyFile(p.KeyPath, b.KeyPath, 0600); err != nil {
+		return err
+	}
+	return copyFile(p.CertPath, b.CertPath, 0644)
+}
+
+// copyFile writes a temporary copy and renames it so dst is never left half written
+func copyFile(src, dst string, perm os.FileMode) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	tmp := dst + ".tmp"
+	if err := writeFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// writeFile syncs the data to disk before returning so a rename afterwards can't leave an empty file
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,194 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	der  []byte
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newCert(t *testing.T, name string, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	issuer, issuerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		issuer, issuerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{der: der, cert: cert, key: key}
+}
+
+func newKeyPair(t *testing.T) KeyPair {
+	dir := filepath.Join(t.TempDir(), "certificates")
+	return KeyPair{CertPath: filepath.Join(dir, "agent.cer"), KeyPath: filepath.Join(dir, "agent.key")}
+}
+
+func installedCert(t *testing.T, p KeyPair) []byte {
+	t.Helper()
+
+	c, err := p.Load()
+	if err != nil {
+		t.Fatalf("the installed pair can't be loaded: %v", err)
+	}
+	return c.Certificate[0]
+}
+
+func TestInstall(t *testing.T) {
+	ca := newCert(t, "OpenUEM CA", nil)
+	otherCA := newCert(t, "Other CA", nil)
+	current := newCert(t, "agent", ca)
+	renewed := newCert(t, "agent", ca)
+	untrusted := newCert(t, "agent", otherCA)
+
+	tests := []struct {
+		name    string
+		cert    *testCert
+		key     *ecdsa.PrivateKey
+		err     bool
+		want    *testCert
+		backups bool
+	}{
+		{name: "renewed", cert: renewed, key: renewed.key, want: renewed, backups: true},
+		{name: "key of another certificate", cert: renewed, key: current.key, err: true, want: current},
+		{name: "untrusted certificate", cert: untrusted, key: untrusted.key, err: true, want: current},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newKeyPair(t)
+			if err := p.Install(current.der, current.key, ca.cert); err != nil {
+				t.Fatal(err)
+			}
+
+			err := p.Install(tt.cert.der, tt.key, ca.cert)
+			if (err != nil) != tt.err {
+				t.Fatalf("Install() error = %v", err)
+			}
+			if !bytes.Equal(installedCert(t, p), tt.want.der) {
+				t.Error("the installed certificate is not the expected one")
+			}
+
+			_, err = os.Stat(p.backup().CertPath)
+			if (err == nil) != tt.backups {
+				t.Errorf("backup exists = %v, want %v", err == nil, tt.backups)
+			}
+
+			// The staging folders are always removed
+			entries, _ := os.ReadDir(filepath.Dir(p.CertPath))
+			for _, e := range entries {
+				if e.IsDir() {
+					t.Errorf("%s was left in the certificates folder", e.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestInstallFirstCertificate(t *testing.T) {
+	ca := newCert(t, "OpenUEM CA", nil)
+	cert := newCert(t, "agent", ca)
+
+	p := newKeyPair(t)
+	if err := p.Install(cert.der, cert.key, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(installedCert(t, p), cert.der) {
+		t.Error("the installed certificate is not the expected one")
+	}
+	if _, err := os.Stat(p.backup().CertPath); err == nil {
+		t.Error("a backup was made without a previous certificate")
+	}
+
+	info, err := os.Stat(p.KeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		t.Errorf("the private key has permissions %v", info.Mode().Perm())
+	}
+}
+
+func TestRecover(t *testing.T) {
+	ca := newCert(t, "OpenUEM CA", nil)
+	first := newCert(t, "agent", ca)
+	second := newCert(t, "agent", ca)
+
+	p := newKeyPair(t)
+	if err := p.Install(first.der, first.key, ca.cert); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing to recover while the pair can be loaded, or without a backup
+	if restored, err := p.Recover(); restored || err != nil {
+		t.Fatalf("Recover() = %v, %v with a valid pair", restored, err)
+	}
+
+	if err := p.Install(second.der, second.key, ca.cert); err != nil {
+		t.Fatal(err)
+	}
+
+	// An Install interrupted after moving the key leaves a pair that can't be loaded
+	if err := SavePrivateKey(newCert(t, "other", ca).key, p.KeyPath); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := p.Recover()
+	if err != nil || !restored {
+		t.Fatalf("Recover() = %v, %v, want the backup restored", restored, err)
+	}
+	if !bytes.Equal(installedCert(t, p), first.der) {
+		t.Error("the restored certificate is not the previous one")
+	}
+}
+
+func TestRecoverWithoutBackup(t *testing.T) {
+	p := newKeyPair(t)
+	if err := os.MkdirAll(filepath.Dir(p.CertPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p.CertPath, []byte("broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored, err := p.Recover(); restored || err != nil {
+		t.Errorf("Recover() = %v, %v without a backup, want nothing done", restored, err)
+	}
+}
@@ -0,0 +1,98 @@
+package keys
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ParsePrivateKey reads a DER private key in PKCS#8, PKCS#1 or SEC 1 format,
+// only RSA, ECDSA and Ed25519 keys are supported
+func ParsePrivateKey(der []byte) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return supported(key)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("the private key is not a PKCS#8, PKCS#1 or EC private key")
+}
+
+func supported(key any) (crypto.Signer, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	}
+	return nil, fmt.Errorf("unsupported private key type %T, only RSA, ECDSA and Ed25519 keys can be used", key)
+}
+
+// ReadPrivateKey reads a PEM private key file
+func ReadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("the file has no PEM data")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY":
+		return ParsePrivateKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("the file has a %s instead of a private key", block.Type)
+}
+
+// SavePrivateKey writes RSA keys in PKCS#1 as the agent has always done, other keys in PKCS#8
+func SavePrivateKey(key crypto.Signer, path string) error {
+	block := pem.Block{}
+	if rsaKey, ok := key.(*rsa.PrivateKey); ok {
+		block.Type = "RSA PRIVATE KEY"
+		block.Bytes = x509.MarshalPKCS1PrivateKey(rsaKey)
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return err
+		}
+		block.Type = "PRIVATE KEY"
+		block.Bytes = der
+	}
+
+	buf := new(bytes.Buffer)
+	if err := pem.Encode(buf, &block); err != nil {
+		return err
+	}
+	return writeFile(path, buf.Bytes(), 0600)
+}
+
+// MatchesCertificate checks that key is the private key of the certificate
+func MatchesCertificate(cert *x509.Certificate, key crypto.Signer) error {
+	publicKey, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return fmt.Errorf("unsupported public key type %T", cert.PublicKey)
+	}
+
+	if !publicKey.Equal(key.Public()) {
+		return errors.New("the private key doesn't match the certificate")
+	}
+	return nil
+}
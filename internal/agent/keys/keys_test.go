@@ -0,0 +1,119 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newKeys(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]crypto.Signer{"RSA": rsaKey, "ECDSA": ecKey, "Ed25519": edKey}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	all := newKeys(t)
+	pkcs8 := func(key crypto.Signer) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+	sec1, err := x509.MarshalECPrivateKey(all["ECDSA"].(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		der  []byte
+		key  crypto.Signer
+		err  bool
+	}{
+		{name: "PKCS#1 RSA", der: x509.MarshalPKCS1PrivateKey(all["RSA"].(*rsa.PrivateKey)), key: all["RSA"]},
+		{name: "PKCS#8 RSA", der: pkcs8(all["RSA"]), key: all["RSA"]},
+		{name: "PKCS#8 ECDSA", der: pkcs8(all["ECDSA"]), key: all["ECDSA"]},
+		{name: "SEC 1 ECDSA", der: sec1, key: all["ECDSA"]},
+		{name: "PKCS#8 Ed25519", der: pkcs8(all["Ed25519"]), key: all["Ed25519"]},
+		{name: "not a key", der: []byte("not a key"), err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePrivateKey(tt.der)
+			if tt.err {
+				if err == nil {
+					t.Fatal("ParsePrivateKey() accepted an invalid key")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePrivateKey() error = %v", err)
+			}
+			if !key.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(tt.key.Public()) {
+				t.Error("ParsePrivateKey() returned another key")
+			}
+		})
+	}
+}
+
+func TestSaveAndReadPrivateKey(t *testing.T) {
+	wantType := map[string]string{"RSA": "RSA PRIVATE KEY", "ECDSA": "PRIVATE KEY", "Ed25519": "PRIVATE KEY"}
+
+	for name, key := range newKeys(t) {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "agent.key")
+			if err := SavePrivateKey(key, path); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if block, _ := pem.Decode(data); block == nil || block.Type != wantType[name] {
+				t.Errorf("key saved as %v, want a %s block", block, wantType[name])
+			}
+
+			read, err := ReadPrivateKey(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !read.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(key.Public()) {
+				t.Error("ReadPrivateKey() returned another key")
+			}
+		})
+	}
+}
+
+func TestReadPrivateKeyNotAKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.key")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadPrivateKey(path); err == nil {
+		t.Error("ReadPrivateKey() accepted a certificate")
+	}
+}
@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/open-uem/openuem-agent/internal/agent/handlers"
+	"github.com/open-uem/openuem-agent/internal/logger"
+)
+
+const DEFAULT_LOG_TAIL_DURATION = 60
+const MAX_LOG_TAIL_DURATION = 600
+const MAX_LOG_TAILS = 5
+
+type LogsResponse struct {
+	Lines     []string `json:"lines"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
+
+// LogTailRequest asks the agent to publish its new log lines to Inbox for Duration seconds
+type LogTailRequest struct {
+	Inbox    string `json:"inbox"`
+	Duration int    `json:"duration,omitempty"`
+	Level    string `json:"level,omitempty"`
+}
+
+type LogTailResponse struct {
+	Inbox string    `json:"inbox"`
+	Until time.Time `json:"until"`
+}
+
+// LogsHandler answers with the last lines of the agent log, an empty payload returns
+// the last DEFAULT_QUERY_LINES lines
+func (a *Agent) LogsHandler(msg *nats.Msg) error {
+	q := logger.Query{}
+	if len(msg.Data) > 0 {
+		if err := json.Unmarshal(msg.Data, &q); err != nil {
+			return fmt.Errorf("could not decode logger.Query payload, reason: %v", err)
+		}
+	}
+	if q.Level != "" {
+		if _, err := logger.ParseLevel(q.Level); err != nil {
+			return err
+		}
+	}
+
+	lines, err := logger.ReadLines(logger.Path(a.Config.LogDir), q)
+	if err != nil {
+		return fmt.Errorf("could not read the agent log, reason: %v", err)
+	}
+
+	data, err := logsResponse(lines, int(a.NATSConnection.MaxPayload()))
+	if err != nil {
+		return err
+	}
+
+	if err := msg.Respond(data); err != nil {
+		return handlers.Responded(err)
+	}
+	return nil
+}
+
+// logsResponse drops the oldest lines until the response fits in a message
+func logsResponse(lines []string, maxPayload int) ([]byte, error) {
+	r := LogsResponse{Lines: lines}
+	for {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return nil, err
+		}
+		if maxPayload <= 0 || len(data) <= maxPayload || len(r.Lines) == 0 {
+			return data, nil
+		}
+
+		excess := len(data) - maxPayload
+		drop := 0
+		for drop < len(r.Lines) && excess > 0 {
+			excess -= len(r.Lines[drop]) + 3
+			drop++
+		}
+		r.Lines = r.Lines[drop:]
+		r.Truncated = true
+	}
+}
+
+// LogTailHandler publishes every new log line to the requested inbox until the duration
+// is over, an empty message tells the subscriber that the tail has ended
+func (a *Agent) LogTailHandler(msg *nats.Msg, data LogTailRequest) error {
+	if data.Inbox == "" {
+		return fmt.Errorf("an inbox is required to send the log lines")
+	}
+	if data.Level != "" {
+		if _, err := logger.ParseLevel(data.Level); err != nil {
+			return err
+		}
+	}
+	if data.Duration <= 0 {
+		data.Duration = DEFAULT_LOG_TAIL_DURATION
+	}
+	data.Duration = min(data.Duration, MAX_LOG_TAIL_DURATION)
+
+	if a.logTails.Add(1) > MAX_LOG_TAILS {
+		a.logTails.Add(-1)
+		return fmt.Errorf("there are already %d log tails running", MAX_LOG_TAILS)
+	}
+
+	nc := a.NATSConnection
+	until := time.Now().Add(time.Duration(data.Duration) * time.Second)
+	lines, stop := logger.Follow()
+
+	response, err := json.Marshal(LogTailResponse{Inbox: data.Inbox, Until: until})
+	if err != nil {
+		stop()
+		a.logTails.Add(-1)
+		return err
+	}
+	if err := msg.Respond(response); err != nil {
+		stop()
+		a.logTails.Add(-1)
+		return handlers.Responded(err)
+	}
+
+	slog.Info("log tail has been started", "inbox", data.Inbox, "until", until)
+
+	go func() {
+		defer a.logTails.Add(-1)
+		defer stop()
+
+		q := logger.Query{Level: data.Level}
+		timer := time.NewTimer(time.Until(until))
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-timer.C:
+				if err := nc.Publish(data.Inbox, nil); err != nil {
+					slog.Error("could not send the end of the log tail", "inbox", data.Inbox, "error", err)
+				}
+				slog.Info("log tail has ended", "inbox", data.Inbox)
+				return
+			case line := <-lines:
+				if !q.Matches(line) {
+					continue
+				}
+				if err := nc.Publish(data.Inbox, []byte(line)); err != nil {
+					slog.Error("log tail has been stopped, could not publish", "inbox", data.Inbox, "error", err)
+					return
+				}
+			}
+		}
+	}()
+
+	return nil
+}
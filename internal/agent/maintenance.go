@@ -0,0 +1,278 @@
+package agent
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/nats-io/nats.go"
+	openuem_nats "github.com/open-uem/nats"
+	"github.com/open-uem/openuem-agent/internal/agent/handlers"
+	"github.com/open-uem/openuem-agent/internal/agent/maintenance"
+	"github.com/open-uem/openuem-agent/internal/agent/signing"
+	"github.com/open-uem/openuem-agent/internal/commands/report"
+)
+
+const MAINTENANCE_CHECK_INTERVAL = time.Minute
+
+// PROFILES_ACTION is queued instead of a message when the scheduled profiles job runs outside the windows
+const PROFILES_ACTION = "profiles"
+
+// NewConfigRequest is the agent.newconfig payload, MaintenanceWindows is left as it was if it's not sent
+type NewConfigRequest struct {
+	openuem_nats.Config
+	MaintenanceWindows *[]maintenance.Window `json:"maintenance_windows,omitempty"`
+}
+
+func (a *Agent) OpenMaintenanceQueue() {
+	var err error
+	a.MaintenanceQueue, err = maintenance.NewQueue(filepath.Join(a.Config.DataDir, "maintenance"))
+	if err != nil {
+		slog.Error("could not open the maintenance queue, actions will run when they're received", "error", err)
+	}
+}
+
+// maintenanceWindows keeps the last valid schedule so a wrong setting doesn't open the windows
+type maintenanceWindows struct {
+	mu       sync.Mutex
+	schedule maintenance.Schedule
+	valid    bool
+}
+
+// maintenanceWindow tells if actions can run now and when the next window opens. If the windows
+// can't be read the last valid ones are used or, if there are none, actions wait until they're fixed
+func (a *Agent) maintenanceWindow(now time.Time) (bool, time.Time) {
+	w := a.maintenanceWindows
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	s, err := maintenance.ParseSchedule(a.Config.MaintenanceWindows)
+	if err == nil {
+		w.schedule, w.valid = s, true
+	} else {
+		slog.Error("could not read the maintenance windows, the last valid ones are used", "error", err)
+		if !w.valid {
+			return false, time.Time{}
+		}
+	}
+	return w.schedule.Open(now), w.schedule.NextOpen(now)
+}
+
+// applyMaintenanceWindows sets the windows sent by the console in c
+func applyMaintenanceWindows(c *Config, windows *[]maintenance.Window) error {
+	if windows == nil {
+		return nil
+	}
+	for _, w := range *windows {
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("could not set the maintenance window %s, reason: %v", w, err)
+		}
+	}
+	c.MaintenanceWindows = maintenance.Schedule(*windows).String()
+	return nil
+}
+
+// urgent tells if the console asked to run the action now. When the command must be signed,
+// it must be a claim of a valid signature so it can't be added to a command that was signed
+// to wait for the windows. Otherwise the urgent header is enough
+func (a *Agent) urgent(h *handlers.Handler, msg *nats.Msg) bool {
+	if signature := msg.Header.Get(signing.HEADER); signature != "" {
+		signer, err := signing.Verify(signature, msg.Subject, msg.Data, a.signatureOptions())
+		if err == nil && signer.Header.Urgent {
+			return true
+		}
+	}
+
+	c := command{Name: h.Name, Subject: msg.Subject, Privileged: h.Audited}
+	if commandPolicy(c, a.Config.SignaturePolicy, a.Config.SignaturePolicies) != signing.POLICY_OFF {
+		return false
+	}
+	urgent, err := strconv.ParseBool(msg.Header.Get(maintenance.URGENT_HEADER))
+	return err == nil && urgent
+}
+
+// deferHandlerCommand queues the message if it's received outside the maintenance windows
+// and replies with the time the next window opens
+func (a *Agent) deferHandlerCommand(h *handlers.Handler, msg *nats.Msg) (bool, error) {
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	msg.Header.Del(maintenance.STATUS_HEADER)
+
+	open, next := a.maintenanceWindow(time.Now())
+	if open {
+		return false, nil
+	}
+	if a.urgent(h, msg) {
+		msg.Header.Set(maintenance.STATUS_HEADER, "run outside the maintenance windows as urgent")
+		return false, nil
+	}
+	if a.MaintenanceQueue == nil {
+		return false, fmt.Errorf("the action can't be deferred to the next maintenance window, the maintenance queue is not open")
+	}
+
+	item, err := a.MaintenanceQueue.Push(maintenance.Item{Handler: h.Name, Subject: msg.Subject, Header: requesterHeaders(msg.Header), Data: msg.Data})
+	if err != nil {
+		return false, fmt.Errorf("could not defer the action to the next maintenance window, reason: %v", err)
+	}
+
+	until := "the maintenance windows are fixed"
+	if !next.IsZero() {
+		until = next.Format(time.RFC3339)
+	}
+	msg.Header.Set(maintenance.STATUS_HEADER, fmt.Sprintf("deferred until %s", until))
+	slog.Info("action has been deferred to the next maintenance window", "handler", h.Name, "id", item.ID, "next_window", until)
+
+	if msg.Reply != "" {
+		resp := nats.NewMsg(msg.Reply)
+		resp.Header.Set(maintenance.DEFERRED_ID_HEADER, item.ID)
+		resp.Header.Set(maintenance.DEFERRED_UNTIL_HEADER, until)
+		if err := msg.RespondMsg(resp); err != nil {
+			slog.Error("could not respond to deferred message", "subject", msg.Subject, "error", err)
+		}
+	}
+	return true, nil
+}
+
+// deferScheduledProfiles queues a single profiles run if the scheduled job starts outside the windows
+func (a *Agent) deferScheduledProfiles() bool {
+	if open, _ := a.maintenanceWindow(time.Now()); open || a.MaintenanceQueue == nil {
+		return false
+	}
+
+	if !a.MaintenanceQueue.Has(PROFILES_ACTION) {
+		if _, err := a.MaintenanceQueue.Push(maintenance.Item{Handler: PROFILES_ACTION}); err != nil {
+			slog.Error("could not defer the profiles to the next maintenance window", "error", err)
+			return false
+		}
+		slog.Info("profiles have been deferred to the next maintenance window")
+	}
+	return true
+}
+
+func (a *Agent) startMaintenanceJob() error {
+	_, err := a.TaskScheduler.NewJob(
+		gocron.DurationJob(MAINTENANCE_CHECK_INTERVAL),
+		gocron.NewTask(a.MaintenanceTask),
+		gocron.WithName("maintenance-queue"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		slog.Error("could not start the maintenance queue job", "error", err)
+		return err
+	}
+	return nil
+}
+
+// MaintenanceTask runs the deferred actions in the order they were received while a window is open,
+// reboots and power offs are left for the end
+func (a *Agent) MaintenanceTask() {
+	if a.MaintenanceQueue == nil || a.NATSConnection == nil || !a.NATSConnection.IsConnected() {
+		return
+	}
+
+	if open, _ := a.maintenanceWindow(time.Now()); !open {
+		return
+	}
+
+	items, err := a.MaintenanceQueue.List()
+	if err != nil {
+		slog.Error("could not read the maintenance queue", "error", err)
+		return
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return !disruptive(items[i].Handler) && disruptive(items[j].Handler)
+	})
+
+	for _, item := range items {
+		if open, _ := a.maintenanceWindow(time.Now()); !open {
+			slog.Info("the maintenance window has closed, the remaining actions will run in the next one")
+			return
+		}
+
+		// The action is removed first so an action that restarts the computer is not run again
+		if err := a.MaintenanceQueue.Remove(item); err != nil {
+			slog.Error("could not remove the action from the maintenance queue", "id", item.ID, "error", err)
+			continue
+		}
+		a.runDeferredAction(item)
+	}
+}
+
+func disruptive(handler string) bool {
+	return handler == "agent.reboot" || handler == "agent.poweroff"
+}
+
+func (a *Agent) runDeferredAction(item maintenance.Item) {
+	slog.Info("running action deferred to the maintenance window", "handler", item.Handler, "id", item.ID, "queued_at", item.QueuedAt)
+
+	if item.Handler == PROFILES_ACTION {
+		a.runScheduledProfiles()
+		return
+	}
+
+	h, ok := a.Handlers.Lookup(item.Handler)
+	if !ok {
+		slog.Error("the deferred action has no handler", "handler", item.Handler, "id", item.ID)
+		return
+	}
+
+	// The message was verified when it was received, its nonce can't be checked again.
+	// Responses go to an inbox of the agent as the requester is no longer waiting
+	msg := &nats.Msg{Subject: item.Subject, Header: nats.Header(item.Header), Data: item.Data, Reply: nats.NewInbox()}
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	msg.Header.Set(maintenance.STATUS_HEADER, fmt.Sprintf("run from the maintenance queue, deferred at %s", item.QueuedAt.Format(time.RFC3339)))
+
+	sub, err := a.NATSConnection.SubscribeSync(msg.Reply)
+	if err != nil {
+		slog.Error("could not subscribe to the inbox for the deferred action", "handler", item.Handler, "error", err)
+	} else {
+		msg.Sub = sub
+		defer func() {
+			if err := sub.Unsubscribe(); err != nil {
+				slog.Error("could not unsubscribe from the inbox for the deferred action", "error", err)
+			}
+		}()
+	}
+
+	start := time.Now()
+	err = handlers.Recover(h, h.Handle)(msg)
+	if err != nil {
+		slog.Error("deferred action could not be run", "handler", item.Handler, "id", item.ID, "error", err)
+	}
+	if h.Audited {
+		a.auditCommand(command{Name: h.Name, Subject: msg.Subject, Header: msg.Header, Data: msg.Data}, start, err)
+	}
+}
+
+// MaintenanceStatus reads the maintenance windows of c and the actions waiting in queue, if it's open
+func MaintenanceStatus(c Config, queue *maintenance.Queue) *report.Maintenance {
+	s, err := maintenance.ParseSchedule(c.MaintenanceWindows)
+	if err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	m := report.Maintenance{Windows: s.String(), Open: s.Open(now)}
+	if !m.Open {
+		m.NextWindow = s.NextOpen(now)
+	}
+
+	if queue != nil {
+		items, err := queue.List()
+		if err != nil {
+			slog.Error("could not read the maintenance queue", "error", err)
+		}
+		for _, item := range items {
+			m.Deferred = append(m.Deferred, report.DeferredAction{ID: item.ID, Action: item.Handler, Subject: item.Subject, QueuedAt: item.QueuedAt})
+		}
+	}
+	return &m
+}
@@ -0,0 +1,131 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/google/uuid"
+)
+
+// STATUS_HEADER is set by the agent on the received message when the action is deferred
+const STATUS_HEADER = "Openuem-Maintenance-Status"
+
+// URGENT_HEADER asks to run the action outside the maintenance windows. It's only read when
+// the command doesn't require a signature, signed commands use the urgent claim instead
+const URGENT_HEADER = "Openuem-Urgent"
+
+// Headers of the response sent to requests whose action has been deferred
+const (
+	DEFERRED_ID_HEADER    = "Openuem-Deferred-Id"
+	DEFERRED_UNTIL_HEADER = "Openuem-Deferred-Until"
+)
+
+const DEFERRED_PREFIX = "deferred/"
+
+// Item is an action waiting for the next maintenance window, Handler is the name
+// of the handler that runs it with the original message
+type Item struct {
+	ID       string              `json:"id"`
+	Handler  string              `json:"handler"`
+	Subject  string              `json:"subject,omitempty"`
+	Header   map[string][]string `json:"header,omitempty"`
+	Data     []byte              `json:"data,omitempty"`
+	QueuedAt time.Time           `json:"queued_at"`
+}
+
+func (i Item) key() []byte {
+	return []byte(fmt.Sprintf("%s%s/%s", DEFERRED_PREFIX, i.QueuedAt.UTC().Format("20060102T150405.000000000"), i.ID))
+}
+
+// Queue keeps the deferred actions in the order they were received
+type Queue struct {
+	mu sync.Mutex
+	DB *badger.DB
+}
+
+func NewQueue(path string) (*Queue, error) {
+	opts := badger.DefaultOptions(path)
+	opts.Logger = nil
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Queue{DB: db}, nil
+}
+
+func (q *Queue) Close() error {
+	return q.DB.Close()
+}
+
+func (q *Queue) Push(item Item) (Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if item.ID == "" {
+		item.ID = uuid.New().String()
+	}
+	if item.QueuedAt.IsZero() {
+		item.QueuedAt = time.Now()
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return item, err
+	}
+
+	return item, q.DB.Update(func(txn *badger.Txn) error {
+		return txn.Set(item.key(), data)
+	})
+}
+
+// List returns the deferred actions, oldest first
+func (q *Queue) List() ([]Item, error) {
+	items := []Item{}
+
+	err := q.DB.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(DEFERRED_PREFIX)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := Item{}
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &item)
+			}); err != nil {
+				return err
+			}
+			items = append(items, item)
+		}
+		return nil
+	})
+	return items, err
+}
+
+// Has tells if there's already an action for the handler
+func (q *Queue) Has(handler string) bool {
+	items, err := q.List()
+	if err != nil {
+		return false
+	}
+	for _, i := range items {
+		if i.Handler == handler {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *Queue) Remove(item Item) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.DB.Update(func(txn *badger.Txn) error {
+		return txn.Delete(item.key())
+	})
+}
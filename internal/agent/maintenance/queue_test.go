@@ -0,0 +1,57 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueue(t *testing.T) {
+	q, err := NewQueue(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	now := time.Now()
+	reboot, err := q.Push(Item{Handler: "agent.reboot", QueuedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reboot.ID == "" {
+		t.Fatal("the item has no ID")
+	}
+	if _, err := q.Push(Item{Handler: "agent.installpackage", Data: []byte(`{"package_id":"7zip"}`), QueuedAt: now.Add(time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Push(Item{Handler: "profiles", QueuedAt: now.Add(-time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := q.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"profiles", "agent.reboot", "agent.installpackage"}
+	if len(items) != len(want) {
+		t.Fatalf("List() = %d items, want %d", len(items), len(want))
+	}
+	for i, item := range items {
+		if item.Handler != want[i] {
+			t.Errorf("List()[%d] = %s, want %s", i, item.Handler, want[i])
+		}
+	}
+	if string(items[2].Data) != `{"package_id":"7zip"}` {
+		t.Errorf("the data of the item is %q", items[2].Data)
+	}
+
+	if !q.Has("profiles") || q.Has("agent.poweroff") {
+		t.Error("Has() doesn't match the queued handlers")
+	}
+
+	if err := q.Remove(reboot); err != nil {
+		t.Fatal(err)
+	}
+	if q.Has("agent.reboot") {
+		t.Error("the removed item is still queued")
+	}
+}
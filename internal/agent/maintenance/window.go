@@ -0,0 +1,220 @@
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	// Windows has no timezone database so it's embedded in the agent
+	_ "time/tzdata"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Window is a range of time repeated every week on Days. Start and End are HH:MM in
+// Timezone, or in the local time if it's empty. If End is earlier than Start the window
+// ends the next day and End can be 24:00 to include the whole day
+type Window struct {
+	Days     []string `json:"days"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Timezone string   `json:"timezone,omitempty"`
+}
+
+func (w Window) Validate() error {
+	_, err := w.parse()
+	return err
+}
+
+// String returns the window as written in the config file, e.g. mon,tue 22:00-06:00 Europe/Madrid
+func (w Window) String() string {
+	s := fmt.Sprintf("%s %s-%s", strings.ReplaceAll(strings.Join(w.Days, ","), " ", ""), w.Start, w.End)
+	if w.Timezone != "" {
+		s += " " + w.Timezone
+	}
+	return s
+}
+
+type parsedWindow struct {
+	days     map[time.Weekday]bool
+	start    int
+	end      int
+	location *time.Location
+}
+
+func (w Window) parse() (parsedWindow, error) {
+	p := parsedWindow{days: map[time.Weekday]bool{}, location: time.Local}
+
+	if len(w.Days) == 0 {
+		return p, errors.New("the window has no days")
+	}
+	for _, d := range w.Days {
+		if err := addDays(p.days, d); err != nil {
+			return p, err
+		}
+	}
+
+	var err error
+	if p.start, err = minutes(w.Start); err != nil {
+		return p, err
+	}
+	if p.end, err = minutes(w.End); err != nil {
+		return p, err
+	}
+	if p.start == p.end || p.start == 24*60 {
+		return p, fmt.Errorf("%s-%s is not a valid time range", w.Start, w.End)
+	}
+
+	if w.Timezone != "" {
+		if p.location, err = time.LoadLocation(w.Timezone); err != nil {
+			return p, fmt.Errorf("%q is not a valid timezone", w.Timezone)
+		}
+	}
+	return p, nil
+}
+
+// addDays reads a day name, e.g. mon or monday, or a range of days, e.g. mon-fri
+func addDays(days map[time.Weekday]bool, value string) error {
+	day := func(name string) (time.Weekday, error) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if len(name) >= 3 {
+			if d, ok := weekdays[name[:3]]; ok && strings.HasPrefix(strings.ToLower(d.String()), name) {
+				return d, nil
+			}
+		}
+		return 0, fmt.Errorf("%q is not a day of the week", name)
+	}
+
+	from, to, isRange := strings.Cut(value, "-")
+	first, err := day(from)
+	if err != nil {
+		return err
+	}
+	if !isRange {
+		days[first] = true
+		return nil
+	}
+
+	last, err := day(to)
+	if err != nil {
+		return err
+	}
+	for d := first; ; d = (d + 1) % 7 {
+		days[d] = true
+		if d == last {
+			return nil
+		}
+	}
+}
+
+func minutes(value string) (int, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(value, "%d:%d", &h, &m); err != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("%q is not a valid time, use HH:MM", value)
+	}
+	return h*60 + m, nil
+}
+
+func (p parsedWindow) contains(t time.Time) bool {
+	t = t.In(p.location)
+	now := t.Hour()*60 + t.Minute()
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+
+	if p.start < p.end {
+		return p.days[today] && now >= p.start && now < p.end
+	}
+	return (p.days[today] && now >= p.start) || (p.days[yesterday] && now < p.end)
+}
+
+// nextStart returns when the window opens next after t
+func (p parsedWindow) nextStart(t time.Time) time.Time {
+	t = t.In(p.location)
+	for i := 0; i <= 7; i++ {
+		day := t.AddDate(0, 0, i)
+		if !p.days[day.Weekday()] {
+			continue
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day(), p.start/60, p.start%60, 0, 0, p.location)
+		if start.After(t) {
+			return start
+		}
+	}
+	return time.Time{}
+}
+
+// Schedule is the list of maintenance windows, without windows it's always open
+type Schedule []Window
+
+// ParseSchedule reads the windows written in the config file separated by semicolons,
+// e.g. mon-fri 22:00-06:00 Europe/Madrid; sat,sun 00:00-24:00
+func ParseSchedule(value string) (Schedule, error) {
+	s := Schedule{}
+	for _, part := range strings.Split(value, ";") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("%q must be days, a time range and an optional timezone", strings.TrimSpace(part))
+		}
+
+		start, end, found := strings.Cut(fields[1], "-")
+		if !found {
+			return nil, fmt.Errorf("%q is not a valid time range, use HH:MM-HH:MM", fields[1])
+		}
+		w := Window{Days: strings.Split(fields[0], ","), Start: start, End: end}
+		if len(fields) == 3 {
+			w.Timezone = fields[2]
+		}
+		if err := w.Validate(); err != nil {
+			return nil, err
+		}
+		s = append(s, w)
+	}
+	return s, nil
+}
+
+func (s Schedule) String() string {
+	windows := []string{}
+	for _, w := range s {
+		windows = append(windows, w.String())
+	}
+	return strings.Join(windows, "; ")
+}
+
+// Open tells if actions can run at t
+func (s Schedule) Open(t time.Time) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, w := range s {
+		if p, err := w.parse(); err == nil && p.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns t if a window is open or when the next one opens
+func (s Schedule) NextOpen(t time.Time) time.Time {
+	if s.Open(t) {
+		return t
+	}
+
+	next := time.Time{}
+	for _, w := range s {
+		p, err := w.parse()
+		if err != nil {
+			continue
+		}
+		if start := p.nextStart(t); !start.IsZero() && (next.IsZero() || start.Before(next)) {
+			next = start
+		}
+	}
+	return next
+}
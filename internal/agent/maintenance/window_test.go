@@ -0,0 +1,123 @@
+package maintenance
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	l, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		value   string
+		windows int
+		err     string
+	}{
+		{value: "", windows: 0},
+		{value: "mon-fri 22:00-06:00 Europe/Madrid", windows: 1},
+		{value: "mon-fri 22:00-06:00 Europe/Madrid; sat,sunday 00:00-24:00 UTC", windows: 2},
+		{value: "fri-mon 01:00-02:00;", windows: 1},
+		{value: "foo 10:00-11:00", err: "not a day of the week"},
+		{value: "mo 10:00-11:00", err: "not a day of the week"},
+		{value: "mon 25:00-26:00", err: "not a valid time"},
+		{value: "mon 10:60-11:00", err: "not a valid time"},
+		{value: "mon 10:00", err: "not a valid time range"},
+		{value: "mon 10:00-10:00", err: "not a valid time range"},
+		{value: "mon 24:00-01:00", err: "not a valid time range"},
+		{value: "mon 10:00-11:00 Mars/Base", err: "not a valid timezone"},
+		{value: "mon", err: "must be days, a time range and an optional timezone"},
+	}
+
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.value)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseSchedule(%q) error = %v, want %q", tt.value, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) unexpected error: %v", tt.value, err)
+			continue
+		}
+		if len(s) != tt.windows {
+			t.Errorf("ParseSchedule(%q) = %d windows, want %d", tt.value, len(s), tt.windows)
+		}
+
+		// The schedule is written back to the config file
+		again, err := ParseSchedule(s.String())
+		if err != nil || again.String() != s.String() {
+			t.Errorf("ParseSchedule(%q) can't be read back from %q: %v", tt.value, s.String(), err)
+		}
+	}
+}
+
+func TestScheduleOpen(t *testing.T) {
+	madrid := mustLocation(t, "Europe/Madrid")
+	s, err := ParseSchedule("mon-fri 22:00-06:00 Europe/Madrid; sat,sun 00:00-24:00 UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2026-10-12 is a Monday
+	tests := []struct {
+		name string
+		t    time.Time
+		open bool
+		next time.Time
+	}{
+		{name: "wednesday night", t: time.Date(2026, 10, 14, 23, 0, 0, 0, madrid), open: true},
+		{name: "past midnight", t: time.Date(2026, 10, 15, 5, 59, 0, 0, madrid), open: true},
+		{name: "end of the window", t: time.Date(2026, 10, 15, 6, 0, 0, 0, madrid), next: time.Date(2026, 10, 15, 22, 0, 0, 0, madrid)},
+		{name: "thursday noon", t: time.Date(2026, 10, 15, 12, 0, 0, 0, madrid), next: time.Date(2026, 10, 15, 22, 0, 0, 0, madrid)},
+		{name: "friday night runs into saturday", t: time.Date(2026, 10, 17, 1, 30, 0, 0, madrid), open: true},
+		{name: "whole saturday", t: time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC), open: true},
+		{name: "last minute of sunday", t: time.Date(2026, 10, 18, 23, 59, 0, 0, time.UTC), open: true},
+		{name: "monday after the weekend", t: time.Date(2026, 10, 12, 3, 0, 0, 0, madrid), next: time.Date(2026, 10, 12, 22, 0, 0, 0, madrid)},
+		{name: "other timezone", t: time.Date(2026, 10, 14, 21, 30, 0, 0, time.UTC), open: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Open(tt.t); got != tt.open {
+				t.Fatalf("Open(%v) = %v, want %v", tt.t, got, tt.open)
+			}
+
+			want := tt.next
+			if tt.open {
+				want = tt.t
+			}
+			if got := s.NextOpen(tt.t); !got.Equal(want) {
+				t.Errorf("NextOpen(%v) = %v, want %v", tt.t, got, want)
+			}
+		})
+	}
+}
+
+func TestNextOpenNextWeek(t *testing.T) {
+	s, err := ParseSchedule("wed 10:00-11:00 UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after := time.Date(2026, 10, 14, 11, 0, 0, 0, time.UTC)
+	want := time.Date(2026, 10, 21, 10, 0, 0, 0, time.UTC)
+	if got := s.NextOpen(after); !got.Equal(want) {
+		t.Errorf("NextOpen(%v) = %v, want %v", after, got, want)
+	}
+}
+
+func TestEmptyScheduleIsOpen(t *testing.T) {
+	if !(Schedule{}).Open(time.Now()) {
+		t.Error("a schedule without windows must be open")
+	}
+}
@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/open-uem/openuem-agent/internal/agent/handlers"
+	"github.com/open-uem/openuem-agent/internal/agent/maintenance"
+	"github.com/open-uem/openuem-agent/internal/agent/signing"
+)
+
+func TestUrgent(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    string
+		overrides string
+		header    string
+		urgent    bool
+	}{
+		{name: "signatures off with urgent header", policy: signing.POLICY_OFF, header: "true", urgent: true},
+		{name: "signatures off without header", policy: signing.POLICY_OFF},
+		{name: "signatures off with wrong header", policy: signing.POLICY_OFF, header: "now"},
+		{name: "header ignored when signatures are enforced", policy: signing.POLICY_ENFORCE, header: "true"},
+		{name: "header ignored when signatures are checked", policy: signing.POLICY_WARN, header: "true"},
+		{name: "signatures off for the command", policy: signing.POLICY_ENFORCE, overrides: "agent.reboot=off", header: "true", urgent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Agent{Config: Config{SignaturePolicy: tt.policy, SignaturePolicies: tt.overrides}}
+			h := &handlers.Handler{Name: "agent.reboot", Audited: true}
+
+			msg := nats.NewMsg("agent.reboot.2b3c9a4e-5f6d-4e7a-8b9c-0d1e2f3a4b5c")
+			if tt.header != "" {
+				msg.Header.Set(maintenance.URGENT_HEADER, tt.header)
+			}
+
+			if got := a.urgent(h, msg); got != tt.urgent {
+				t.Errorf("urgent() = %v, want %v", got, tt.urgent)
+			}
+		})
+	}
+}
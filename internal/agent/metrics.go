@@ -0,0 +1,23 @@
+package agent
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/open-uem/openuem-agent/internal/metrics"
+)
+
+func (a *Agent) StartMetricsServer() {
+	var err error
+
+	if a.Config.MetricsListenAddress == "" {
+		return
+	}
+
+	a.MetricsServer, err = metrics.Serve(a.Config.MetricsListenAddress)
+	if err != nil {
+		slog.Error("could not start the metrics server", "error", err)
+		return
+	}
+	slog.Info(fmt.Sprintf("metrics server is listening on %s", a.Config.MetricsListenAddress))
+}
@@ -0,0 +1,262 @@
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	openuem_nats "github.com/open-uem/nats"
+)
+
+const DEFAULT_MAX_ATTEMPTS = 20
+const DEFAULT_MAX_AGE_HOURS = 168
+
+const BASE_BACKOFF = 30 * time.Second
+const MAX_BACKOFF = 6 * time.Hour
+
+const PENDING_PREFIX = "pending/"
+const DEAD_PREFIX = "dead/"
+
+var ErrNotFound = errors.New("the entry was not found in the dead letters")
+
+// Entry is a deployment result waiting to be acknowledged by the worker
+type Entry struct {
+	ID          string                    `json:"id"`
+	Action      openuem_nats.DeployAction `json:"action"`
+	Attempts    int                       `json:"attempts"`
+	CreatedAt   time.Time                 `json:"created_at"`
+	NextAttempt time.Time                 `json:"next_attempt"`
+	LastError   string                    `json:"last_error,omitempty"`
+}
+
+// Outbox stores deployment results before they're sent so none is lost if the
+// worker can't be reached. Results that can't be delivered after MaxAttempts or
+// MaxAge are moved to the dead letters where they can be retried manually
+type Outbox struct {
+	mu          sync.Mutex
+	sending     map[string]bool
+	seq         atomic.Uint64
+	DB          *badger.DB
+	MaxAttempts int
+	MaxAge      time.Duration
+}
+
+func New(path string, maxAttempts int, maxAgeHours int) (*Outbox, error) {
+	if maxAttempts <= 0 {
+		maxAttempts = DEFAULT_MAX_ATTEMPTS
+	}
+
+	if maxAgeHours <= 0 {
+		maxAgeHours = DEFAULT_MAX_AGE_HOURS
+	}
+
+	opts := badger.DefaultOptions(path)
+	opts.Logger = nil
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Outbox{
+		sending:     map[string]bool{},
+		DB:          db,
+		MaxAttempts: maxAttempts,
+		MaxAge:      time.Duration(maxAgeHours) * time.Hour,
+	}, nil
+}
+
+func (o *Outbox) Close() error {
+	return o.DB.Close()
+}
+
+// Add stores a deployment result so it's ready to be sent right away
+func (o *Outbox) Add(action openuem_nats.DeployAction) error {
+	now := time.Now()
+	e := Entry{
+		ID:          fmt.Sprintf("%020d-%06d", now.UnixNano(), o.seq.Add(1)%1000000),
+		Action:      action,
+		CreatedAt:   now,
+		NextAttempt: now,
+	}
+
+	return o.DB.Update(func(txn *badger.Txn) error {
+		return put(txn, PENDING_PREFIX, e)
+	})
+}
+
+// Flush sends the pending results whose backoff has expired, from the oldest to the newest.
+// The results are sent without holding the lock, a result being sent by another flush is skipped
+func (o *Outbox) Flush(send func(action *openuem_nats.DeployAction) error) (int, error) {
+	entries, err := o.due()
+	if err != nil {
+		return 0, err
+	}
+	defer o.release(entries)
+
+	sent := 0
+	var errs []error
+	for _, e := range entries {
+		sendErr := send(&e.Action)
+
+		o.mu.Lock()
+		err := o.DB.Update(func(txn *badger.Txn) error {
+			if err := txn.Delete([]byte(PENDING_PREFIX + e.ID)); err != nil {
+				return err
+			}
+
+			if sendErr == nil {
+				return nil
+			}
+
+			e.Attempts++
+			e.LastError = sendErr.Error()
+			if e.Attempts >= o.MaxAttempts || time.Since(e.CreatedAt) > o.MaxAge {
+				return put(txn, DEAD_PREFIX, e)
+			}
+			e.NextAttempt = time.Now().Add(Backoff(e.Attempts))
+			return put(txn, PENDING_PREFIX, e)
+		})
+		o.mu.Unlock()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if sendErr != nil {
+			errs = append(errs, sendErr)
+			continue
+		}
+		sent++
+	}
+
+	return sent, errors.Join(errs...)
+}
+
+// due takes the pending results whose backoff has expired and that no other flush is sending
+func (o *Outbox) due() ([]Entry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entries, err := o.list(PENDING_PREFIX)
+	if err != nil {
+		return nil, err
+	}
+
+	due := []Entry{}
+	for _, e := range entries {
+		if time.Now().Before(e.NextAttempt) || o.sending[e.ID] {
+			continue
+		}
+		o.sending[e.ID] = true
+		due = append(due, e)
+	}
+	return due, nil
+}
+
+func (o *Outbox) release(entries []Entry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, e := range entries {
+		delete(o.sending, e.ID)
+	}
+}
+
+// Backoff doubles the wait after each attempt, with full jitter so agents don't retry at once
+func Backoff(attempts int) time.Duration {
+	wait := MAX_BACKOFF
+	if attempts < 20 {
+		wait = min(BASE_BACKOFF<<(attempts-1), MAX_BACKOFF)
+	}
+	return time.Duration(rand.Int64N(int64(wait))) + time.Second
+}
+
+func (o *Outbox) Pending() ([]Entry, error) {
+	return o.list(PENDING_PREFIX)
+}
+
+func (o *Outbox) DeadLetters() ([]Entry, error) {
+	return o.list(DEAD_PREFIX)
+}
+
+// Retry moves a dead letter back to the pending results with its attempts reset.
+// An empty id retries all the dead letters
+func (o *Outbox) Retry(id string) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entries, err := o.list(DEAD_PREFIX)
+	if err != nil {
+		return 0, err
+	}
+
+	retried := 0
+	err = o.DB.Update(func(txn *badger.Txn) error {
+		for _, e := range entries {
+			if id != "" && e.ID != id {
+				continue
+			}
+
+			if err := txn.Delete([]byte(DEAD_PREFIX + e.ID)); err != nil {
+				return err
+			}
+
+			e.Attempts = 0
+			e.CreatedAt = time.Now()
+			e.NextAttempt = time.Now()
+			if err := put(txn, PENDING_PREFIX, e); err != nil {
+				return err
+			}
+			retried++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if id != "" && retried == 0 {
+		return 0, ErrNotFound
+	}
+
+	return retried, nil
+}
+
+func (o *Outbox) list(prefix string) ([]Entry, error) {
+	entries := []Entry{}
+
+	err := o.DB.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(prefix)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			e := Entry{}
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &e)
+			}); err != nil {
+				return fmt.Errorf("could not decode outbox entry %s, reason: %v", strings.TrimPrefix(string(it.Item().Key()), prefix), err)
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+
+	return entries, err
+}
+
+func put(txn *badger.Txn, prefix string, e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return txn.Set([]byte(prefix+e.ID), data)
+}
@@ -0,0 +1,202 @@
+package outbox
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	openuem_nats "github.com/open-uem/nats"
+)
+
+func newOutbox(t *testing.T, maxAttempts int) *Outbox {
+	t.Helper()
+
+	o, err := New(t.TempDir(), maxAttempts, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { o.Close() })
+	return o
+}
+
+func TestFlush(t *testing.T) {
+	errUnreachable := errors.New("worker is not reachable")
+
+	tests := []struct {
+		name        string
+		maxAttempts int
+		flushes     int
+		sendErr     error
+		sent        int
+		pending     int
+		dead        int
+	}{
+		{name: "sent", maxAttempts: 3, flushes: 1, sent: 2},
+		{name: "kept for later", maxAttempts: 3, flushes: 1, sendErr: errUnreachable, pending: 2},
+		{name: "backoff not expired", maxAttempts: 3, flushes: 2, sendErr: errUnreachable, pending: 2},
+		{name: "dead letter", maxAttempts: 1, flushes: 1, sendErr: errUnreachable, dead: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newOutbox(t, tt.maxAttempts)
+			for _, id := range []string{"7zip", "firefox"} {
+				if err := o.Add(openuem_nats.DeployAction{PackageId: id}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			calls, sent := 0, 0
+			for i := 0; i < tt.flushes; i++ {
+				n, err := o.Flush(func(action *openuem_nats.DeployAction) error {
+					calls++
+					return tt.sendErr
+				})
+				if (err != nil) != (tt.sendErr != nil && i == 0) {
+					t.Fatalf("Flush() error = %v", err)
+				}
+				sent += n
+			}
+
+			// Entries are retried after their backoff, never on the next flush
+			if calls != 2 {
+				t.Errorf("send was called %d times, want 2", calls)
+			}
+			if sent != tt.sent {
+				t.Errorf("Flush() sent %d results, want %d", sent, tt.sent)
+			}
+			pending, _ := o.Pending()
+			if len(pending) != tt.pending {
+				t.Errorf("%d pending results, want %d", len(pending), tt.pending)
+			}
+			dead, _ := o.DeadLetters()
+			if len(dead) != tt.dead {
+				t.Errorf("%d dead letters, want %d", len(dead), tt.dead)
+			}
+			for _, e := range append(pending, dead...) {
+				if e.Attempts != 1 || e.LastError != errUnreachable.Error() {
+					t.Errorf("entry %s has %d attempts and error %q", e.ID, e.Attempts, e.LastError)
+				}
+			}
+		})
+	}
+}
+
+func TestFlushOrder(t *testing.T) {
+	o := newOutbox(t, 0)
+	want := []string{"7zip", "firefox", "vlc"}
+	for _, id := range want {
+		if err := o.Add(openuem_nats.DeployAction{PackageId: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := []string{}
+	if _, err := o.Flush(func(action *openuem_nats.DeployAction) error {
+		got = append(got, action.PackageId)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for i := range want {
+		if i >= len(got) || got[i] != want[i] {
+			t.Fatalf("results sent in order %v, want %v", got, want)
+		}
+	}
+}
+
+func TestFlushConcurrent(t *testing.T) {
+	o := newOutbox(t, 0)
+	if err := o.Add(openuem_nats.DeployAction{PackageId: "7zip"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first flush is sending while the second one runs, it must neither wait nor send it again
+	sending, done := make(chan struct{}), make(chan struct{})
+	calls := atomic.Int32{}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		o.Flush(func(action *openuem_nats.DeployAction) error {
+			calls.Add(1)
+			close(sending)
+			<-done
+			return nil
+		})
+	}()
+
+	<-sending
+	sent, err := o.Flush(func(action *openuem_nats.DeployAction) error {
+		calls.Add(1)
+		return nil
+	})
+	close(done)
+	wg.Wait()
+
+	if err != nil || sent != 0 {
+		t.Errorf("second Flush() = %d, %v, want nothing sent", sent, err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("result was sent %d times, want 1", calls.Load())
+	}
+	if pending, _ := o.Pending(); len(pending) != 0 {
+		t.Errorf("%d pending results after sending", len(pending))
+	}
+}
+
+func TestRetry(t *testing.T) {
+	o := newOutbox(t, 1)
+	for _, id := range []string{"7zip", "firefox"} {
+		if err := o.Add(openuem_nats.DeployAction{PackageId: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	o.Flush(func(action *openuem_nats.DeployAction) error { return errors.New("worker is not reachable") })
+
+	dead, _ := o.DeadLetters()
+	if len(dead) != 2 {
+		t.Fatalf("%d dead letters, want 2", len(dead))
+	}
+
+	if _, err := o.Retry("unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Retry(unknown) error = %v, want ErrNotFound", err)
+	}
+
+	if n, err := o.Retry(dead[0].ID); err != nil || n != 1 {
+		t.Fatalf("Retry(%s) = %d, %v", dead[0].ID, n, err)
+	}
+	pending, _ := o.Pending()
+	if len(pending) != 1 || pending[0].Attempts != 0 || time.Now().Before(pending[0].NextAttempt) {
+		t.Fatalf("retried entry is %+v, want it due with no attempts", pending)
+	}
+
+	if n, err := o.Retry(""); err != nil || n != 1 {
+		t.Fatalf("Retry() = %d, %v, want the other dead letter", n, err)
+	}
+	if dead, _ := o.DeadLetters(); len(dead) != 0 {
+		t.Errorf("%d dead letters after retrying all", len(dead))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		max      time.Duration
+	}{
+		{attempts: 1, max: BASE_BACKOFF},
+		{attempts: 3, max: 4 * BASE_BACKOFF},
+		{attempts: 15, max: MAX_BACKOFF},
+		{attempts: 100, max: MAX_BACKOFF},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			if got := Backoff(tt.attempts); got < time.Second || got > tt.max+time.Second {
+				t.Fatalf("Backoff(%d) = %s, want between 1s and %s", tt.attempts, got, tt.max+time.Second)
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package payload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	ENCODING_NONE = "none"
+	ENCODING_GZIP = "gzip"
+	ENCODING_ZSTD = "zstd"
+)
+
+// Headers used to tell workers how the payload has been encoded. Messages
+// without the encoding header are plain JSON
+const (
+	ENCODING_HEADER          = "Openuem-Encoding"
+	ENCODING_REJECTED_HEADER = "Openuem-Encoding-Rejected"
+	CHUNK_ID_HEADER          = "Openuem-Chunk-Id"
+	CHUNK_INDEX_HEADER       = "Openuem-Chunk-Index"
+	CHUNK_TOTAL_HEADER       = "Openuem-Chunk-Total"
+)
+
+// Payloads smaller than this are not worth compressing
+const MIN_COMPRESS_SIZE = 1024
+
+// Room left in every message for the headers when payloads are chunked
+const HEADERS_OVERHEAD = 1024
+
+// Conn is the part of a NATS connection used to send payloads
+type Conn interface {
+	HeadersSupported() bool
+	MaxPayload() int64
+	Request(subject string, data []byte, timeout time.Duration) (*nats.Msg, error)
+	RequestMsg(msg *nats.Msg, timeout time.Duration) (*nats.Msg, error)
+}
+
+type Sender struct {
+	mu       sync.Mutex
+	Encoding string
+}
+
+func NewSender(encoding string) *Sender {
+	return &Sender{Encoding: encoding}
+}
+
+func IsValidEncoding(encoding string) bool {
+	return encoding == ENCODING_NONE || encoding == ENCODING_GZIP || encoding == ENCODING_ZSTD
+}
+
+// Request compresses the payload with the negotiated encoding and splits it in
+// chunks if it's still bigger than the server's max payload. If the worker
+// rejects the encoding, the payload is sent again uncompressed and the
+// encoding is not used anymore
+func (s *Sender) Request(nc *nats.Conn, subject string, data []byte, timeout time.Duration) (*nats.Msg, error) {
+	if nc == nil {
+		return nil, fmt.Errorf("NATS connection is not ready")
+	}
+	return s.request(nc, subject, data, timeout)
+}
+
+func (s *Sender) request(nc Conn, subject string, data []byte, timeout time.Duration) (*nats.Msg, error) {
+	if !nc.HeadersSupported() {
+		return nc.Request(subject, data, timeout)
+	}
+
+	encoding := Encoding(data, s.encoding())
+
+	response, err := request(nc, subject, data, encoding, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if encoding != ENCODING_NONE && response.Header.Get(ENCODING_REJECTED_HEADER) != "" {
+		slog.Warn(fmt.Sprintf("worker has rejected %s encoding, payloads won't be compressed", encoding))
+		s.mu.Lock()
+		s.Encoding = ENCODING_NONE
+		s.mu.Unlock()
+		return request(nc, subject, data, ENCODING_NONE, timeout)
+	}
+
+	return response, nil
+}
+
+// Encoding returns the encoding used to send data when encoding has been negotiated
+func Encoding(data []byte, encoding string) string {
+	if len(data) < MIN_COMPRESS_SIZE {
+		return ENCODING_NONE
+	}
+	return encoding
+}
+
+func (s *Sender) encoding() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Encoding == "" {
+		return ENCODING_NONE
+	}
+	return s.Encoding
+}
+
+// request sends the payload in one message or in chunks. Every chunk is a request of
+// its own, so if one of them fails the worker is left with a partial set. The worker
+// must discard the chunk sets that are not complete after a while, and as the chunk id
+// is derived from the payload, a spooled report sent again reuses it and replaces the
+// chunks received before
+func request(nc Conn, subject string, data []byte, encoding string, timeout time.Duration) (*nats.Msg, error) {
+	body, err := Compress(data, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := chunks(subject, body, encoding, int(nc.MaxPayload())-HEADERS_OVERHEAD)
+
+	var response *nats.Msg
+	for i, msg := range msgs {
+		response, err = nc.RequestMsg(msg, timeout)
+		if err != nil {
+			if len(msgs) == 1 {
+				return nil, err
+			}
+			return nil, fmt.Errorf("could not send chunk %d of %d, reason: %v", i+1, len(msgs), err)
+		}
+	}
+
+	// The response to the last chunk is the response to the whole payload
+	return response, nil
+}
+
+// chunks splits the body in messages that are not bigger than chunkSize
+func chunks(subject string, body []byte, encoding string, chunkSize int) []*nats.Msg {
+	if chunkSize <= 0 || len(body) <= chunkSize {
+		return []*nats.Msg{newMsg(subject, body, encoding)}
+	}
+
+	id := uuid.NewSHA1(uuid.NameSpaceOID, body).String()
+	total := (len(body) + chunkSize - 1) / chunkSize
+
+	msgs := []*nats.Msg{}
+	for i := 0; i < total; i++ {
+		end := min((i+1)*chunkSize, len(body))
+
+		msg := newMsg(subject, body[i*chunkSize:end], encoding)
+		msg.Header.Set(CHUNK_ID_HEADER, id)
+		msg.Header.Set(CHUNK_INDEX_HEADER, strconv.Itoa(i))
+		msg.Header.Set(CHUNK_TOTAL_HEADER, strconv.Itoa(total))
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func newMsg(subject string, data []byte, encoding string) *nats.Msg {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	if encoding != ENCODING_NONE {
+		msg.Header.Set(ENCODING_HEADER, encoding)
+	}
+	return msg
+}
+
+func Compress(data []byte, encoding string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch encoding {
+	case ENCODING_GZIP:
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case ENCODING_ZSTD:
+		w, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return data, nil
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,219 @@
+package payload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/nats-io/nats.go"
+)
+
+func decompress(t *testing.T, data []byte, encoding string) []byte {
+	t.Helper()
+
+	var r io.Reader
+	switch encoding {
+	case ENCODING_GZIP:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	case ENCODING_ZSTD:
+		zr, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return data
+	}
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestCompress(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"section":"software","name":"7zip"}`), 100)
+
+	tests := []struct {
+		encoding string
+		smaller  bool
+	}{
+		{encoding: ENCODING_NONE},
+		{encoding: ENCODING_GZIP, smaller: true},
+		{encoding: ENCODING_ZSTD, smaller: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			body, err := Compress(data, tt.encoding)
+			if err != nil {
+				t.Fatalf("Compress() error = %v", err)
+			}
+			if tt.smaller && len(body) >= len(data) {
+				t.Errorf("compressed payload has %d bytes, the original has %d", len(body), len(data))
+			}
+			if !bytes.Equal(decompress(t, body, tt.encoding), data) {
+				t.Error("decoded payload is not the original payload")
+			}
+		})
+	}
+}
+
+func TestChunks(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		chunkSize int
+		total     int
+	}{
+		{name: "fits in one message", size: 100, chunkSize: 100, total: 1},
+		{name: "no max payload", size: 100, chunkSize: 0, total: 1},
+		{name: "exact boundary", size: 300, chunkSize: 100, total: 3},
+		{name: "last chunk is shorter", size: 250, chunkSize: 100, total: 3},
+		{name: "one byte more", size: 101, chunkSize: 100, total: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := make([]byte, tt.size)
+			for i := range body {
+				body[i] = byte(i)
+			}
+
+			msgs := chunks("report", body, ENCODING_GZIP, tt.chunkSize)
+			if len(msgs) != tt.total {
+				t.Fatalf("got %d chunks, want %d", len(msgs), tt.total)
+			}
+
+			joined := []byte{}
+			for i, msg := range msgs {
+				if msg.Header.Get(ENCODING_HEADER) != ENCODING_GZIP {
+					t.Errorf("chunk %d has encoding %q", i, msg.Header.Get(ENCODING_HEADER))
+				}
+				if tt.chunkSize > 0 && len(msg.Data) > tt.chunkSize {
+					t.Errorf("chunk %d has %d bytes, more than %d", i, len(msg.Data), tt.chunkSize)
+				}
+				joined = append(joined, msg.Data...)
+
+				if tt.total == 1 {
+					if msg.Header.Get(CHUNK_ID_HEADER) != "" {
+						t.Error("a single message must not have chunk headers")
+					}
+					continue
+				}
+				if msg.Header.Get(CHUNK_ID_HEADER) != msgs[0].Header.Get(CHUNK_ID_HEADER) {
+					t.Errorf("chunk %d has a different chunk id", i)
+				}
+				if msg.Header.Get(CHUNK_INDEX_HEADER) != strconv.Itoa(i) {
+					t.Errorf("chunk %d has index %q", i, msg.Header.Get(CHUNK_INDEX_HEADER))
+				}
+				if msg.Header.Get(CHUNK_TOTAL_HEADER) != strconv.Itoa(tt.total) {
+					t.Errorf("chunk %d has total %q", i, msg.Header.Get(CHUNK_TOTAL_HEADER))
+				}
+			}
+
+			if !bytes.Equal(joined, body) {
+				t.Error("joined chunks are not the original payload")
+			}
+			if last := msgs[len(msgs)-1].Data; tt.total > 1 && len(last) != tt.size-(tt.total-1)*tt.chunkSize {
+				t.Errorf("last chunk has %d bytes, want %d", len(last), tt.size-(tt.total-1)*tt.chunkSize)
+			}
+		})
+	}
+}
+
+func TestChunkIdIsReusedForTheSamePayload(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 300)
+	first := chunks("report", body, ENCODING_NONE, 100)[0].Header.Get(CHUNK_ID_HEADER)
+	retry := chunks("report", body, ENCODING_NONE, 100)[0].Header.Get(CHUNK_ID_HEADER)
+	other := chunks("report", bytes.Repeat([]byte("b"), 300), ENCODING_NONE, 100)[0].Header.Get(CHUNK_ID_HEADER)
+
+	if first != retry {
+		t.Errorf("the same payload has chunk ids %s and %s", first, retry)
+	}
+	if first == other {
+		t.Error("different payloads have the same chunk id")
+	}
+}
+
+// fakeConn records the messages sent and answers like a worker that only accepts the given encodings
+type fakeConn struct {
+	maxPayload int64
+	accepted   map[string]bool
+	msgs       []*nats.Msg
+}
+
+func (c *fakeConn) HeadersSupported() bool { return true }
+
+func (c *fakeConn) MaxPayload() int64 { return c.maxPayload }
+
+func (c *fakeConn) Request(subject string, data []byte, timeout time.Duration) (*nats.Msg, error) {
+	return c.RequestMsg(&nats.Msg{Subject: subject, Data: data}, timeout)
+}
+
+func (c *fakeConn) RequestMsg(msg *nats.Msg, timeout time.Duration) (*nats.Msg, error) {
+	c.msgs = append(c.msgs, msg)
+
+	response := nats.NewMsg(msg.Subject)
+	if encoding := msg.Header.Get(ENCODING_HEADER); encoding != "" && !c.accepted[encoding] {
+		response.Header.Set(ENCODING_REJECTED_HEADER, encoding)
+	}
+	return response, nil
+}
+
+func TestRequest(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"section":"software","name":"7zip"}`), 100)
+
+	tests := []struct {
+		name      string
+		encoding  string
+		size      int
+		accepted  []string
+		encodings []string
+		after     string
+	}{
+		{name: "accepted", encoding: ENCODING_ZSTD, size: len(data), accepted: []string{ENCODING_ZSTD}, encodings: []string{ENCODING_ZSTD}, after: ENCODING_ZSTD},
+		{name: "rejected falls back to none", encoding: ENCODING_GZIP, size: len(data), encodings: []string{ENCODING_GZIP, ""}, after: ENCODING_NONE},
+		{name: "small payload is not compressed", encoding: ENCODING_GZIP, size: MIN_COMPRESS_SIZE - 1, encodings: []string{""}, after: ENCODING_GZIP},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nc := &fakeConn{maxPayload: 1024 * 1024, accepted: map[string]bool{}}
+			for _, e := range tt.accepted {
+				nc.accepted[e] = true
+			}
+
+			s := NewSender(tt.encoding)
+			if _, err := s.request(nc, "report", data[:tt.size], time.Second); err != nil {
+				t.Fatalf("request() error = %v", err)
+			}
+
+			if len(nc.msgs) != len(tt.encodings) {
+				t.Fatalf("%d messages sent, want %d", len(nc.msgs), len(tt.encodings))
+			}
+			for i, msg := range nc.msgs {
+				encoding := msg.Header.Get(ENCODING_HEADER)
+				if encoding != tt.encodings[i] {
+					t.Errorf("message %d has encoding %q, want %q", i, encoding, tt.encodings[i])
+				}
+				if !bytes.Equal(decompress(t, msg.Data, encoding), data[:tt.size]) {
+					t.Errorf("message %d doesn't decode to the payload", i)
+				}
+			}
+			if s.encoding() != tt.after {
+				t.Errorf("sender uses %q after the request, want %q", s.encoding(), tt.after)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	openuem_nats "github.com/open-uem/nats"
+)
+
+type JSONProfileReports struct {
+	Reports []openuem_nats.ProfileReport `json:"reports"`
+}
+
+func (a *Agent) pendingProfileReportsPath() string {
+	return filepath.Join(a.Config.DataDir, "pending_profile_acks.json")
+}
+
+func (a *Agent) ReadProfileReportsNotACK() ([]openuem_nats.ProfileReport, error) {
+	data, err := os.ReadFile(a.pendingProfileReportsPath())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []openuem_nats.ProfileReport{}, nil
+		}
+		return nil, err
+	}
+
+	jReports := JSONProfileReports{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &jReports); err != nil {
+			return nil, err
+		}
+	}
+
+	return jReports.Reports, nil
+}
+
+func (a *Agent) SaveProfileReportsNotACK(reports []openuem_nats.ProfileReport) error {
+	filename := a.pendingProfileReportsPath()
+
+	if len(reports) == 0 {
+		if err := os.Remove(filename); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(JSONProfileReports{Reports: reports}, "", " ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, data, 0644)
+}
+
+func (a *Agent) SaveProfileReportNotACK(report openuem_nats.ProfileReport) error {
+	reports, err := a.ReadProfileReportsNotACK()
+	if err != nil {
+		return err
+	}
+
+	return a.SaveProfileReportsNotACK(append(reports, report))
+}
@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/nats-io/nats.go"
+	"github.com/open-uem/openuem-agent/internal/logger"
+	"github.com/open-uem/openuem-agent/internal/metrics"
+)
+
+const DEFAULT_RECONNECT_INITIAL_DELAY = 2
+const DEFAULT_RECONNECT_MULTIPLIER = 2.0
+const DEFAULT_RECONNECT_MAX_DELAY = 300
+
+// Backoff grows the wait exponentially and picks a random delay up to it (full jitter)
+// so agents don't reconnect in lockstep after a server outage
+type Backoff struct {
+	Initial    time.Duration
+	Multiplier float64
+	Max        time.Duration
+}
+
+func (a *Agent) ReconnectBackoff() Backoff {
+	return Backoff{
+		Initial:    time.Duration(a.Config.ReconnectInitialDelay) * time.Second,
+		Multiplier: a.Config.ReconnectMultiplier,
+		Max:        time.Duration(a.Config.ReconnectMaxDelay) * time.Second,
+	}
+}
+
+func (b Backoff) Delay(attempt int) time.Duration {
+	ceiling := float64(b.Initial) * math.Pow(b.Multiplier, float64(attempt))
+	if ceiling > float64(b.Max) || math.IsInf(ceiling, 0) || math.IsNaN(ceiling) {
+		ceiling = float64(b.Max)
+	}
+
+	if ceiling < 1 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(ceiling)))
+}
+
+// ConnectToNATS connects to the NATS servers using the reconnect strategy from the [NATS] section.
+// If the servers can't be reached it tries using WebSockets if a port has been set
+func (a *Agent) ConnectToNATS() (*nats.Conn, error) {
+	backoff := a.ReconnectBackoff()
+
+	opts := []nats.Option{
+		nats.ClientTLSConfig(a.natsClientCertificate, a.natsRootCAs),
+		nats.MaxReconnects(-1),
+		nats.CustomReconnectDelay(backoff.Delay),
+		nats.ReconnectHandler(a.onNATSReconnect),
+		nats.DisconnectErrHandler(a.onNATSDisconnect),
+		nats.ClosedHandler(func(nc *nats.Conn) {
+			slog.Info("connection closed.", "error", nc.LastError())
+		}),
+	}
+
+	nc, err := nats.Connect(a.Config.NATSServers, opts...)
+	if err != nil {
+		if a.Config.WebSocketPort == "" {
+			return nil, err
+		}
+
+		port, err := strconv.Atoi(a.Config.WebSocketPort)
+		if err != nil {
+			return nil, fmt.Errorf("the WebSocket port is not valid, reason: %v", err)
+		}
+
+		webSocketServers := []string{}
+		for s := range strings.SplitSeq(a.Config.NATSServers, ",") {
+			server := strings.Split(s, ":")[0]
+			webSocketServers = append(webSocketServers, fmt.Sprintf("wss://%s:%d", server, port))
+		}
+
+		nc, err = nats.Connect(strings.Join(webSocketServers, ","), opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	slog.Info("connection established with NATS server")
+	return nc, nil
+}
+
+// natsClientCertificate is read on every connection so a renewed certificate is used after reconnecting
+func (a *Agent) natsClientCertificate() (tls.Certificate, error) {
+	return tls.LoadX509KeyPair(a.Config.AgentCert, a.Config.AgentKey)
+}
+
+func (a *Agent) natsRootCAs() (*x509.CertPool, error) {
+	data, err := os.ReadFile(a.Config.CACert)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA certificate, reason: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", a.Config.CACert)
+	}
+	return pool, nil
+}
+
+func (a *Agent) onNATSDisconnect(nc *nats.Conn, err error) {
+	if err != nil {
+		slog.Info("disconnected from message broker due to, will attempt reconnect", "error", err)
+	}
+}
+
+// onNATSReconnect recreates the JetStream consumer as the server may have lost it while
+// we were away. The core subscriptions are restored by the NATS client itself
+func (a *Agent) onNATSReconnect(nc *nats.Conn) {
+	slog.Info("reconnected to the message broker")
+	metrics.NATSReconnects.Inc()
+
+	go func() {
+		a.CreateAgentJetStreamConsumer()
+
+		// Send what was kept while we were offline
+		if err := a.ReplaySpooledReports(); err != nil {
+			slog.Error("could not send reports from the spool", "error", err)
+		}
+		a.FlushDeployOutbox()
+	}()
+}
+
+// startNATSConnectJob schedules the next connection attempt, waiting longer after each failure
+func (a *Agent) startNATSConnectJob() error {
+	var err error
+
+	delay := a.ReconnectBackoff().Delay(a.natsConnectAttempts)
+	a.natsConnectAttempts++
+
+	a.NATSConnectJob, err = a.TaskScheduler.NewJob(
+		gocron.OneTimeJob(gocron.OneTimeJobStartDateTime(time.Now().Add(delay))),
+		gocron.NewTask(a.natsConnectTask),
+		gocron.WithName("nats-connect"),
+		gocron.WithLimitedRuns(1),
+	)
+	if err != nil {
+		logger.Fatal("could not start the NATS connect job", "error", err)
+		return err
+	}
+	slog.Info(fmt.Sprintf("new NATS connect attempt has been scheduled in %s", delay.Round(time.Second)))
+	return nil
+}
+
+func (a *Agent) natsConnectTask() {
+	nc, err := a.ConnectToNATS()
+	if err != nil {
+		slog.Error(err.Error())
+		a.startNATSConnectJob()
+		return
+	}
+
+	// We have connected
+	a.NATSConnection = nc
+	a.natsConnectAttempts = 0
+	metrics.NATSReconnects.Inc()
+	a.SubscribeToNATSSubjects()
+
+	// Send the reports that were kept while we were offline
+	if err := a.ReplaySpooledReports(); err != nil {
+		slog.Error("could not send reports from the spool", "error", err)
+	}
+
+	// Start the rest of tasks
+	a.startJobsAfterNATSConnect()
+
+	// The expiry check run while the agent was offline couldn't request a renewal
+	a.CertificateExpiryTask()
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/open-uem/openuem-agent/internal/agent/replay"
+	"github.com/open-uem/openuem-agent/internal/agent/signing"
+)
+
+const NONCE_CACHE_RETRY_DELAY = time.Minute
+
+var errNonceCacheUnavailable = errors.New("the nonce cache is not available")
+
+func (a *Agent) OpenReplayCache() {
+	var err error
+	a.ReplayCache, err = replay.New(filepath.Join(a.Config.DataDir, "nonces"), a.Config.ClockSkew)
+	if err != nil {
+		slog.Error("could not open the nonce cache, commands that require replay protection will be rejected", "error", err)
+	}
+}
+
+// verifyCommandReplay checks that the command is recent and hasn't been received before following
+// its policy. The nonce and the issued-at time are taken from the signature if the command is signed
+func (a *Agent) verifyCommandReplay(c command, signer *signing.Signer) error {
+	c.Header.Del(replay.STATUS_HEADER)
+
+	policy := commandPolicy(c, a.Config.ReplayPolicy, a.Config.ReplayPolicies)
+	if policy == signing.POLICY_OFF {
+		return nil
+	}
+
+	// The nonce was stored the first time the message was delivered but it wasn't acknowledged
+	if c.Redelivered {
+		c.Header.Set(replay.STATUS_HEADER, "redelivered, the nonce was checked on the first delivery")
+		return nil
+	}
+
+	err := a.checkNonce(c, signer)
+	if err == nil {
+		c.Header.Set(replay.STATUS_HEADER, "fresh")
+		return nil
+	}
+
+	if policy == signing.POLICY_WARN {
+		c.Header.Set(replay.STATUS_HEADER, fmt.Sprintf("accepted with policy %s: %v", policy, err))
+		slog.Warn("command could be a replay but it is accepted", "handler", c.Name, "subject", c.Subject, "policy", policy, "error", err)
+		return nil
+	}
+
+	c.Header.Set(replay.STATUS_HEADER, fmt.Sprintf("rejected: %v", err))
+	return fmt.Errorf("command rejected as a possible replay, reason: %w", err)
+}
+
+func (a *Agent) checkNonce(c command, signer *signing.Signer) error {
+	if a.ReplayCache == nil {
+		return errNonceCacheUnavailable
+	}
+
+	received := c.Received
+	if received.IsZero() {
+		received = time.Now()
+	}
+
+	if signer != nil {
+		issuedAt := time.Time{}
+		if signer.Header.IssuedAt != 0 {
+			issuedAt = time.Unix(signer.Header.IssuedAt, 0)
+		}
+		return a.ReplayCache.Check(signer.Header.Nonce, issuedAt, received)
+	}
+
+	issuedAt, err := replay.ParseIssuedAt(c.Header.Get(replay.ISSUED_AT_HEADER))
+	if err != nil {
+		return err
+	}
+	return a.ReplayCache.Check(c.Header.Get(replay.NONCE_HEADER), issuedAt, received)
+}
@@ -0,0 +1,106 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// Headers with the claims of commands that are not signed, signed commands carry
+// them in the JWS header so they can't be changed
+const (
+	NONCE_HEADER     = "Openuem-Nonce"
+	ISSUED_AT_HEADER = "Openuem-Issued-At"
+)
+
+// STATUS_HEADER is set by the agent on the received message with the check result
+const STATUS_HEADER = "Openuem-Replay-Status"
+
+const DEFAULT_CLOCK_SKEW_SECONDS = 300
+
+const NONCE_PREFIX = "nonce/"
+
+const MAX_NONCE_LENGTH = 128
+
+var ErrMissing = errors.New("the command has no nonce or issued-at time")
+var ErrReplayed = errors.New("the command has already been received")
+
+// Cache remembers the nonces of the commands received within the clock skew window,
+// older commands are rejected by their issued-at time so nonces expire after twice the skew
+type Cache struct {
+	DB   *badger.DB
+	Skew time.Duration
+}
+
+func New(path string, skewSeconds int) (*Cache, error) {
+	if skewSeconds <= 0 {
+		skewSeconds = DEFAULT_CLOCK_SKEW_SECONDS
+	}
+
+	opts := badger.DefaultOptions(path)
+	opts.Logger = nil
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cache{DB: db, Skew: time.Duration(skewSeconds) * time.Second}, nil
+}
+
+func (c *Cache) Close() error {
+	return c.DB.Close()
+}
+
+// Check accepts a command issued within the clock skew window of the time it was received whose
+// nonce hasn't been seen, the nonce is stored in the same transaction so the same command can't be
+// accepted twice. JetStream commands are received when they're stored in the stream so commands
+// delivered late to an offline agent are still accepted, their nonce is kept for longer
+func (c *Cache) Check(nonce string, issuedAt time.Time, received time.Time) error {
+	if nonce == "" || issuedAt.IsZero() {
+		return ErrMissing
+	}
+	if len(nonce) > MAX_NONCE_LENGTH {
+		return fmt.Errorf("the nonce is longer than %d characters", MAX_NONCE_LENGTH)
+	}
+
+	skew := received.Sub(issuedAt)
+	if skew > c.Skew || skew < -c.Skew {
+		return fmt.Errorf("the command was issued at %s, outside the %s clock skew window", issuedAt.UTC().Format(time.RFC3339), c.Skew)
+	}
+
+	ttl := 2 * c.Skew
+	if late := time.Since(received); late > 0 {
+		ttl += late
+	}
+
+	key := []byte(NONCE_PREFIX + nonce)
+	return c.DB.Update(func(txn *badger.Txn) error {
+		_, err := txn.Get(key)
+		if err == nil {
+			return ErrReplayed
+		}
+		if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		return txn.SetEntry(badger.NewEntry(key, nil).WithTTL(ttl))
+	})
+}
+
+// ParseIssuedAt reads the issued-at header, either Unix seconds or RFC 3339
+func ParseIssuedAt(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, fmt.Errorf("%q is not a valid issued-at time, use Unix seconds or RFC 3339", value)
+	}
+	return t, nil
+}
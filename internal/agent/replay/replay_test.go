@@ -0,0 +1,103 @@
+package replay
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newCache(t *testing.T) *Cache {
+	t.Helper()
+
+	c, err := New(t.TempDir(), 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		nonce    string
+		issuedAt time.Time
+		received time.Time
+		err      string
+	}{
+		{name: "fresh", nonce: "a", issuedAt: now, received: now},
+		{name: "issued within the skew", nonce: "b", issuedAt: now.Add(-50 * time.Second), received: now},
+		{name: "issued in the future within the skew", nonce: "c", issuedAt: now.Add(50 * time.Second), received: now},
+		{name: "stale", nonce: "d", issuedAt: now.Add(-2 * time.Minute), received: now, err: "outside the 1m0s clock skew window"},
+		{name: "issued in the future", nonce: "e", issuedAt: now.Add(2 * time.Minute), received: now, err: "outside the 1m0s clock skew window"},
+		{name: "delivered late from the stream", nonce: "f", issuedAt: now.Add(-72 * time.Hour), received: now.Add(-72 * time.Hour)},
+		{name: "stored late in the stream", nonce: "g", issuedAt: now.Add(-72 * time.Hour), received: now, err: "outside the 1m0s clock skew window"},
+		{name: "no nonce", issuedAt: now, received: now, err: ErrMissing.Error()},
+		{name: "no issued-at time", nonce: "h", received: now, err: ErrMissing.Error()},
+		{name: "long nonce", nonce: strings.Repeat("n", MAX_NONCE_LENGTH+1), issuedAt: now, received: now, err: "longer than"},
+	}
+
+	c := newCache(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.Check(tt.nonce, tt.issuedAt, tt.received)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error is %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheckReplayed(t *testing.T) {
+	c := newCache(t)
+	now := time.Now()
+
+	if err := c.Check("nonce", now, now); err != nil {
+		t.Fatalf("first check: %v", err)
+	}
+	if err := c.Check("nonce", now, now); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("second check error is %v, want %v", err, ErrReplayed)
+	}
+
+	// A command delivered late keeps its nonce after the skew window
+	late := now.Add(-72 * time.Hour)
+	if err := c.Check("late", late, late); err != nil {
+		t.Fatalf("late check: %v", err)
+	}
+	if err := c.Check("late", late, late); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("late replay error is %v, want %v", err, ErrReplayed)
+	}
+}
+
+func TestParseIssuedAt(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		err   bool
+	}{
+		{value: "", want: time.Time{}},
+		{value: "1790000000", want: time.Unix(1790000000, 0)},
+		{value: "2026-10-17T22:00:00Z", want: time.Date(2026, 10, 17, 22, 0, 0, 0, time.UTC)},
+		{value: "yesterday", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseIssuedAt(tt.value)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseIssuedAt(%q) error = %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseIssuedAt(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/open-uem/openuem-agent/internal/agent/spool"
+	"github.com/open-uem/openuem-agent/internal/commands/report"
+)
+
+func (a *Agent) OpenReportSpool(wd string) {
+	var err error
+
+	a.ReportSpool, err = spool.New(filepath.Join(wd, "spool"), a.Config.ReportSpoolMaxSize, a.Config.ReportSpoolMaxAge)
+	if err != nil {
+		slog.Error("could not open the report spool, reports won't be kept while offline", "error", err)
+		return
+	}
+
+	if n := a.ReportSpool.Len(); n > 0 {
+		slog.Info(fmt.Sprintf("report spool has %d reports pending to be sent", n))
+	}
+}
+
+// SendOrSpoolReport sends the reports waiting in the spool and then the new
+// report. If something fails the report is kept in the spool to be sent later
+func (a *Agent) SendOrSpoolReport(r *report.Report) error {
+	if a.NATSConnection == nil || !a.NATSConnection.IsConnected() {
+		a.SpoolReport(r)
+		return errors.New("NATS connection is not ready")
+	}
+
+	if err := a.ReplaySpooledReports(); err != nil {
+		a.SpoolReport(r)
+		return err
+	}
+
+	if err := a.SendReport(r); err != nil {
+		a.SpoolReport(r)
+		return err
+	}
+
+	return nil
+}
+
+func (a *Agent) SpoolReport(r *report.Report) {
+	if a.ReportSpool == nil {
+		return
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		slog.Error("could not marshal report for the spool", "error", err)
+		return
+	}
+
+	if err := a.ReportSpool.Push(r.ExecutionTime, data); err != nil {
+		slog.Error("could not save report in the spool", "error", err)
+		return
+	}
+
+	slog.Info("report has been saved in the spool and will be sent when NATS is reachable")
+}
+
+func (a *Agent) ReplaySpooledReports() error {
+	if a.ReportSpool == nil {
+		return nil
+	}
+
+	sent, err := a.ReportSpool.Replay(a.sendReportData)
+	if sent > 0 {
+		slog.Info(fmt.Sprintf("%d reports from the spool have been sent", sent))
+
+		// The worker now has an older state, so the next report must be a full report
+		a.ReportDelta.Reset()
+	}
+
+	return err
+}
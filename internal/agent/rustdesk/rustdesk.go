@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 
@@ -112,11 +112,20 @@ func RustDeskRespond(msg *nats.Msg, id string, errMessage string) {
 
 	data, err := json.Marshal(result)
 	if err != nil {
-		log.Printf("[ERROR]: could not marshal RustDesk response, reason: %v\n", err)
+		slog.Error("could not marshal RustDesk response", "error", err)
 	}
 
 	if err := msg.Respond(data); err != nil {
-		log.Printf("[ERROR]: could not respond to agent rustdesk start message, reason: %v\n", err)
+		slog.Error("could not respond to agent rustdesk start message", "error", err)
 		return
 	}
 }
+
+// ErrorResponse encodes an error as the RustDesk result the console expects
+func ErrorResponse(err error) []byte {
+	data, mErr := json.Marshal(openuem_nats.RustDeskResult{Error: err.Error()})
+	if mErr != nil {
+		slog.Error("could not marshal RustDesk response", "error", mErr)
+	}
+	return data
+}
@@ -4,7 +4,7 @@ package rustdesk
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"os/user"
@@ -23,7 +23,7 @@ func (cfg *RustDeskConfig) Configure(config []byte) error {
 	// Unmarshal configuration data sent by OpenUEM
 	var rdConfig nats.RustDesk
 	if err := json.Unmarshal(config, &rdConfig); err != nil {
-		log.Println("[ERROR]: could not unmarshall RustDesk configuration")
+		slog.Error("could not unmarshall RustDesk configuration")
 		return err
 	}
 
@@ -33,7 +33,7 @@ func (cfg *RustDeskConfig) Configure(config []byte) error {
 		rdConfig.Key == "" &&
 		rdConfig.APIServer == "" &&
 		!rdConfig.DirectIPAccess {
-		log.Println("[INFO]: no RustDesk server settings have been found for tenant, using RustDesk's default settings")
+		slog.Info("no RustDesk server settings have been found for tenant, using RustDesk's default settings")
 	}
 
 	// Configuration file location
@@ -62,7 +62,7 @@ func (cfg *RustDeskConfig) Configure(config []byte) error {
 
 	rdTOML, err := toml.Marshal(cfgTOML)
 	if err != nil {
-		log.Printf("[ERROR]: could not marshall TOML file for RustDesk configuration, reason: %v", err)
+		slog.Error("could not marshall TOML file for RustDesk configuration", "error", err)
 		return err
 	}
 
@@ -74,14 +74,14 @@ func (cfg *RustDeskConfig) Configure(config []byte) error {
 	} else {
 		// Check if configuration path exists, if not create path
 		if err := os.MkdirAll(configPath, 0644); err != nil {
-			log.Printf("[ERROR]: could not create directory file for RustDesk configuration, reason: %v", err)
+			slog.Error("could not create directory file for RustDesk configuration", "error", err)
 			return err
 		}
 	}
 
 	// Write the new configuration file for RustDesk
 	if err := os.WriteFile(configFile, rdTOML, 0600); err != nil {
-		log.Printf("[ERROR]: could not create TOML file for RustDesk configuration, reason: %v", err)
+		slog.Error("could not create TOML file for RustDesk configuration", "error", err)
 		return err
 	}
 
@@ -92,7 +92,7 @@ func (cfg *RustDeskConfig) Configure(config []byte) error {
 	}
 
 	if err := RestartRustDeskService(username); err != nil {
-		log.Printf("[ERROR]: could not start RustDesk service, reason: %v", err)
+		slog.Error("could not start RustDesk service", "error", err)
 		return err
 	}
 
@@ -123,13 +123,13 @@ func (cfg *RustDeskConfig) GetRustDeskID() (string, error) {
 	if cfg.User == nil || cfg.User.Username == "" {
 		out, err = exec.Command(cfg.Binary, cfg.GetIDArgs...).CombinedOutput()
 		if err != nil {
-			log.Printf("[ERROR]: could not get RustDesk ID, reason: %v", err)
+			slog.Error("could not get RustDesk ID", "error", err)
 			return "", err
 		}
 	} else {
 		out, err = runtime.RunAsUserWithOutput(cfg.User.Username, cfg.Binary, cfg.GetIDArgs, true)
 		if err != nil {
-			log.Printf("[ERROR]: could not get RustDesk ID, reason: %v", err)
+			slog.Error("could not get RustDesk ID", "error", err)
 			return "", err
 		}
 	}
@@ -137,7 +137,7 @@ func (cfg *RustDeskConfig) GetRustDeskID() (string, error) {
 	id := strings.TrimSpace(string(out))
 	_, err = strconv.Atoi(id)
 	if err != nil {
-		log.Printf("[ERROR]: RustDesk ID is not a number, reason: %v", err)
+		slog.Error("RustDesk ID is not a number", "error", err)
 		return "", err
 	}
 
@@ -156,21 +156,21 @@ func getRustDeskUserInfo() (*RustDeskUser, error) {
 
 	u, err := user.Lookup(username)
 	if err != nil {
-		log.Println("[ERROR]: could not find user information")
+		slog.Error("could not find user information")
 		return nil, err
 	}
 	rdUser.Home = u.HomeDir
 
 	uid, err := strconv.Atoi(u.Uid)
 	if err != nil {
-		log.Println("[ERROR]: could not get UID of logged in user")
+		slog.Error("could not get UID of logged in user")
 		return nil, err
 	}
 	rdUser.Uid = uid
 
 	gid, err := strconv.Atoi(u.Gid)
 	if err != nil {
-		log.Println("[ERROR]: could not get GID of logged in user")
+		slog.Error("could not get GID of logged in user")
 		return nil, err
 	}
 	rdUser.Gid = gid
@@ -190,7 +190,7 @@ func (cfg *RustDeskConfig) KillRustDeskProcess() error {
 		}
 		if n == "rustdesk" {
 			if err := p.Kill(); err != nil {
-				log.Println("[ERROR]: could not kill RustDesk process ")
+				slog.Error("could not kill RustDesk process")
 			}
 		}
 	}
@@ -202,7 +202,7 @@ func (cfg *RustDeskConfig) KillRustDeskProcess() error {
 	}
 
 	if err := RestartRustDeskService(username); err != nil {
-		log.Printf("[ERROR]: could not start RustDesk service, reason: %v", err)
+		slog.Error("could not start RustDesk service", "error", err)
 		return err
 	}
 
@@ -238,7 +238,7 @@ func (cfg *RustDeskConfig) ConfigRollBack() error {
 	}
 
 	if err := RestartRustDeskService(username); err != nil {
-		log.Printf("[ERROR]: could not start RustDesk service, reason: %v", err)
+		slog.Error("could not start RustDesk service", "error", err)
 		return err
 	}
 
@@ -318,7 +318,7 @@ func (cfg *RustDeskConfig) SetRustDeskPassword(config []byte) error {
 	// Unmarshal configuration data
 	var rdConfig openuem_nats.RustDesk
 	if err := json.Unmarshal(config, &rdConfig); err != nil {
-		log.Println("[ERROR]: could not unmarshall RustDesk configuration")
+		slog.Error("could not unmarshall RustDesk configuration")
 		return err
 	}
 
@@ -345,12 +345,12 @@ func (cfg *RustDeskConfig) SetRustDeskPassword(config []byte) error {
 	cmd := exec.Command(cfg.Binary, "--password", rdConfig.PermanentPassword)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		log.Printf("[ERROR]: could not execute RustDesk command to set password, reason: %v", err)
+		slog.Error("could not execute RustDesk command to set password", "error", err)
 		return err
 	}
 
 	if strings.TrimSpace(string(out)) != "Done!" {
-		log.Printf("[ERROR]: could not change RustDesk password, reason: %s", string(out))
+		slog.Error("could not change RustDesk password", "output", string(out))
 		return err
 	}
 
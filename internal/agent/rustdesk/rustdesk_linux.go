@@ -5,7 +5,7 @@ package rustdesk
 import (
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"os/user"
@@ -62,7 +62,7 @@ func (cfg *RustDeskConfig) Configure(config []byte) error {
 	// Unmarshal configuration data
 	var rdConfig nats.RustDesk
 	if err := json.Unmarshal(config, &rdConfig); err != nil {
-		log.Println("[ERROR]: could not unmarshall RustDesk configuration")
+		slog.Error("could not unmarshall RustDesk configuration")
 		return err
 	}
 
@@ -71,7 +71,7 @@ func (cfg *RustDeskConfig) Configure(config []byte) error {
 		rdConfig.Key == "" &&
 		rdConfig.APIServer == "" &&
 		!rdConfig.DirectIPAccess {
-		log.Println("[INFO]: no RustDesk server settings have been found for tenant, using RustDesk's default settings")
+		slog.Info("no RustDesk server settings have been found for tenant, using RustDesk's default settings")
 	}
 
 	// Configuration file location
@@ -80,7 +80,7 @@ func (cfg *RustDeskConfig) Configure(config []byte) error {
 	configPath := ""
 	if cfg.IsFlatpak {
 		if cfg.User == nil || cfg.User.Home == "" {
-			log.Println("[ERROR]: Rustdesk was installed with Flatpak, but the agent haven't found which user is logged in, which is required to use this integration")
+			slog.Error("Rustdesk was installed with Flatpak, but the agent haven't found which user is logged in, which is required to use this integration")
 			return errors.New("Rustdesk was installed with Flatpak, but the agent haven't found which user is logged in, which is required to use this integration")
 		}
 		rootConfigPath = filepath.Join(cfg.User.Home, ".var")
@@ -114,7 +114,7 @@ func (cfg *RustDeskConfig) Configure(config []byte) error {
 
 	rdTOML, err := toml.Marshal(cfgTOML)
 	if err != nil {
-		log.Printf("[ERROR]: could not marshall TOML file for RustDesk configuration, reason: %v", err)
+		slog.Error("could not marshall TOML file for RustDesk configuration", "error", err)
 		return err
 	}
 
@@ -131,25 +131,25 @@ func (cfg *RustDeskConfig) Configure(config []byte) error {
 
 	if cfg.IsFlatpak {
 		if err := os.MkdirAll(configPath, 0755); err != nil {
-			log.Printf("[ERROR]: could not create directory file for RustDesk configuration, reason: %v", err)
+			slog.Error("could not create directory file for RustDesk configuration", "error", err)
 			return err
 		}
 
 		if err := ChownRecursively(rootConfigPath, cfg.User.Uid, cfg.User.Gid); err != nil {
-			log.Printf("[ERROR]: could not chown directory file for RustDesk configuration, reason: %v", err)
+			slog.Error("could not chown directory file for RustDesk configuration", "error", err)
 			return err
 		}
 
 	}
 
 	if err := os.WriteFile(configFile, rdTOML, 0600); err != nil {
-		log.Printf("[ERROR]: could not create TOML file for RustDesk configuration, reason: %v", err)
+		slog.Error("could not create TOML file for RustDesk configuration", "error", err)
 		return err
 	}
 
 	if cfg.IsFlatpak {
 		if err := os.Chown(configFile, cfg.User.Uid, cfg.User.Gid); err != nil {
-			log.Printf("[ERROR]: could not chown the TOML file for RustDesk configuration, reason: %v", err)
+			slog.Error("could not chown the TOML file for RustDesk configuration", "error", err)
 			return err
 		}
 	}
@@ -174,13 +174,13 @@ func (cfg *RustDeskConfig) GetRustDeskID() (string, error) {
 	if cfg.User == nil || cfg.User.Username == "" {
 		out, err = exec.Command(cfg.Binary, cfg.GetIDArgs...).CombinedOutput()
 		if err != nil {
-			log.Printf("[ERROR]: could not get RustDesk ID, reason: %v", err)
+			slog.Error("could not get RustDesk ID", "error", err)
 			return "", err
 		}
 	} else {
 		out, err = runtime.RunAsUserWithOutput(cfg.User.Username, cfg.Binary, cfg.GetIDArgs, true)
 		if err != nil {
-			log.Printf("[ERROR]: could not get RustDesk ID, reason: %v", err)
+			slog.Error("could not get RustDesk ID", "error", err)
 			return "", err
 		}
 	}
@@ -188,7 +188,7 @@ func (cfg *RustDeskConfig) GetRustDeskID() (string, error) {
 	id := strings.TrimSpace(string(out))
 	_, err = strconv.Atoi(id)
 	if err != nil {
-		log.Printf("[ERROR]: RustDesk ID is not a number, reason: %v", err)
+		slog.Error("RustDesk ID is not a number", "error", err)
 		return "", err
 	}
 
@@ -201,7 +201,7 @@ func getRustDeskUserInfo() (*RustDeskUser, error) {
 	// Get current user logged in, uid, gid and home user
 	username, err := runtime.GetLoggedInUser()
 	if err != nil {
-		log.Println("[ERROR]: could not get logged in user")
+		slog.Error("could not get logged in user")
 		return nil, err
 	}
 	rdUser.Username = username
@@ -214,14 +214,14 @@ func getRustDeskUserInfo() (*RustDeskUser, error) {
 
 	uid, err := strconv.Atoi(u.Uid)
 	if err != nil {
-		log.Println("[ERROR]: could not get UID of logged in user")
+		slog.Error("could not get UID of logged in user")
 		return nil, err
 	}
 	rdUser.Uid = uid
 
 	gid, err := strconv.Atoi(u.Gid)
 	if err != nil {
-		log.Println("[ERROR]: could not get GID of logged in user")
+		slog.Error("could not get GID of logged in user")
 		return nil, err
 	}
 	rdUser.Gid = gid
@@ -241,7 +241,7 @@ func (cfg *RustDeskConfig) KillRustDeskProcess() error {
 		}
 		if n == "rustdesk" {
 			if err := p.Kill(); err != nil {
-				log.Println("[ERROR]: could not kill RustDesk process ")
+				slog.Error("could not kill RustDesk process")
 			}
 		}
 	}
@@ -268,7 +268,7 @@ func (cfg *RustDeskConfig) ConfigRollBack() error {
 	configFile := ""
 	if cfg.IsFlatpak {
 		if rdUser == nil || rdUser.Home == "" {
-			log.Println("[ERROR]: Rustdesk was installed with Flatpak, but the agent haven't found which user is logged in, which is required to use this integration")
+			slog.Error("Rustdesk was installed with Flatpak, but the agent haven't found which user is logged in, which is required to use this integration")
 			return errors.New("Rustdesk was installed with Flatpak, but the agent haven't found which user is logged in, which is required to use this integration")
 		}
 		configPath := filepath.Join(rdUser.Home, ".var", "app", "com.rustdesk.RustDesk", "config", "rustdesk")
@@ -321,7 +321,7 @@ func (cfg *RustDeskConfig) SetRustDeskPassword(config []byte) error {
 	// Unmarshal configuration data
 	var rdConfig openuem_nats.RustDesk
 	if err := json.Unmarshal(config, &rdConfig); err != nil {
-		log.Println("[ERROR]: could not unmarshall RustDesk configuration")
+		slog.Error("could not unmarshall RustDesk configuration")
 		return err
 	}
 
@@ -348,12 +348,12 @@ func (cfg *RustDeskConfig) SetRustDeskPassword(config []byte) error {
 		cmd := exec.Command(cfg.Binary, "--password", rdConfig.PermanentPassword)
 		out, err := cmd.CombinedOutput()
 		if err != nil {
-			log.Printf("[ERROR]: could not execute RustDesk command to set password, reason: %v", err)
+			slog.Error("could not execute RustDesk command to set password", "error", err)
 			return err
 		}
 
 		if strings.TrimSpace(string(out)) != "Done!" {
-			log.Printf("[ERROR]: could not change RustDesk password, reason: %s", string(out))
+			slog.Error("could not change RustDesk password", "output", string(out))
 			return err
 		}
 	} else {
@@ -366,7 +366,7 @@ func (cfg *RustDeskConfig) SetRustDeskPassword(config []byte) error {
 		if _, err := os.Stat(configFile); err == nil {
 			config, err := os.ReadFile(configFile)
 			if err != nil {
-				log.Printf("[ERROR]: could not read RustDesk.toml config file reason: %v", err)
+				slog.Error("could not read RustDesk.toml config file", "error", err)
 				return err
 			}
 
@@ -386,17 +386,17 @@ func (cfg *RustDeskConfig) SetRustDeskPassword(config []byte) error {
 			// Write new configuration
 			rdTOML, err := toml.Marshal(cfgTOML)
 			if err != nil {
-				log.Printf("[ERROR]: could not marshall TOML file for RustDesk configuration, reason: %v", err)
+				slog.Error("could not marshall TOML file for RustDesk configuration", "error", err)
 				return err
 			}
 
 			if err := os.WriteFile(configFile, rdTOML, 0600); err != nil {
-				log.Printf("[ERROR]: could not create TOML file for RustDesk configuration, reason: %v", err)
+				slog.Error("could not create TOML file for RustDesk configuration", "error", err)
 				return err
 			}
 		} else {
 			//
-			log.Print("[ERROR]: cannot set RustDesk password for flatpak, disable the use of permanent password for this tenant")
+			slog.Error("cannot set RustDesk password for flatpak, disable the use of permanent password for this tenant")
 			return errors.New("cannot set RustDesk password for flatpak, disable the use of permanent password for this tenant")
 		}
 	}
@@ -6,7 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -40,7 +40,7 @@ func (cfg *RustDeskConfig) Configure(config []byte) error {
 	// Unmarshal configuration data
 	var rdConfig nats.RustDesk
 	if err := json.Unmarshal(config, &rdConfig); err != nil {
-		log.Println("[ERROR]: could not unmarshall RustDesk configuration")
+		slog.Error("could not unmarshall RustDesk configuration")
 		return err
 	}
 
@@ -49,7 +49,7 @@ func (cfg *RustDeskConfig) Configure(config []byte) error {
 		rdConfig.Key == "" &&
 		rdConfig.APIServer == "" &&
 		!rdConfig.DirectIPAccess {
-		log.Println("[INFO]: no RustDesk settings has been found for tenant, using RustDesk's default settings")
+		slog.Info("no RustDesk settings has been found for tenant, using RustDesk's default settings")
 	}
 
 	// Configuration file location
@@ -80,7 +80,7 @@ func (cfg *RustDeskConfig) Configure(config []byte) error {
 
 	rdTOML, err := toml.Marshal(cfgTOML)
 	if err != nil {
-		log.Printf("[ERROR]: could not marshall TOML file for RustDesk configuration, reason: %v", err)
+		slog.Error("could not marshall TOML file for RustDesk configuration", "error", err)
 		return err
 	}
 
@@ -109,24 +109,24 @@ func (cfg *RustDeskConfig) Configure(config []byte) error {
 	}
 
 	if err := os.MkdirAll(configPath, 0755); err != nil {
-		log.Printf("[ERROR]: could not create directory file for RustDesk configuration, reason: %v", err)
+		slog.Error("could not create directory file for RustDesk configuration", "error", err)
 		return err
 	}
 
 	if err := os.WriteFile(configFile, rdTOML, 0600); err != nil {
-		log.Printf("[ERROR]: could not create TOML file for RustDesk configuration, reason: %v", err)
+		slog.Error("could not create TOML file for RustDesk configuration", "error", err)
 		return err
 	}
 
 	// Restart RustDesk service after configuration changes
 	if err := openuem_utils.WindowsSvcControl("RustDesk", svc.Stop, svc.Stopped); err != nil {
-		log.Printf("[ERROR]: could not stop RustDesk service, reason: %v\n", err)
+		slog.Error("could not stop RustDesk service", "error", err)
 		return err
 	}
 
 	// Start service
 	if err := openuem_utils.WindowsStartService("RustDesk"); err != nil {
-		log.Printf("[ERROR]: could not start RustDesk service, reason: %v\n", err)
+		slog.Error("could not start RustDesk service", "error", err)
 		return err
 	}
 
@@ -142,13 +142,13 @@ func (cfg *RustDeskConfig) GetRustDeskID() (string, error) {
 	if err != nil || username == "" {
 		out, err = exec.Command(cfg.Binary, cfg.GetIDArgs...).CombinedOutput()
 		if err != nil {
-			log.Printf("[ERROR]: could not get RustDesk ID, reason: %v", err)
+			slog.Error("could not get RustDesk ID", "error", err)
 			return "", err
 		}
 	} else {
 		out, err = runtime.RunAsUserWithOutput(cfg.Binary, cfg.GetIDArgs)
 		if err != nil {
-			log.Printf("[ERROR]: could not get RustDesk ID, reason: %v", err)
+			slog.Error("could not get RustDesk ID", "error", err)
 			return "", err
 		}
 	}
@@ -156,7 +156,7 @@ func (cfg *RustDeskConfig) GetRustDeskID() (string, error) {
 	id := strings.TrimSpace(string(out))
 	_, err = strconv.Atoi(id)
 	if err != nil {
-		log.Printf("[ERROR]: RustDesk ID is not a number, reason: %v", err)
+		slog.Error("RustDesk ID is not a number", "error", err)
 		return "", err
 	}
 
@@ -174,14 +174,14 @@ func (cfg *RustDeskConfig) KillRustDeskProcess() error {
 		out, err := exec.Command(cfg.Binary, cfg.GetIDArgs...).CombinedOutput()
 		if err != nil {
 			if !strings.Contains(err.Error(), "128") && !strings.Contains(err.Error(), "255") {
-				log.Printf("[WARN]: could not kill RustDesk app, reason: %s, %v", string(out), err)
+				slog.Warn("could not kill RustDesk app", "output", string(out), "error", err)
 				return fmt.Errorf("[WARN]: could not kill RustDesk app, reason: %s, %v", string(out), err)
 			}
 		}
 	} else {
 		if err := runtime.RunAsUser("taskkill", args); err != nil {
 			if !strings.Contains(err.Error(), "128") && !strings.Contains(err.Error(), "255") {
-				log.Printf("[WARN]: could not kill RustDesk app, reason: %v", err)
+				slog.Warn("could not kill RustDesk app", "error", err)
 				return fmt.Errorf("[WARN]: could not kill RustDesk app, reason: %v", err)
 			}
 		}
@@ -208,13 +208,13 @@ func (cfg *RustDeskConfig) ConfigRollBack() error {
 
 	// Restart RustDesk service after configuration changes
 	if err := openuem_utils.WindowsSvcControl("RustDesk", svc.Stop, svc.Stopped); err != nil {
-		log.Printf("[ERROR]: could not stop RustDesk service, reason: %v\n", err)
+		slog.Error("could not stop RustDesk service", "error", err)
 		return err
 	}
 
 	// Start service
 	if err := openuem_utils.WindowsStartService("RustDesk"); err != nil {
-		log.Printf("[ERROR]: could not start RustDesk service, reason: %v\n", err)
+		slog.Error("could not start RustDesk service", "error", err)
 		return err
 	}
 
@@ -230,7 +230,7 @@ func (cfg *RustDeskConfig) SetRustDeskPassword(config []byte) error {
 	// Unmarshal configuration data
 	var rdConfig openuem_nats.RustDesk
 	if err := json.Unmarshal(config, &rdConfig); err != nil {
-		log.Println("[ERROR]: could not unmarshall RustDesk configuration")
+		slog.Error("could not unmarshall RustDesk configuration")
 		return err
 	}
 
@@ -256,12 +256,12 @@ func (cfg *RustDeskConfig) SetRustDeskPassword(config []byte) error {
 	cmd := exec.Command(cfg.Binary, "--password", rdConfig.PermanentPassword)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		log.Printf("[ERROR]: could not execute RustDesk command to set password, reason: %v", err)
+		slog.Error("could not execute RustDesk command to set password", "error", err)
 		return err
 	}
 
 	if strings.TrimSpace(string(out)) != "Done!" {
-		log.Printf("[ERROR]: could not change RustDesk password, reason: %s", string(out))
+		slog.Error("could not change RustDesk password", "output", string(out))
 		return err
 	}
 
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/gliderlabs/ssh"
+	"github.com/open-uem/openuem-agent/internal/commands/sftp"
+)
+
+// StartSFTPServer starts the SFTP server in the background if it's enabled and a port is set.
+// The BadgerDB KV where the certificate checks are cached is opened the first time
+func (a *Agent) StartSFTPServer() {
+	if a.Config.SFTPPort == "" || a.Config.SFTPDisabled {
+		slog.Info("SFTP port is not set so SFTP server is not started!")
+		return
+	}
+
+	if a.SFTPServer != nil {
+		return
+	}
+
+	if a.BadgerDB == nil {
+		var err error
+		badgerPath := filepath.Join(a.Config.DataDir, "badgerdb")
+		if err := os.RemoveAll(badgerPath); err != nil {
+			slog.Error("could not remove badgerdb directory", "error", err)
+			return
+		}
+
+		if err := os.MkdirAll(badgerPath, 0700); err != nil {
+			slog.Error("could not recreate badgerdb directory")
+			return
+		}
+
+		a.BadgerDB, err = badger.Open(badger.DefaultOptions(badgerPath))
+		if err != nil {
+			slog.Error(err.Error())
+			return
+		}
+	}
+
+	server := sftp.New()
+	port := a.Config.SFTPPort
+	a.SFTPServer = server
+
+	go func() {
+		a.Status.SetSFTPRunning(true)
+		slog.Info(fmt.Sprintf("SFTP server has started on port %s!", port))
+		err := server.Serve(":"+port, a.SFTPCert, a.CACert, a.BadgerDB)
+		a.Status.SetSFTPRunning(false)
+		if err != nil && !errors.Is(err, ssh.ErrServerClosed) {
+			slog.Error(err.Error())
+		}
+	}()
+}
+
+// StopSFTPServer closes the SFTP server if it's running, the BadgerDB KV is kept open
+func (a *Agent) StopSFTPServer() {
+	if a.SFTPServer == nil {
+		return
+	}
+
+	if err := a.SFTPServer.Server.Close(); err != nil {
+		slog.Error("could not close SFTP server", "error", err)
+	}
+	a.SFTPServer = nil
+	slog.Info("SFTP server has been stopped")
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/open-uem/openuem-agent/internal/agent/handlers"
+	"github.com/open-uem/openuem-agent/internal/agent/signing"
+)
+
+// command is a message that asks the agent to do something, received either by a
+// handler or by the JetStream consumer. JetStream commands are received when they're
+// stored in the stream and they're redelivered if the agent didn't acknowledge them
+type command struct {
+	Name        string
+	Subject     string
+	Header      nats.Header
+	Data        []byte
+	Privileged  bool
+	Received    time.Time
+	Redelivered bool
+}
+
+// commandPolicy returns the policy set for the command in overrides,
+// or policy for the privileged commands
+func commandPolicy(c command, policy string, overrides string) string {
+	policies, err := signing.ParsePolicies(overrides)
+	if err == nil {
+		if p, ok := policies[c.Name]; ok {
+			return p
+		}
+	}
+
+	if c.Privileged && policy != "" {
+		return policy
+	}
+	return signing.POLICY_OFF
+}
+
+// verifyHandlerCommand checks the signature and the freshness of the messages received by the handlers
+func (a *Agent) verifyHandlerCommand(h *handlers.Handler, msg *nats.Msg) error {
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	return a.verifyCommand(command{Name: h.Name, Subject: msg.Subject, Header: msg.Header, Data: msg.Data, Privileged: h.Audited})
+}
+
+func (a *Agent) verifyCommand(c command) error {
+	signer, err := a.verifyCommandSignature(c)
+	if err != nil {
+		return err
+	}
+	return a.verifyCommandReplay(c, signer)
+}
+
+// verifyCommandSignature checks the signature of the command following its policy and leaves
+// the result in the signature status header so it's audited. The signer is returned when the
+// signature is valid
+func (a *Agent) verifyCommandSignature(c command) (*signing.Signer, error) {
+	c.Header.Del(signing.STATUS_HEADER)
+
+	policy := commandPolicy(c, a.Config.SignaturePolicy, a.Config.SignaturePolicies)
+	if policy == signing.POLICY_OFF {
+		return nil, nil
+	}
+
+	signer, err := signing.Verify(c.Header.Get(signing.HEADER), c.Subject, c.Data, a.signatureOptions())
+	if err == nil {
+		c.Header.Set(signing.STATUS_HEADER, fmt.Sprintf("valid, signed by %s", signer.Name()))
+		return signer, nil
+	}
+
+	if policy == signing.POLICY_WARN {
+		c.Header.Set(signing.STATUS_HEADER, fmt.Sprintf("accepted with policy %s: %v", policy, err))
+		slog.Warn("command signature is not valid but the command is accepted", "handler", c.Name, "subject", c.Subject, "policy", policy, "error", err)
+		return nil, nil
+	}
+
+	c.Header.Set(signing.STATUS_HEADER, fmt.Sprintf("rejected: %v", err))
+	return nil, fmt.Errorf("command signature rejected, reason: %v", err)
+}
+
+func (a *Agent) signatureOptions() signing.VerifyOptions {
+	// The setting is checked when the config is read
+	fingerprints, _ := signing.ParseFingerprints(a.Config.SignerFingerprints)
+	return signing.VerifyOptions{CA: a.CACert, Fingerprints: fingerprints}
+}
@@ -0,0 +1,282 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// HEADER carries a JWS with detached payload (RFC 7515, appendix F) that signs the message data
+const HEADER = "Openuem-Signature"
+
+// STATUS_HEADER is set by the agent on the received message with the verification result,
+// the value sent by the publisher is always removed
+const STATUS_HEADER = "Openuem-Signature-Status"
+
+const (
+	POLICY_OFF     = "off"
+	POLICY_WARN    = "warn"
+	POLICY_ENFORCE = "enforce"
+)
+
+var ErrMissing = errors.New("the command is not signed")
+
+// VerifyOptions tells which certificates can sign commands. The signing certificate must
+// chain to CA and be a code signing certificate without TLS usages, so the certificates of
+// the agents, the SFTP and the remote desktop servers can't sign commands even though they
+// are issued by the same CA. If Fingerprints is set, the SHA-256 of the signing certificate
+// must be one of them
+type VerifyOptions struct {
+	CA           *x509.Certificate
+	Fingerprints []string
+}
+
+// Header is the JWS protected header. The signing certificate and its intermediates
+// are in X5C, Subject is the NATS subject the command was sent to and Nonce and
+// IssuedAt (Unix seconds) protect the command from being replayed. Urgent commands
+// run outside the maintenance windows
+type Header struct {
+	Alg      string   `json:"alg"`
+	X5C      []string `json:"x5c"`
+	Subject  string   `json:"sub"`
+	Nonce    string   `json:"nonce,omitempty"`
+	IssuedAt int64    `json:"iat,omitempty"`
+	Urgent   bool     `json:"urgent,omitempty"`
+}
+
+type Signer struct {
+	Certificate *x509.Certificate
+	Header      Header
+}
+
+// Name identifies the signer in logs and audit entries
+func (s *Signer) Name() string {
+	if s.Certificate.Subject.CommonName != "" {
+		return s.Certificate.Subject.CommonName
+	}
+	return s.Certificate.Subject.String()
+}
+
+func ValidPolicy(policy string) error {
+	switch policy {
+	case POLICY_OFF, POLICY_WARN, POLICY_ENFORCE:
+		return nil
+	}
+	return fmt.Errorf("%q is not a valid signature policy, use %s, %s or %s", policy, POLICY_OFF, POLICY_WARN, POLICY_ENFORCE)
+}
+
+// ParsePolicies reads a list of handler=policy pairs separated by commas, e.g. agent.reboot=enforce,agent.ansible=warn
+func ParsePolicies(value string) (map[string]string, error) {
+	policies := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, policy, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%q must be a handler=policy pair", pair)
+		}
+		policy = strings.TrimSpace(policy)
+		if err := ValidPolicy(policy); err != nil {
+			return nil, err
+		}
+		policies[strings.TrimSpace(name)] = policy
+	}
+	return policies, nil
+}
+
+// Verify checks that signature is a valid JWS of payload for subject made with a
+// certificate allowed by opts
+func Verify(signature string, subject string, payload []byte, opts VerifyOptions) (*Signer, error) {
+	if signature == "" {
+		return nil, ErrMissing
+	}
+	if opts.CA == nil {
+		return nil, errors.New("the CA certificate is not available to check the signature")
+	}
+
+	parts := strings.Split(signature, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("the signature is not a JWS in compact serialization")
+	}
+	if parts[1] != "" {
+		return nil, errors.New("the signature must have a detached payload")
+	}
+
+	protected, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the JWS header, reason: %v", err)
+	}
+	h := Header{}
+	if err := json.Unmarshal(protected, &h); err != nil {
+		return nil, fmt.Errorf("could not decode the JWS header, reason: %v", err)
+	}
+	if h.Subject != subject {
+		return nil, fmt.Errorf("the command was signed for %q and not for %q", h.Subject, subject)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the JWS signature, reason: %v", err)
+	}
+
+	cert, err := verifyChain(h.X5C, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	input := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload)
+	if err := verifySignature(h.Alg, cert.PublicKey, []byte(input), sig); err != nil {
+		return nil, err
+	}
+
+	return &Signer{Certificate: cert, Header: h}, nil
+}
+
+// ParseFingerprints reads a list of SHA-256 certificate fingerprints in hex separated by commas,
+// the colons of the fingerprints shown by openssl are allowed
+func ParseFingerprints(value string) ([]string, error) {
+	fingerprints := []string{}
+	for _, f := range strings.Split(value, ",") {
+		f = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(f), ":", ""))
+		if f == "" {
+			continue
+		}
+		if decoded, err := hex.DecodeString(f); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("%q is not a SHA-256 certificate fingerprint", f)
+		}
+		fingerprints = append(fingerprints, f)
+	}
+	return fingerprints, nil
+}
+
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func verifyChain(x5c []string, opts VerifyOptions) (*x509.Certificate, error) {
+	if len(x5c) == 0 {
+		return nil, errors.New("the JWS header has no signing certificate")
+	}
+
+	certs := []*x509.Certificate{}
+	for _, encoded := range x5c {
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode the signing certificate, reason: %v", err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the signing certificate, reason: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(opts.CA)
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+
+	leaf := certs[0]
+	if err := signingIdentity(leaf); err != nil {
+		return nil, err
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}}); err != nil {
+		return nil, fmt.Errorf("the signing certificate is not valid, reason: %v", err)
+	}
+
+	if len(opts.Fingerprints) > 0 && !slices.Contains(opts.Fingerprints, Fingerprint(leaf)) {
+		return nil, fmt.Errorf("the signing certificate %s is not one of the allowed signers", Fingerprint(leaf))
+	}
+	return leaf, nil
+}
+
+// signingIdentity accepts code signing certificates only. Certificates without extended key
+// usages are valid for any usage so they are rejected as well as those with TLS usages like the
+// agent, SFTP and remote desktop certificates
+func signingIdentity(leaf *x509.Certificate) error {
+	if leaf.KeyUsage != 0 && leaf.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return errors.New("the signing certificate can't be used for digital signatures")
+	}
+	if !slices.Contains(leaf.ExtKeyUsage, x509.ExtKeyUsageCodeSigning) {
+		return errors.New("the signing certificate is not a code signing certificate")
+	}
+	for _, usage := range leaf.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageAny || usage == x509.ExtKeyUsageClientAuth || usage == x509.ExtKeyUsageServerAuth {
+			return errors.New("agent and server certificates can't sign commands")
+		}
+	}
+	return nil
+}
+
+func verifySignature(alg string, pub any, input []byte, sig []byte) error {
+	invalid := errors.New("the signature doesn't match the command")
+
+	switch alg {
+	case "RS256", "PS256":
+		k, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s requires an RSA certificate", alg)
+		}
+		digest := sha256.Sum256(input)
+		var err error
+		if alg == "RS256" {
+			err = rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig)
+		} else {
+			err = rsa.VerifyPSS(k, crypto.SHA256, digest[:], sig, nil)
+		}
+		if err != nil {
+			return invalid
+		}
+	case "ES256", "ES384":
+		k, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s requires an ECDSA certificate", alg)
+		}
+		digest, size := ecdsaDigest(alg, input)
+		if len(sig) != 2*size {
+			return invalid
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return invalid
+		}
+	case "EdDSA":
+		k, ok := pub.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s requires an Ed25519 certificate", alg)
+		}
+		if !ed25519.Verify(k, input, sig) {
+			return invalid
+		}
+	default:
+		return fmt.Errorf("the %q algorithm is not supported", alg)
+	}
+	return nil
+}
+
+func ecdsaDigest(alg string, input []byte) ([]byte, int) {
+	if alg == "ES384" {
+		digest := sha512.Sum384(input)
+		return digest[:], 48
+	}
+	digest := sha256.Sum256(input)
+	return digest[:], 32
+}
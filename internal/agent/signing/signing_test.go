@@ -0,0 +1,239 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+const subject = "agent.reboot.0d0c3b5e-5a1f-4a4c-9a44-1b6b9c7b2a11"
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newCert(t *testing.T, name string, parent *testCert, template x509.Certificate) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template.SerialNumber = serial
+	template.Subject = pkix.Name{CommonName: name}
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now().Add(-time.Hour)
+	}
+	if template.NotAfter.IsZero() {
+		template.NotAfter = time.Now().Add(time.Hour)
+	}
+
+	signer, signerKey := &template, crypto.Signer(key)
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+func newCA(t *testing.T, name string) *testCert {
+	return newCert(t, name, nil, x509.Certificate{IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign})
+}
+
+func leafTemplate(usages ...x509.ExtKeyUsage) x509.Certificate {
+	return x509.Certificate{KeyUsage: x509.KeyUsageDigitalSignature, ExtKeyUsage: usages}
+}
+
+// sign creates a JWS with detached payload the same way the console does
+func sign(t *testing.T, signer *testCert, h Header, payload []byte) string {
+	t.Helper()
+
+	h.Alg = "ES256"
+	h.X5C = []string{base64.StdEncoding.EncodeToString(signer.cert.Raw)}
+	protected, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(protected)
+
+	digest := sha256.Sum256([]byte(encoded + "." + base64.RawURLEncoding.EncodeToString(payload)))
+	r, s, err := ecdsa.Sign(rand.Reader, signer.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	return encoded + ".." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerify(t *testing.T) {
+	ca := newCA(t, "OpenUEM CA")
+	otherCA := newCA(t, "Other CA")
+	console := newCert(t, "console", ca, leafTemplate(x509.ExtKeyUsageCodeSigning))
+	agent := newCert(t, "agent", ca, leafTemplate(x509.ExtKeyUsageClientAuth))
+	server := newCert(t, "sftp", ca, leafTemplate(x509.ExtKeyUsageServerAuth))
+	anyUsage := newCert(t, "any", ca, leafTemplate())
+	mixed := newCert(t, "mixed", ca, leafTemplate(x509.ExtKeyUsageCodeSigning, x509.ExtKeyUsageClientAuth))
+	untrusted := newCert(t, "console", otherCA, leafTemplate(x509.ExtKeyUsageCodeSigning))
+	expired := newCert(t, "console", ca, x509.Certificate{
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		NotBefore:   time.Now().Add(-48 * time.Hour),
+		NotAfter:    time.Now().Add(-24 * time.Hour),
+	})
+
+	payload := []byte(`{"date":"2026-10-17T22:00:00Z"}`)
+	valid := sign(t, console, Header{Subject: subject}, payload)
+	attached := strings.Replace(valid, "..", "."+base64.RawURLEncoding.EncodeToString(payload)+".", 1)
+
+	tests := []struct {
+		name      string
+		signature string
+		subject   string
+		payload   []byte
+		opts      VerifyOptions
+		err       string
+	}{
+		{name: "valid", signature: valid, opts: VerifyOptions{CA: ca.cert}},
+		{name: "pinned signer", signature: valid, opts: VerifyOptions{CA: ca.cert, Fingerprints: []string{Fingerprint(console.cert)}}},
+		{name: "not pinned signer", signature: valid, opts: VerifyOptions{CA: ca.cert, Fingerprints: []string{Fingerprint(agent.cert)}}, err: "not one of the allowed signers"},
+		{name: "missing", signature: "", opts: VerifyOptions{CA: ca.cert}, err: ErrMissing.Error()},
+		{name: "no CA", signature: valid, opts: VerifyOptions{}, err: "CA certificate is not available"},
+		{name: "tampered payload", signature: valid, payload: []byte(`{"date":"2026-10-17T09:00:00Z"}`), opts: VerifyOptions{CA: ca.cert}, err: "doesn't match"},
+		{name: "other subject", signature: valid, subject: "agent.poweroff.0d0c3b5e-5a1f-4a4c-9a44-1b6b9c7b2a11", opts: VerifyOptions{CA: ca.cert}, err: "was signed for"},
+		{name: "attached payload", signature: attached, opts: VerifyOptions{CA: ca.cert}, err: "detached payload"},
+		{name: "agent certificate", signature: sign(t, agent, Header{Subject: subject}, payload), opts: VerifyOptions{CA: ca.cert}, err: "not a code signing certificate"},
+		{name: "server certificate", signature: sign(t, server, Header{Subject: subject}, payload), opts: VerifyOptions{CA: ca.cert}, err: "not a code signing certificate"},
+		{name: "certificate for any usage", signature: sign(t, anyUsage, Header{Subject: subject}, payload), opts: VerifyOptions{CA: ca.cert}, err: "not a code signing certificate"},
+		{name: "code signing and TLS certificate", signature: sign(t, mixed, Header{Subject: subject}, payload), opts: VerifyOptions{CA: ca.cert}, err: "can't sign commands"},
+		{name: "untrusted chain", signature: sign(t, untrusted, Header{Subject: subject}, payload), opts: VerifyOptions{CA: ca.cert}, err: "not valid"},
+		{name: "expired certificate", signature: sign(t, expired, Header{Subject: subject}, payload), opts: VerifyOptions{CA: ca.cert}, err: "not valid"},
+		{name: "not a JWS", signature: "signature", opts: VerifyOptions{CA: ca.cert}, err: "compact serialization"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.subject == "" {
+				tt.subject = subject
+			}
+			if tt.payload == nil {
+				tt.payload = payload
+			}
+
+			signer, err := Verify(tt.signature, tt.subject, tt.payload, tt.opts)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if signer.Name() != "console" {
+					t.Errorf("signer is %q, want console", signer.Name())
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error is %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseFingerprints(t *testing.T) {
+	fingerprint := strings.Repeat("ab", 32)
+	withColons := strings.TrimSuffix(strings.Repeat("AB:", 32), ":")
+
+	tests := []struct {
+		value string
+		want  int
+		err   bool
+	}{
+		{value: "", want: 0},
+		{value: fingerprint, want: 1},
+		{value: fingerprint + ", " + withColons, want: 2},
+		{value: "abcd", err: true},
+		{value: strings.Repeat("zz", 32), err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFingerprints(tt.value)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseFingerprints(%q) error = %v", tt.value, err)
+			continue
+		}
+		if len(got) != tt.want {
+			t.Errorf("ParseFingerprints(%q) = %v, want %d fingerprints", tt.value, got, tt.want)
+		}
+		for _, f := range got {
+			if f != fingerprint {
+				t.Errorf("ParseFingerprints(%q) = %v, want lowercase fingerprints without colons", tt.value, got)
+			}
+		}
+	}
+}
+
+func TestParsePolicies(t *testing.T) {
+	tests := []struct {
+		value string
+		want  map[string]string
+		err   bool
+	}{
+		{value: "", want: map[string]string{}},
+		{value: "agent.reboot=enforce, agent.ansible=warn", want: map[string]string{"agent.reboot": POLICY_ENFORCE, "agent.ansible": POLICY_WARN}},
+		{value: "agent.reboot", err: true},
+		{value: "agent.reboot=always", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePolicies(tt.value)
+		if (err != nil) != tt.err {
+			t.Errorf("ParsePolicies(%q) error = %v", tt.value, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("ParsePolicies(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("ParsePolicies(%q)[%s] = %q, want %q", tt.value, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestVerifyUrgentClaim(t *testing.T) {
+	ca := newCA(t, "OpenUEM CA")
+	console := newCert(t, "console", ca, leafTemplate(x509.ExtKeyUsageCodeSigning))
+	payload := []byte(`{}`)
+
+	for _, urgent := range []bool{false, true} {
+		signature := sign(t, console, Header{Subject: subject, Urgent: urgent}, payload)
+		signer, err := Verify(signature, subject, payload, VerifyOptions{CA: ca.cert})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signer.Header.Urgent != urgent {
+			t.Errorf("urgent claim is %v, want %v", signer.Header.Urgent, urgent)
+		}
+	}
+}
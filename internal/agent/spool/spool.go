@@ -0,0 +1,218 @@
+package spool
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+const DEFAULT_MAX_SIZE_MB = 50
+const DEFAULT_MAX_AGE_HOURS = 168
+
+// Spool keeps the reports that could not be sent to the NATS server. Reports
+// are keyed by their execution time so they can be replayed in order
+type Spool struct {
+	mu       sync.Mutex
+	replayMu sync.Mutex
+	DB       *badger.DB
+	MaxSize  int64
+	MaxAge   time.Duration
+}
+
+func New(path string, maxSizeMB int, maxAgeHours int) (*Spool, error) {
+	if maxSizeMB <= 0 {
+		maxSizeMB = DEFAULT_MAX_SIZE_MB
+	}
+
+	if maxAgeHours <= 0 {
+		maxAgeHours = DEFAULT_MAX_AGE_HOURS
+	}
+
+	opts := badger.DefaultOptions(path)
+	opts.Logger = nil
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Spool{
+		DB:      db,
+		MaxSize: int64(maxSizeMB) * 1024 * 1024,
+		MaxAge:  time.Duration(maxAgeHours) * time.Hour,
+	}, nil
+}
+
+func (s *Spool) Close() error {
+	return s.DB.Close()
+}
+
+// Push stores the report payload. Entries expire after MaxAge and the oldest
+// entries are evicted if the spool grows beyond MaxSize
+func (s *Spool) Push(executionTime time.Time, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if int64(len(data)) > s.MaxSize {
+		return errors.New("report is bigger than the spool max size")
+	}
+
+	ttl := s.MaxAge - time.Since(executionTime)
+	if ttl <= 0 {
+		return errors.New("report is older than the spool max age")
+	}
+
+	if err := s.DB.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry(spoolKey(executionTime), data).WithTTL(ttl))
+	}); err != nil {
+		return err
+	}
+
+	return s.evict()
+}
+
+// Replay sends the stored reports from the oldest to the newest. It stops at
+// the first report that can't be sent so order is kept for the next replay.
+// The spool isn't locked while a report is sent, so new reports can be pushed
+// meanwhile, they're sent in the next replay
+func (s *Spool) Replay(send func(data []byte) error) (int, error) {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+
+	s.mu.Lock()
+	keys, err := s.keys()
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, key := range keys {
+		data, err := s.get(key)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			// The report has expired or has been evicted since the keys were read
+			continue
+		}
+		if err != nil {
+			return sent, err
+		}
+
+		if err := send(data); err != nil {
+			return sent, err
+		}
+
+		if err := s.DB.Update(func(txn *badger.Txn) error {
+			return txn.Delete(key)
+		}); err != nil {
+			return sent, err
+		}
+
+		sent++
+	}
+	return sent, nil
+}
+
+func (s *Spool) Len() int {
+	count := 0
+
+	if err := s.DB.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			count++
+		}
+		return nil
+	}); err != nil {
+		slog.Error("could not count reports in spool", "error", err)
+	}
+
+	return count
+}
+
+func (s *Spool) keys() ([][]byte, error) {
+	keys := [][]byte{}
+
+	err := s.DB.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			keys = append(keys, it.Item().KeyCopy(nil))
+		}
+		return nil
+	})
+
+	return keys, err
+}
+
+func (s *Spool) get(key []byte) ([]byte, error) {
+	var data []byte
+
+	err := s.DB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+
+		data, err = item.ValueCopy(nil)
+		return err
+	})
+
+	return data, err
+}
+
+func (s *Spool) evict() error {
+	keys := [][]byte{}
+	sizes := []int64{}
+	total := int64(0)
+
+	if err := s.DB.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			keys = append(keys, item.KeyCopy(nil))
+			sizes = append(sizes, item.ValueSize())
+			total += item.ValueSize()
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	discarded := 0
+	err := s.DB.Update(func(txn *badger.Txn) error {
+		for i := 0; i < len(keys) && total > s.MaxSize; i++ {
+			if err := txn.Delete(keys[i]); err != nil {
+				return err
+			}
+			total -= sizes[i]
+			discarded++
+		}
+		return nil
+	})
+	if err == nil && discarded > 0 {
+		slog.Warn(fmt.Sprintf("report spool is full, the %d oldest reports have been discarded", discarded))
+	}
+	return err
+}
+
+// Big endian keys keep badger's iteration in chronological order
+func spoolKey(executionTime time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(executionTime.UnixNano()))
+	return key
+}
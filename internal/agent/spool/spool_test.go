@@ -0,0 +1,134 @@
+package spool
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSpool(t *testing.T) *Spool {
+	t.Helper()
+
+	s, err := New(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestPush(t *testing.T) {
+	tests := []struct {
+		name          string
+		executionTime time.Time
+		size          int
+		err           bool
+	}{
+		{name: "stored", executionTime: time.Now(), size: 10},
+		{name: "older than max age", executionTime: time.Now().Add(-DEFAULT_MAX_AGE_HOURS * time.Hour), size: 10, err: true},
+		{name: "bigger than max size", executionTime: time.Now(), size: DEFAULT_MAX_SIZE_MB*1024*1024 + 1, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSpool(t)
+			err := s.Push(tt.executionTime, make([]byte, tt.size))
+			if (err != nil) != tt.err {
+				t.Fatalf("Push() error = %v", err)
+			}
+
+			want := 1
+			if tt.err {
+				want = 0
+			}
+			if s.Len() != want {
+				t.Errorf("spool has %d reports, want %d", s.Len(), want)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	errUnreachable := errors.New("NATS is not reachable")
+
+	tests := []struct {
+		name    string
+		failAt  int
+		sent    []string
+		pending int
+	}{
+		{name: "all sent in order", failAt: -1, sent: []string{"first", "second", "third"}},
+		{name: "first fails", failAt: 0, pending: 3},
+		{name: "stops at the failed report", failAt: 1, sent: []string{"first"}, pending: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSpool(t)
+
+			// Pushed out of order, the execution time decides the order of the replay
+			now := time.Now()
+			reports := map[string]time.Time{
+				"third":  now,
+				"first":  now.Add(-2 * time.Minute),
+				"second": now.Add(-time.Minute),
+			}
+			for data, executionTime := range reports {
+				if err := s.Push(executionTime, []byte(data)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			calls := 0
+			sent := []string{}
+			n, err := s.Replay(func(data []byte) error {
+				defer func() { calls++ }()
+				if calls == tt.failAt {
+					return errUnreachable
+				}
+				sent = append(sent, string(data))
+				return nil
+			})
+			if (err != nil) != (tt.failAt >= 0) {
+				t.Fatalf("Replay() error = %v", err)
+			}
+			if n != len(tt.sent) || strings.Join(sent, ",") != strings.Join(tt.sent, ",") {
+				t.Errorf("Replay() sent %d reports %v, want %v", n, sent, tt.sent)
+			}
+			if s.Len() != tt.pending {
+				t.Errorf("spool has %d reports, want %d", s.Len(), tt.pending)
+			}
+		})
+	}
+}
+
+func TestPushWhileReplaying(t *testing.T) {
+	s := newSpool(t)
+	now := time.Now()
+	if err := s.Push(now.Add(-time.Minute), []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.Replay(func(data []byte) error {
+			// A report produced while the spool is being sent must not wait for the send
+			return s.Push(now, []byte("new"))
+		})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Replay() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Push() blocked while the spool was being replayed")
+	}
+
+	if s.Len() != 1 {
+		t.Errorf("spool has %d reports, want the one pushed during the replay", s.Len())
+	}
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/open-uem/openuem-agent/internal/agent/status"
+	openuem_utils "github.com/open-uem/utils"
+)
+
+func (a *Agent) StartStatusServer() {
+	var err error
+
+	mux := status.NewMux(a.GetStatus)
+	a.RegisterOutboxRoutes(mux)
+
+	socketPath := status.SocketPath(a.Config.DataDir)
+	a.StatusServer, err = status.Serve(socketPath, mux)
+	if err != nil {
+		slog.Error("could not start the status server", "error", err)
+		return
+	}
+	slog.Info(fmt.Sprintf("status server is listening on %s", socketPath))
+}
+
+func (a *Agent) GetStatus() *status.AgentStatus {
+	c := a.currentConfig()
+	s := status.AgentStatus{
+		AgentID:     c.UUID,
+		SFTPRunning: a.Status.SFTPRunning(),
+		Jobs:        []status.Job{},
+	}
+
+	if a.NATSConnection != nil && a.NATSConnection.IsConnected() {
+		s.NATSConnected = true
+		s.NATSServer = a.NATSConnection.ConnectedUrlRedacted()
+	}
+
+	s.LastReport, s.LastReportError = a.Status.LastReport()
+
+	for _, j := range a.TaskScheduler.Jobs() {
+		nextRun, err := j.NextRun()
+		if err != nil {
+			continue
+		}
+		s.Jobs = append(s.Jobs, status.Job{Name: j.Name(), NextRun: nextRun})
+	}
+
+	if a.DeployOutbox != nil {
+		if entries, err := a.DeployOutbox.Pending(); err == nil {
+			s.PendingACKs = len(entries)
+		}
+		if entries, err := a.DeployOutbox.DeadLetters(); err == nil {
+			s.DeadLetters = len(entries)
+		}
+	}
+
+	s.VNCProxyRunning = a.RemoteDesktop != nil && a.RemoteDesktop.RequiresVNCProxy
+
+	certificates := map[string]string{
+		"agent.cer":  c.AgentCert,
+		"ca.cer":     c.CACert,
+		"sftp.cer":   c.SFTPCert,
+		"server.cer": serverCertificatePath(c.DataDir),
+	}
+	for _, name := range []string{"agent.cer", "ca.cer", "sftp.cer", "server.cer"} {
+		certificate := status.Certificate{Name: name, Path: certificates[name]}
+		cert, err := openuem_utils.ReadPEMCertificate(certificate.Path)
+		if err != nil {
+			certificate.Error = "could not read certificate"
+		} else {
+			certificate.NotAfter = cert.NotAfter
+		}
+		s.Certificates = append(s.Certificates, certificate)
+	}
+
+	return &s
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin
+
+package status
+
+import "os"
+
+// secureDir leaves the socket folder only to the user running the agent, so the socket
+// can't be reached before its own permissions are set
+func secureDir(dir string) error {
+	return os.Chmod(dir, 0700)
+}
+
+// secure leaves the socket only to the user running the agent
+func secure(socketPath string) error {
+	return os.Chmod(socketPath, 0600)
+}
@@ -0,0 +1,44 @@
+//go:build windows
+
+package status
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// SOCKET_SDDL gives full control to SYSTEM and the Administrators group only,
+// inherited permissions are removed
+const SOCKET_SDDL = "D:P(A;;GA;;;SY)(A;;GA;;;BA)"
+
+// SOCKET_DIR_SDDL is SOCKET_SDDL inherited by the files created in the folder
+const SOCKET_DIR_SDDL = "D:P(A;OICI;GA;;;SY)(A;OICI;GA;;;BA)"
+
+// secureDir replaces the ACL of the socket folder, so the socket is protected from the start
+func secureDir(dir string) error {
+	return setACL(dir, SOCKET_DIR_SDDL)
+}
+
+// secure replaces the ACL of the socket, file permissions are ignored on Windows
+func secure(socketPath string) error {
+	return setACL(socketPath, SOCKET_SDDL)
+}
+
+func setACL(path string, sddl string) error {
+	sd, err := windows.SecurityDescriptorFromString(sddl)
+	if err != nil {
+		return fmt.Errorf("could not parse the socket security descriptor, reason: %v", err)
+	}
+
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return fmt.Errorf("could not get the socket ACL, reason: %v", err)
+	}
+
+	if err := windows.SetNamedSecurityInfo(path, windows.SE_FILE_OBJECT,
+		windows.DACL_SECURITY_INFORMATION|windows.PROTECTED_DACL_SECURITY_INFORMATION, nil, nil, dacl, nil); err != nil {
+		return fmt.Errorf("could not set the ACL of %s, reason: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type Job struct {
+	Name    string    `json:"name"`
+	NextRun time.Time `json:"next_run"`
+}
+
+type Certificate struct {
+	Name     string    `json:"name"`
+	Path     string    `json:"path"`
+	NotAfter time.Time `json:"not_after,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type AgentStatus struct {
+	AgentID         string        `json:"agent_id"`
+	NATSConnected   bool          `json:"nats_connected"`
+	NATSServer      string        `json:"nats_server,omitempty"`
+	LastReport      time.Time     `json:"last_report,omitempty"`
+	LastReportError string        `json:"last_report_error,omitempty"`
+	Jobs            []Job         `json:"jobs"`
+	PendingACKs     int           `json:"pending_acks"`
+	DeadLetters     int           `json:"dead_letters"`
+	SFTPRunning     bool          `json:"sftp_running"`
+	VNCProxyRunning bool          `json:"vnc_proxy_running"`
+	Certificates    []Certificate `json:"certificates"`
+}
+
+// Tracker keeps the state that can't be read from the agent at any time
+type Tracker struct {
+	mu              sync.Mutex
+	lastReport      time.Time
+	lastReportError string
+	sftpRunning     bool
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+func (t *Tracker) ReportSent(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.lastReport = time.Now()
+	t.lastReportError = ""
+	if err != nil {
+		t.lastReportError = err.Error()
+	}
+}
+
+func (t *Tracker) LastReport() (time.Time, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.lastReport, t.lastReportError
+}
+
+func (t *Tracker) SetSFTPRunning(running bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sftpRunning = running
+}
+
+func (t *Tracker) SFTPRunning() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.sftpRunning
+}
+
+// NewMux returns the routes served on the status socket, other local commands can add theirs
+func NewMux(getStatus func() *AgentStatus) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(getStatus()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	return mux
+}
+
+// SOCKET_FOLDER keeps the socket in a folder of its own inside the data folder
+const SOCKET_FOLDER = "run"
+
+// SocketPath is in the data folder, so a rootless agent can create it and several
+// agents on the same host don't replace each other's socket
+func SocketPath(dataDir string) string {
+	return filepath.Join(dataDir, SOCKET_FOLDER, "openuem-agent.sock")
+}
+
+// Serve exposes the agent status on a Unix socket that only the user running the agent,
+// or the administrators on Windows, can use
+func Serve(socketPath string, handler http.Handler) (*http.Server, error) {
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0700); err != nil {
+		return nil, err
+	}
+	if err := secureDir(filepath.Dir(socketPath)); err != nil {
+		return nil, err
+	}
+
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := secure(socketPath); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "status server stopped, reason: %v\n", err)
+		}
+	}()
+
+	return server, nil
+}
+
+// NewClient returns an HTTP client that talks to the agent over its Unix socket
+func NewClient(socketPath string) *http.Client {
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+}
+
+func Query(socketPath string) (*AgentStatus, error) {
+	resp, err := NewClient(socketPath).Get("http://openuem-agent/status")
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to the agent, is the service running? reason: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("agent returned %s", resp.Status)
+	}
+
+	s := AgentStatus{}
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (s *AgentStatus) Print() {
+	fmt.Printf("\n** 🕵  Agent status *************************************************************************************************\n")
+	fmt.Printf("%-40s |  %s\n", "Agent ID", s.AgentID)
+	fmt.Printf("%-40s |  %t\n", "NATS connected", s.NATSConnected)
+	fmt.Printf("%-40s |  %s\n", "NATS server", s.NATSServer)
+	if s.LastReport.IsZero() {
+		fmt.Printf("%-40s |  %s\n", "Last report", "no report has been sent yet")
+	} else {
+		fmt.Printf("%-40s |  %s\n", "Last report", s.LastReport.Local().Format(time.RFC1123))
+		if s.LastReportError != "" {
+			fmt.Printf("%-40s |  failed, %s\n", "Last report result", s.LastReportError)
+		} else {
+			fmt.Printf("%-40s |  %s\n", "Last report result", "sent")
+		}
+	}
+	fmt.Printf("%-40s |  %d\n", "Pending ACKs", s.PendingACKs)
+	fmt.Printf("%-40s |  %d\n", "Dead letters", s.DeadLetters)
+	fmt.Printf("%-40s |  %t\n", "SFTP server running", s.SFTPRunning)
+	fmt.Printf("%-40s |  %t\n", "VNC proxy running", s.VNCProxyRunning)
+
+	fmt.Printf("\n** ⏰ Scheduled jobs ************************************************************************************************\n")
+	if len(s.Jobs) == 0 {
+		fmt.Printf("%-40s\n", "No jobs scheduled")
+	}
+	for _, j := range s.Jobs {
+		fmt.Printf("%-40s |  next run %s\n", j.Name, j.NextRun.Local().Format(time.RFC1123))
+	}
+
+	fmt.Printf("\n** 🔐 Certificates **************************************************************************************************\n")
+	for _, c := range s.Certificates {
+		if c.Error != "" {
+			fmt.Printf("%-40s |  %s\n", c.Name, c.Error)
+			continue
+		}
+		fmt.Printf("%-40s |  expires %s\n", c.Name, c.NotAfter.Local().Format(time.RFC1123))
+	}
+}
@@ -0,0 +1,46 @@
+package status
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	dataDir := t.TempDir()
+	socketPath := SocketPath(dataDir)
+
+	server, err := Serve(socketPath, NewMux(func() *AgentStatus { return &AgentStatus{AgentID: "agent"} }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	s, err := Query(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.AgentID != "agent" {
+		t.Errorf("agent id is %q, want agent", s.AgentID)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	for path, want := range map[string]os.FileMode{filepath.Dir(socketPath): 0700, socketPath: 0600} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != want {
+			t.Errorf("%s has permissions %v, want %v", path, info.Mode().Perm(), want)
+		}
+	}
+}
+
+func TestSocketPathPerDataDir(t *testing.T) {
+	if SocketPath("/var/lib/agent-a") == SocketPath("/var/lib/agent-b") {
+		t.Error("two agents with their own data folder share the status socket")
+	}
+}
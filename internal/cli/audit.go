@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/open-uem/openuem-agent/internal/agent"
+	"github.com/open-uem/openuem-agent/internal/agent/audit"
+)
+
+func auditCommand(args []string) int {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the entries and the verification as JSON")
+	verify := fs.Bool("verify", false, "only check that the audit log hasn't been tampered with")
+	limit := fs.Int("limit", audit.DEFAULT_QUERY_LIMIT, "number of entries to show")
+	since := fs.Duration("since", 0, "show only the entries of the last duration, e.g. 24h")
+	handler := fs.String("handler", "", "show only the entries of handlers starting with this name, e.g. agent.netbird")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	c, err := agent.LoadConfig()
+	if err != nil && !errors.As(err, &agent.ConfigErrors{}) {
+		c = agent.DefaultConfig()
+	}
+	path := agent.AuditLogPath(c.DataDir)
+
+	r := agent.AuditResponse{Verification: agent.VerifyAuditLog(c)}
+	if !*verify {
+		q := audit.Query{Limit: *limit, Handler: *handler}
+		if *since > 0 {
+			q.Since = time.Now().Add(-*since)
+		}
+		r.Entries, err = audit.Read(path, q)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not read the audit log %s: %v\n", path, err)
+			return 1
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			fmt.Fprintf(os.Stderr, "could not encode the audit log: %v\n", err)
+			return 1
+		}
+	} else {
+		printAudit(r, *verify)
+	}
+
+	if !r.Verification.Valid {
+		return 1
+	}
+	return 0
+}
+
+func printAudit(r agent.AuditResponse, verifyOnly bool) {
+	if !verifyOnly {
+		if len(r.Entries) == 0 {
+			fmt.Println("There are no audit entries")
+		}
+
+		for _, e := range r.Entries {
+			fmt.Printf("%-40s |  %d\n", "Entry", e.Seq)
+			fmt.Printf("%-40s |  %s\n", "Time", e.Time.Local().Format(time.RFC1123))
+			fmt.Printf("%-40s |  %s\n", "Subject", e.Subject)
+			for k, v := range e.Requester {
+				fmt.Printf("%-40s |  %s: %s\n", "Requester", k, v)
+			}
+			if len(e.Parameters) > 0 {
+				fmt.Printf("%-40s |  %s\n", "Parameters", e.Parameters)
+			}
+			if e.Error != "" {
+				fmt.Printf("%-40s |  %s: %s\n", "Result", e.Result, e.Error)
+			} else {
+				fmt.Printf("%-40s |  %s\n", "Result", e.Result)
+			}
+			fmt.Printf("%-40s |  %d ms\n\n", "Duration", e.DurationMs)
+		}
+	}
+
+	v := r.Verification
+	if v.Valid {
+		fmt.Printf("The audit log has %d entries and its chain is intact\n", v.Entries)
+		if v.Unverified > 0 {
+			fmt.Printf("%d entries were written before the agent certificate was renewed and can't be verified\n", v.Unverified)
+		}
+		if v.Discarded > 0 {
+			fmt.Printf("%d older entries have been removed when the audit log was rotated\n", v.Discarded)
+		}
+		return
+	}
+	if v.BrokenAt == 0 {
+		fmt.Printf("The audit log could not be verified: %s\n", v.Error)
+		return
+	}
+	fmt.Printf("The audit log has been tampered with at entry %d: %s\n", v.BrokenAt, v.Error)
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+)
+
+// Run executes a command from the agent's binary instead of starting the
+// service and returns the exit code
+func Run(args []string) int {
+	switch args[0] {
+	case "status":
+		return statusCommand(args[1:])
+	case "outbox":
+		return outboxCommand(args[1:])
+	case "config":
+		return configCommand(args[1:])
+	case "report":
+		return reportCommand(args[1:])
+	case "doctor":
+		return doctorCommand(args[1:])
+	case "audit":
+		return auditCommand(args[1:])
+	case "help", "-h", "--help":
+		usage()
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", args[0])
+		usage()
+		return 2
+	}
+}
+
+func usage() {
+	fmt.Println("Usage: openuem-agent [--setting=value...] [command]")
+	fmt.Println("")
+	fmt.Println("Without a command the agent service is started.")
+	fmt.Println("")
+	fmt.Println("Commands:")
+	fmt.Printf("  %-20s %s\n", "status", "show the status of the running agent")
+	fmt.Printf("  %-20s %s\n", "outbox", "list and retry the deployment results that could not be delivered")
+	fmt.Printf("  %-20s %s\n", "config", "check the agent's configuration and show where each value comes from")
+	fmt.Printf("  %-20s %s\n", "report", "run a report and print it, use --json for the full report as JSON, --wire for the compressed payload or --section to run only one part")
+	fmt.Printf("  %-20s %s\n", "doctor", "check certificates, NATS servers, ports, tools and folders, use --json for the result")
+	fmt.Printf("  %-20s %s\n", "audit", "show the remote actions carried out by the agent and check the audit log, use --verify to only check it")
+	fmt.Printf("  %-20s %s\n", "help", "show this help")
+	fmt.Println("")
+	fmt.Println("Any setting can be overridden with a flag before the command, e.g. --nats-servers=host:port,")
+	fmt.Println("or an environment variable, e.g. OPENUEM_NATS_SERVERS. These values are never saved to openuem.ini.")
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/open-uem/openuem-agent/internal/agent"
+	openuem_utils "github.com/open-uem/utils"
+)
+
+func configCommand(args []string) int {
+	if len(args) == 0 {
+		configUsage()
+		return 2
+	}
+
+	switch args[0] {
+	case "validate":
+		return configValidateCommand(args[1:])
+	case "show":
+		return configShowCommand(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown config command: %s\n\n", args[0])
+		configUsage()
+		return 2
+	}
+}
+
+func configUsage() {
+	fmt.Println("Usage: openuem-agent config [validate|show]")
+	fmt.Println("")
+	fmt.Printf("  %-20s %s\n", "validate [--json]", "check the config file and show every problem found")
+	fmt.Printf("  %-20s %s\n", "show [--json]", "show the value used for each setting and where it comes from")
+}
+
+func configValidateCommand(args []string) int {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the problems as JSON")
+	file := fs.String("file", openuem_utils.GetAgentConfigFile(), "the config file to check")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	problems := agent.ConfigErrors{}
+	if _, err := agent.LoadConfigFile(*file); err != nil && !errors.As(err, &problems) {
+		fmt.Fprintf(os.Stderr, "could not validate %s: %v\n", *file, err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(problems); err != nil {
+			fmt.Fprintf(os.Stderr, "could not encode the problems: %v\n", err)
+			return 1
+		}
+	} else if len(problems) == 0 {
+		fmt.Printf("%s is valid\n", *file)
+	} else {
+		fmt.Printf("%s has %d problems:\n", *file, len(problems))
+		for _, p := range problems {
+			fmt.Printf("  %s\n", p)
+		}
+	}
+
+	// Warnings don't stop the agent, their settings use the default value
+	if problems.Fatal() != nil {
+		return 1
+	}
+	return 0
+}
+
+func configShowCommand(args []string) int {
+	fs := flag.NewFlagSet("config show", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the settings as JSON")
+	file := fs.String("file", openuem_utils.GetAgentConfigFile(), "the config file to read")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	problems := agent.ConfigErrors{}
+	values, err := agent.EffectiveConfig(*file)
+	if err != nil && !errors.As(err, &problems) {
+		fmt.Fprintf(os.Stderr, "could not read %s: %v\n", *file, err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(values); err != nil {
+			fmt.Fprintf(os.Stderr, "could not encode the settings: %v\n", err)
+			return 1
+		}
+	} else {
+		for _, v := range values {
+			fmt.Printf("%-40s |  %-8s %s\n", "["+v.Section+"] "+v.Key, v.Source, v.Value)
+		}
+	}
+
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "\n%s has %d problems, run config validate to see them\n", *file, len(problems))
+	}
+	if problems.Fatal() != nil {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/open-uem/openuem-agent/internal/agent"
+	"github.com/open-uem/openuem-agent/internal/agent/doctor"
+	"github.com/open-uem/openuem-agent/internal/agent/status"
+)
+
+func doctorCommand(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the result as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	c, err := agent.LoadConfig()
+	if err != nil && !errors.As(err, &agent.ConfigErrors{}) {
+		c = agent.DefaultConfig()
+	}
+	o := agent.DoctorOptions(c, err)
+
+	// If the service is running its ports are in use by the agent itself
+	if s, err := status.Query(status.SocketPath(c.DataDir)); err == nil {
+		o.SFTPListening = s.SFTPRunning
+		o.VNCListening = s.VNCProxyRunning
+	}
+
+	r := doctor.Run(o)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			fmt.Fprintf(os.Stderr, "could not encode the result: %v\n", err)
+			return 1
+		}
+	} else {
+		r.Print()
+	}
+
+	if r.Failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/open-uem/openuem-agent/internal/agent/outbox"
+	"github.com/open-uem/openuem-agent/internal/agent/status"
+)
+
+func outboxCommand(args []string) int {
+	if len(args) == 0 {
+		outboxUsage()
+		return 2
+	}
+
+	switch args[0] {
+	case "list":
+		return outboxListCommand(args[1:])
+	case "retry":
+		return outboxRetryCommand(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown outbox command: %s\n\n", args[0])
+		outboxUsage()
+		return 2
+	}
+}
+
+func outboxUsage() {
+	fmt.Println("Usage: openuem-agent outbox [list|retry]")
+	fmt.Println("")
+	fmt.Printf("  %-20s %s\n", "list [--json]", "show the deployment results that could not be delivered")
+	fmt.Printf("  %-20s %s\n", "retry <id>|--all", "send again one or all of the dead letters")
+}
+
+func outboxListCommand(args []string) int {
+	fs := flag.NewFlagSet("outbox list", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the dead letters as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	resp, err := status.NewClient(socketPath()).Get("http://openuem-agent/outbox/dead")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not connect to the agent, is the service running? reason: %v\n", err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "could not get the dead letters: %s\n", readError(resp))
+		return 1
+	}
+
+	entries := []outbox.Entry{}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		fmt.Fprintf(os.Stderr, "could not decode the dead letters: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(entries); err != nil {
+			fmt.Fprintf(os.Stderr, "could not encode the dead letters: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("There are no dead letters")
+		return 0
+	}
+
+	for _, e := range entries {
+		fmt.Printf("%-40s |  %s\n", "ID", e.ID)
+		fmt.Printf("%-40s |  %s %s (%s)\n", "Deployment", e.Action.Action, e.Action.PackageName, e.Action.PackageId)
+		fmt.Printf("%-40s |  %s\n", "Created", e.CreatedAt.Local().Format(time.RFC1123))
+		fmt.Printf("%-40s |  %d\n", "Attempts", e.Attempts)
+		fmt.Printf("%-40s |  %s\n\n", "Last error", e.LastError)
+	}
+	return 0
+}
+
+func outboxRetryCommand(args []string) int {
+	fs := flag.NewFlagSet("outbox retry", flag.ContinueOnError)
+	all := fs.Bool("all", false, "retry all the dead letters")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	id := fs.Arg(0)
+	if (id == "") == !*all {
+		fmt.Fprintln(os.Stderr, "use either an entry id or --all")
+		return 2
+	}
+
+	resp, err := status.NewClient(socketPath()).Post("http://openuem-agent/outbox/retry?id="+url.QueryEscape(id), "", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not connect to the agent, is the service running? reason: %v\n", err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "could not retry the dead letters: %s\n", readError(resp))
+		return 1
+	}
+
+	result := map[string]int{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		fmt.Fprintf(os.Stderr, "could not decode the agent response: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("%d dead letters will be sent again\n", result["retried"])
+	return 0
+}
+
+func readError(resp *http.Response) string {
+	data, err := io.ReadAll(resp.Body)
+	if err != nil || len(data) == 0 {
+		return resp.Status
+	}
+	return string(data)
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/open-uem/openuem-agent/internal/agent"
+	"github.com/open-uem/openuem-agent/internal/agent/payload"
+	"github.com/open-uem/openuem-agent/internal/commands/report"
+)
+
+func reportCommand(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the full report as JSON, the agent may send a delta report instead and compresses it")
+	wire := fs.Bool("wire", false, "write the full report as it's sent to the server, compressed with the Compression setting. Big payloads are also split in chunks")
+	section := fs.String("section", "", "run only this section of the report")
+	verbose := fs.Bool("verbose", false, "show the messages logged by the collectors")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	c, err := agent.LoadConfig()
+	if err != nil {
+		problems := agent.ConfigErrors{}
+		if errors.As(err, &problems) {
+			fmt.Fprintf(os.Stderr, "the config file has %d problems, run config validate to see them\n", len(problems))
+		} else {
+			fmt.Fprintf(os.Stderr, "could not read the agent's config, the report won't have the agent's settings: %v\n", err)
+		}
+	}
+
+	var r *report.Report
+	if *section != "" {
+		r, err = report.RunSection(*section, c.Debug)
+	} else {
+		r, err = agent.CollectReport(c)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not run the report: %v\n", err)
+		return 1
+	}
+
+	if *asJSON || *wire {
+		// This is what the agent sends in a full report before the payload is compressed
+		data, err := json.Marshal(r)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not encode the report: %v\n", err)
+			return 1
+		}
+		if *asJSON {
+			fmt.Println(string(data))
+			return 0
+		}
+		return writeWirePayload(data, c.Compression)
+	}
+
+	if *section != "" {
+		r.PrintSection(*section)
+	} else {
+		r.Print()
+	}
+	return 0
+}
+
+// writeWirePayload writes the payload compressed as the agent does and tells the encoding in stderr
+func writeWirePayload(data []byte, compression string) int {
+	encoding := payload.Encoding(data, compression)
+	if encoding == "" {
+		encoding = payload.ENCODING_NONE
+	}
+
+	body, err := payload.Compress(data, encoding)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not compress the report: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s, %d bytes (%d bytes uncompressed)\n", payload.ENCODING_HEADER, encoding, len(body), len(data))
+
+	if _, err := os.Stdout.Write(body); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write the report: %v\n", err)
+		return 1
+	}
+	return 0
+}
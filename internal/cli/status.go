@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/open-uem/openuem-agent/internal/agent"
+	"github.com/open-uem/openuem-agent/internal/agent/status"
+)
+
+// socketPath finds the status socket of the agent, on Windows it's in the data folder
+func socketPath() string {
+	c, err := agent.LoadConfig()
+	if err != nil && !errors.As(err, &agent.ConfigErrors{}) {
+		c = agent.DefaultConfig()
+	}
+	return status.SocketPath(c.DataDir)
+}
+
+func statusCommand(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the status as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	s, err := status.Query(socketPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not get the agent status: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(s); err != nil {
+			fmt.Fprintf(os.Stderr, "could not encode the agent status: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	s.Print()
+	return 0
+}
@@ -3,7 +3,7 @@
 package deploy
 
 import (
-	"log"
+	"log/slog"
 	"runtime"
 	"strings"
 
@@ -23,7 +23,7 @@ func InstallPackage(action nats.DeployAction, keepUpdated bool, debug bool) (str
 		isCask = true
 	}
 
-	log.Printf("[INFO]: received a request to install package %s using brew", action.PackageId)
+	slog.Info("received a request to install package using brew", "package_id", action.PackageId)
 
 	brewPath := getBrewPath()
 
@@ -35,17 +35,17 @@ func InstallPackage(action nats.DeployAction, keepUpdated bool, debug bool) (str
 
 	username, err := openuem_runtime.GetLoggedInUser()
 	if err != nil {
-		log.Printf("[ERROR]: could not find the logged in user, reason %v", err)
+		slog.Error("could not find the logged in user", "error", err)
 		return "", "", err
 	}
 
 	out, err := openuem_runtime.RunAsUserWithOutput(username, brewPath, args, false)
 	if err != nil {
-		log.Printf("[ERROR]: found and error with brew install command, reason %s", string(out))
+		slog.Error("found and error with brew install command", "output", string(out))
 		return "", string(out), err
 	}
 
-	log.Printf("[INFO]: brew has installed an application: %s", action.PackageId)
+	slog.Info("brew has installed an application", "package_id", action.PackageId)
 
 	return "", "", nil
 }
@@ -62,7 +62,7 @@ func UpdatePackage(action nats.DeployAction) (string, string, error) {
 	if action.PackageBrewType == "cask" {
 		isCask = true
 	}
-	log.Printf("[INFO]: received a request to upgrade package %s", action.PackageId)
+	slog.Info("received a request to upgrade package", "package_id", action.PackageId)
 
 	brewPath := getBrewPath()
 
@@ -74,17 +74,17 @@ func UpdatePackage(action nats.DeployAction) (string, string, error) {
 
 	username, err := openuem_runtime.GetLoggedInUser()
 	if err != nil {
-		log.Printf("[ERROR]: could not find the logged in user, reason %v", err)
+		slog.Error("could not find the logged in user", "error", err)
 		return "", "", err
 	}
 
 	out, err := openuem_runtime.RunAsUserWithOutput(username, brewPath, args, false)
 	if err != nil {
-		log.Printf("[ERROR]: found and error with brew upgrade command, reason %s", string(out))
+		slog.Error("found and error with brew upgrade command", "output", string(out))
 		return "", string(out), err
 	}
 
-	log.Printf("[INFO]: brew has updated an application: %s", action.PackageId)
+	slog.Info("brew has updated an application", "package_id", action.PackageId)
 
 	return "", "", nil
 }
@@ -101,7 +101,7 @@ func UninstallPackage(action nats.DeployAction) (string, string, error) {
 	if action.PackageBrewType == "cask" {
 		isCask = true
 	}
-	log.Printf("[INFO]: received a request to remove package %s using brew", action.PackageId)
+	slog.Info("received a request to remove package using brew", "package_id", action.PackageId)
 
 	brewPath := getBrewPath()
 
@@ -113,17 +113,17 @@ func UninstallPackage(action nats.DeployAction) (string, string, error) {
 
 	username, err := openuem_runtime.GetLoggedInUser()
 	if err != nil {
-		log.Printf("[ERROR]: could not find the logged in user, reason %v", err)
+		slog.Error("could not find the logged in user", "error", err)
 		return "", "", err
 	}
 
 	out, err := openuem_runtime.RunAsUserWithOutput(username, brewPath, args, false)
 	if err != nil {
-		log.Printf("[ERROR]: found and error with brew remove command, reason %s", string(out))
+		slog.Error("found and error with brew remove command", "output", string(out))
 		return "", string(out), err
 	}
 
-	log.Printf("[INFO]: brew has removed an application: %s", action.PackageId)
+	slog.Info("brew has removed an application", "package_id", action.PackageId)
 
 	return "", "", nil
 }
@@ -4,7 +4,7 @@ package deploy
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os/exec"
 
 	"github.com/open-uem/nats"
@@ -12,11 +12,11 @@ import (
 )
 
 func InstallPackage(action nats.DeployAction, keepUpdated bool, debug bool) (string, string, error) {
-	log.Printf("[INFO]: received a request to install package %s", action.PackageId)
+	slog.Info("received a request to install package", "package_id", action.PackageId)
 
 	cmd := "flatpak remote-add --if-not-exists flathub https://flathub.org/repo/flathub.flatpakrepo"
 	if err := exec.Command("bash", "-c", cmd).Run(); err != nil {
-		log.Printf("[ERROR]: could not start flatpak remote-add command, reason: %v", err)
+		slog.Error("could not start flatpak remote-add command", "error", err)
 		return "", "", err
 	}
 
@@ -26,22 +26,22 @@ func InstallPackage(action nats.DeployAction, keepUpdated bool, debug bool) (str
 	}
 
 	if out, err := runtime.RunAsUserWithOutput("root", "flatpak", []string{"install", "--noninteractive", "--assumeyes", "flathub", packageRef}, true); err != nil {
-		log.Printf("[ERROR]: found and error with flatpak install command, reason %v", err)
+		slog.Error("found and error with flatpak install command", "error", err)
 		return "", string(out), err
 	}
 
-	log.Printf("[INFO]: flatpak has installed an application: %s", packageRef)
+	slog.Info(fmt.Sprintf("flatpak has installed an application: %s", packageRef))
 
 	return "", "", nil
 }
 
 func UpdatePackage(action nats.DeployAction) (string, string, error) {
-	log.Printf("[INFO]: received a request to update package %s", action.PackageId)
+	slog.Info("received a request to update package", "package_id", action.PackageId)
 
 	cmd := "flatpak remote-add --if-not-exists flathub https://flathub.org/repo/flathub.flatpakrepo"
 
 	if err := exec.Command("bash", "-c", cmd).Run(); err != nil {
-		log.Printf("[ERROR]: could not start flatpak remote-add command, reason: %v", err)
+		slog.Error("could not start flatpak remote-add command", "error", err)
 		return "", "", err
 	}
 
@@ -51,21 +51,21 @@ func UpdatePackage(action nats.DeployAction) (string, string, error) {
 	}
 
 	if out, err := runtime.RunAsUserWithOutput("root", "flatpak", []string{"update", "--noninteractive", "--assumeyes", packageRef}, true); err != nil {
-		log.Printf("[ERROR]: found and error with flatpak update command, reason %v", err)
+		slog.Error("found and error with flatpak update command", "error", err)
 		return "", string(out), err
 	}
 
-	log.Printf("[INFO]: flatpak has updated an application %s", action.PackageId)
+	slog.Info("flatpak has updated an application", "package_id", action.PackageId)
 
 	return "", "", nil
 }
 
 func UninstallPackage(action nats.DeployAction) (string, string, error) {
-	log.Printf("[INFO]: received a request to remove package %s using flatpak", action.PackageId)
+	slog.Info("received a request to remove package using flatpak", "package_id", action.PackageId)
 
 	cmd := "flatpak remote-add --if-not-exists flathub https://flathub.org/repo/flathub.flatpakrepo"
 	if err := exec.Command("bash", "-c", cmd).Run(); err != nil {
-		log.Printf("[ERROR]: could not start flatpak remote-add command, reason: %v", err)
+		slog.Error("could not start flatpak remote-add command", "error", err)
 		return "", "", err
 	}
 
@@ -75,11 +75,11 @@ func UninstallPackage(action nats.DeployAction) (string, string, error) {
 	}
 
 	if out, err := runtime.RunAsUserWithOutput("root", "flatpak", []string{"remove", "--noninteractive", "--assumeyes", packageRef}, true); err != nil {
-		log.Printf("[ERROR]: found and error with flatpak remove command, reason %v", err)
+		slog.Error("found and error with flatpak remove command", "error", err)
 		return "", string(out), err
 	}
 
-	log.Printf("[INFO]: flatpak has removed an application %s", packageRef)
+	slog.Info(fmt.Sprintf("flatpak has removed an application %s", packageRef))
 
 	return "", "", nil
 }
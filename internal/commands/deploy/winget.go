@@ -6,7 +6,7 @@ import (
 	"bytes"
 	"fmt"
 	"io/fs"
-	"log"
+	"log/slog"
 	"os/exec"
 	"path/filepath"
 	"slices"
@@ -25,11 +25,11 @@ func InstallPackage(action nats.DeployAction, keepUpdated bool, debug bool) (str
 
 	wgPath, err := locateWinGet()
 	if err != nil {
-		log.Printf("[ERROR]: could not locate the winget.exe command %v", err)
+		slog.Error("could not locate the winget.exe command", "error", err)
 		return "", "", err
 	}
 
-	log.Printf("[INFO]: received a request to install package %s using winget", action.PackageId)
+	slog.Info("received a request to install package using winget", "package_id", action.PackageId)
 
 	// Fix 194: Remove spinner, blank lines and progress bar from output
 	// Ref: https://github.com/microsoft/winget-cli/issues/3494#issuecomment-1933874691
@@ -47,22 +47,20 @@ func InstallPackage(action nats.DeployAction, keepUpdated bool, debug bool) (str
 
 	err = cmd.Start()
 	if err != nil {
-		log.Printf("[ERROR]: could not start winget.exe command %v", err)
+		slog.Error("could not start winget.exe command", "error", err)
 		return "", "", err
 	}
 
 	err = runtime.SetPriorityWindows(cmd.Process.Pid, windows.IDLE_PRIORITY_CLASS)
 	if err != nil {
-		log.Println("[ERROR]: could not change process priority")
+		slog.Error("could not change process priority")
 	}
 
-	if debug {
-		log.Printf("[DEBUG]: winget.exe is installing an app, using command %s %s %s %s %s %s %s %s\n", wgPath, "install", action.PackageId, "--scope", "machine", "--silent", "--accept-package-agreements", "--accept-source-agreements")
-	}
+	slog.Debug(fmt.Sprintf("winget.exe is installing an app, using command %s %s %s %s %s %s %s", wgPath, "install", "--scope", "machine", "--silent", "--accept-package-agreements", "--accept-source-agreements"), "package_id", action.PackageId)
 	err = cmd.Wait()
 	if err != nil {
 		if _, ok := err.(*exec.ExitError); !ok {
-			log.Printf("[ERROR]: there was an error running winget.exe: %v", err)
+			slog.Error("there was an error running winget.exe", "error", err)
 			return "", "", err
 		}
 		errCode := strings.ReplaceAll(strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(err.Error(), "exit status "))), "0X", "0x")
@@ -73,16 +71,16 @@ func InstallPackage(action nats.DeployAction, keepUpdated bool, debug bool) (str
 
 		// Package is already installed and no applicable update is found
 		if errCode == "0x8A15002B" {
-			log.Printf("[INFO]: %s cannot be updated. %s", action.PackageId, errMessage)
+			slog.Info("cannot be updated.", "package_id", action.PackageId, "error", errMessage)
 			if !keepUpdated {
 				return "", "", nil
 			}
 		}
 
-		log.Printf("[ERROR]: there was an error running winget.exe: %v", errMessage)
+		slog.Error("there was an error running winget.exe", "error", errMessage)
 		return stdout.String(), stderr.String(), nil
 	}
-	log.Printf("[INFO]: winget.exe has installed an application: %s", action.PackageId)
+	slog.Info("winget.exe has installed an application", "package_id", action.PackageId)
 
 	return stdout.String(), stderr.String(), nil
 }
@@ -93,7 +91,7 @@ func UpdatePackage(action nats.DeployAction) (string, string, error) {
 
 	wgPath, err := locateWinGet()
 	if err != nil {
-		log.Printf("[ERROR]: could not locate the winget.exe command %v", err)
+		slog.Error("could not locate the winget.exe command", "error", err)
 		return "", "", err
 	}
 
@@ -109,20 +107,20 @@ func UpdatePackage(action nats.DeployAction) (string, string, error) {
 
 	err = cmd.Start()
 	if err != nil {
-		log.Printf("[ERROR]: could not start winget.exe command %v", err)
+		slog.Error("could not start winget.exe command", "error", err)
 		return "", "", err
 	}
 
 	err = runtime.SetPriorityWindows(cmd.Process.Pid, windows.IDLE_PRIORITY_CLASS)
 	if err != nil {
-		log.Println("[ERROR]: could not change process priority")
+		slog.Error("could not change process priority")
 	}
 
-	log.Printf("[INFO]: winget.exe is upgrading an app, using command %s %s %s %s %s %s %s %s\n", wgPath, "install", action.PackageId, "--scope", "machine", "--silent", "--accept-package-agreements", "--accept-source-agreements")
+	slog.Info(fmt.Sprintf("winget.exe is upgrading an app, using command %s %s %s %s %s %s %s", wgPath, "install", "--scope", "machine", "--silent", "--accept-package-agreements", "--accept-source-agreements"), "package_id", action.PackageId)
 	err = cmd.Wait()
 	if err != nil {
 		if _, ok := err.(*exec.ExitError); !ok {
-			log.Printf("[ERROR]: there was an error waiting for winget.exe to finish %v", err)
+			slog.Error("there was an error waiting for winget.exe to finish", "error", err)
 			return "", "", err
 		}
 
@@ -132,10 +130,10 @@ func UpdatePackage(action nats.DeployAction) (string, string, error) {
 			errMessage = err.Error()
 		}
 
-		log.Printf("[ERROR]: there was an error waiting for winget.exe to finish %v", errMessage)
+		slog.Error("there was an error waiting for winget.exe to finish", "error", errMessage)
 		return stdout.String(), stderr.String(), nil
 	}
-	log.Println("[INFO]: winget.exe has upgraded an application", wgPath)
+	slog.Info(fmt.Sprintf("winget.exe has upgraded an application %v", wgPath))
 
 	return stdout.String(), stderr.String(), nil
 }
@@ -144,11 +142,11 @@ func UninstallPackage(action nats.DeployAction) (string, string, error) {
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 
-	log.Printf("[INFO]: received a request to remove package %s using winget", action.PackageId)
+	slog.Info("received a request to remove package using winget", "package_id", action.PackageId)
 
 	wgPath, err := locateWinGet()
 	if err != nil {
-		log.Printf("[ERROR]: could not locate the winget.exe command %v", err)
+		slog.Error("could not locate the winget.exe command", "error", err)
 		return "", "", err
 	}
 
@@ -163,20 +161,20 @@ func UninstallPackage(action nats.DeployAction) (string, string, error) {
 	cmd.Stderr = &stderr
 	err = cmd.Start()
 	if err != nil {
-		log.Printf("[ERROR]: could not start winget.exe command %v", err)
+		slog.Error("could not start winget.exe command", "error", err)
 		return "", "", err
 	}
 
 	err = runtime.SetPriorityWindows(cmd.Process.Pid, windows.IDLE_PRIORITY_CLASS)
 	if err != nil {
-		log.Println("[ERROR]: could not change process priority")
+		slog.Error("could not change process priority")
 	}
 
-	log.Printf("[INFO]: winget.exe is uninstalling the app %s\n", action.PackageId)
+	slog.Info("winget.exe is uninstalling the app", "package_id", action.PackageId)
 	err = cmd.Wait()
 	if err != nil {
 		if _, ok := err.(*exec.ExitError); !ok {
-			log.Printf("[ERROR]: there was an error running winget.exe: %v", err)
+			slog.Error("there was an error running winget.exe", "error", err)
 			return "", "", err
 		}
 		errCode := strings.ReplaceAll(strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(err.Error(), "exit status "))), "0X", "0x")
@@ -186,14 +184,14 @@ func UninstallPackage(action nats.DeployAction) (string, string, error) {
 		}
 
 		if errCode == "0x8A150014" {
-			log.Printf("[INFO]: %s cannot be uninstalled. %s", action.PackageId, errMessage)
+			slog.Info("cannot be uninstalled.", "package_id", action.PackageId, "error", errMessage)
 			return stdout.String(), stderr.String(), nil
 		}
 
-		log.Printf("[ERROR]: there was an error running winget.exe: %v", errMessage)
+		slog.Error("there was an error running winget.exe", "error", errMessage)
 		return stdout.String(), stderr.String(), nil
 	}
-	log.Println("[INFO]: winget.exe has uninstalled an application")
+	slog.Info("winget.exe has uninstalled an application")
 
 	return stdout.String(), stderr.String(), nil
 }
@@ -239,7 +237,7 @@ func GetExplicitelyDeletedPackages(deployments []string, installed string) []str
 func GetWinGetInstalledPackagesList() (string, error) {
 	wgPath, err := locateWinGet()
 	if err != nil {
-		log.Printf("[ERROR]: could not locate the winget.exe command %v", err)
+		slog.Error("could not locate the winget.exe command", "error", err)
 		return "", err
 	}
 
@@ -252,9 +250,7 @@ func GetWinGetInstalledPackagesList() (string, error) {
 }
 
 func RemovePackagesFromCfg(cfg *wingetcfg.WinGetCfg, explicitelyDeleted []string, exclusions []string, installed string, debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: Installed packages ", installed)
-	}
+	slog.Debug(fmt.Sprintf("Installed packages %v", installed))
 
 	validResources := []*wingetcfg.WinGetResource{}
 	for _, r := range cfg.Properties.Resources {
@@ -264,9 +260,7 @@ func RemovePackagesFromCfg(cfg *wingetcfg.WinGetCfg, explicitelyDeleted []string
 			isAlreadyInstalled := strings.Contains(installed, r.Settings["id"].(string))
 			isInstallAction := r.Settings["Ensure"].(string) == "Present"
 
-			if debug {
-				log.Printf("[DEBUG]: Package %s, Is installed? %t, Excluded? %t, Explicitely Deleted %t,", r.Settings["id"], isAlreadyInstalled, isPackageExcluded, isPackageExplicitelyDeleted)
-			}
+			slog.Debug(fmt.Sprintf("Package %s, Is installed? %t, Excluded? %t, Explicitely Deleted %t", r.Settings["id"], isAlreadyInstalled, isPackageExcluded, isPackageExplicitelyDeleted))
 
 			if !isPackageExcluded && !isPackageExplicitelyDeleted &&
 				((isInstallAction && !isAlreadyInstalled) || (!isInstallAction && isAlreadyInstalled)) {
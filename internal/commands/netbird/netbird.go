@@ -2,7 +2,7 @@ package netbird
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 
 	"github.com/nats-io/nats.go"
 	openuem_nats "github.com/open-uem/nats"
@@ -12,11 +12,11 @@ import (
 func Respond(msg *nats.Msg, n *openuem_nats.Netbird) {
 	data, err := json.Marshal(n)
 	if err != nil {
-		log.Printf("[ERROR]: could not marshal NetBird action response, reason: %v\n", err)
+		slog.Error("could not marshal NetBird action response", "error", err)
 	}
 
 	if err := msg.Respond(data); err != nil {
-		log.Printf("[ERROR]: could not respond to NetBird action message, reason: %v\n", err)
+		slog.Error("could not respond to NetBird action message", "error", err)
 		return
 	}
 }
@@ -24,3 +24,12 @@ func Respond(msg *nats.Msg, n *openuem_nats.Netbird) {
 func RefreshInfo(data []byte) (*openuem_nats.Netbird, error) {
 	return report.RetrieveNetbirdInfo()
 }
+
+// ErrorResponse encodes an error as the NetBird response the console expects
+func ErrorResponse(err error) []byte {
+	data, mErr := json.Marshal(openuem_nats.Netbird{Error: err.Error()})
+	if mErr != nil {
+		slog.Error("could not marshal NetBird action response", "error", mErr)
+	}
+	return data
+}
@@ -7,7 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"strings"
@@ -26,7 +26,7 @@ func Install() (*openuem_nats.Netbird, error) {
 
 	out, err := c1.CombinedOutput()
 	if err != nil {
-		log.Printf("[ERROR]: could not install the NetBird client, reason: %s", string(out))
+		slog.Error("could not install the NetBird client", "output", string(out))
 		return nil, errors.New(string(out))
 	}
 
@@ -65,7 +65,7 @@ func Uninstall() error {
 
 	out, err := c1.CombinedOutput()
 	if err != nil {
-		log.Printf("[ERROR]: could not install the NetBird client, reason: %s", string(out))
+		slog.Error("could not install the NetBird client", "output", string(out))
 		return errors.New(string(out))
 	}
 
@@ -83,7 +83,7 @@ func SwitchProfile(request openuem_nats.NetbirdSettings) (*openuem_nats.Netbird,
 		args := []string{"-c", command}
 		out, err := runtime.RunAsUserWithOutputAndTimeout(username, "bash", args, true, 2*time.Minute)
 		if err != nil {
-			log.Printf("[ERROR]: could not switch NetBird profile, reason: %s", string(out))
+			slog.Error("could not switch NetBird profile", "output", string(out))
 			return nil, err
 		}
 	}
@@ -94,7 +94,7 @@ func SwitchProfile(request openuem_nats.NetbirdSettings) (*openuem_nats.Netbird,
 func Register(data []byte) (*openuem_nats.Netbird, error) {
 	request := openuem_nats.NetbirdSettings{}
 	if err := json.Unmarshal(data, &request); err != nil {
-		log.Printf("[ERROR]: could not unmarshal the NetBird register request, reason: %v", err)
+		slog.Error("could not unmarshal the NetBird register request", "error", err)
 		return nil, err
 	}
 
@@ -102,7 +102,7 @@ func Register(data []byte) (*openuem_nats.Netbird, error) {
 
 	// First, we must set the connection down
 	if err := exec.Command(bin, "down", "--management-url", request.ManagementURL).Run(); err != nil {
-		log.Println("[ERROR]: could not execute netbird down")
+		slog.Error("could not execute netbird down")
 		return nil, err
 	}
 
@@ -115,14 +115,14 @@ func Register(data []byte) (*openuem_nats.Netbird, error) {
 
 		out, err := exec.CommandContext(ctx, "bash", "-c", command).CombinedOutput()
 		if err != nil {
-			log.Printf("[ERROR]: could not execute netbird up, reason: %s", string(out))
+			slog.Error("could not execute netbird up", "output", string(out))
 			return nil, err
 		}
 	} else {
 		args := []string{"-c", command}
 		out, err := runtime.RunAsUserWithOutputAndTimeout(username, "bash", args, true, 30*time.Second)
 		if err != nil {
-			log.Printf("[ERROR]: could not execute netbird up to register the client, reason: %s", string(out))
+			slog.Error("could not execute netbird up to register the client", "output", string(out))
 			return nil, err
 		}
 	}
@@ -133,7 +133,7 @@ func Register(data []byte) (*openuem_nats.Netbird, error) {
 func NetbirdUp(data []byte) (*openuem_nats.Netbird, error) {
 	request := openuem_nats.NetbirdSettings{}
 	if err := json.Unmarshal(data, &request); err != nil {
-		log.Printf("[ERROR]: could not unmarshal the NetBird request, reason: %v", err)
+		slog.Error("could not unmarshal the NetBird request", "error", err)
 		return nil, err
 	}
 
@@ -146,14 +146,14 @@ func NetbirdUp(data []byte) (*openuem_nats.Netbird, error) {
 
 		out, err := exec.CommandContext(ctx, "bash", "-c", command).CombinedOutput()
 		if err != nil {
-			log.Printf("[ERROR]: could not execute netbird up, reason: %s", string(out))
+			slog.Error("could not execute netbird up", "output", string(out))
 			return nil, err
 		}
 	} else {
 		args := []string{"-c", command}
 		out, err := runtime.RunAsUserWithOutputAndTimeout(username, "bash", args, true, 30*time.Second)
 		if err != nil {
-			log.Printf("[ERROR]: could not execute netbird up, reason: %s", string(out))
+			slog.Error("could not execute netbird up", "output", string(out))
 			return nil, err
 		}
 	}
@@ -164,7 +164,7 @@ func NetbirdUp(data []byte) (*openuem_nats.Netbird, error) {
 func NetbirdDown(data []byte) (*openuem_nats.Netbird, error) {
 	request := openuem_nats.NetbirdSettings{}
 	if err := json.Unmarshal(data, &request); err != nil {
-		log.Printf("[ERROR]: could not unmarshal the NetBird request, reason: %v", err)
+		slog.Error("could not unmarshal the NetBird request", "error", err)
 		return nil, err
 	}
 
@@ -177,14 +177,14 @@ func NetbirdDown(data []byte) (*openuem_nats.Netbird, error) {
 	if err != nil || username == "" {
 		out, err := exec.CommandContext(ctx, "bash", "-c", command).CombinedOutput()
 		if err != nil {
-			log.Printf("[ERROR]: could not execute netbird down, reason: %s", string(out))
+			slog.Error("could not execute netbird down", "output", string(out))
 			return nil, err
 		}
 	} else {
 		args := []string{"-c", command}
 		out, err := runtime.RunAsUserWithOutputAndTimeout(username, "bash", args, true, 60*time.Second)
 		if err != nil {
-			log.Printf("[ERROR]: could not execute netbird down, reason: %s", string(out))
+			slog.Error("could not execute netbird down", "output", string(out))
 			return nil, err
 		}
 	}
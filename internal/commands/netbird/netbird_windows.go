@@ -5,7 +5,7 @@ package netbird
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"os/exec"
 	"time"
 
@@ -21,7 +21,7 @@ func Install() (*openuem_nats.Netbird, error) {
 	}
 
 	if _, _, err := deploy.InstallPackage(action, false, false); err != nil {
-		log.Printf("[ERROR]: could not install the NetBird client, reason: %v", err)
+		slog.Error("could not install the NetBird client", "error", err)
 		return nil, err
 	}
 
@@ -34,7 +34,7 @@ func Uninstall() error {
 	}
 
 	if _, _, err := deploy.UninstallPackage(action); err != nil {
-		log.Printf("[ERROR]: could not uninstall the NetBird client, reason: %v", err)
+		slog.Error("could not uninstall the NetBird client", "error", err)
 		return err
 	}
 	return nil
@@ -51,7 +51,7 @@ func SwitchProfile(request openuem_nats.NetbirdSettings) (*openuem_nats.Netbird,
 
 		out, err := exec.CommandContext(ctx, netBirdBin, args...).CombinedOutput()
 		if err != nil {
-			log.Printf("[ERROR]: could not switch NetBird profile, reason: %s", string(out))
+			slog.Error("could not switch NetBird profile", "output", string(out))
 			return nil, err
 		}
 
@@ -61,21 +61,21 @@ func SwitchProfile(request openuem_nats.NetbirdSettings) (*openuem_nats.Netbird,
 		args = []string{"up"}
 		out, err = exec.CommandContext(ctx, netBirdBin, args...).CombinedOutput()
 		if err != nil {
-			log.Printf("[ERROR]: could not switch NetBird profile, reason: %s", string(out))
+			slog.Error("could not switch NetBird profile", "output", string(out))
 			return nil, err
 		}
 	} else {
 		args := []string{"profile", "select", request.Profile}
 		out, err := runtime.RunAsUserWithOutput(netBirdBin, args)
 		if err != nil {
-			log.Printf("[ERROR]: could not switch NetBird profile, reason: %s", string(out))
+			slog.Error("could not switch NetBird profile", "output", string(out))
 			return nil, err
 		}
 
 		args = []string{"up"}
 		out, err = runtime.RunAsUserWithOutputAndTimeout(netBirdBin, args, 60*time.Second)
 		if err != nil {
-			log.Printf("[ERROR]: could not switch NetBird profile, reason: %s", string(out))
+			slog.Error("could not switch NetBird profile", "output", string(out))
 			return nil, err
 		}
 	}
@@ -86,7 +86,7 @@ func SwitchProfile(request openuem_nats.NetbirdSettings) (*openuem_nats.Netbird,
 func Register(data []byte) (*openuem_nats.Netbird, error) {
 	request := openuem_nats.NetbirdSettings{}
 	if err := json.Unmarshal(data, &request); err != nil {
-		log.Printf("[ERROR]: could not unmarshal the NetBird register request, reason: %v", err)
+		slog.Error("could not unmarshal the NetBird register request", "error", err)
 		return nil, err
 	}
 
@@ -94,7 +94,7 @@ func Register(data []byte) (*openuem_nats.Netbird, error) {
 
 	// First, we must set the connection down
 	if err := exec.Command(bin, "down", "--management-url", request.ManagementURL).Run(); err != nil {
-		log.Println("[ERROR]: could not execute netbird down")
+		slog.Error("could not execute netbird down")
 		return nil, err
 	}
 
@@ -102,7 +102,7 @@ func Register(data []byte) (*openuem_nats.Netbird, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 	if err := exec.CommandContext(ctx, bin, "up", "--setup-key", request.OneOffKey, "--management-url", request.ManagementURL).Run(); err != nil {
-		log.Println("[ERROR]: could not execute netbird up")
+		slog.Error("could not execute netbird up")
 		return nil, err
 	}
 
@@ -112,7 +112,7 @@ func Register(data []byte) (*openuem_nats.Netbird, error) {
 func NetbirdUp(data []byte) (*openuem_nats.Netbird, error) {
 	request := openuem_nats.NetbirdSettings{}
 	if err := json.Unmarshal(data, &request); err != nil {
-		log.Printf("[ERROR]: could not unmarshal the NetBird request, reason: %v", err)
+		slog.Error("could not unmarshal the NetBird request", "error", err)
 		return nil, err
 	}
 
@@ -126,14 +126,14 @@ func NetbirdUp(data []byte) (*openuem_nats.Netbird, error) {
 
 		out, err := exec.CommandContext(ctx, netBirdBin, args...).CombinedOutput()
 		if err != nil {
-			log.Printf("[ERROR]: could not execute netbird up, reason: %s", string(out))
+			slog.Error("could not execute netbird up", "output", string(out))
 			return nil, err
 		}
 	} else {
 
 		out, err := runtime.RunAsUserWithOutputAndTimeout(netBirdBin, args, 60*time.Second)
 		if err != nil {
-			log.Printf("[ERROR]: could not execute netbird up, reason: %s", string(out))
+			slog.Error("could not execute netbird up", "output", string(out))
 			return nil, err
 		}
 	}
@@ -144,7 +144,7 @@ func NetbirdUp(data []byte) (*openuem_nats.Netbird, error) {
 func NetbirdDown(data []byte) (*openuem_nats.Netbird, error) {
 	request := openuem_nats.NetbirdSettings{}
 	if err := json.Unmarshal(data, &request); err != nil {
-		log.Printf("[ERROR]: could not unmarshal the NetBird request, reason: %v", err)
+		slog.Error("could not unmarshal the NetBird request", "error", err)
 		return nil, err
 	}
 
@@ -158,13 +158,13 @@ func NetbirdDown(data []byte) (*openuem_nats.Netbird, error) {
 
 		out, err := exec.CommandContext(ctx, netBirdBin, args...).CombinedOutput()
 		if err != nil {
-			log.Printf("[ERROR]: could not execute netbird down, reason: %s", string(out))
+			slog.Error("could not execute netbird down", "output", string(out))
 			return nil, err
 		}
 	} else {
 		out, err := runtime.RunAsUserWithOutputAndTimeout(netBirdBin, args, 60*time.Second)
 		if err != nil {
-			log.Printf("[ERROR]: could not execute netbird down, reason: %s", string(out))
+			slog.Error("could not execute netbird down", "output", string(out))
 			return nil, err
 		}
 	}
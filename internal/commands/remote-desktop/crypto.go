@@ -5,7 +5,8 @@ import (
 	"crypto/cipher"
 	"crypto/des"
 	"encoding/hex"
-	"log"
+
+	"github.com/open-uem/openuem-agent/internal/logger"
 )
 
 // Ref: https://stackoverflow.com/questions/41579325/golang-how-do-i-decrypt-with-des-cbc-and-pkcs7
@@ -13,17 +14,17 @@ func DESEncode(password string) []byte {
 	cipherKey := "e84ad660c4721ae0"
 	bCipherKey, err := hex.DecodeString(cipherKey)
 	if err != nil {
-		log.Fatalf("could not decode cipher key hex string")
+		logger.Fatal("could not decode cipher key hex string")
 	}
 
 	block, err := des.NewCipher(bCipherKey)
 	if err != nil {
-		log.Fatalf("could not create cipher block")
+		logger.Fatal("could not create cipher block")
 	}
 
 	bIV, err := hex.DecodeString("0000000000000000")
 	if err != nil {
-		log.Fatalf("could not decode IV hex string")
+		logger.Fatal("could not decode IV hex string")
 	}
 
 	// If password length is less than 8 characters we must padd byte(0) to the left
@@ -1,7 +1,9 @@
 package remotedesktop
 
 import (
-	"log"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
 	"time"
@@ -30,9 +32,15 @@ type RemoteDesktopService struct {
 }
 
 func New(certPath, keyPath, sid, proxyPort string) (*RemoteDesktopService, error) {
+	// The proxy accepts RSA, ECDSA and Ed25519 keys in PKCS#1, PKCS#8 or SEC 1 format,
+	// check them now so a bad key is reported before the VNC server is configured
+	if _, err := tls.LoadX509KeyPair(certPath, keyPath); err != nil {
+		return nil, fmt.Errorf("the server certificate can't be used by the VNC proxy, reason: %v", err)
+	}
+
 	agentOS := GetAgentOS()
 
-	server, err := GetSupportedRemoteDesktopService(agentOS, sid, proxyPort)
+	server, err := GetSupportedRemoteDesktopService(agentOS, sid, proxyPort, certPath, keyPath)
 	if err != nil {
 		return nil, err
 	}
@@ -49,7 +57,7 @@ func New(certPath, keyPath, sid, proxyPort string) (*RemoteDesktopService, error
 }
 
 func (rd *RemoteDesktopService) StartVNCProxy(port string) {
-	log.Printf("[INFO]: starting VNC proxy on port %s\n", rd.ProxyPort)
+	slog.Info(fmt.Sprintf("starting VNC proxy on port %s", rd.ProxyPort))
 	// Launch proxy only if port is available
 	_, err := net.DialTimeout("tcp", ":"+rd.ProxyPort, 5*time.Second)
 	if err != nil {
@@ -68,13 +76,13 @@ func (rd *RemoteDesktopService) StartVNCProxy(port string) {
 			return nil
 		})
 
-		log.Println("[INFO]: NoVNC proxy server started")
+		slog.Info("NoVNC proxy server started")
 
 		if err := rd.Proxy.StartTLS(":"+rd.ProxyPort, rd.ProxyCert, rd.ProxyKey); err != http.ErrServerClosed {
-			log.Printf("[ERROR]: could not start VNC proxy\n, %v", err)
+			slog.Error("could not start VNC proxy", "error", err)
 		}
 
 	} else {
-		log.Printf("[ERROR]: VNC proxy port %s is not available\n", rd.ProxyPort)
+		slog.Error(fmt.Sprintf("VNC proxy port %s is not available", rd.ProxyPort))
 	}
 }
@@ -5,7 +5,7 @@ package remotedesktop
 import (
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os/exec"
 
 	"github.com/open-uem/openuem-agent/internal/commands/runtime"
@@ -13,13 +13,13 @@ import (
 )
 
 func (rd *RemoteDesktopService) Start(pin string, notifyUser bool) {
-	log.Println("[INFO]: a request to start a remote desktop service has been received")
+	slog.Info("a request to start a remote desktop service has been received")
 
 	// Show PIN to user if needed
 	if notifyUser {
 		go func() {
 			if err := notifyPINToUser(pin); err != nil {
-				log.Printf("[ERROR]: could not show PIN message to user, reason: %v\n", err)
+				slog.Error("could not show PIN message to user", "error", err)
 				return
 			}
 		}()
@@ -27,14 +27,14 @@ func (rd *RemoteDesktopService) Start(pin string, notifyUser bool) {
 
 	// Configure Remote Desktop service
 	if err := rd.Configure(); err != nil {
-		log.Printf("[ERROR]: could not configure Remote Desktop service, reason: %v\n", err)
+		slog.Error("could not configure Remote Desktop service", "error", err)
 		return
 	}
-	log.Println("[INFO]: the remote desktop service has been configured")
+	slog.Info("the remote desktop service has been configured")
 
 	// Save PIN
 	if err := rd.SavePIN(pin); err != nil {
-		log.Printf("[ERROR]: could not save PIN before Remote Desktop service is started, reason: %v\n", err)
+		slog.Error("could not save PIN before Remote Desktop service is started", "error", err)
 		return
 	}
 
@@ -42,9 +42,9 @@ func (rd *RemoteDesktopService) Start(pin string, notifyUser bool) {
 
 	// Start Remote Desktop service
 	go func() {
-		log.Println("[INFO]: starting the remote desktop service...")
+		slog.Info("starting the remote desktop service...")
 		if err := rd.StartService(vncPort); err != nil {
-			log.Printf("[ERROR]: could not start Remote Desktop service, reason: %v", err)
+			slog.Error("could not start Remote Desktop service", "error", err)
 			return
 		}
 	}()
@@ -58,25 +58,25 @@ func (rd *RemoteDesktopService) Start(pin string, notifyUser bool) {
 func (rd *RemoteDesktopService) Stop() {
 	if rd.RequiresVNCProxy {
 		if err := rd.Proxy.Close(); err != nil {
-			log.Printf("[ERROR]: could not stop VNC proxy, reason: %v\n", err)
+			slog.Error("could not stop VNC proxy", "error", err)
 		} else {
-			log.Println("[INFO]: VNC proxy has been stopped")
+			slog.Info("VNC proxy has been stopped")
 		}
 	}
 
 	if err := rd.RemovePIN(); err != nil {
-		log.Printf("[ERROR]: could not remove remote desktop credentials, reason: %v", err)
+		slog.Error("could not remove remote desktop credentials", "error", err)
 	}
-	log.Println("[INFO]: the PIN for the remote desktop service has been removed")
+	slog.Info("the PIN for the remote desktop service has been removed")
 
 	// Stop gracefully Remote Desktop service
 	if err := rd.StopService(); err != nil {
-		log.Printf("[ERROR]: could not stop the remote desktop service, reason: %v", err)
+		slog.Error("could not stop the remote desktop service", "error", err)
 	}
-	log.Println("[INFO]: the remote desktop service has been stopped")
+	slog.Info("the remote desktop service has been stopped")
 }
 
-func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDesktopService, error) {
+func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort, certPath, keyPath string) (*RemoteDesktopService, error) {
 	supportedServers := map[string]RemoteDesktopService{
 		// Reference: https://community.hetzner.com/tutorials/how-to-enable-vnc-on-macos-via-ssh
 		"MacOS Remote Management": {
@@ -129,7 +129,7 @@ func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDe
 			RemovePIN: func() error {
 				pin, err := openuem_utils.GenerateRandomPIN(8)
 				if err != nil {
-					log.Printf("[ERROR]: could not generate random PIN, reason: %v\n", err)
+					slog.Error("could not generate random PIN", "error", err)
 					return err
 				}
 				command := fmt.Sprintf("/System/Library/CoreServices/RemoteManagement/ARDAgent.app/Contents/Resources/kickstart -configure -clientopts -setvncpw -vncpw %s", pin)
@@ -139,7 +139,7 @@ func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDe
 				}
 
 				if err != nil {
-					log.Printf("[ERROR]: could not generate random PIN, reason: %v\n", err)
+					slog.Error("could not generate random PIN", "error", err)
 					return err
 				}
 
@@ -152,7 +152,7 @@ func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDe
 					return errors.New("could not set VNC credentials")
 				}
 
-				log.Println("[INFO]: VNC credentials saved")
+				slog.Info("VNC credentials saved")
 				return nil
 			},
 		},
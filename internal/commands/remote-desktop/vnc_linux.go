@@ -6,7 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net"
 	"os"
 	"os/exec"
@@ -20,13 +20,13 @@ import (
 )
 
 func (rd *RemoteDesktopService) Start(pin string, notifyUser bool) {
-	log.Println("[INFO]: a request to start a remote desktop service has been received")
+	slog.Info("a request to start a remote desktop service has been received")
 
 	// Show PIN to user if needed
 	if notifyUser {
 		go func() {
 			if err := notifyPINToUser(pin); err != nil {
-				log.Printf("[ERROR]: could not show PIN message to user, reason: %v\n", err)
+				slog.Error("could not show PIN message to user", "error", err)
 				return
 			}
 		}()
@@ -34,14 +34,14 @@ func (rd *RemoteDesktopService) Start(pin string, notifyUser bool) {
 
 	// Configure Remote Desktop service
 	if err := rd.Configure(); err != nil {
-		log.Printf("[ERROR]: could not configure Remote Desktop service, reason: %v\n", err)
+		slog.Error("could not configure Remote Desktop service", "error", err)
 		return
 	}
-	log.Println("[INFO]: the remote desktop service has been configured")
+	slog.Info("the remote desktop service has been configured")
 
 	// Save PIN
 	if err := rd.SavePIN(pin); err != nil {
-		log.Printf("[ERROR]: could not save PIN before Remote Desktop service is started, reason: %v\n", err)
+		slog.Error("could not save PIN before Remote Desktop service is started", "error", err)
 		return
 	}
 
@@ -50,16 +50,16 @@ func (rd *RemoteDesktopService) Start(pin string, notifyUser bool) {
 	if rd.RequiresVNCProxy {
 		vncPort = getFirstVNCAvailablePort()
 		if vncPort == "" {
-			log.Println("[ERROR]: could not get a free port for VNC")
+			slog.Error("could not get a free port for VNC")
 			return
 		}
 	}
 
 	// Start Remote Desktop service
 	go func() {
-		log.Println("[INFO]: starting the remote desktop service...")
+		slog.Info("starting the remote desktop service...")
 		if err := rd.StartService(vncPort); err != nil {
-			log.Printf("[ERROR]: could not start Remote Desktop service, reason: %v", err)
+			slog.Error("could not start Remote Desktop service", "error", err)
 			return
 		}
 	}()
@@ -73,25 +73,25 @@ func (rd *RemoteDesktopService) Start(pin string, notifyUser bool) {
 func (rd *RemoteDesktopService) Stop() {
 	if rd.RequiresVNCProxy {
 		if err := rd.Proxy.Close(); err != nil {
-			log.Printf("[ERROR]: could not stop VNC proxy, reason: %v\n", err)
+			slog.Error("could not stop VNC proxy", "error", err)
 		} else {
-			log.Println("[INFO]: VNC proxy has been stopped")
+			slog.Info("VNC proxy has been stopped")
 		}
 	}
 
 	if err := rd.RemovePIN(); err != nil {
-		log.Printf("[ERROR]: could not remove remote desktop credentials, reason: %v", err)
+		slog.Error("could not remove remote desktop credentials", "error", err)
 	}
-	log.Println("[INFO]: the PIN for the remote desktop service has been removed")
+	slog.Info("the PIN for the remote desktop service has been removed")
 
 	// Stop gracefully Remote Desktop service
 	if err := rd.StopService(); err != nil {
-		log.Printf("[ERROR]: could not stop the remote desktop service, reason: %v", err)
+		slog.Error("could not stop the remote desktop service", "error", err)
 	}
-	log.Println("[INFO]: the remote desktop service has been stopped")
+	slog.Info("the remote desktop service has been stopped")
 }
 
-func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDesktopService, error) {
+func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort, certPath, keyPath string) (*RemoteDesktopService, error) {
 	// Get logged in username
 	username, err := runtime.GetLoggedInUser()
 	if err != nil {
@@ -157,29 +157,29 @@ func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDe
 				path := filepath.Join(openuemDir, "x11vncpasswd")
 
 				if err := os.Remove(path); err != nil {
-					log.Println("[INFO]: could not remove vnc password")
+					slog.Info("could not remove vnc password")
 				}
 
 				if err := runtime.RunAsUser(username, `/usr/bin/x11vnc`, []string{"-storepasswd", pin, path}, false); err != nil {
 					return err
 				}
 
-				log.Println("[INFO]: PIN saved to ", path)
+				slog.Info(fmt.Sprintf("PIN saved to %v", path))
 				return nil
 			},
 			RemovePIN: func() error {
 				homeDir, _, _, err := runtime.GetUserInfo(username)
 				if err != nil {
-					log.Printf("[ERROR]: could not get user info, reason: %v", err)
+					slog.Error("could not get user info", "error", err)
 					return err
 				}
 
 				openuemDir := filepath.Join(homeDir, ".openuem")
 				if err := os.RemoveAll(openuemDir); err != nil {
-					log.Println("[ERROR]: could not remove .openuem directory")
+					slog.Error("could not remove .openuem directory")
 				}
 
-				log.Println("[INFO]: PIN removed from ", openuemDir)
+				slog.Info(fmt.Sprintf("PIN removed from %v", openuemDir))
 				return nil
 			},
 		},
@@ -216,7 +216,7 @@ func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDe
 					return err
 				}
 
-				if err := copyCertFile("/etc/openuem-agent/certificates/server.cer", rdpCert, uid, gid); err != nil {
+				if err := copyCertFile(certPath, rdpCert, uid, gid); err != nil {
 					return err
 				}
 
@@ -225,7 +225,7 @@ func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDe
 					return errors.New("could not set set-tls-cert")
 				}
 
-				if err := copyCertFile("/etc/openuem-agent/certificates/server.key", rdpKey, uid, gid); err != nil {
+				if err := copyCertFile(keyPath, rdpKey, uid, gid); err != nil {
 					return err
 				}
 
@@ -249,28 +249,28 @@ func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDe
 			RemovePIN: func() error {
 				homeDir, _, _, err := runtime.GetUserInfo(username)
 				if err != nil {
-					log.Printf("[ERROR]: could not get user info, reason: %v", err)
+					slog.Error("could not get user info", "error", err)
 					return err
 				}
 
 				openuemDir := filepath.Join(homeDir, ".openuem")
 				if err := os.RemoveAll(openuemDir); err != nil {
-					log.Println("[ERROR]: could not remove .openuem directory")
+					slog.Error("could not remove .openuem directory")
 				}
 
 				err = runtime.RunAsUserWithMachineCtl(username, "/usr/bin/grdctl rdp disable")
 				if err != nil {
-					log.Println("[ERROR]: could not disable grdctl")
+					slog.Error("could not disable grdctl")
 				}
 
 				err = runtime.RunAsUserWithMachineCtl(username, "/usr/bin/grdctl rdp enable-view-only")
 				if err != nil {
-					log.Println("[ERROR]: could not set enable-view-only")
+					slog.Error("could not set enable-view-only")
 				}
 
 				err = runtime.RunAsUserWithMachineCtl(username, "/usr/bin/grdctl rdp clear-credentials")
 				if err != nil {
-					log.Println("[ERROR]: could not clear password for grd")
+					slog.Error("could not clear password for grd")
 				}
 
 				return nil
@@ -282,7 +282,7 @@ func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDe
 					return errors.New("could not set rdp credentials")
 				}
 
-				log.Println("[INFO]: gnome remote desktop credentials saved")
+				slog.Info("gnome remote desktop credentials saved")
 				return nil
 			},
 		},
@@ -302,7 +302,7 @@ func IsWaylandDisplayServer() bool {
 	// Get logged in username
 	username, err := runtime.GetLoggedInUser()
 	if err != nil {
-		log.Printf("[ERROR]: could not get logged in Username, reason: %v\n", err)
+		slog.Error("could not get logged in Username", "error", err)
 		return false
 	}
 
@@ -312,7 +312,7 @@ func IsWaylandDisplayServer() bool {
 
 	_, uid, gid, err := runtime.GetUserInfo(username)
 	if err != nil {
-		log.Printf("[ERROR]: could not get user info, reason: %v\n", err)
+		slog.Error("could not get user info", "error", err)
 		return false
 	}
 
@@ -386,7 +386,7 @@ func notifyPINToUser(pin string) error {
 
 func createOpenUEMDir(openuemDir string, uid, gid int) error {
 	if err := os.MkdirAll(openuemDir, 0770); err != nil {
-		log.Printf("[ERROR]: could not create openuem dir for current user, reason: %v", err)
+		slog.Error("could not create openuem dir for current user", "error", err)
 		return err
 	}
 
@@ -400,7 +400,6 @@ func createOpenUEMDir(openuemDir string, uid, gid int) error {
 	return nil
 }
 
-// "/etc/openuem-agent/certificates/server.cer"
 func copyCertFile(src, dst string, uid, gid int) error {
 	if err := copyFileContents(src, dst); err != nil {
 		return err
@@ -4,7 +4,7 @@ package remotedesktop
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 
@@ -17,7 +17,7 @@ import (
 func (rd *RemoteDesktopService) Start(pin string, notifyUser bool) {
 	cwd, err := openuem_utils.GetWd()
 	if err != nil {
-		log.Printf("[ERROR]: could not get working directory, reason: %v\n", err)
+		slog.Error("could not get working directory", "error", err)
 		return
 	}
 
@@ -25,20 +25,20 @@ func (rd *RemoteDesktopService) Start(pin string, notifyUser bool) {
 	if notifyUser {
 		go func() {
 			if err := runtime.RunAsUser(filepath.Join(cwd, "openuem-messenger.exe"), []string{"info", "--message", pin, "--type", "pin"}); err != nil {
-				log.Printf("[ERROR]: could not show PIN message to user, reason: %v\n", err)
+				slog.Error("could not show PIN message to user", "error", err)
 			}
 		}()
 	}
 
 	// Configure Remote Desktop service
 	if err := rd.Configure(); err != nil {
-		log.Printf("[ERROR]: could not configure Remote Desktop service, reason: %v\n", err)
+		slog.Error("could not configure Remote Desktop service", "error", err)
 		return
 	}
 
 	// Save PIN
 	if err := rd.SavePIN(pin); err != nil {
-		log.Printf("[ERROR]: could not save PIN before Remote Desktop service is started, reason: %v\n", err)
+		slog.Error("could not save PIN before Remote Desktop service is started", "error", err)
 		return
 	}
 
@@ -46,10 +46,10 @@ func (rd *RemoteDesktopService) Start(pin string, notifyUser bool) {
 	go func() {
 		vncPort := ""
 		if err := rd.StartService(vncPort); err != nil {
-			log.Printf("[ERROR]: could not start Remote Desktop service, reason: %v", err)
+			slog.Error("could not start Remote Desktop service", "error", err)
 			return
 		}
-		log.Println("[INFO]: the remote desktop service should have been started")
+		slog.Info("the remote desktop service should have been started")
 	}()
 
 	// Start VNC Proxy
@@ -63,32 +63,32 @@ func (rd *RemoteDesktopService) Stop() {
 	if rd.RequiresVNCProxy {
 		// Stop proxy
 		if err := rd.Proxy.Close(); err != nil {
-			log.Printf("[ERROR]: could not stop VNC proxy, reason: %v\n", err)
+			slog.Error("could not stop VNC proxy", "error", err)
 		}
 	}
 
 	// Create new random PIN
 	pin, err := openuem_utils.GenerateRandomPIN(6)
 	if err != nil {
-		log.Printf("[ERROR]: could not generate random PIN, reason: %v\n", err)
+		slog.Error("could not generate random PIN", "error", err)
 		return
 	}
 
 	// Save PIN
 	if err := rd.SavePIN(pin); err != nil {
-		log.Printf("[ERROR]: could not save PIN before Remote Desktop service is started, reason: %v\n", err)
+		slog.Error("could not save PIN before Remote Desktop service is started", "error", err)
 		return
 	}
 
 	// Stop gracefully Remote Desktop service
 	if err := rd.StopService(); err != nil {
-		log.Printf("[ERROR]: could not stop the remote desktop service, reason: %v", err)
+		slog.Error("could not stop the remote desktop service", "error", err)
 	}
-	log.Println("[INFO]: the remote desktop service has been stopped")
+	slog.Info("the remote desktop service has been stopped")
 
 }
 
-func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDesktopService, error) {
+func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort, certPath, keyPath string) (*RemoteDesktopService, error) {
 	supportedServers := map[string]RemoteDesktopService{
 		"TightVNC": {
 			RequiresVNCProxy: true,
@@ -103,7 +103,7 @@ func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDe
 
 				// Kill Remote Desktop service as some remnants can be there
 				if err := runtime.RunAsUser("taskkill", []string{"/F", "/T", "/IM", "tvnserver.exe"}); err != nil {
-					log.Printf("[WARN]: Remote Desktop service kill error, %v\n", err)
+					slog.Warn("Remote Desktop service kill", "error", err)
 				}
 				return nil
 			},
@@ -154,7 +154,7 @@ func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDe
 					return err
 				}
 
-				log.Println("[INFO]: PIN saved to registry")
+				slog.Info("PIN saved to registry")
 				return nil
 			},
 		},
@@ -175,7 +175,7 @@ func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDe
 				iniFile := `C:\Program Files\uvnc bvba\UltraVNC\ultravnc.ini`
 				cfg, err := ini.Load(iniFile)
 				if err != nil {
-					log.Println(`C:\Program Files\uvnc bvba\UltraVNC\ultravnc.ini cannot be opened`)
+					slog.Info(fmt.Sprint(`C:\Program Files\uvnc bvba\UltraVNC\ultravnc.ini cannot be opened`))
 					return err
 				}
 
@@ -186,10 +186,10 @@ func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDe
 				adminSection.Key("HTTPConnect").SetValue("0")
 
 				if err := cfg.SaveTo(iniFile); err != nil {
-					log.Printf("[ERROR]: could not save UltraVNC ini file, reason: %v\n", err)
+					slog.Error("could not save UltraVNC ini file", "error", err)
 					return err
 				}
-				log.Println("[INFO]: Remote Desktop service configured")
+				slog.Info("Remote Desktop service configured")
 				return nil
 			},
 			SavePIN: func(pin string) error {
@@ -203,9 +203,9 @@ func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDe
 
 				cfg.Section("ultravnc").Key("passwd").SetValue(encryptedPIN)
 				if err := cfg.SaveTo(iniFile); err != nil {
-					log.Printf("[ERROR]: could not save file, reason: %v\n", err)
+					slog.Error("could not save file", "error", err)
 				}
-				log.Println("[INFO]: PIN saved to file")
+				slog.Info("PIN saved to file")
 				return nil
 			},
 		},
@@ -254,7 +254,7 @@ func GetSupportedRemoteDesktopService(agentOS, sid, proxyPort string) (*RemoteDe
 				if err != nil {
 					return err
 				}
-				log.Println("[INFO]: PIN saved to registry")
+				slog.Info("PIN saved to registry")
 				return nil
 			},
 		},
@@ -5,7 +5,7 @@ package report
 import (
 	"context"
 	"errors"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
 )
@@ -18,14 +18,12 @@ type antivirusProduct struct {
 }
 
 func (r *Report) getAntivirusInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: antivirus info has been requested")
-	}
+	slog.Debug("antivirus info has been requested")
 	if err := r.getAntivirusFromWMI(); err != nil {
-		log.Printf("[ERROR]: could not get antivirus information from WMI AntiVirusProduct: %v", err)
+		slog.Error("could not get antivirus information from WMI AntiVirusProduct", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: antivirus information has been retrieved from WMI AntiVirusProduct")
+		slog.Info("antivirus information has been retrieved from WMI AntiVirusProduct")
 	}
 	return nil
 }
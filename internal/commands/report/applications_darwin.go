@@ -4,7 +4,7 @@ package report
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"os/exec"
 	"strings"
 
@@ -15,9 +15,7 @@ func (r *Report) getApplicationsInfo(debug bool) error {
 	var appData SPApplicationsDataType
 	r.Applications = []openuem_nats.Application{}
 
-	if debug {
-		log.Println("[DEBUG]: applications info has been requested")
-	}
+	slog.Debug("applications info has been requested")
 
 	out, err := exec.Command("system_profiler", "-json", "SPApplicationsDataType").Output()
 	if err != nil {
@@ -49,7 +47,7 @@ func (r *Report) getApplicationsInfo(debug bool) error {
 		r.Applications = append(r.Applications, app)
 	}
 
-	log.Println("[INFO]: desktop apps information has been retrieved from package manager")
+	slog.Info("desktop apps information has been retrieved from package manager")
 
 	return nil
 }
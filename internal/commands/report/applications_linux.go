@@ -6,7 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -19,9 +19,7 @@ import (
 )
 
 func (r *Report) getApplicationsInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: applications info has been requested")
-	}
+	slog.Debug("applications info has been requested")
 
 	os := r.OS
 	if os == "" {
@@ -41,9 +39,7 @@ func (r *Report) getApplicationsInfo(debug bool) error {
 		desktopDirs = append(desktopDirs, filepath.Join(home, ".local/share/flatpak/exports/share/applications"))
 	}
 
-	if debug {
-		log.Println("[DEBUG]: getting the list of desktop files...")
-	}
+	slog.Debug("getting the list of desktop files...")
 
 	// Get list of .desktop files
 	desktopFiles := []string{}
@@ -52,15 +48,11 @@ func (r *Report) getApplicationsInfo(debug bool) error {
 	}
 	desktopFiles = slices.Compact(desktopFiles)
 
-	if debug {
-		log.Printf("[DEBUG]: the list of desktop files found has been generated. %d files have been found", len(desktopFiles))
-	}
+	slog.Debug(fmt.Sprintf("the list of desktop files found has been generated. %d files have been found", len(desktopFiles)))
 
 	appNames := []string{}
 
-	if debug {
-		log.Println("[DEBUG]: getting which packages generate the desktop files and its information...")
-	}
+	slog.Debug("getting which packages generate the desktop files and its information...")
 
 	// app.InstallDate not available for snap and .deb packages
 	for _, p := range desktopFiles {
@@ -125,7 +117,7 @@ func (r *Report) getApplicationsInfo(debug bool) error {
 		}
 	}
 
-	log.Println("[INFO]: desktop apps information has been retrieved from package manager")
+	slog.Info("desktop apps information has been retrieved from package manager")
 
 	return nil
 }
@@ -133,18 +125,14 @@ func (r *Report) getApplicationsInfo(debug bool) error {
 func getDpkgInfo(desktopFilePath string, debug bool) (*openuem_nats.Application, error) {
 	app := openuem_nats.Application{}
 
-	if debug {
-		log.Printf("[DEBUG]: reading %s desktop file", desktopFilePath)
-	}
+	slog.Debug(fmt.Sprintf("reading %s desktop file", desktopFilePath))
 
 	desktopFile, err := os.ReadFile(desktopFilePath)
 	if err != nil {
 		return nil, err
 	}
 
-	if debug {
-		log.Printf("[DEBUG]: getting app name from %s desktop file", desktopFilePath)
-	}
+	slog.Debug(fmt.Sprintf("getting app name from %s desktop file", desktopFilePath))
 
 	// Get app's name from desktop file for a more precise name as suggested by @carlesgs
 	reg := regexp.MustCompile(`Name=(.*?)\n`)
@@ -154,9 +142,7 @@ func getDpkgInfo(desktopFilePath string, debug bool) (*openuem_nats.Application,
 		break
 	}
 
-	if debug {
-		log.Printf("[DEBUG]: using dpkg to find which package generates %s desktop file", desktopFilePath)
-	}
+	slog.Debug(fmt.Sprintf("using dpkg to find which package generates %s desktop file", desktopFilePath))
 
 	// Find the package name that provides .desktop file
 	command := fmt.Sprintf(`dpkg -S %s 2>/dev/null | awk '{print $1}' | cut -f 1 -d ':'  | sort --unique`, desktopFilePath)
@@ -165,9 +151,7 @@ func getDpkgInfo(desktopFilePath string, debug bool) (*openuem_nats.Application,
 		return nil, errors.New("could not find deb package that creates .desktop file")
 	}
 
-	if debug {
-		log.Printf("[DEBUG]: using dpkg to find the package %s information", string(out))
-	}
+	slog.Debug("using dpkg to find the package information", "output", string(out))
 
 	// Get information from the package
 	command = fmt.Sprintf(`dpkg -s %s`, string(out))
@@ -176,9 +160,7 @@ func getDpkgInfo(desktopFilePath string, debug bool) (*openuem_nats.Application,
 		return nil, errors.New("could not find deb package")
 	}
 
-	if debug {
-		log.Printf("[DEBUG]: getting properties from package %s information", string(out))
-	}
+	slog.Debug("getting properties from package information", "output", string(out))
 
 	reg = regexp.MustCompile(`Version: \s*(.*?)\s`)
 	matches = reg.FindAllStringSubmatch(string(out), -1)
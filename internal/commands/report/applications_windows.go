@@ -5,7 +5,7 @@ package report
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 
 	openuem_nats "github.com/open-uem/nats"
@@ -13,9 +13,7 @@ import (
 )
 
 func (r *Report) getApplicationsInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: applications info has been requested")
-	}
+	slog.Debug("applications info has been requested")
 	r.Applications = []openuem_nats.Application{}
 	myApps, err := getApplications(debug)
 	if err != nil {
@@ -37,19 +35,15 @@ func getApplications(debug bool) (map[string]openuem_nats.Application, error) {
 	applications := make(map[string]openuem_nats.Application)
 
 	if err := getApplicationsFromRegistry(applications, registry.LOCAL_MACHINE, APPS, ""); err != nil {
-		if debug {
-			log.Printf("[DEBUG]: could not get apps information from HKLM, reason: %v", err)
-		}
+		slog.Debug("could not get apps information from HKLM", "error", err)
 	} else {
-		log.Printf("[INFO]: apps information has been retrieved from %s\\%s", "HKLM", APPS)
+		slog.Info(fmt.Sprintf("apps information has been retrieved from %s\\%s", "HKLM", APPS))
 	}
 
 	if err := getApplicationsFromRegistry(applications, registry.LOCAL_MACHINE, APPS32BITS, ""); err != nil {
-		if debug {
-			log.Printf("[DEBUG]: could not get apps information from HKLM (32 bits), reason: %v", err)
-		}
+		slog.Debug("could not get apps information from HKLM (32 bits)", "error", err)
 	} else {
-		log.Printf("[INFO]: apps information has been retrieved from %s\\%s", "HKLM", APPS32BITS)
+		slog.Info(fmt.Sprintf("apps information has been retrieved from %s\\%s", "HKLM", APPS32BITS))
 	}
 
 	// Bug: #94
@@ -64,32 +58,24 @@ func getApplications(debug bool) (map[string]openuem_nats.Application, error) {
 	// We use the registry query instead
 	sids, err := GetSIDSFromRegistry()
 	if err != nil {
-		log.Println("[ERROR]: could not get user SIDs")
+		slog.Error("could not get user SIDs")
 		return nil, err
 	}
 
 	for _, s := range sids {
-		if debug {
-			log.Printf("[DEBUG]: apps information has been requested for %s", "HKCU\\APPS")
-		}
+		slog.Debug(fmt.Sprintf("apps information has been requested for %s", "HKCU\\APPS"))
 		if err := getApplicationsFromRegistry(applications, registry.USERS, APPS, s); err != nil {
-			if debug {
-				log.Printf("[DEBUG]: could not get apps information from HKEY_USERS for sid %s, reason: %v", s, err)
-			}
+			slog.Debug(fmt.Sprintf("could not get apps information from HKEY_USERS for sid %s", s), "error", err)
 			continue
 		}
-		log.Printf("[INFO]: apps information retrieved from HKEY_USERS for sid %s\n", s)
+		slog.Info(fmt.Sprintf("apps information retrieved from HKEY_USERS for sid %s", s))
 
-		if debug {
-			log.Printf("[DEBUG]: apps information has been requested for %s", "HKCU\\APPS32BITS")
-		}
+		slog.Debug(fmt.Sprintf("apps information has been requested for %s", "HKCU\\APPS32BITS"))
 		if err := getApplicationsFromRegistry(applications, registry.USERS, APPS32BITS, s); err != nil {
-			if debug {
-				log.Printf("[DEBUG]: could not get apps information from HKEY_USERS (32bits) for sid %s, reason: %v", s, err)
-			}
+			slog.Debug(fmt.Sprintf("could not get apps information from HKEY_USERS (32bits) for sid %s", s), "error", err)
 			continue
 		}
-		log.Printf("[INFO]: apps information retrieved from HKEY_USERS (32 bits) for sid %s\n", s)
+		slog.Info(fmt.Sprintf("apps information retrieved from HKEY_USERS (32 bits) for sid %s", s))
 	}
 
 	return applications, nil
@@ -142,7 +128,7 @@ func GetSID(username string) (string, error) {
 	user := strings.Split(username, "\\")
 
 	if len(user) != 2 {
-		log.Println("[ERROR]: could not parse username for WMI Win32_UserAccount query")
+		slog.Error("could not parse username for WMI Win32_UserAccount query")
 		return "", fmt.Errorf("could not parse username, expect a domain and a name")
 	}
 
@@ -151,12 +137,12 @@ func GetSID(username string) (string, error) {
 	ctx := context.Background()
 	err := WMIQueryWithContext(ctx, qSID, &response, namespace)
 	if err != nil {
-		log.Printf("[ERROR]: could not generate SQL for WMI Win32_UserAccount: %v", err)
+		slog.Error("could not generate SQL for WMI Win32_UserAccount", "error", err)
 		return "", err
 	}
 
 	if len(response) != 1 {
-		log.Printf("[ERROR]: expected one result got %d: %v", len(response), err)
+		slog.Error(fmt.Sprintf("expected one result got %d", len(response)), "error", err)
 		return "", err
 	}
 
@@ -200,7 +186,7 @@ func GetSIDs() ([]struct{ SID string }, error) {
 	ctx := context.Background()
 	err := WMIQueryWithContext(ctx, qSID, &response, namespace)
 	if err != nil {
-		log.Printf("[ERROR]: could not generate SQL for WMI Win32_UserAccount: %v", err)
+		slog.Error("could not generate SQL for WMI Win32_UserAccount", "error", err)
 		return nil, err
 	}
 
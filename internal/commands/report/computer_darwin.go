@@ -5,19 +5,17 @@ package report
 import (
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
 	"os/exec"
 	"strconv"
 	"strings"
 )
 
 func (r *Report) getComputerInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: computer system info has been requested")
-	}
+	slog.Debug("computer system info has been requested")
 	if err := r.getComputerSystemInfo1(); err != nil {
 		if err := r.getComputerSystemInfo2(); err != nil {
-			log.Printf("[ERROR]: could not get information from System Profiler: %v", err)
+			slog.Error("could not get information from System Profiler", "error", err)
 			return err
 		}
 	}
@@ -3,7 +3,7 @@
 package report
 
 import (
-	"log"
+	"log/slog"
 	"strings"
 	"syscall"
 
@@ -11,34 +11,28 @@ import (
 )
 
 func (r *Report) getComputerInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: computer system info has been requested")
-	}
+	slog.Debug("computer system info has been requested")
 	if err := r.getComputerSystemInfo(); err != nil {
-		log.Printf("[ERROR]: could not get information from SysInfo: %v", err)
+		slog.Error("could not get information from SysInfo", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: computer system info has been retrieved from SysInfo")
+		slog.Info("computer system info has been retrieved from SysInfo")
 	}
 
-	if debug {
-		log.Println("[DEBUG]: serial number has been requested")
-	}
+	slog.Debug("serial number has been requested")
 	if err := r.getSerialNumber(); err != nil {
-		log.Printf("[ERROR]: could not get information from SysInfo: %v", err)
+		slog.Error("could not get information from SysInfo", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: serial number info has been retrieved from SysInfo")
+		slog.Info("serial number info has been retrieved from SysInfo")
 	}
 
-	if debug {
-		log.Println("[DEBUG]: processor info has been requested")
-	}
+	slog.Debug("processor info has been requested")
 	if err := r.getProcessorInfo(); err != nil {
-		log.Printf("[ERROR]: could not get information from SysInfo: %v", err)
+		slog.Error("could not get information from SysInfo", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: processor info has been retrieved from SysInfo")
+		slog.Info("processor info has been retrieved from SysInfo")
 	}
 	return nil
 }
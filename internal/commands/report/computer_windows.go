@@ -5,7 +5,7 @@ package report
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 )
 
@@ -26,34 +26,28 @@ type processorInfo struct {
 }
 
 func (r *Report) getComputerInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: computer system info has been requested")
-	}
+	slog.Debug("computer system info has been requested")
 	if err := r.getComputerSystemInfo(); err != nil {
-		log.Printf("[ERROR]: could not get information from WMI Win32_ComputerSystem: %v", err)
+		slog.Error("could not get information from WMI Win32_ComputerSystem", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: computer system info has been retrieved from WMI Win32_ComputerSystem")
+		slog.Info("computer system info has been retrieved from WMI Win32_ComputerSystem")
 	}
 
-	if debug {
-		log.Println("[DEBUG]: serial number has been requested")
-	}
+	slog.Debug("serial number has been requested")
 	if err := r.getSerialNumber(); err != nil {
-		log.Printf("[ERROR]: could not get information from WMI Win32_Bios: %v", err)
+		slog.Error("could not get information from WMI Win32_Bios", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: serial number info has been retrieved from WMI Win32_Bios")
+		slog.Info("serial number info has been retrieved from WMI Win32_Bios")
 	}
 
-	if debug {
-		log.Println("[DEBUG]: processor info has been requested")
-	}
+	slog.Debug("processor info has been requested")
 	if err := r.getProcessorInfo(); err != nil {
-		log.Printf("[ERROR]: could not get information from WMI Win32_Processor: %v", err)
+		slog.Error("could not get information from WMI Win32_Processor", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: processor info has been retrieved from WMI Win32_Processor")
+		slog.Info("processor info has been retrieved from WMI Win32_Processor")
 	}
 	return nil
 }
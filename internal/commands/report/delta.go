@@ -0,0 +1,150 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DEFAULT_FULL_REPORT_EVERY = 12
+
+const (
+	DELTA_REPORT     = "delta"
+	HEARTBEAT_REPORT = "heartbeat"
+)
+
+// DeltaReport contains only the report sections that have changed since the
+// last report accepted by the worker. The manifest has the hash of every
+// section so the worker can check that it's in sync with the agent
+type DeltaReport struct {
+	AgentID       string                     `json:"id"`
+	ExecutionTime time.Time                  `json:"execution_time"`
+	Type          string                     `json:"type"`
+	Hash          string                     `json:"hash"`
+	Manifest      map[string]string          `json:"manifest,omitempty"`
+	Sections      map[string]json.RawMessage `json:"sections,omitempty"`
+}
+
+type DeltaResponse struct {
+	FullReportRequired bool `json:"full_report_required,omitempty"`
+}
+
+type DeltaTracker struct {
+	mu        sync.Mutex
+	manifest  map[string]string
+	sinceFull int
+	FullEvery int
+}
+
+func NewDeltaTracker(fullEvery int) *DeltaTracker {
+	if fullEvery <= 0 {
+		fullEvery = DEFAULT_FULL_REPORT_EVERY
+	}
+	return &DeltaTracker{FullEvery: fullEvery}
+}
+
+// Sections splits the report by its top-level JSON keys and returns the hash
+// of each section. The execution time and agent id are not sections as they're
+// sent with every report
+func (r *Report) Sections() (map[string]json.RawMessage, map[string]string, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sections := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return nil, nil, err
+	}
+	delete(sections, "id")
+	delete(sections, "execution_time")
+
+	manifest := map[string]string{}
+	for name, section := range sections {
+		sum := sha256.Sum256(section)
+		manifest[name] = hex.EncodeToString(sum[:])
+	}
+
+	return sections, manifest, nil
+}
+
+// Delta returns the delta report to be sent or nil if a full report is required
+func (t *DeltaTracker) Delta(r *Report, sections map[string]json.RawMessage, manifest map[string]string) *DeltaReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.manifest == nil || t.sinceFull >= t.FullEvery {
+		return nil
+	}
+
+	delta := DeltaReport{
+		AgentID:       r.AgentID,
+		ExecutionTime: r.ExecutionTime,
+		Type:          HEARTBEAT_REPORT,
+		Hash:          manifestHash(manifest),
+	}
+
+	changed := map[string]json.RawMessage{}
+	for name, hash := range manifest {
+		if t.manifest[name] != hash {
+			changed[name] = sections[name]
+		}
+	}
+
+	removed := false
+	for name := range t.manifest {
+		if _, ok := manifest[name]; !ok {
+			removed = true
+		}
+	}
+
+	if len(changed) > 0 || removed {
+		delta.Type = DELTA_REPORT
+		delta.Manifest = manifest
+		delta.Sections = changed
+	}
+
+	return &delta
+}
+
+// Sent stores the manifest of the last report accepted by the worker
+func (t *DeltaTracker) Sent(manifest map[string]string, full bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.manifest = manifest
+	if full {
+		t.sinceFull = 0
+	} else {
+		t.sinceFull++
+	}
+}
+
+// Reset forces the next report to be a full report
+func (t *DeltaTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.manifest = nil
+	t.sinceFull = 0
+}
+
+func manifestHash(manifest map[string]string) string {
+	names := []string{}
+	for name := range manifest {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	entries := []string{}
+	for _, name := range names {
+		entries = append(entries, name+"="+manifest[name])
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(entries, "\n")))
+	return hex.EncodeToString(sum[:])
+}
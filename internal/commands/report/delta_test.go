@@ -0,0 +1,143 @@
+package report
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	openuem_nats "github.com/open-uem/nats"
+)
+
+func newReport(hostname string, maintenance *Maintenance) *Report {
+	return &Report{
+		AgentReport: openuem_nats.AgentReport{
+			AgentID:       "2b3c9a4e-5f6d-4e7a-8b9c-0d1e2f3a4b5c",
+			ExecutionTime: time.Now(),
+			OS:            "linux",
+			Hostname:      hostname,
+		},
+		Maintenance: maintenance,
+	}
+}
+
+func sections(t *testing.T, r *Report) (map[string]json.RawMessage, map[string]string) {
+	t.Helper()
+
+	s, m, err := r.Sections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, m
+}
+
+func TestSections(t *testing.T) {
+	s, m := sections(t, newReport("pc01", nil))
+
+	for _, name := range []string{"id", "execution_time"} {
+		if _, ok := s[name]; ok {
+			t.Errorf("%s must not be a section", name)
+		}
+	}
+	if string(s["hostname"]) != `"pc01"` {
+		t.Errorf("hostname section is %s", s["hostname"])
+	}
+	if len(m) != len(s) {
+		t.Errorf("manifest has %d hashes for %d sections", len(m), len(s))
+	}
+
+	_, again := sections(t, newReport("pc01", nil))
+	if manifestHash(m) != manifestHash(again) {
+		t.Error("the same report has a different manifest hash")
+	}
+}
+
+func TestDelta(t *testing.T) {
+	window := &Maintenance{Windows: "Sat 02:00-04:00"}
+
+	tests := []struct {
+		name     string
+		previous *Report
+		current  *Report
+		typ      string
+		changed  []string
+	}{
+		{name: "unchanged", previous: newReport("pc01", window), current: newReport("pc01", window), typ: HEARTBEAT_REPORT},
+		{name: "changed section", previous: newReport("pc01", window), current: newReport("pc02", window), typ: DELTA_REPORT, changed: []string{"hostname"}},
+		{name: "added section", previous: newReport("pc01", nil), current: newReport("pc01", window), typ: DELTA_REPORT, changed: []string{"maintenance"}},
+		{name: "removed section", previous: newReport("pc01", window), current: newReport("pc01", nil), typ: DELTA_REPORT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewDeltaTracker(0)
+			_, previous := sections(t, tt.previous)
+			tracker.Sent(previous, true)
+
+			s, m := sections(t, tt.current)
+			delta := tracker.Delta(tt.current, s, m)
+			if delta == nil {
+				t.Fatal("got a full report, want a delta")
+			}
+
+			if delta.Type != tt.typ {
+				t.Errorf("got a %s report, want a %s report", delta.Type, tt.typ)
+			}
+			if delta.Hash != manifestHash(m) || delta.AgentID != tt.current.AgentID {
+				t.Error("the delta doesn't identify the current report")
+			}
+
+			changed := []string{}
+			for name := range delta.Sections {
+				changed = append(changed, name)
+			}
+			slices.Sort(changed)
+			if !slices.Equal(changed, tt.changed) {
+				t.Errorf("changed sections are %v, want %v", changed, tt.changed)
+			}
+
+			// The worker needs the manifest to know which sections have been removed
+			if tt.typ == DELTA_REPORT && len(delta.Manifest) != len(m) {
+				t.Errorf("manifest has %d sections, want %d", len(delta.Manifest), len(m))
+			}
+			if tt.typ == HEARTBEAT_REPORT && delta.Manifest != nil {
+				t.Error("a heartbeat must not have a manifest")
+			}
+		})
+	}
+}
+
+func TestDeltaFallsBackToFullReport(t *testing.T) {
+	r := newReport("pc01", nil)
+	s, m := sections(t, r)
+
+	tests := []struct {
+		name    string
+		prepare func(tracker *DeltaTracker)
+		full    bool
+	}{
+		{name: "nothing sent yet", prepare: func(tracker *DeltaTracker) {}, full: true},
+		{name: "full report sent", prepare: func(tracker *DeltaTracker) { tracker.Sent(m, true) }},
+		{name: "reset", prepare: func(tracker *DeltaTracker) { tracker.Sent(m, true); tracker.Reset() }, full: true},
+		{name: "deltas below the limit", prepare: func(tracker *DeltaTracker) {
+			tracker.Sent(m, true)
+			tracker.Sent(m, false)
+		}},
+		{name: "deltas reach the limit", prepare: func(tracker *DeltaTracker) {
+			tracker.Sent(m, true)
+			tracker.Sent(m, false)
+			tracker.Sent(m, false)
+		}, full: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewDeltaTracker(2)
+			tt.prepare(tracker)
+
+			if delta := tracker.Delta(r, s, m); (delta == nil) != tt.full {
+				t.Errorf("Delta() = %v, want a full report: %v", delta, tt.full)
+			}
+		})
+	}
+}
@@ -4,7 +4,7 @@ package report
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -13,15 +13,13 @@ import (
 )
 
 func (r *Report) getLogicalDisksInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: logical disks info has been requested")
-	}
+	slog.Debug("logical disks info has been requested")
 	err := r.getLogicalDisksFromMacOS(debug)
 	if err != nil {
-		log.Printf("[ERROR]: could not get logical disks information: %v", err)
+		slog.Error("could not get logical disks information", "error", err)
 		return err
 	} else {
-		log.Println("[INFO]: logical disks information has been retrieved")
+		slog.Info("logical disks information has been retrieved")
 	}
 	return nil
 }
@@ -32,9 +30,7 @@ type dfInfo struct {
 }
 
 func (r *Report) getLogicalDisksFromMacOS(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: logical disks info has been requested")
-	}
+	slog.Debug("logical disks info has been requested")
 
 	diskUsage := make(map[string]dfInfo)
 
@@ -4,7 +4,7 @@ package report
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 
 	"github.com/moby/sys/mountinfo"
@@ -13,15 +13,13 @@ import (
 )
 
 func (r *Report) getLogicalDisksInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: logical disks info has been requested")
-	}
+	slog.Debug("logical disks info has been requested")
 	err := r.getLogicalDisksFromLinux(debug)
 	if err != nil {
-		log.Printf("[ERROR]: could not get logical disks information from mountinfo: %v", err)
+		slog.Error("could not get logical disks information from mountinfo", "error", err)
 		return err
 	} else {
-		log.Println("[INFO]: logical disks information has been retrieved from mountinfo")
+		slog.Info("logical disks information has been retrieved from mountinfo")
 	}
 	return nil
 }
@@ -40,11 +38,9 @@ func (r *Report) getLogicalDisksFromLinux(debug bool) error {
 	for _, m := range mounts {
 		var stat unix.Statfs_t
 		if !strings.Contains(m.Mountpoint, "snap") {
-			if debug {
-				log.Println("[DEBUG]: logical disk info started for: ", m.Mountpoint)
-			}
+			slog.Debug(fmt.Sprintf("logical disk info started for: %v", m.Mountpoint))
 			if err := unix.Statfs(m.Mountpoint, &stat); err != nil {
-				log.Printf("[ERROR]: could not get information for mountpoint %s, reason: %v", m.Mountpoint, err)
+				slog.Error(fmt.Sprintf("could not get information for mountpoint %s", m.Mountpoint), "error", err)
 				continue
 			}
 			myDisk := openuem_nats.LogicalDisk{}
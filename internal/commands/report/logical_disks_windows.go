@@ -5,7 +5,7 @@ package report
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 
 	openuem_nats "github.com/open-uem/nats"
@@ -42,9 +42,7 @@ func (r *Report) getLogicalDisksFromWMI(debug bool) error {
 
 		if v.Size != 0 {
 			myDisk.Label = strings.TrimSpace(v.DeviceID)
-			if debug {
-				log.Println("[DEBUG]: logical disk info started for: ", myDisk.Label)
-			}
+			slog.Debug(fmt.Sprintf("logical disk info started for: %v", myDisk.Label))
 			myDisk.Usage = int8(100 - (v.FreeSpace * 100 / v.Size))
 			myDisk.Filesystem = strings.TrimSpace(v.FileSystem)
 			myDisk.VolumeName = strings.TrimSpace(v.VolumeName)
@@ -52,32 +50,26 @@ func (r *Report) getLogicalDisksFromWMI(debug bool) error {
 			myDisk.SizeInUnits = convertBytesToUnits(v.Size)
 			myDisk.RemainingSpaceInUnits = convertBytesToUnits(v.FreeSpace)
 
-			if debug {
-				log.Println("[DEBUG]: bit locker status info has been requested for: ", myDisk.Label)
-			}
+			slog.Debug(fmt.Sprintf("bit locker status info has been requested for: %v", myDisk.Label))
 
 			// TODO - This query halts report if in sequence in go routine often works fine
 			myDisk.BitLockerStatus = getBitLockerStatus(myDisk.Label)
 
 			r.LogicalDisks = append(r.LogicalDisks, myDisk)
-			if debug {
-				log.Println("[DEBUG]: logical disk info finished for: ", myDisk.Label)
-			}
+			slog.Debug(fmt.Sprintf("logical disk info finished for: %v", myDisk.Label))
 		}
 	}
 	return nil
 }
 
 func (r *Report) getLogicalDisksInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: logical disks info has been requested")
-	}
+	slog.Debug("logical disks info has been requested")
 	err := r.getLogicalDisksFromWMI(debug)
 	if err != nil {
-		log.Printf("[ERROR]: could not get logical disks information from WMI Win32_LogicalDisk: %v", err)
+		slog.Error("could not get logical disks information from WMI Win32_LogicalDisk", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: logical disks information has been retrieved from WMI Win32_LogicalDisk")
+		slog.Info("logical disks information has been retrieved from WMI Win32_LogicalDisk")
 	}
 	return nil
 }
@@ -95,16 +87,16 @@ func getBitLockerStatus(driveLetter string) string {
 	ctx := context.Background()
 	err := WMIQueryWithContext(ctx, qBitLocker, &response, namespace)
 	if err != nil {
-		log.Printf("[ERROR]: could not get bitlocker status from WMI Win32_EncryptableVolume: %v", err)
+		slog.Error("could not get bitlocker status from WMI Win32_EncryptableVolume", "error", err)
 		return "Unknown"
 	}
 
 	if len(response) != 1 {
-		log.Printf("[INFO]: no bitlocker result for drive %s got %d rows: %v", driveLetter, len(response), err)
+		slog.Info(fmt.Sprintf("no bitlocker result for drive %s got %d rows", driveLetter, len(response)), "error", err)
 		return "Unknown"
 	}
 
-	log.Printf("[INFO]: could get bitlocker status from WMI Win32_EncryptableVolume for driver %s", driveLetter)
+	slog.Info(fmt.Sprintf("could get bitlocker status from WMI Win32_EncryptableVolume for driver %s", driveLetter))
 	switch response[0].ProtectionStatus {
 	case 0:
 		return "Unencrypted"
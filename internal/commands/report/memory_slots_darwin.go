@@ -4,7 +4,7 @@ package report
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"os/exec"
 	"runtime"
 	"strconv"
@@ -50,9 +50,7 @@ func (r *Report) getMemorySlotsInfo(debug bool) error {
 
 	r.MemorySlots = []openuem_nats.MemorySlot{}
 
-	if debug {
-		log.Println("[DEBUG]: memory slots info has been requested")
-	}
+	slog.Debug("memory slots info has been requested")
 
 	out, err := exec.Command("system_profiler", "-json", "SPMemoryDataType").Output()
 	if err != nil {
@@ -92,6 +90,6 @@ func (r *Report) getMemorySlotsInfo(debug bool) error {
 		}
 	}
 
-	log.Printf("[INFO]: memory slots information has been retrieved")
+	slog.Info("memory slots information has been retrieved")
 	return nil
 }
@@ -3,7 +3,7 @@
 package report
 
 import (
-	"log"
+	"log/slog"
 	"os/exec"
 	"regexp"
 
@@ -13,9 +13,7 @@ import (
 func (r *Report) getMemorySlotsInfo(debug bool) error {
 	r.MemorySlots = []openuem_nats.MemorySlot{}
 
-	if debug {
-		log.Println("[DEBUG]: memory slots info has been requested")
-	}
+	slog.Debug("memory slots info has been requested")
 
 	out, err := exec.Command("dmidecode", "--type", "17").Output()
 	if err != nil {
@@ -100,6 +98,6 @@ func (r *Report) getMemorySlotsInfo(debug bool) error {
 		}
 	}
 
-	log.Printf("[INFO]: memory slots information has been retrieved from Linux dmidecode")
+	slog.Info("memory slots information has been retrieved from Linux dmidecode")
 	return nil
 }
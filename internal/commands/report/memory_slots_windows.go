@@ -5,16 +5,14 @@ package report
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strconv"
 
 	openuem_nats "github.com/open-uem/nats"
 )
 
 func (r *Report) getMemorySlotsInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: memory slots info has been requested")
-	}
+	slog.Debug("memory slots info has been requested")
 
 	// Get memory slots information
 	// Ref: https://learn.microsoft.com/en-us/windows/win32/cimwin32prov/win32-physicalmemory
@@ -36,7 +34,7 @@ func (r *Report) getMemorySlotsInfo(debug bool) error {
 	ctx := context.Background()
 	err := WMIQueryWithContext(ctx, qMonitors, &slotsDst, namespace)
 	if err != nil {
-		log.Printf("[ERROR]: could not get memory slots information from WMI Win32_PhysicalMemory: %v", err)
+		slog.Error("could not get memory slots information from WMI Win32_PhysicalMemory", "error", err)
 		return err
 	}
 	for _, v := range slotsDst {
@@ -50,7 +48,7 @@ func (r *Report) getMemorySlotsInfo(debug bool) error {
 		mySlot.MemoryType = convertUint16ToMemoryType(v.SMBIOSMemoryType)
 		r.MemorySlots = append(r.MemorySlots, mySlot)
 	}
-	log.Printf("[INFO]: memory slots information has been retrieved from Win32_PhysicalMemory")
+	slog.Info("memory slots information has been retrieved from Win32_PhysicalMemory")
 	return nil
 }
 
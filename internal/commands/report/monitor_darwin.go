@@ -4,7 +4,7 @@ package report
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"os/exec"
 
 	openuem_nats "github.com/open-uem/nats"
@@ -14,9 +14,7 @@ func (r *Report) getMonitorsInfo(debug bool) error {
 	var displaysData SPDisplaysDataType
 	r.Monitors = []openuem_nats.Monitor{}
 
-	if debug {
-		log.Println("[DEBUG]: monitors info has been requested")
-	}
+	slog.Debug("monitors info has been requested")
 
 	out, err := exec.Command("system_profiler", "-json", "SPDisplaysDataType").Output()
 	if err != nil {
@@ -37,7 +35,7 @@ func (r *Report) getMonitorsInfo(debug bool) error {
 		}
 	}
 
-	log.Printf("[INFO]: monitors information has been retrieved")
+	slog.Info("monitors information has been retrieved")
 	return nil
 }
 
@@ -3,7 +3,7 @@
 package report
 
 import (
-	"log"
+	"log/slog"
 	"os/exec"
 	"regexp"
 
@@ -13,9 +13,7 @@ import (
 func (r *Report) getMonitorsInfo(debug bool) error {
 	r.Monitors = []openuem_nats.Monitor{}
 
-	if debug {
-		log.Println("[DEBUG]: monitors info has been requested")
-	}
+	slog.Debug("monitors info has been requested")
 
 	out, err := exec.Command("hwinfo", "--monitor").Output()
 	if err != nil {
@@ -66,6 +64,6 @@ func (r *Report) getMonitorsInfo(debug bool) error {
 		}
 	}
 
-	log.Printf("[INFO]: monitors information has been retrieved from Linux hwinfo")
+	slog.Info("monitors information has been retrieved from Linux hwinfo")
 	return nil
 }
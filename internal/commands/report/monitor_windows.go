@@ -4,7 +4,7 @@ package report
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"strconv"
 	"strings"
 
@@ -12,9 +12,7 @@ import (
 )
 
 func (r *Report) getMonitorsInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: monitors info has been requested")
-	}
+	slog.Debug("monitors info has been requested")
 
 	// Get monitors information
 	// Ref: https://learn.microsoft.com/en-us/windows/win32/wmicoreprov/wmimonitorid
@@ -34,7 +32,7 @@ func (r *Report) getMonitorsInfo(debug bool) error {
 	ctx := context.Background()
 	err := WMIQueryWithContext(ctx, qMonitors, &monitorDst, namespace)
 	if err != nil {
-		log.Printf("[ERROR]: could not get information from WMI WmiMonitorID: %v", err)
+		slog.Error("could not get information from WMI WmiMonitorID", "error", err)
 		return err
 	}
 	for _, v := range monitorDst {
@@ -47,7 +45,7 @@ func (r *Report) getMonitorsInfo(debug bool) error {
 
 		r.Monitors = append(r.Monitors, myMonitor)
 	}
-	log.Printf("[INFO]: monitors information has been retrieved from WMI WmiMonitorID")
+	slog.Info("monitors information has been retrieved from WMI WmiMonitorID")
 	return nil
 }
 
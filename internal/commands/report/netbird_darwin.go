@@ -6,7 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"strings"
@@ -29,7 +29,7 @@ func RetrieveNetbirdInfo() (*nats.Netbird, error) {
 		// Get NetBird version
 		out, err := exec.Command(netbirdBin, "version").CombinedOutput()
 		if err != nil {
-			log.Printf("[ERROR]: could not get NetBird version, reason: %v", string(out))
+			slog.Error("could not get NetBird version", "output", string(out))
 			return nil, err
 		}
 		data.Version = strings.TrimSpace(string(out))
@@ -39,7 +39,7 @@ func RetrieveNetbirdInfo() (*nats.Netbird, error) {
 		command := `export LC_ALL=C && /usr/local/bin/netbird service status`
 		out, err = exec.Command("bash", "-c", command).CombinedOutput()
 		if err != nil {
-			log.Printf("[ERROR]: could not check NetBird service status, reason: %s", string(out))
+			slog.Error("could not check NetBird service status", "output", string(out))
 			return nil, err
 		}
 
@@ -67,7 +67,7 @@ func RetrieveNetbirdInfo() (*nats.Netbird, error) {
 			args := []string{"-c", command}
 			out, err := runtime.RunAsUserWithOutputAndTimeout(username, "bash", args, true, 60*time.Second)
 			if err != nil {
-				log.Printf("[ERROR]: could not switch NetBird profile, reason: %s", string(out))
+				slog.Error("could not switch NetBird profile", "output", string(out))
 				return nil, err
 			}
 
@@ -98,7 +98,7 @@ func RetrieveNetbirdInfo() (*nats.Netbird, error) {
 			command = `export LC_ALL=C && /usr/local/bin/netbird profile list | awk 'NR>1 {print $2}'`
 			out, err = exec.Command("bash", "-c", command).CombinedOutput()
 			if err != nil {
-				log.Printf("[ERROR]: could not get NetBird profiles, reason: %s", string(out))
+				slog.Error("could not get NetBird profiles", "output", string(out))
 				return nil, err
 			}
 			data.Profiles = strings.Split(strings.TrimSpace(string(out)), "\n")
@@ -106,7 +106,7 @@ func RetrieveNetbirdInfo() (*nats.Netbird, error) {
 			args := []string{"-c", `export LC_ALL=C && /usr/local/bin/netbird profile list | awk 'NR>1 {print $2}'`}
 			out, err := runtime.RunAsUserWithOutput(username, "bash", args, true)
 			if err != nil {
-				log.Printf("[ERROR]: could not get NetBird profiles, reason: %s", string(out))
+				slog.Error("could not get NetBird profiles", "output", string(out))
 				return nil, err
 			}
 			data.Profiles = strings.Split(strings.TrimSpace(string(out)), "\n")
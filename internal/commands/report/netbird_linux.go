@@ -5,7 +5,7 @@ package report
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"strings"
@@ -27,7 +27,7 @@ func RetrieveNetbirdInfo() (*nats.Netbird, error) {
 		// Get NetBird version
 		out, err := exec.Command(netbirdBin, "version").CombinedOutput()
 		if err != nil {
-			log.Printf("[ERROR]: could not get NetBird version, reason: %v", string(out))
+			slog.Error("could not get NetBird version", "output", string(out))
 			return nil, err
 		}
 		data.Version = strings.TrimSpace(string(out))
@@ -37,7 +37,7 @@ func RetrieveNetbirdInfo() (*nats.Netbird, error) {
 		command := `export LC_ALL=C && netbird service status`
 		out, err = exec.Command("bash", "-c", command).CombinedOutput()
 		if err != nil {
-			log.Printf("[INFO]: could not check NetBird service status, reason: %s", string(out))
+			slog.Info("could not check NetBird service status", "output", string(out))
 			return nil, err
 		}
 
@@ -56,7 +56,7 @@ func RetrieveNetbirdInfo() (*nats.Netbird, error) {
 			if err != nil || username == "" {
 				out, err = exec.Command(netbirdBin, "status", "--json").CombinedOutput()
 				if err != nil {
-					log.Printf("[ERROR]: could not execute NetBird service status, reason: %s", string(out))
+					slog.Error("could not execute NetBird service status", "output", string(out))
 					return nil, err
 				}
 			} else {
@@ -64,7 +64,7 @@ func RetrieveNetbirdInfo() (*nats.Netbird, error) {
 				args := []string{"-c", command}
 				out, err = runtime.RunAsUserWithOutput(username, "bash", args, true)
 				if err != nil {
-					log.Printf("[ERROR]: could not execute NetBird service status, reason: %s", string(out))
+					slog.Error("could not execute NetBird service status", "output", string(out))
 					return nil, err
 				}
 			}
@@ -96,7 +96,7 @@ func RetrieveNetbirdInfo() (*nats.Netbird, error) {
 			command = `export LC_ALL=C && netbird profile list | awk 'NR>1 {print $2}'`
 			out, err = exec.Command("bash", "-c", command).CombinedOutput()
 			if err != nil {
-				log.Printf("[ERROR]: could not get NetBird profiles, reason: %s", string(out))
+				slog.Error("could not get NetBird profiles", "output", string(out))
 				return nil, err
 			}
 			data.Profiles = strings.Split(strings.TrimSpace(string(out)), "\n")
@@ -104,7 +104,7 @@ func RetrieveNetbirdInfo() (*nats.Netbird, error) {
 			args := []string{"-c", `export LC_ALL=C && netbird profile list | awk 'NR>1 {print $2}'`}
 			out, err := runtime.RunAsUserWithOutput(username, "bash", args, true)
 			if err != nil {
-				log.Printf("[ERROR]: could not get NetBird profiles, reason: %s", string(out))
+				slog.Error("could not get NetBird profiles", "output", string(out))
 				return nil, err
 			}
 			data.Profiles = strings.Split(strings.TrimSpace(string(out)), "\n")
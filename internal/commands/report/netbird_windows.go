@@ -4,7 +4,7 @@ package report
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"strings"
@@ -26,7 +26,7 @@ func RetrieveNetbirdInfo() (*nats.Netbird, error) {
 		// Get NetBird version
 		out, err := exec.Command(netbirdBin, "version").CombinedOutput()
 		if err != nil {
-			log.Printf("[ERROR]: could not get NetBird version, reason: %v", string(out))
+			slog.Error("could not get NetBird version", "output", string(out))
 			return nil, err
 		}
 		data.Version = strings.TrimSpace(string(out))
@@ -35,7 +35,7 @@ func RetrieveNetbirdInfo() (*nats.Netbird, error) {
 		data.ServiceStatus = "netbird.service_not_installed"
 		out, err = exec.Command(netbirdBin, "service", "status").CombinedOutput()
 		if err != nil {
-			log.Printf("[ERROR]: could not check NetBird service status, reason: %s", string(out))
+			slog.Error("could not check NetBird service status", "output", string(out))
 			return nil, err
 		}
 
@@ -56,13 +56,13 @@ func RetrieveNetbirdInfo() (*nats.Netbird, error) {
 			if err != nil || username == "" {
 				out, err = exec.Command(netbirdBin, args...).CombinedOutput()
 				if err != nil {
-					log.Printf("[ERROR]: could not execute NetBird service status, reason: %s", string(out))
+					slog.Error("could not execute NetBird service status", "output", string(out))
 					return nil, err
 				}
 			} else {
 				out, err = runtime.RunAsUserWithOutput(netbirdBin, args)
 				if err != nil {
-					log.Printf("[ERROR]: could not execute NetBird service status, reason: %s", string(out))
+					slog.Error("could not execute NetBird service status", "output", string(out))
 					return nil, err
 				}
 			}
@@ -97,14 +97,14 @@ func RetrieveNetbirdInfo() (*nats.Netbird, error) {
 		if err != nil || username == "" {
 			out, err = exec.Command(netbirdBin, args...).CombinedOutput()
 			if err != nil {
-				log.Printf("[ERROR]: could not get NetBird profiles, reason: %s", string(out))
+				slog.Error("could not get NetBird profiles", "output", string(out))
 				return nil, err
 			}
 			rawProfiles = strings.Split(strings.TrimSpace(string(out)), "\n")
 		} else {
 			out, err = runtime.RunAsUserWithOutput(netbirdBin, args)
 			if err != nil {
-				log.Printf("[ERROR]: could not get NetBird profiles, reason: %s", string(out))
+				slog.Error("could not get NetBird profiles", "output", string(out))
 				return nil, err
 			}
 			rawProfiles = strings.Split(strings.TrimSpace(string(out)), "\n")
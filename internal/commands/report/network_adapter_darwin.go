@@ -4,7 +4,7 @@ package report
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"os/exec"
 	"strings"
 
@@ -12,16 +12,14 @@ import (
 )
 
 func (r *Report) getNetworkAdaptersInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: network adapters info has been requested")
-	}
+	slog.Debug("network adapters info has been requested")
 
 	err := r.getNetworkAdaptersFromMac()
 	if err != nil {
-		log.Printf("[ERROR]: could not get network adapters information: %v", err)
+		slog.Error("could not get network adapters information", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: network adapters information has been retrieved")
+		slog.Info("network adapters information has been retrieved")
 	}
 	return nil
 }
@@ -63,7 +61,7 @@ func (r *Report) getNetworkAdaptersFromMac() error {
 func getDNSDomain() string {
 	out, err := exec.Command("hostname", "-d").Output()
 	if err != nil {
-		log.Println("[ERROR]: could not get the domain")
+		slog.Error("could not get the domain")
 		return ""
 	}
 
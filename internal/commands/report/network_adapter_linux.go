@@ -5,7 +5,7 @@ package report
 import (
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"os"
 	"os/exec"
@@ -19,16 +19,14 @@ import (
 )
 
 func (r *Report) getNetworkAdaptersInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: network adapters info has been requested")
-	}
+	slog.Debug("network adapters info has been requested")
 
 	err := r.getNetworkAdaptersFromLinux()
 	if err != nil {
-		log.Printf("[ERROR]: could not get network adapters information from ethtool: %v", err)
+		slog.Error("could not get network adapters information from ethtool", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: network adapters information has been retrieved from ethtool")
+		slog.Info("network adapters information has been retrieved from ethtool")
 	}
 	return nil
 }
@@ -49,20 +47,20 @@ func (r *Report) getNetworkAdaptersFromLinux() error {
 
 	ethHandle, err := ethtool.NewEthtool()
 	if err != nil {
-		log.Printf("[ERROR]: could not initialize ethtool, %v\n", err)
+		slog.Error("could not initialize ethtool", "error", err)
 		return err
 	}
 	defer ethHandle.Close()
 
 	ifaces, err := net.Interfaces()
 	if err != nil {
-		log.Printf("[ERROR]: could not get linux interfaces, %v\n", err)
+		slog.Error("could not get linux interfaces", "error", err)
 		return err
 	}
 
 	gateway, gwInterface, err := getDefaultGateway()
 	if err != nil {
-		log.Printf("[ERROR]: could not get default gateway, %v\n", err)
+		slog.Error("could not get default gateway", "error", err)
 	}
 
 	for _, i := range ifaces {
@@ -76,7 +74,7 @@ func (r *Report) getNetworkAdaptersFromLinux() error {
 		myNetworkAdapter.MACAddress = i.HardwareAddr.String()
 		ethSettings, err := ethtool.CmdGetMapped(i.Name)
 		if err != nil {
-			log.Printf("[INFO]: could not get eth settings using ethtool, %v\n", err)
+			slog.Info("could not get eth settings using ethtool", "error", err)
 			myNetworkAdapter.Speed = " - "
 		} else {
 			speedInBps := ethSettings["Speed"]
@@ -92,13 +90,13 @@ func (r *Report) getNetworkAdaptersFromLinux() error {
 
 		iface, err := net.InterfaceByName(i.Name)
 		if err != nil {
-			log.Printf("[ERROR]: could not get iface info from name, %v\n", err)
+			slog.Error("could not get iface info from name", "error", err)
 			continue
 		}
 
 		addresses, err := iface.Addrs()
 		if err != nil {
-			log.Printf("[ERROR]: could not get IP addresses assigned to interface, %v\n", err)
+			slog.Error("could not get IP addresses assigned to interface", "error", err)
 			continue
 		}
 
@@ -166,7 +164,7 @@ func getDNSservers() string {
 
 	file, err := os.ReadFile("/etc/resolv.conf")
 	if err != nil {
-		log.Println("[ERROR]: could not read /etc/resolv.conf")
+		slog.Error("could not read /etc/resolv.conf")
 		return ""
 	}
 
@@ -183,7 +181,7 @@ func isDHCPEnabled(ip string) bool {
 	command := fmt.Sprintf("ip -o address | grep %s | grep dynamic | wc -l", ip)
 	out, err := exec.Command("bash", "-c", command).Output()
 	if err != nil {
-		log.Println("[ERROR]: could not check if IP address has been set via DHCP")
+		slog.Error("could not check if IP address has been set via DHCP")
 		return false
 	}
 
@@ -193,7 +191,7 @@ func isDHCPEnabled(ip string) bool {
 func getDNSDomain() string {
 	out, err := exec.Command("hostname", "-d").Output()
 	if err != nil {
-		log.Println("[ERROR]: could not get the domain")
+		slog.Error("could not get the domain")
 		return ""
 	}
 
@@ -210,7 +208,7 @@ func getPhysicalAdapters() []string {
 	sysClassNet := "/sys/class/net"
 	devices, err := os.ReadDir(sysClassNet)
 	if err != nil {
-		log.Printf("[ERROR]: could not read /sys/class/net directory, reason: %v", err)
+		slog.Error("could not read /sys/class/net directory", "error", err)
 		return adapters
 	}
 
@@ -240,7 +238,7 @@ func getVirtualAdapters() []string {
 	sysClassNet := "/sys/class/net"
 	devices, err := os.ReadDir(sysClassNet)
 	if err != nil {
-		log.Printf("[ERROR]: could not read /sys/class/net directory, reason: %v", err)
+		slog.Error("could not read /sys/class/net directory", "error", err)
 		return adapters
 	}
 
@@ -248,7 +246,7 @@ func getVirtualAdapters() []string {
 		fullpath := path.Join(sysClassNet, link.Name())
 		dev, err := os.Readlink(fullpath)
 		if err != nil {
-			log.Printf("[ERROR]: could not read %s link, reason: %v", fullpath, err)
+			slog.Error(fmt.Sprintf("could not read %s link", fullpath), "error", err)
 			continue
 		}
 
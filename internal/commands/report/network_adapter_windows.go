@@ -5,7 +5,7 @@ package report
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -32,16 +32,14 @@ type networkAdapterConfiguration struct {
 }
 
 func (r *Report) getNetworkAdaptersInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: network adapters info has been requested")
-	}
+	slog.Debug("network adapters info has been requested")
 
 	err := r.getNetworkAdaptersFromWMI()
 	if err != nil {
-		log.Printf("[ERROR]: could not get network adapters information from WMI Win32_NetworkAdapter: %v", err)
+		slog.Error("could not get network adapters information from WMI Win32_NetworkAdapter", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: network adapters information has been retrieved from WMI Win32_NetworkAdapter")
+		slog.Info("network adapters information has been retrieved from WMI Win32_NetworkAdapter")
 	}
 	return nil
 }
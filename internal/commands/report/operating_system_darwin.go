@@ -3,7 +3,7 @@
 package report
 
 import (
-	"log"
+	"log/slog"
 	"os/exec"
 	"runtime"
 	"strings"
@@ -11,39 +11,35 @@ import (
 )
 
 func (r *Report) getOperatingSystemInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: operating system info has been requested")
-	}
+	slog.Debug("operating system info has been requested")
 
 	if err := r.getOSInfo(); err != nil {
-		log.Printf("[ERROR]: could not get OS info: %v", err)
+		slog.Error("could not get OS info", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: OS info has been retrieved")
+		slog.Info("OS info has been retrieved")
 	}
 
 	if err := r.getOSInstallationDate(); err != nil {
-		log.Printf("[ERROR]: could not get OS installation date: %v", err)
+		slog.Error("could not get OS installation date", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: OS installation date has been retrieved")
+		slog.Info("OS installation date has been retrieved")
 	}
 
 	if err := r.getSysBootupTime(); err != nil {
-		log.Printf("[ERROR]: could not get system boot up time: %v", err)
+		slog.Error("could not get system boot up time", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: system boot up time has been retrieved")
+		slog.Info("system boot up time has been retrieved")
 	}
 
-	if debug {
-		log.Println("[DEBUG]: username info has been requested")
-	}
+	slog.Debug("username info has been requested")
 	if err := r.getUsername(); err != nil {
-		log.Printf("[ERROR]: could not get current username: %v", err)
+		slog.Error("could not get current username", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: username has been retrieved")
+		slog.Info("username has been retrieved")
 	}
 	return nil
 }
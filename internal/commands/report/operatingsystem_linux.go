@@ -3,7 +3,7 @@
 package report
 
 import (
-	"log"
+	"log/slog"
 	"os/exec"
 	"strings"
 	"syscall"
@@ -17,9 +17,7 @@ func (r *Report) getOperatingSystemInfo(debug bool) error {
 
 	si.GetSysInfo()
 
-	if debug {
-		log.Println("[DEBUG]: operating system info has been requested")
-	}
+	slog.Debug("operating system info has been requested")
 
 	if si.OS.Release == "" {
 		r.OperatingSystem.Version = si.OS.Vendor + " " + si.OS.Version
@@ -44,27 +42,25 @@ func (r *Report) getOperatingSystemInfo(debug bool) error {
 	}
 
 	if err := r.getOSInstallationDate(); err != nil {
-		log.Printf("[ERROR]: could not get OS installation date: %v", err)
+		slog.Error("could not get OS installation date", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: OS installation date has been retrieved from Linux")
+		slog.Info("OS installation date has been retrieved from Linux")
 	}
 
 	if err := r.getSysBootupTime(); err != nil {
-		log.Printf("[ERROR]: could not get system boot up time: %v", err)
+		slog.Error("could not get system boot up time", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: system boot up time has been retrieved from Linux")
+		slog.Info("system boot up time has been retrieved from Linux")
 	}
 
-	if debug {
-		log.Println("[DEBUG]: username info has been requested")
-	}
+	slog.Debug("username info has been requested")
 	if err := r.getUsername(); err != nil {
-		log.Printf("[ERROR]: could not get current username from Linux: %v", err)
+		slog.Error("could not get current username from Linux", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: linux username has been retrieved from Linux")
+		slog.Info("linux username has been retrieved from Linux")
 	}
 	return nil
 }
@@ -5,7 +5,7 @@ package report
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os/exec"
 	"strings"
 	"time"
@@ -22,45 +22,37 @@ type windowsVersion struct {
 const MAX_DISPLAYNAME_LENGTH = 256
 
 func (r *Report) getOSInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: os info (operating system info) has been requested")
-	}
+	slog.Debug("os info (operating system info) has been requested")
 	r.OperatingSystem = openuem_nats.OperatingSystem{}
 	if err := r.getOperatingSystemInfo(debug); err != nil {
-		log.Printf("[ERROR]: could not get operating system info from WMI Win32_OperatingSystem: %v", err)
+		slog.Error("could not get operating system info from WMI Win32_OperatingSystem", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: operating system information has been retrieved using WMI Win32_OperatingSystem")
+		slog.Info("operating system information has been retrieved using WMI Win32_OperatingSystem")
 	}
 
-	if debug {
-		log.Println("[DEBUG]: os edition has been requested")
-	}
+	slog.Debug("os edition has been requested")
 	if err := r.getEdition(); err != nil {
-		log.Printf("[ERROR]: could not get current version from Windows Registry: %v", err)
+		slog.Error("could not get current version from Windows Registry", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: current version has been retrieved from Windows Registry")
+		slog.Info("current version has been retrieved from Windows Registry")
 	}
 
-	if debug {
-		log.Println("[DEBUG]: arch info has been requested")
-	}
+	slog.Debug("arch info has been requested")
 	if err := r.getArch(); err != nil {
-		log.Printf("[ERROR]: could not get windows arch from Windows Registry: %v", err)
+		slog.Error("could not get windows arch from Windows Registry", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: windows arch has been retrieved from Windows Registry")
+		slog.Info("windows arch has been retrieved from Windows Registry")
 	}
 
-	if debug {
-		log.Println("[DEBUG]: username info has been requested")
-	}
+	slog.Debug("username info has been requested")
 	if err := r.getUsername(); err != nil {
-		log.Printf("[ERROR]: could not get windows username from WMI Win32_Computer: %v", err)
+		slog.Error("could not get windows username from WMI Win32_Computer", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: windows username has been retrieved from WMI Win32_Computer")
+		slog.Info("windows username has been retrieved from WMI Win32_Computer")
 	}
 	return nil
 }
@@ -77,9 +69,7 @@ func (r *Report) getOperatingSystemInfo(debug bool) error {
 		Domain string
 	}
 
-	if debug {
-		log.Println("[DEBUG]: operating system info has been requested")
-	}
+	slog.Debug("operating system info has been requested")
 
 	namespace := `root\cimv2`
 	qOS := "SELECT Version, Caption, InstallDate, LastBootUpTime FROM Win32_OperatingSystem"
@@ -200,7 +190,7 @@ func GetLoggedOnUsername() (string, error) {
 	// Reference: https://stackoverflow.com/questions/32017531/finding-current-logged-on-users-while-running-as-system-no-environment-variab
 	out, err := exec.Command("PowerShell", "-command", "Get-Process -IncludeUserName -Name explorer | Select-Object UserName -Unique -Expand UserName").Output()
 	if err != nil {
-		log.Printf("[INFO]: could not get the owner of the explorer.exe process to get the username")
+		slog.Info("could not get the owner of the explorer.exe process to get the username")
 		return "", nil
 	}
 	username := strings.TrimSpace(string(out))
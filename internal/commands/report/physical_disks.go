@@ -1,24 +1,25 @@
 package report
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 )
 
 func (r *Report) logPhysicalDisks() {
-	log.Printf("\n** 💾 Physical Disks *************************************************************************************************\n")
+	slog.Info("** 💾 Physical Disks *************************************************************************************************")
 	if len(r.LogicalDisks) > 0 {
 		for i, myDisk := range r.PhysicalDisks {
-			log.Printf("%-40s |  %s \n", "Disk ID", myDisk.DeviceID)
-			log.Printf("%-40s |  %s \n", "Model", myDisk.Model)
-			log.Printf("%-40s |  %s \n", "Serial Number", myDisk.SerialNumber)
-			log.Printf("%-40s |  %s \n", "Size in units", myDisk.SizeInUnits)
+			slog.Info(fmt.Sprintf("%-40s | %s", "Disk ID", myDisk.DeviceID))
+			slog.Info(fmt.Sprintf("%-40s | %s", "Model", myDisk.Model))
+			slog.Info(fmt.Sprintf("%-40s | %s", "Serial Number", myDisk.SerialNumber))
+			slog.Info(fmt.Sprintf("%-40s | %s", "Size in units", myDisk.SizeInUnits))
 
 			if len(r.PhysicalDisks) > 1 && i+1 != len(r.PhysicalDisks) {
-				log.Printf("---------------------------------------------------------------------------------------------------------------------\n")
+				slog.Info("---------------------------------------------------------------------------------------------------------------------")
 			}
 		}
 		return
 	}
 
-	log.Printf("%-40s\n", "No physical disks found")
+	slog.Info(fmt.Sprintf("%-40s", "No physical disks found"))
 }
@@ -4,7 +4,7 @@ package report
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"os/exec"
 
 	openuem_nats "github.com/open-uem/nats"
@@ -29,9 +29,7 @@ func (r *Report) getPhysicalDisksInfo(debug bool) error {
 	var devices SPSerialATADataTypes
 	r.PhysicalDisks = []openuem_nats.PhysicalDisk{}
 
-	if debug {
-		log.Println("[DEBUG]: physical disk info retrieval started")
-	}
+	slog.Debug("physical disk info retrieval started")
 
 	out, err := exec.Command("system_profiler", "-json", "SPSerialATADataType").Output()
 	if err != nil {
@@ -57,9 +55,7 @@ func (r *Report) getPhysicalDisksInfo(debug bool) error {
 		r.PhysicalDisks = append(r.PhysicalDisks, disk)
 	}
 
-	if debug {
-		log.Println("[DEBUG]: physical disk info retrieval finished")
-	}
+	slog.Debug("physical disk info retrieval finished")
 
 	return nil
 }
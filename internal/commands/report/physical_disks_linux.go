@@ -4,7 +4,7 @@ package report
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"os/exec"
 	"strings"
 
@@ -26,9 +26,7 @@ func (r *Report) getPhysicalDisksInfo(debug bool) error {
 	var blockDevices BlockDevices
 	r.PhysicalDisks = []openuem_nats.PhysicalDisk{}
 
-	if debug {
-		log.Println("[DEBUG]: physical disk info retrieval started")
-	}
+	slog.Debug("physical disk info retrieval started")
 
 	out, err := exec.Command("lsblk", "--json", "--nodeps", "--bytes", "-o", "name,serial,model,size").Output()
 	if err != nil {
@@ -53,9 +51,7 @@ func (r *Report) getPhysicalDisksInfo(debug bool) error {
 		}
 	}
 
-	if debug {
-		log.Println("[DEBUG]: physical disk info retrieval finished")
-	}
+	slog.Debug("physical disk info retrieval finished")
 
 	return nil
 }
@@ -4,7 +4,8 @@ package report
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"strings"
 
 	openuem_nats "github.com/open-uem/nats"
@@ -20,9 +21,7 @@ type physicalDisk struct {
 func (r *Report) getPhysicalDisksFromWMI(debug bool) error {
 	var disksDst []physicalDisk
 
-	if debug {
-		log.Println("[DEBUG]: physical disk info retrieval started")
-	}
+	slog.Debug("physical disk info retrieval started")
 
 	namespace := `root\cimv2`
 	qDiskDrive := "SELECT DeviceID, Model, Size, SerialNumber FROM Win32_DiskDrive"
@@ -37,30 +36,22 @@ func (r *Report) getPhysicalDisksFromWMI(debug bool) error {
 
 		if v.Size != 0 {
 			myDisk.DeviceID = strings.TrimSpace(v.DeviceID)
-			if debug {
-				log.Println("[DEBUG]: physical disk info started for: ", myDisk.DeviceID)
-			}
+			slog.Debug(fmt.Sprintf("physical disk info started for: %v", myDisk.DeviceID))
 			myDisk.Model = strings.TrimSpace(v.Model)
 			myDisk.SerialNumber = strings.TrimSpace(v.SerialNumber)
 			myDisk.SizeInUnits = convertBytesToUnits(v.Size)
 
 			r.PhysicalDisks = append(r.PhysicalDisks, myDisk)
-			if debug {
-				log.Println("[DEBUG]: physical disk info finished for: ", myDisk.DeviceID)
-			}
+			slog.Debug(fmt.Sprintf("physical disk info finished for: %v", myDisk.DeviceID))
 		}
 	}
 
-	if debug {
-		log.Println("[DEBUG]: physical disk info retrieval finished")
-	}
+	slog.Debug("physical disk info retrieval finished")
 
 	return nil
 }
 
 func (r *Report) getPhysicalDisksInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: physical disks info has been requested")
-	}
+	slog.Debug("physical disks info has been requested")
 	return r.getPhysicalDisksFromWMI(debug)
 }
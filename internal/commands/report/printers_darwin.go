@@ -4,7 +4,7 @@ package report
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"os/exec"
 	"strings"
 
@@ -12,16 +12,14 @@ import (
 )
 
 func (r *Report) getPrintersInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: printers info has been requested")
-	}
+	slog.Debug("printers info has been requested")
 
 	err := r.getPrintersFromMac()
 	if err != nil {
-		log.Printf("[ERROR]: could not get printers information: %v", err)
+		slog.Error("could not get printers information", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: printers information has been retrieved")
+		slog.Info("printers information has been retrieved")
 	}
 	return nil
 }
@@ -40,7 +38,7 @@ func (r *Report) getPrintersFromMac() error {
 	}
 
 	if err := json.Unmarshal(out, &printerData); err != nil {
-		log.Printf("[ERROR]: could not unmarshal printers information: %s", out)
+		slog.Error("could not unmarshal printers information", "output", out)
 		return err
 	}
 
@@ -3,7 +3,7 @@
 package report
 
 import (
-	"log"
+	"log/slog"
 	"os/exec"
 	"strings"
 
@@ -11,16 +11,14 @@ import (
 )
 
 func (r *Report) getPrintersInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: printers info has been requested")
-	}
+	slog.Debug("printers info has been requested")
 
 	err := r.getPrintersFromLinux()
 	if err != nil {
-		log.Printf("[ERROR]: could not get printers information from Linux hwinfo: %v", err)
+		slog.Error("could not get printers information from Linux hwinfo", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: printers information has been retrieved from Linux hwinfo")
+		slog.Info("printers information has been retrieved from Linux hwinfo")
 	}
 	return nil
 }
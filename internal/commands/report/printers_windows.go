@@ -3,7 +3,7 @@
 package report
 
 import (
-	"log"
+	"log/slog"
 	"regexp"
 	"strconv"
 	"strings"
@@ -13,16 +13,14 @@ import (
 )
 
 func (r *Report) getPrintersInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: printers info has been requested")
-	}
+	slog.Debug("printers info has been requested")
 
 	err := r.getPrintersFromPowershell()
 	if err != nil {
-		log.Printf("[ERROR]: could not get printers information from WMI Win32_Printer: %v", err)
+		slog.Error("could not get printers information from WMI Win32_Printer", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: printers information has been retrieved from WMI Win32_Printer")
+		slog.Info("printers information has been retrieved from WMI Win32_Printer")
 	}
 	return nil
 }
@@ -32,7 +30,7 @@ func (r *Report) getPrintersFromPowershell() error {
 
 	out, err := runtime.RunAsUserWithOutput("powershell", []string{"gwmi", "Win32_Printer | Select Name, Default, PortName, Network, Shared"})
 	if err != nil {
-		log.Printf("[ERROR]: could not run powershell to get printers, reason: %v\n", err)
+		slog.Error("could not run powershell to get printers", "error", err)
 	}
 
 	reg := regexp.MustCompile(`(?:Name     : )(.*)`)
@@ -109,13 +107,13 @@ func (r *Report) getPrintersFromPowershell() error {
 // 		if err != nil {
 // 			return err
 // 		}
-// 		log.Println("[INFO]: printers info has been retrieved for logged in user")
+// 		slog.Info("printers info has been retrieved for logged in user")
 // 	} else {
 // 		err := WMIQueryWithContext(ctx, qPrinters, &printersDst, namespace)
 // 		if err != nil {
 // 			return err
 // 		}
-// 		log.Println("[INFO]: printers info has been retrieved for nt system/authority")
+// 		slog.Info("printers info has been retrieved for nt system/authority")
 // 	}
 
 // 	for _, v := range printersDst {
@@ -2,12 +2,53 @@ package report
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	openuem_nats "github.com/open-uem/nats"
 )
 
+// SECTIONS are the parts of the report that can be run and printed on their own
+var SECTIONS = []string{
+	"computer",
+	"operating_system",
+	"physical_disks",
+	"logical_disks",
+	"monitors",
+	"printers",
+	"shares",
+	"antivirus",
+	"system_update",
+	"network_adapters",
+	"applications",
+}
+
 type Report struct {
 	openuem_nats.AgentReport
+	Certificates []CertificateExpiry `json:"certificates,omitempty"`
+	Maintenance  *Maintenance        `json:"maintenance,omitempty"`
+}
+
+// CertificateExpiry tells the console when the agent's certificates must be renewed
+type CertificateExpiry struct {
+	Name     string    `json:"name"`
+	NotAfter time.Time `json:"not_after,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Maintenance tells the console when the agent runs the actions deferred to a maintenance window
+type Maintenance struct {
+	Windows    string           `json:"windows,omitempty"`
+	Open       bool             `json:"open"`
+	NextWindow time.Time        `json:"next_window,omitempty"`
+	Deferred   []DeferredAction `json:"deferred,omitempty"`
+}
+
+type DeferredAction struct {
+	ID       string    `json:"id"`
+	Action   string    `json:"action"`
+	Subject  string    `json:"subject,omitempty"`
+	QueuedAt time.Time `json:"queued_at"`
 }
 
 func (r *Report) logOS() {
@@ -38,4 +79,77 @@ func (r *Report) Print() {
 	r.logSystemUpdate()
 	r.logNetworkAdapters()
 	r.logApplications()
+	r.logCertificates()
+	r.logMaintenance()
+}
+
+func (r *Report) logCertificates() {
+	fmt.Printf("\n** 🔐 Certificates **************************************************************************************************\n")
+	if len(r.Certificates) == 0 {
+		fmt.Printf("%-40s\n", "No certificates have been checked")
+	}
+	for _, c := range r.Certificates {
+		if c.Error != "" {
+			fmt.Printf("%-40s |  %s\n", c.Name, c.Error)
+			continue
+		}
+		fmt.Printf("%-40s |  expires %s\n", c.Name, c.NotAfter.Local().Format(time.RFC1123))
+	}
+}
+
+func (r *Report) logMaintenance() {
+	fmt.Printf("\n** 🛠  Maintenance ***************************************************************************************************\n")
+	if r.Maintenance == nil || r.Maintenance.Windows == "" {
+		fmt.Printf("%-40s\n", "No maintenance windows, actions run when they're received")
+		return
+	}
+
+	fmt.Printf("%-40s |  %s\n", "Windows", r.Maintenance.Windows)
+	if r.Maintenance.Open {
+		fmt.Printf("%-40s |  %s\n", "Status", "open")
+	} else {
+		fmt.Printf("%-40s |  closed, next window opens %s\n", "Status", r.Maintenance.NextWindow.Local().Format(time.RFC1123))
+	}
+
+	if len(r.Maintenance.Deferred) == 0 {
+		fmt.Printf("%-40s |  %s\n", "Deferred actions", "none")
+	}
+	for _, d := range r.Maintenance.Deferred {
+		fmt.Printf("%-40s |  queued %s\n", d.Action, d.QueuedAt.Local().Format(time.RFC1123))
+	}
+}
+
+// RunSection runs a single collector, the rest of the report is left empty
+func RunSection(name string, debug bool) (*Report, error) {
+	r := Report{}
+
+	collect, ok := r.collectors(debug)[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown section %s, the sections are: %s", name, strings.Join(SECTIONS, ", "))
+	}
+
+	if err := runCollector(collect); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (r *Report) PrintSection(name string) {
+	printers := map[string]func(){
+		"computer":         r.logComputer,
+		"operating_system": r.logOS,
+		"physical_disks":   r.logPhysicalDisks,
+		"logical_disks":    r.logLogicalDisks,
+		"monitors":         r.logMonitors,
+		"printers":         r.logPrinters,
+		"shares":           r.logShares,
+		"antivirus":        r.logAntivirus,
+		"system_update":    r.logSystemUpdate,
+		"network_adapters": r.logNetworkAdapters,
+		"applications":     r.logApplications,
+	}
+
+	if printSection, ok := printers[name]; ok {
+		printSection()
+	}
 }
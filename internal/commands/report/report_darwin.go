@@ -3,7 +3,7 @@
 package report
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -13,22 +13,21 @@ import (
 	"time"
 
 	openuem_nats "github.com/open-uem/nats"
+	"github.com/open-uem/openuem-agent/internal/metrics"
 )
 
-func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAddress string, sftpDisabled, remoteAssistanceDisabled bool, tenantID string, siteID string) (*Report, error) {
+func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAddress string, sftpDisabled, remoteAssistanceDisabled bool, tenantID string, siteID string, dataDir string) (*Report, error) {
 	var wg sync.WaitGroup
 	var err error
 
-	if debug {
-		log.Println("[DEBUG]: preparing report info")
-	}
+	slog.Debug("preparing report info")
 
 	report := Report{}
 	report.AgentID = agentId
 	report.OS = "macOS"
 	report.SFTPPort = sftpPort
 	report.VNCProxyPort = vncProxyPort
-	report.CertificateReady = isCertificateReady()
+	report.CertificateReady = isCertificateReady(dataDir)
 	report.Enabled = enabled
 	report.SftpServiceDisabled = sftpDisabled
 	report.RemoteAssistanceDisabled = remoteAssistanceDisabled
@@ -45,20 +44,18 @@ func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAd
 
 	report.Hostname = getMacOSHostname()
 	if report.Hostname == "" {
-		log.Printf("[ERROR]: could not get computer name: %v", err)
+		slog.Error("could not get computer name", "error", err)
 		report.Hostname = "UNKNOWN"
 	}
 
-	if debug {
-		log.Println("[DEBUG]: report info ready")
-	}
+	slog.Debug("report info ready")
 
-	if debug {
-		log.Println("[DEBUG]: launching goroutines")
-	}
+	slog.Debug("launching goroutines")
 
 	// These operations will be run using goroutines
 	wg.Go(func() {
+		defer metrics.ObserveCollector("computer", time.Now())
+
 		if err := report.getComputerInfo(debug); err != nil {
 			// Retry
 			report.getComputerInfo(debug)
@@ -66,6 +63,8 @@ func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAd
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("memory_slots", time.Now())
+
 		if err := report.getMemorySlotsInfo(debug); err != nil {
 			// Retry
 			report.getMemorySlotsInfo(debug)
@@ -73,6 +72,8 @@ func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAd
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("operating_system", time.Now())
+
 		if err := report.getOperatingSystemInfo(debug); err != nil {
 			// Retry
 			report.getOperatingSystemInfo(debug)
@@ -80,6 +81,8 @@ func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAd
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("monitors", time.Now())
+
 		if err := report.getMonitorsInfo(debug); err != nil {
 			// Retry
 			report.getMonitorsInfo(debug)
@@ -87,6 +90,8 @@ func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAd
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("printers", time.Now())
+
 		if err := report.getPrintersInfo(debug); err != nil {
 			// Retry
 			report.getPrintersInfo(debug)
@@ -94,6 +99,8 @@ func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAd
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("shares", time.Now())
+
 		if err := report.getSharesInfo(); err != nil {
 			// Retry
 			report.getSharesInfo()
@@ -101,6 +108,8 @@ func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAd
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("antivirus", time.Now())
+
 		if err := report.getAntivirusInfo(); err != nil {
 			// Retry
 			report.getAntivirusInfo()
@@ -108,6 +117,8 @@ func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAd
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("network_adapters", time.Now())
+
 		if err := report.getNetworkAdaptersInfo(debug); err != nil {
 			// Retry
 			report.getNetworkAdaptersInfo(debug)
@@ -127,6 +138,8 @@ func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAd
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("applications", time.Now())
+
 		if err := report.getApplicationsInfo(debug); err != nil {
 			// Retry
 			report.getApplicationsInfo(debug)
@@ -134,16 +147,22 @@ func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAd
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("remote_desktop", time.Now())
+
 		if err := report.getRemoteDesktopInfo(debug); err != nil {
 			report.getRemoteDesktopInfo(debug)
 		}
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("rustdesk", time.Now())
+
 		report.hasRustDesk(debug)
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("update_task", time.Now())
+
 		if err := report.getUpdateTaskInfo(debug); err != nil {
 			// Retry
 			report.getUpdateTaskInfo(debug)
@@ -151,45 +170,52 @@ func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAd
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("physical_disks", time.Now())
+
 		if err := report.getPhysicalDisksInfo(debug); err != nil {
-			log.Printf("[ERROR]: could not get physical disks information: %v", err)
+			slog.Error("could not get physical disks information", "error", err)
 		} else {
-			log.Printf("[INFO]: physical disks information has been retrieved")
+			slog.Info("physical disks information has been retrieved")
 		}
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("netbird", time.Now())
+
 		if err := report.getNetbirdInfo(); err == nil {
-			log.Printf("[INFO]: Netbird information has been retrieved")
+			slog.Info("Netbird information has been retrieved")
 		}
 	})
 
 	wg.Wait()
 
 	// These tasks can affect previous tasks
+	start := time.Now()
 	if err := report.getSystemUpdateInfo(); err != nil {
 		// Retry
 		report.getSystemUpdateInfo()
 	}
+	metrics.ObserveCollector("system_update", start)
 
+	start = time.Now()
 	if err := report.getLogicalDisksInfo(debug); err != nil {
 		// Retry
 		report.getLogicalDisksInfo(debug)
 	}
+	metrics.ObserveCollector("logical_disks", start)
 
 	return &report, nil
 }
 
-func isCertificateReady() bool {
-	wd := "/etc/openuem-agent"
-
-	certPath := filepath.Join(wd, "certificates", "server.cer")
+// isCertificateReady checks if the server certificate and key have been saved in the data folder
+func isCertificateReady(dataDir string) bool {
+	certPath := filepath.Join(dataDir, "certificates", "server.cer")
 	_, err := os.Stat(certPath)
 	if err != nil {
 		return false
 	}
 
-	keyPath := filepath.Join(wd, "certificates", "server.key")
+	keyPath := filepath.Join(dataDir, "certificates", "server.key")
 	_, err = os.Stat(keyPath)
 	return err == nil
 }
@@ -201,3 +227,24 @@ func getMacOSHostname() string {
 	}
 	return strings.ToUpper(strings.TrimSpace(string(out)))
 }
+
+// collectors returns the function that fills each section that can be run on its own
+func (r *Report) collectors(debug bool) map[string]func() error {
+	return map[string]func() error{
+		"computer":         func() error { return r.getComputerInfo(debug) },
+		"operating_system": func() error { return r.getOperatingSystemInfo(debug) },
+		"physical_disks":   func() error { return r.getPhysicalDisksInfo(debug) },
+		"logical_disks":    func() error { return r.getLogicalDisksInfo(debug) },
+		"monitors":         func() error { return r.getMonitorsInfo(debug) },
+		"printers":         func() error { return r.getPrintersInfo(debug) },
+		"shares":           func() error { return r.getSharesInfo() },
+		"antivirus":        func() error { return r.getAntivirusInfo() },
+		"system_update":    func() error { return r.getSystemUpdateInfo() },
+		"network_adapters": func() error { return r.getNetworkAdaptersInfo(debug) },
+		"applications":     func() error { return r.getApplicationsInfo(debug) },
+	}
+}
+
+func runCollector(collect func() error) error {
+	return collect()
+}
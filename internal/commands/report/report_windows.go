@@ -3,7 +3,7 @@
 package report
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -12,45 +12,40 @@ import (
 
 	"github.com/doncicuto/comshim"
 	openuem_nats "github.com/open-uem/nats"
+	"github.com/open-uem/openuem-agent/internal/metrics"
 	openuem_utils "github.com/open-uem/utils"
 	"golang.org/x/sys/windows"
 	"gopkg.in/ini.v1"
 )
 
-func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAddress string, sftpDisabled, remoteAssistanceDisabled bool, tenantID string, siteID string) (*Report, error) {
+func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAddress string, sftpDisabled, remoteAssistanceDisabled bool, tenantID string, siteID string, dataDir string) (*Report, error) {
 	var wg sync.WaitGroup
 	var err error
 
-	if debug {
-		log.Println("[DEBUG]: preparing com")
-	}
+	slog.Debug("preparing com")
 	// Prepare COM
 	if err := comshim.Add(1); err != nil {
-		log.Printf("[ERROR]: run report could not add initial thread, %v", err)
+		slog.Error("run report could not add initial thread", "error", err)
 		return nil, err
 	}
-	log.Println("[INFO]: comshim added")
+	slog.Info("comshim added")
 	defer func() {
 		if err := comshim.Done(); err != nil {
-			log.Printf("[ERROR]: run report got en error in comshim Done, %v", err)
+			slog.Error("run report got en error in comshim Done", "error", err)
 		}
-		log.Println("[INFO]: comshim done")
+		slog.Info("comshim done")
 	}()
 
-	if debug {
-		log.Println("[DEBUG]: com prepared")
-	}
+	slog.Debug("com prepared")
 
-	if debug {
-		log.Println("[DEBUG]: preparing report info")
-	}
+	slog.Debug("preparing report info")
 
 	report := Report{}
 	report.AgentID = agentId
 	report.OS = "windows"
 	report.SFTPPort = sftpPort
 	report.VNCProxyPort = vncProxyPort
-	report.CertificateReady = isCertificateReady()
+	report.CertificateReady = isCertificateReady(dataDir)
 	report.Enabled = enabled
 	report.DebugMode = debug
 	report.SftpServiceDisabled = sftpDisabled
@@ -70,13 +65,13 @@ func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAd
 
 	key, err := cfg.Section("Agent").GetKey("RestartRequired")
 	if err != nil {
-		log.Println("[ERROR]: could not read RestartRequired from INI")
+		slog.Error("could not read RestartRequired from INI")
 		return nil, err
 	}
 
 	report.RestartRequired, err = key.Bool()
 	if err != nil {
-		log.Println("[ERROR]: could not parse RestartRequired")
+		slog.Error("could not parse RestartRequired")
 		return nil, err
 	}
 
@@ -90,53 +85,67 @@ func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAd
 
 	report.Hostname, err = windows.ComputerName()
 	if err != nil {
-		log.Printf("[ERROR]: could not get computer name: %v", err)
+		slog.Error("could not get computer name", "error", err)
 		report.Hostname = "UNKNOWN"
 	}
 
-	if debug {
-		log.Println("[DEBUG]: report info ready")
-	}
+	slog.Debug("report info ready")
 
-	if debug {
-		log.Println("[DEBUG]: launching goroutines")
-	}
+	slog.Debug("launching goroutines")
 
+	start := time.Now()
 	if err := report.getComputerInfo(debug); err != nil {
-		log.Println("[ERROR]: error retrieving computer info")
+		slog.Error("error retrieving computer info")
 	}
+	metrics.ObserveCollector("computer", start)
 
+	start = time.Now()
 	if err := report.getOperatingSystemInfo(debug); err != nil {
-		log.Println("[ERROR]: error retrieving operating system info")
+		slog.Error("error retrieving operating system info")
 	}
+	metrics.ObserveCollector("operating_system", start)
 
+	start = time.Now()
 	if err := report.getOSInfo(debug); err != nil {
-		log.Println("[ERROR]: error retrieving additional operating system info")
+		slog.Error("error retrieving additional operating system info")
 	}
+	metrics.ObserveCollector("operating_system_details", start)
 
+	start = time.Now()
 	if err := report.getMonitorsInfo(debug); err != nil {
-		log.Println("[ERROR]: error retrieving monitors info")
+		slog.Error("error retrieving monitors info")
 	}
+	metrics.ObserveCollector("monitors", start)
 
+	start = time.Now()
 	if err := report.getMemorySlotsInfo(debug); err != nil {
-		log.Println("[ERROR]: error retrieving memory slots info")
+		slog.Error("error retrieving memory slots info")
 	}
+	metrics.ObserveCollector("memory_slots", start)
 
+	start = time.Now()
 	if err := report.getPrintersInfo(debug); err != nil {
-		log.Println("[ERROR]: error retrieving printers info")
+		slog.Error("error retrieving printers info")
 	}
+	metrics.ObserveCollector("printers", start)
 
+	start = time.Now()
 	if err := report.getSharesInfo(debug); err != nil {
-		log.Println("[ERROR]: error retrieving shares info")
+		slog.Error("error retrieving shares info")
 	}
+	metrics.ObserveCollector("shares", start)
 
+	start = time.Now()
 	if err := report.getAntivirusInfo(debug); err != nil {
-		log.Println("[ERROR]: error retrieving antivirus info")
+		slog.Error("error retrieving antivirus info")
 	}
+	metrics.ObserveCollector("antivirus", start)
 
+	start = time.Now()
 	if err := report.getNetworkAdaptersInfo(debug); err != nil {
-		log.Println("[ERROR]: error retrieving network adapters info")
+		slog.Error("error retrieving network adapters info")
 	}
+	metrics.ObserveCollector("network_adapters", start)
 	for _, n := range report.NetworkAdapters {
 		if n.DefaultGateway != "" {
 			if n.Addresses == "" {
@@ -149,21 +158,29 @@ func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAd
 		}
 	}
 
+	start = time.Now()
 	if err := report.getApplicationsInfo(debug); err != nil {
-		log.Println("[ERROR]: error retrieving applications info")
+		slog.Error("error retrieving applications info")
 	}
+	metrics.ObserveCollector("applications", start)
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("remote_desktop", time.Now())
+
 		if err := report.getRemoteDesktopInfo(debug); err != nil {
 			report.getRemoteDesktopInfo(debug)
 		}
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("rustdesk", time.Now())
+
 		report.hasRustDesk(debug)
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("update_task", time.Now())
+
 		if err := report.getUpdateTaskInfo(debug); err != nil {
 			// Retry
 			report.getUpdateTaskInfo(debug)
@@ -171,49 +188,88 @@ func RunReport(agentId string, enabled, debug bool, vncProxyPort, sftpPort, ipAd
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("physical_disks", time.Now())
+
 		if err := report.getPhysicalDisksInfo(debug); err != nil {
-			log.Printf("[ERROR]: could not get physical disks information: %v", err)
+			slog.Error("could not get physical disks information", "error", err)
 		} else {
-			log.Printf("[INFO]: physical disks information has been retrieved")
+			slog.Info("physical disks information has been retrieved")
 		}
 	})
 
 	wg.Go(func() {
+		defer metrics.ObserveCollector("netbird", time.Now())
+
 		if err := report.getNetbirdInfo(); err == nil {
-			log.Printf("[INFO]: Netbird information has been retrieved")
+			slog.Info("Netbird information has been retrieved")
 		}
 	})
 
 	wg.Wait()
 
 	// These tasks can affect previous tasks
+	start = time.Now()
 	if err := report.getSystemUpdateInfo(debug); err != nil {
 		// Retry
 		report.getSystemUpdateInfo(debug)
 	}
+	metrics.ObserveCollector("system_update", start)
 
+	start = time.Now()
 	if err := report.getLogicalDisksInfo(debug); err != nil {
 		// Retry
 		report.getLogicalDisksInfo(debug)
 	}
+	metrics.ObserveCollector("logical_disks", start)
 
 	return &report, nil
 }
 
-func isCertificateReady() bool {
-	wd, err := openuem_utils.GetWd()
-	if err != nil {
-		log.Println("[ERROR]: could not get working directory")
-		return false
-	}
-
-	certPath := filepath.Join(wd, "certificates", "server.cer")
-	_, err = os.Stat(certPath)
+// isCertificateReady checks if the server certificate and key have been saved in the data folder
+func isCertificateReady(dataDir string) bool {
+	certPath := filepath.Join(dataDir, "certificates", "server.cer")
+	_, err := os.Stat(certPath)
 	if err != nil {
 		return false
 	}
 
-	keyPath := filepath.Join(wd, "certificates", "server.key")
+	keyPath := filepath.Join(dataDir, "certificates", "server.key")
 	_, err = os.Stat(keyPath)
 	return err == nil
 }
+
+// collectors returns the function that fills each section that can be run on its own
+func (r *Report) collectors(debug bool) map[string]func() error {
+	return map[string]func() error{
+		"computer": func() error { return r.getComputerInfo(debug) },
+		"operating_system": func() error {
+			if err := r.getOperatingSystemInfo(debug); err != nil {
+				return err
+			}
+			return r.getOSInfo(debug)
+		},
+		"physical_disks":   func() error { return r.getPhysicalDisksInfo(debug) },
+		"logical_disks":    func() error { return r.getLogicalDisksInfo(debug) },
+		"monitors":         func() error { return r.getMonitorsInfo(debug) },
+		"printers":         func() error { return r.getPrintersInfo(debug) },
+		"shares":           func() error { return r.getSharesInfo(debug) },
+		"antivirus":        func() error { return r.getAntivirusInfo(debug) },
+		"system_update":    func() error { return r.getSystemUpdateInfo(debug) },
+		"network_adapters": func() error { return r.getNetworkAdaptersInfo(debug) },
+		"applications":     func() error { return r.getApplicationsInfo(debug) },
+	}
+}
+
+// runCollector prepares COM as WMI queries need it
+func runCollector(collect func() error) error {
+	if err := comshim.Add(1); err != nil {
+		return err
+	}
+	defer func() {
+		if err := comshim.Done(); err != nil {
+			slog.Error("got an error in comshim Done", "error", err)
+		}
+	}()
+
+	return collect()
+}
@@ -3,7 +3,7 @@
 package report
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -13,9 +13,7 @@ import (
 
 func (r *Report) hasRustDesk(debug bool) {
 
-	if debug {
-		log.Println("[DEBUG]: check if RustDesk is available has been requested")
-	}
+	slog.Debug("check if RustDesk is available has been requested")
 
 	commonPath := "/usr/bin/rustdesk"
 	if _, err := os.Stat(commonPath); err == nil {
@@ -41,16 +39,14 @@ func (r *Report) hasRustDesk(debug bool) {
 	}
 
 	if r.HasRustDesk {
-		log.Println("[INFO]: RustDesk is available")
+		slog.Info("RustDesk is available")
 	} else {
-		log.Println("[INFO]: RustDesk is not available")
+		slog.Info("RustDesk is not available")
 	}
 }
 
 func (r *Report) hasRustDeskService(debug bool) {
-	if debug {
-		log.Println("[DEBUG]: check if RustDesk service is available")
-	}
+	slog.Debug("check if RustDesk service is available")
 	r.HasRustDeskService = false
 }
 
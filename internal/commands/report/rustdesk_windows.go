@@ -3,14 +3,12 @@
 package report
 
 import (
-	"log"
+	"log/slog"
 	"os"
 )
 
 func (r *Report) hasRustDesk(debug bool) {
-	if debug {
-		log.Println("[DEBUG]: check if RustDesk is available has been requested")
-	}
+	slog.Debug("check if RustDesk is available has been requested")
 
 	binPath := "C:\\Program Files\\RustDesk\\rustdesk.exe"
 
@@ -19,8 +17,8 @@ func (r *Report) hasRustDesk(debug bool) {
 	}
 
 	if r.HasRustDesk {
-		log.Println("[INFO]: RustDesk is available")
+		slog.Info("RustDesk is available")
 	} else {
-		log.Println("[INFO]: RustDesk is not available")
+		slog.Info("RustDesk is not available")
 	}
 }
@@ -3,7 +3,7 @@
 package report
 
 import (
-	"log"
+	"log/slog"
 	"os/exec"
 	"regexp"
 
@@ -20,7 +20,7 @@ func (r *Report) getShares() {
 	listExported := `sharing -l`
 	out, err := exec.Command("bash", "-c", listExported).Output()
 	if err != nil {
-		log.Printf("[ERROR]: could not run the sharing command, reason: %v", err)
+		slog.Error("could not run the sharing command", "error", err)
 		return
 	}
 
@@ -45,5 +45,5 @@ func (r *Report) getShares() {
 		}
 	}
 
-	log.Println("[INFO]: shares information has been retrieved")
+	slog.Info("shares information has been retrieved")
 }
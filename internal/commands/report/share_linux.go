@@ -4,7 +4,7 @@ package report
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os/exec"
 	"strings"
 
@@ -24,7 +24,7 @@ func (r *Report) getExportedNFSShares() {
 	listExportedNFS := `showmount -e --no-headers | awk '{print $1}'`
 	out, err := exec.Command("bash", "-c", listExportedNFS).Output()
 	if err != nil {
-		log.Printf("[ERROR]: could not run the showmount command, reason: %v", err)
+		slog.Error("could not run the showmount command", "error", err)
 		r.Shares = shares
 		return
 	}
@@ -39,7 +39,7 @@ func (r *Report) getExportedNFSShares() {
 			nfsInfo := fmt.Sprintf("cat /etc/exports | grep %s | awk '{print $2}'", exportedNFS.Name)
 			out, err = exec.Command("bash", "-c", nfsInfo).Output()
 			if err != nil {
-				log.Printf("[ERROR]: could not get information from /etc/exports, reason: %v", err)
+				slog.Error("could not get information from /etc/exports", "error", err)
 				continue
 			}
 
@@ -4,20 +4,18 @@ package report
 
 import (
 	"context"
-	"log"
+	"log/slog"
 )
 
 func (r *Report) getSharesInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: shares info has been requested")
-	}
+	slog.Debug("shares info has been requested")
 
 	err := r.getSharesFromWMI()
 	if err != nil {
-		log.Printf("[ERROR]: could not get shares information from WMI Win32_Share: %v", err)
+		slog.Error("could not get shares information from WMI Win32_Share", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: shares information has been retrieved from WMI Win32_Share")
+		slog.Info("shares information has been retrieved from WMI Win32_Share")
 	}
 	return nil
 }
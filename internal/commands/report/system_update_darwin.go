@@ -3,7 +3,7 @@
 package report
 
 import (
-	"log"
+	"log/slog"
 	"os/exec"
 	"regexp"
 	"strconv"
@@ -25,7 +25,7 @@ func (r *Report) CheckSecurityUpdatesAvailable() {
 	out, err := exec.Command("softwareupdate", "-l").CombinedOutput()
 
 	if err != nil {
-		log.Printf("[ERROR]: could not run softwareupdate -l, reason: %v", err)
+		slog.Error("could not run softwareupdate -l", "error", err)
 		return
 	}
 
@@ -37,7 +37,7 @@ func (r *Report) CheckUpdatesStatus() {
 	automaticDownloadsCmd := `defaults read /Library/Preferences/com.apple.SoftwareUpdate.plist AutomaticDownload`
 	out, err := exec.Command("bash", "-c", automaticDownloadsCmd).Output()
 	if err != nil {
-		log.Printf("[ERROR]: could not read AutomaticDownload from SoftwareUpdate.plist, reason: %v", err)
+		slog.Error("could not read AutomaticDownload from SoftwareUpdate.plist", "error", err)
 		download = true
 	} else {
 		downloadsOut := strings.TrimSpace(string(out))
@@ -56,7 +56,7 @@ func (r *Report) CheckUpdatesStatus() {
 	automaticInstallCmd := `defaults read /Library/Preferences/com.apple.SoftwareUpdate.plist AutomaticallyInstallMacOSUpdates`
 	out, err = exec.Command("bash", "-c", automaticInstallCmd).Output()
 	if err != nil {
-		log.Printf("[ERROR]: could not read AutomaticallyInstallMacOSUpdates from SoftwareUpdate.plist, reason: %v", err)
+		slog.Error("could not read AutomaticallyInstallMacOSUpdates from SoftwareUpdate.plist", "error", err)
 		r.SystemUpdate.Status = nats.NOTIFY_BEFORE_INSTALLATION
 		automatic = false
 	} else {
@@ -81,14 +81,14 @@ func (r *Report) CheckSecurityUpdatesLastSearch() {
 	lastSearchCmd := `defaults read /Library/Preferences/com.apple.SoftwareUpdate.plist LastSuccessfulDate`
 	out, err := exec.Command("bash", "-c", lastSearchCmd).Output()
 	if err != nil {
-		log.Printf("[ERROR]: could not read LastSuccessfulDate from SoftwareUpdate.plist, reason: %v", err)
+		slog.Error("could not read LastSuccessfulDate from SoftwareUpdate.plist", "error", err)
 		return
 	}
 
 	//2025-06-04 12:05:57 +0000
 	lastSearch, err := time.Parse("2006-01-02 15:04:05 -0700", strings.TrimSpace(string(out)))
 	if err != nil {
-		log.Printf("[ERROR]: could not parse date from SoftwareUpdate.plist, reason: %v", err)
+		slog.Error("could not parse date from SoftwareUpdate.plist", "error", err)
 		return
 	}
 
@@ -99,7 +99,7 @@ func (r *Report) getUpdatesHistory() error {
 	listUpdatesCmd := `softwareupdate --history | grep -v Display | grep -v '\-'`
 	out, err := exec.Command("bash", "-c", listUpdatesCmd).Output()
 	if err != nil {
-		log.Printf("[ERROR]: could not read software update history, reason: %v", err)
+		slog.Error("could not read software update history", "error", err)
 		return err
 	}
 
@@ -114,13 +114,13 @@ func (r *Report) getUpdatesHistory() error {
 			matches := reg.FindAllStringSubmatch(trimmedSpaces, -1)
 			for _, v := range matches {
 				if len(v) != 1 {
-					log.Println("[ERROR]: could not match date regex for security update")
+					slog.Error("could not match date regex for security update")
 					update.Title = trimmedSpaces
 					break
 				}
 				myDate, err := time.Parse("01/02/2006, 15:04:05", v[0])
 				if err != nil {
-					log.Printf("[ERROR]: could not parse the installation date for security update, reason: %v", err)
+					slog.Error("could not parse the installation date for security update", "error", err)
 					update.Title = trimmedSpaces
 				} else {
 					update.Date = myDate
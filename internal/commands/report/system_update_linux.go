@@ -4,7 +4,7 @@ package report
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"strconv"
@@ -19,22 +19,22 @@ func (r *Report) getSystemUpdateInfo() error {
 	switch r.OS {
 	case "ubuntu", "debian", "linuxmint", "neon":
 		if err := r.getAptInformation(); err != nil {
-			log.Printf("[ERROR]: could not get pending security updates, reason: %v", err)
+			slog.Error("could not get pending security updates", "error", err)
 		} else {
-			log.Println("[INFO]: get pending security updates info has been retrieved")
+			slog.Info("get pending security updates info has been retrieved")
 		}
 	case "fedora", "almalinux", "redhat", "rocky":
 		if strings.Contains(r.OperatingSystem.Description, "Silverblue") || strings.Contains(r.OperatingSystem.Description, "Kinoite") {
 			if err := r.getSilverblueInformation(); err != nil {
-				log.Printf("[ERROR]: could not get pending security updates, reason: %v", err)
+				slog.Error("could not get pending security updates", "error", err)
 			} else {
-				log.Println("[INFO]: get pending security updates info has been retrieved")
+				slog.Info("get pending security updates info has been retrieved")
 			}
 		} else {
 			if err := r.getDnfInformation(); err != nil {
-				log.Printf("[ERROR]: could not get pending security updates, reason: %v", err)
+				slog.Error("could not get pending security updates", "error", err)
 			} else {
-				log.Println("[INFO]: get pending security updates info has been retrieved")
+				slog.Info("get pending security updates info has been retrieved")
 			}
 		}
 
@@ -116,19 +116,19 @@ func (r *Report) getSilverblueInformation() error {
 
 func checkAptSecurityUpdatesAvailable() bool {
 	if err := exec.Command("apt", "update").Run(); err != nil {
-		log.Printf("[ERROR]: could not run apt update, reason: %v", err)
+		slog.Error("could not run apt update", "error", err)
 	}
 
 	secUpdatesAvailable := `apt list --upgradable 2>/dev/null | grep "\-security" | wc -l`
 	out, err := exec.Command("bash", "-c", secUpdatesAvailable).Output()
 	if err != nil {
-		log.Printf("[ERROR]: could not check if updates are available, reason: %v", err)
+		slog.Error("could not check if updates are available", "error", err)
 		return false
 	}
 
 	nUpdates, err := strconv.Atoi(strings.TrimSpace(string(out)))
 	if err != nil {
-		log.Printf("[ERROR]: could not get the number of updates available, reason: %v", err)
+		slog.Error("could not get the number of updates available", "error", err)
 		return false
 	}
 
@@ -139,13 +139,13 @@ func checkDnfSecurityUpdatesAvailable() bool {
 	secUpdatesAvailable := `dnf check-update --refresh --security | wc -l`
 	out, err := exec.Command("bash", "-c", secUpdatesAvailable).Output()
 	if err != nil {
-		log.Printf("[ERROR]: could not check if updates are available, reason: %v", err)
+		slog.Error("could not check if updates are available", "error", err)
 		return false
 	}
 
 	nUpdates, err := strconv.Atoi(strings.TrimSpace(string(out)))
 	if err != nil {
-		log.Printf("[ERROR]: could not get the number of updates available, reason: %v", err)
+		slog.Error("could not get the number of updates available", "error", err)
 		return false
 	}
 
@@ -155,7 +155,7 @@ func checkDnfSecurityUpdatesAvailable() bool {
 func checkUpdatesStatus() string {
 	out, err := exec.Command("apt-config", "dump", "APT::Periodic::Unattended-Upgrade").Output()
 	if err != nil {
-		log.Printf("[ERROR]: could not dump apt-config for Unattended-Upgrade, reason: %v", err)
+		slog.Error("could not dump apt-config for Unattended-Upgrade", "error", err)
 		return nats.NOT_CONFIGURED
 	}
 
@@ -174,13 +174,13 @@ func checkLastTimePackagesInstalled() time.Time {
 	lastInstall := `grep "Upgrade:" -B 4 /var/log/apt/history.log | grep -v "Upgrade:" | grep Start-Date | tail -1 | awk '{print $2,$3}'`
 	out, err := exec.Command("bash", "-c", lastInstall).Output()
 	if err != nil {
-		log.Printf("[ERROR]: could not read DNF history log, reason: %v", err)
+		slog.Error("could not read DNF history log", "error", err)
 		return time.Time{}
 	}
 
 	history := strings.TrimSpace(string(out))
 	if history == "" {
-		log.Println("[INFO]: no info available from /var/log/apt/history.log")
+		slog.Info("no info available from /var/log/apt/history.log")
 		return time.Time{}
 	}
 
@@ -191,7 +191,7 @@ func checkLastTimePackagesInstalled() time.Time {
 
 	t, err := time.ParseInLocation("2006-01-02 15:04:05", history, loc)
 	if err != nil {
-		log.Printf("[ERROR]: could not parse time string %s from APT history log, reason: %v", string(out), err)
+		slog.Error("could not parse time string from APT history log", "output", string(out), "error", err)
 		return time.Time{}
 	}
 
@@ -204,13 +204,13 @@ func checkDnfLastTimePackagesInstalled() time.Time {
 	lastInstall := `dnf history list | grep -m 1 update | awk '{print $5,$6}'`
 	out, err := exec.Command("bash", "-c", lastInstall).Output()
 	if err != nil {
-		log.Printf("[ERROR]: could not read DNF history log, reason: %v", err)
+		slog.Error("could not read DNF history log", "error", err)
 		return time.Time{}
 	}
 
 	history := strings.TrimSpace(string(out))
 	if history == "" {
-		log.Println("[INFO]: no info available from dnf history list")
+		slog.Info("no info available from dnf history list")
 		return time.Time{}
 	}
 
@@ -230,13 +230,13 @@ func checkDnfLastTimePackagesInstalled() time.Time {
 	lastInstall = `dnf history list | grep -m 1 update | awk '{print $6,$7}'`
 	out, err = exec.Command("bash", "-c", lastInstall).Output()
 	if err != nil {
-		log.Printf("[ERROR]: could not read DNF history log, reason: %v", err)
+		slog.Error("could not read DNF history log", "error", err)
 		return time.Time{}
 	}
 
 	history = strings.TrimSpace(string(out))
 	if history == "" {
-		log.Println("[INFO]: no info available from dnf history list")
+		slog.Info("no info available from dnf history list")
 		return time.Time{}
 	}
 
@@ -267,13 +267,13 @@ func checkSilverblueSecurityUpdatesAvailable() bool {
 	secUpdatesAvailable := `sudo rpm-ostree upgrade --check | grep SecAdvisories | wc -l`
 	out, err := exec.Command("bash", "-c", secUpdatesAvailable).Output()
 	if err != nil {
-		log.Printf("[ERROR]: could not check if updates are available, reason: %v", err)
+		slog.Error("could not check if updates are available", "error", err)
 		return false
 	}
 
 	nUpdates, err := strconv.Atoi(strings.TrimSpace(string(out)))
 	if err != nil {
-		log.Printf("[ERROR]: could not get the number of updates available, reason: %v", err)
+		slog.Error("could not get the number of updates available", "error", err)
 		return false
 	}
 
@@ -286,13 +286,13 @@ func checkSilverblueLastTimePackagesInstalled() time.Time {
 	lastInstall := `sudo rpm-ostree status | grep -m1 Version | awk '{print $3}'`
 	out, err := exec.Command("bash", "-c", lastInstall).Output()
 	if err != nil {
-		log.Printf("[ERROR]: could not read DNF history log, reason: %v", err)
+		slog.Error("could not read DNF history log", "error", err)
 		return time.Time{}
 	}
 
 	history := strings.TrimSpace(string(out))
 	if history == "" {
-		log.Println("[INFO]: no info available from dnf history list")
+		slog.Info("no info available from dnf history list")
 		return time.Time{}
 	}
 
@@ -312,18 +312,18 @@ func checkSilverblueLastTimePackagesInstalled() time.Time {
 // 	lastInstall := `grep "'update'" /var/log/zypp/history | cut -f 1 -d '|'`
 // 	out, err := exec.Command("bash", "-c", lastInstall).Output()
 // 	if err != nil {
-// 		log.Printf("[ERROR]: could not read DNF history log, reason: %v", err)
+// 		slog.Error("could not read DNF history log", "error", err)
 // 		return time.Time{}
 // 	}
 
 // 	history := strings.TrimSpace(string(out))
 // 	if history == "" {
-// 		log.Println("[INFO]: no info available from /var/log/zypp/history")
+// 		slog.Info("no info available from /var/log/zypp/history")
 // 	}
 
 // 	t, err := time.Parse("2006-01-02 15:04:05", history)
 // 	if err != nil {
-// 		log.Printf("[ERROR]: could not parse time string %s from Zypper history log, reason: %v", string(out), err)
+// 		slog.Error("could not parse time string from Zypper history log", "output", string(out), "error", err)
 // 		return time.Time{}
 // 	}
 
@@ -349,7 +349,7 @@ func IsGnomeSoftwareUpdatesEnabled() bool {
 	args := []string{"read", "/org/gnome/software/download-updates"}
 	out, err := runtime.RunAsUserWithOutput(username, "/usr/bin/dconf", args, true)
 	if err != nil {
-		log.Printf("[INFO]: could not find the dconf entry for download-updates, reason %v", err)
+		slog.Info("could not find the dconf entry for download-updates", "error", err)
 		return false
 	}
 
@@ -2,7 +2,7 @@ package report
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"time"
 
 	wu "github.com/ceshihao/windowsupdate"
@@ -23,9 +23,7 @@ const (
 )
 
 func (r *Report) getSystemUpdateInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: system updates info has been requested")
-	}
+	slog.Debug("system updates info has been requested")
 
 	// Get information about Windows Update settings
 
@@ -35,45 +33,37 @@ func (r *Report) getSystemUpdateInfo(debug bool) error {
 
 	// TODO 2 (security) check if firewall is enabled in the three possible domains
 
-	if debug {
-		log.Println("[DEBUG]: windows update status info has been requested")
-	}
+	slog.Debug("windows update status info has been requested")
 	if err := r.getWindowsUpdateStatusWithCancelContext(); err != nil {
-		log.Printf("[ERROR]: could not get windows update status info information from wuapi: %v", err)
+		slog.Error("could not get windows update status info information from wuapi", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: windows update status info has been retrieved from wuapi")
+		slog.Info("windows update status info has been retrieved from wuapi")
 	}
 
-	if debug {
-		log.Println("[DEBUG]: windows update dates info has been requested")
-	}
+	slog.Debug("windows update dates info has been requested")
 	if err := r.getWindowsUpdateDatesWithCancelContext(); err != nil {
-		log.Printf("[ERROR]: could not get windows update dates information from wuapi: %v", err)
+		slog.Error("could not get windows update dates information from wuapi", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: windows update dates info has been retrieved from wuapi")
+		slog.Info("windows update dates info has been retrieved from wuapi")
 	}
 
-	if debug {
-		log.Println("[DEBUG]: windows update pending updates info has been requested")
-	}
+	slog.Debug("windows update pending updates info has been requested")
 	if err := r.getPendingUpdatesWithCancelContext(); err != nil {
-		log.Printf("[ERROR]: could not get pending updates information from wuapi: %v", err)
+		slog.Error("could not get pending updates information from wuapi", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: pending updates info has been retrieved from wuapi")
+		slog.Info("pending updates info has been retrieved from wuapi")
 	}
 
-	if debug {
-		log.Println("[DEBUG]: windows update history info has been requested")
-	}
+	slog.Debug("windows update history info has been requested")
 
 	if err := r.getUpdatesHistoryWithCancelContext(); err != nil {
-		log.Printf("[ERROR]: could not get updates history information from wuapi: %v", err)
+		slog.Error("could not get updates history information from wuapi", "error", err)
 		return err
 	} else {
-		log.Printf("[INFO]: updates history info has been retrieved from wuapi")
+		slog.Info("updates history info has been retrieved from wuapi")
 	}
 
 	return nil
@@ -1,7 +1,7 @@
 package report
 
 import (
-	"log"
+	"log/slog"
 	"time"
 
 	openuem_utils "github.com/open-uem/utils"
@@ -9,9 +9,7 @@ import (
 )
 
 func (r *Report) getUpdateTaskInfo(debug bool) error {
-	if debug {
-		log.Println("[DEBUG]: update task info has been requested")
-	}
+	slog.Debug("update task info has been requested")
 
 	// Open ini file
 	configFile := openuem_utils.GetAgentConfigFile()
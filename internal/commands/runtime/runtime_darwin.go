@@ -6,7 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"os/user"
@@ -135,7 +135,7 @@ func RunAsUserWithMachineCtl(username, myCmd string) error {
 	command := fmt.Sprintf("machinectl shell %s@ %s", username, myCmd)
 	cmd := exec.Command("bash", "-c", command)
 	if err := cmd.Run(); err != nil {
-		log.Printf("[ERROR]: could not run command %s, reason: %v", command, err)
+		slog.Error(fmt.Sprintf("could not run command %s", command), "error", err)
 	}
 	return nil
 }
@@ -159,7 +159,7 @@ func GetXAuthority(uid, gid uint32) (string, error) {
 	}
 	envOut, err := envCmd.Output()
 	if err != nil {
-		log.Println("[ERROR]: could not execute bash script to get XAuthority")
+		slog.Error("could not execute bash script to get XAuthority")
 		return "", err
 	}
 	xauthority := string(envOut)
@@ -195,7 +195,7 @@ func RunAsUserInBackground(username, cmdPath string, args []string, env bool) er
 	}
 
 	if err := cmd.Start(); err != nil {
-		log.Printf("[ERROR]: run as user %s found an err: %v", username, err)
+		slog.Error(fmt.Sprintf("run as user %s found an", username), "error", err)
 		return err
 	}
 
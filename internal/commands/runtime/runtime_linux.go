@@ -5,7 +5,7 @@ package runtime
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"os/user"
@@ -60,7 +60,7 @@ func RunAsUser(username, cmdPath string, args []string, env bool) error {
 
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		log.Printf("[ERROR]: run as user %s found an err with this combined output: %s", username, string(out))
+		slog.Error(fmt.Sprintf("run as user %s found an err with this combined", username), "output", string(out))
 		return err
 	}
 
@@ -112,7 +112,7 @@ func RunAsUserWithOutput(username, cmdPath string, args []string, env bool) ([]b
 
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		log.Printf("[ERROR]: run as user %s found an err with this combined output: %s", username, string(out))
+		slog.Error(fmt.Sprintf("run as user %s found an err with this combined", username), "output", string(out))
 		return out, err
 	}
 
@@ -167,7 +167,7 @@ func RunAsUserWithOutputAndTimeout(username, cmdPath string, args []string, env
 
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		log.Printf("[ERROR]: run as user %s found an err with this combined output: %s", username, string(out))
+		slog.Error(fmt.Sprintf("run as user %s found an err with this combined", username), "output", string(out))
 		return nil, err
 	}
 
@@ -178,7 +178,7 @@ func RunAsUserWithMachineCtl(username, myCmd string) error {
 	command := fmt.Sprintf("machinectl shell %s@ %s", username, myCmd)
 	cmd := exec.Command("bash", "-c", command)
 	if err := cmd.Run(); err != nil {
-		log.Printf("[ERROR]: could not run command %s, reason: %v", command, err)
+		slog.Error(fmt.Sprintf("could not run command %s", command), "error", err)
 	}
 	return nil
 }
@@ -254,7 +254,7 @@ func RunAsUserInBackground(username, cmdPath string, args []string, env bool) er
 	}
 
 	if err := cmd.Start(); err != nil {
-		log.Printf("[ERROR]: run as user %s found an err: %v", username, err)
+		slog.Error(fmt.Sprintf("run as user %s found an", username), "error", err)
 		return err
 	}
 
@@ -270,7 +270,7 @@ func GetXAuthority(uid, gid uint32) (string, error) {
 	}
 	envOut, err := envCmd.Output()
 	if err != nil {
-		log.Println("[ERROR]: could not execute bash script to get XAuthority")
+		slog.Error("could not execute bash script to get XAuthority")
 		return "", err
 	}
 	xauthority := string(envOut)
@@ -304,7 +304,7 @@ func GetDisplay(uid, gid uint32) (string, error) {
 	}
 	envOut, err := envCmd.Output()
 	if err != nil {
-		log.Println("[ERROR]: could not execute bash script to get Display")
+		slog.Error("could not execute bash script to get Display")
 		return "", err
 	}
 	display := string(envOut)
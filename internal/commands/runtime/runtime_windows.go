@@ -6,7 +6,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
-	"log"
+	"log/slog"
 	"os/exec"
 	"syscall"
 	"time"
@@ -21,11 +21,11 @@ func getUserToken(pid int) (syscall.Token, error) {
 
 	handle, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
 	if err != nil {
-		log.Printf("[ERROR]: token process, reason: %v\n", err)
+		slog.Error("token process", "error", err)
 	}
 	defer func() {
 		if err := syscall.CloseHandle(handle); err != nil {
-			log.Println("[ERROR]: could not close user token handle")
+			slog.Error("could not close user token handle")
 		}
 
 	}()
@@ -34,7 +34,7 @@ func getUserToken(pid int) (syscall.Token, error) {
 	err = syscall.OpenProcessToken(handle, syscall.TOKEN_ALL_ACCESS, &token)
 
 	if err != nil {
-		log.Printf("[ERROR]: open token process, reason: %v\n", err)
+		slog.Error("open token process", "error", err)
 	}
 	return token, err
 }
@@ -87,7 +87,7 @@ func RunAsUser(cmdPath string, args []string) error {
 
 	err = SetPriorityWindows(cmd.Process.Pid, windows.IDLE_PRIORITY_CLASS)
 	if err != nil {
-		log.Println("[ERROR]: could not change process priority")
+		slog.Error("could not change process priority")
 	}
 
 	err = cmd.Wait()
@@ -131,7 +131,7 @@ func RunAsUserWithOutput(cmdPath string, args []string) ([]byte, error) {
 
 	err = SetPriorityWindows(cmd.Process.Pid, windows.IDLE_PRIORITY_CLASS)
 	if err != nil {
-		log.Println("[ERROR]: could not change process priority")
+		slog.Error("could not change process priority")
 	}
 
 	err = cmd.Wait()
@@ -178,7 +178,7 @@ func RunAsUserWithOutputAndTimeout(cmdPath string, args []string, timeout time.D
 
 	err = SetPriorityWindows(cmd.Process.Pid, windows.IDLE_PRIORITY_CLASS)
 	if err != nil {
-		log.Println("[ERROR]: could not change process priority")
+		slog.Error("could not change process priority")
 	}
 
 	err = cmd.Wait()
@@ -3,11 +3,11 @@ package sftp
 import (
 	"bytes"
 	"crypto"
-	"crypto/rsa"
 	"crypto/x509"
 	"errors"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -15,6 +15,7 @@ import (
 
 	"github.com/dgraph-io/badger/v4"
 	"github.com/gliderlabs/ssh"
+	"github.com/open-uem/openuem-agent/internal/metrics"
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ocsp"
 	gossh "golang.org/x/crypto/ssh"
@@ -25,6 +26,8 @@ type SFTP struct {
 }
 
 func sftpHandler(sess ssh.Session) {
+	metrics.SFTPSessions.Inc()
+
 	debugStream := io.Discard
 	serverOptions := []sftp.ServerOption{
 		sftp.WithDebug(debugStream),
@@ -34,14 +37,14 @@ func sftpHandler(sess ssh.Session) {
 		serverOptions...,
 	)
 	if err != nil {
-		log.Printf("[ERROR]: sftp server init error: %s\n", err)
+		slog.Error("sftp server init", "error", err)
 		return
 	}
 	if err := server.Serve(); err == io.EOF {
 		server.Close()
-		log.Println("[INFO]: sftp client exited session.")
+		slog.Info("sftp client exited session.")
 	} else if err != nil {
-		log.Printf("[ERROR]: sftp server completed with error: %v", err)
+		slog.Error("sftp server completed with", "error", err)
 	}
 }
 
@@ -51,31 +54,30 @@ func New() *SFTP {
 }
 
 func (s *SFTP) Serve(address string, sftpCert, caCert *x509.Certificate, db *badger.DB) error {
+	// The console authenticates with the key of the SFTP certificate, it can be RSA, ECDSA or Ed25519
+	authorizedKey, err := gossh.NewPublicKey(sftpCert.PublicKey)
+	if err != nil {
+		return fmt.Errorf("could not use the public key of the SFTP certificate, reason: %v", err)
+	}
+
 	s.Server = ssh.Server{
 		Addr: address,
 		PublicKeyHandler: func(ctx ssh.Context, key ssh.PublicKey) bool {
-			log.Printf("[INFO]: SSH session opened by %s", ctx.User())
+			slog.Info(fmt.Sprintf("SSH session opened by %s", ctx.User()))
 
 			// Validate certificate against OCSP
 			isValid, err := isCertValidFromCache(sftpCert, caCert, db)
 			if err != nil {
-				log.Println("[ERROR]: found and error trying to validate cert against cache")
+				slog.Error("found and error trying to validate cert against cache")
 				return false
 			}
 
 			if !isValid {
-				log.Println("[ERROR]: cert it not valid according to cache")
+				slog.Error("cert it not valid according to cache")
 				return false
 			}
 
 			// Check that the public key used is authorized
-			rsaPublicKey := sftpCert.PublicKey.(*rsa.PublicKey)
-			authorizedKey, err := gossh.NewPublicKey(rsaPublicKey)
-			if err != nil {
-				log.Println("[ERROR]: could not parse SSH public key from RSA public key")
-				return false
-			}
-
 			return ssh.KeysEqual(key, authorizedKey)
 		},
 		SubsystemHandlers: map[string]ssh.SubsystemHandler{
@@ -113,7 +115,7 @@ func isCertValidFromCache(sftpCert, caCert *x509.Certificate, db *badger.DB) (bo
 						}
 						return nil
 					}); err != nil {
-						log.Println("[ERROR]: could not add cert OCSP status in cache")
+						slog.Error("could not add cert OCSP status in cache")
 						return err
 					}
 					return nil
@@ -134,7 +136,7 @@ func isCertValidFromCache(sftpCert, caCert *x509.Certificate, db *badger.DB) (bo
 
 			return nil
 		}); err != nil {
-		log.Println("[ERROR]: could not check OCSP status in cache")
+		slog.Error("could not check OCSP status in cache")
 		return false, err
 	}
 	return ocspStatus, nil
@@ -143,25 +145,25 @@ func isCertValidFromCache(sftpCert, caCert *x509.Certificate, db *badger.DB) (bo
 func isCertValid(sftpCert, caCert *x509.Certificate) (bool, error) {
 	ocspRequest, err := ocsp.CreateRequest(sftpCert, caCert, &ocsp.RequestOptions{Hash: crypto.SHA256})
 	if err != nil {
-		log.Println("[ERROR]: could not create OCSP Request")
+		slog.Error("could not create OCSP Request")
 		return false, err
 	}
 
 	if len(sftpCert.OCSPServer) == 0 {
-		log.Println("[ERROR]: no OCSP server found in certificate")
+		slog.Error("no OCSP server found in certificate")
 		return false, err
 	}
 
 	ocspServer := sftpCert.OCSPServer[0]
 	ocspURL, err := url.Parse(ocspServer)
 	if err != nil {
-		log.Println("[ERROR]: could not parse OCSP Responder URL")
+		slog.Error("could not parse OCSP Responder URL")
 		return false, err
 	}
 
 	httpRequest, err := http.NewRequest(http.MethodPost, ocspServer, bytes.NewBuffer(ocspRequest))
 	if err != nil {
-		log.Println("[ERROR]: could not create HTTP request to OCSP Responder")
+		slog.Error("could not create HTTP request to OCSP Responder")
 		return false, err
 	}
 
@@ -172,29 +174,29 @@ func isCertValid(sftpCert, caCert *x509.Certificate) (bool, error) {
 	httpClient := &http.Client{}
 	httpResponse, err := httpClient.Do(httpRequest)
 	if err != nil {
-		log.Println("[ERROR]: could not send request to OCSP Responder")
+		slog.Error("could not send request to OCSP Responder")
 		return false, err
 	}
 	defer httpResponse.Body.Close()
 	output, err := io.ReadAll(httpResponse.Body)
 	if err != nil {
-		log.Println("[ERROR]: could not read response from OCSP Responder")
+		slog.Error("could not read response from OCSP Responder")
 		return false, err
 	}
 
 	ocspResponse, err := ocsp.ParseResponse(output, caCert)
 	if err != nil {
-		log.Println("[ERROR]: could not parse OCSP Response")
+		slog.Error("could not parse OCSP Response")
 		return false, err
 	}
 
 	if ocspResponse.Status == 2 {
-		log.Println("[ERROR]: could not check OCSP status, try again later")
+		slog.Error("could not check OCSP status, try again later")
 		return false, err
 	}
 
 	if ocspResponse.Status == 1 {
-		log.Println("[ERROR]: unauthorized. Your certificate has been revoked")
+		slog.Error("unauthorized. Your certificate has been revoked")
 		return false, err
 	}
 	return true, nil
@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+)
+
+// FOLLOW_BUFFER is how many lines a follower can fall behind before lines are dropped
+const FOLLOW_BUFFER = 256
+
+var followers = &broadcaster{subs: map[int]chan string{}}
+
+// broadcaster sends a copy of every line written to the log to the followers,
+// slow followers lose lines instead of blocking the agent
+type broadcaster struct {
+	mu   sync.Mutex
+	next int
+	subs map[int]chan string
+}
+
+func (b *broadcaster) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.subs) == 0 {
+		return len(p), nil
+	}
+
+	line := strings.TrimRight(string(p), "\n")
+	for _, c := range b.subs {
+		select {
+		case c <- line:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Follow returns the lines written to the log from now on until stop is called
+func Follow() (lines <-chan string, stop func()) {
+	b := followers
+	c := make(chan string, FOLLOW_BUFFER)
+
+	b.mu.Lock()
+	id := b.next
+	b.next++
+	b.subs[id] = c
+	b.mu.Unlock()
+
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+			close(c)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+//go:build linux || darwin
+
+package logger
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// reopenOnHangup reopens the log file on SIGHUP so logrotate can move it away
+func reopenOnHangup(f *RotatingFile) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	go func() {
+		for range c {
+			if err := f.Reopen(); err != nil {
+				slog.Error("could not reopen log file", "error", err)
+				continue
+			}
+			slog.Info("log file has been reopened")
+		}
+	}()
+}
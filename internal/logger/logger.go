@@ -1,11 +1,95 @@
 package logger
 
-import "os"
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FORMAT_TEXT = "text"
+	FORMAT_JSON = "json"
+)
+
+// Level is shared by every handler so it can be changed while the agent is running
+var Level = new(slog.LevelVar)
+
+// Options are the logging settings, they're read from the config file before the agent starts
+type Options struct {
+	Dir        string
+	Format     string
+	MaxSize    int
+	MaxAge     int
+	MaxBackups int
+}
 
 type OpenUEMLogger struct {
-	LogFile *os.File
+	LogFile *RotatingFile
 }
 
 func (l *OpenUEMLogger) Close() {
 	l.LogFile.Close()
 }
+
+// Path is the log file in the log folder
+func Path(logDir string) string {
+	return filepath.Join(logDir, LOG_FILENAME)
+}
+
+// open creates the log folder and sends the records of both slog and the log package to the log file
+func open(o Options) *OpenUEMLogger {
+	if err := os.MkdirAll(o.Dir, 0750); err != nil {
+		Fatal("could not create log directory", "error", err)
+	}
+
+	f, err := NewRotatingFile(Path(o.Dir), o.MaxSize, o.MaxAge, o.MaxBackups)
+	if err != nil {
+		Fatal("could not create log file", "error", err)
+	}
+
+	// The log package also writes here through the default handler, followers get a copy of every line
+	slog.SetDefault(slog.New(NewHandler(io.MultiWriter(f, followers), o.Format)))
+
+	return &OpenUEMLogger{LogFile: f}
+}
+
+// NewHandler writes records as logfmt text or as one JSON object per line
+func NewHandler(w io.Writer, format string) slog.Handler {
+	opts := &slog.HandlerOptions{Level: Level}
+	if format == FORMAT_JSON {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// ParseLevel reads debug, info, warn or error
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return l, fmt.Errorf("%q is not a valid log level", level)
+	}
+	return l, nil
+}
+
+// SetLevel changes the level of every record from now on, debug mode always logs debug records
+func SetLevel(level string, debug bool) {
+	if debug {
+		Level.Set(slog.LevelDebug)
+		return
+	}
+
+	l, err := ParseLevel(level)
+	if err != nil {
+		l = slog.LevelInfo
+	}
+	Level.Set(l)
+}
+
+// Fatal logs the error and stops the agent
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
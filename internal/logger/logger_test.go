@@ -0,0 +1,31 @@
+package logger
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestOpenCreatesLogDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "openuem", "logs")
+	defer slog.SetDefault(slog.Default())
+	l := open(Options{Dir: dir})
+	defer l.Close()
+
+	if _, err := l.LogFile.Write([]byte("agent started\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0750 {
+		t.Errorf("log folder has permissions %v, want 0750", info.Mode().Perm())
+	}
+	if _, err := os.Stat(Path(dir)); err != nil {
+		t.Errorf("log file was not written: %v", err)
+	}
+}
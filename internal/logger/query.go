@@ -0,0 +1,168 @@
+package logger
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+const DEFAULT_QUERY_LINES = 100
+const MAX_QUERY_LINES = 5000
+
+// Query selects the last Lines records of the log, optionally those at Level or above
+// and written between Since and Until
+type Query struct {
+	Lines int       `json:"lines,omitempty"`
+	Level string    `json:"level,omitempty"`
+	Since time.Time `json:"since,omitempty"`
+	Until time.Time `json:"until,omitempty"`
+}
+
+func (q Query) filtered() bool {
+	return q.Level != "" || !q.Since.IsZero() || !q.Until.IsZero()
+}
+
+// Matches tells if a log line passes the level and time filters, lines that can't be
+// parsed only pass when there are no filters
+func (q Query) Matches(line string) bool {
+	if !q.filtered() {
+		return true
+	}
+
+	t, level, ok := ParseRecord(line)
+	if !ok {
+		return false
+	}
+
+	if q.Level != "" {
+		min, err := ParseLevel(q.Level)
+		if err == nil && level < min {
+			return false
+		}
+	}
+	if !q.Since.IsZero() && t.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && t.After(q.Until) {
+		return false
+	}
+	return true
+}
+
+// ParseRecord reads the time and level of a record written by the text or the JSON handler
+func ParseRecord(line string) (time.Time, slog.Level, bool) {
+	var t time.Time
+	var level slog.Level
+
+	if strings.HasPrefix(line, "{") {
+		record := struct {
+			Time  time.Time `json:"time"`
+			Level string    `json:"level"`
+		}{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return t, level, false
+		}
+		if err := level.UnmarshalText([]byte(record.Level)); err != nil {
+			return t, level, false
+		}
+		return record.Time, level, true
+	}
+
+	// time=2006-01-02T15:04:05.000Z07:00 level=INFO msg=...
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "time=") || !strings.HasPrefix(fields[1], "level=") {
+		return t, level, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimPrefix(fields[0], "time="))
+	if err != nil {
+		return t, level, false
+	}
+	if err := level.UnmarshalText([]byte(strings.TrimPrefix(fields[1], "level="))); err != nil {
+		return t, level, false
+	}
+	return t, level, true
+}
+
+// ReadLines returns the last lines of the log file that match the query, oldest first.
+// The compressed backups are read too when the current file doesn't have enough lines
+func ReadLines(path string, q Query) ([]string, error) {
+	if q.Lines <= 0 {
+		q.Lines = DEFAULT_QUERY_LINES
+	}
+	q.Lines = min(q.Lines, MAX_QUERY_LINES)
+
+	backups, err := (&RotatingFile{Path: path}).Backups()
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+	for i, file := range append([]string{path}, backups...) {
+		found, oldest, err := readFile(file, q, q.Lines-len(lines))
+		if err != nil {
+			// The current file must be there, a backup may have been removed by a rotation
+			if i == 0 || !os.IsNotExist(err) {
+				return nil, err
+			}
+			continue
+		}
+		lines = append(found, lines...)
+
+		if len(lines) >= q.Lines || (!q.Since.IsZero() && !oldest.IsZero() && oldest.Before(q.Since)) {
+			break
+		}
+	}
+	return lines, nil
+}
+
+// readFile returns the last n matching lines of a file and the time of its first record
+func readFile(path string, q Query, n int) ([]string, time.Time, error) {
+	var oldest time.Time
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, oldest, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, oldest, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	// Only the last n lines are kept
+	ring := make([]string, 0, n)
+	next := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if oldest.IsZero() {
+			oldest, _, _ = ParseRecord(line)
+		}
+		if !q.Matches(line) {
+			continue
+		}
+		if len(ring) < n {
+			ring = append(ring, line)
+			continue
+		}
+		ring[next] = line
+		next = (next + 1) % n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, oldest, err
+	}
+
+	return append(ring[next:], ring[:next]...), oldest, nil
+}
@@ -0,0 +1,238 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DEFAULT_MAX_SIZE_MB = 10
+const DEFAULT_MAX_AGE_DAYS = 7
+const DEFAULT_MAX_BACKUPS = 5
+
+const BACKUP_TIME_FORMAT = "20060102-150405.000"
+
+// RotatingFile appends to the log file and moves it to a compressed backup once it grows
+// beyond MaxSize or gets older than MaxAge, only the newest MaxBackups backups are kept
+type RotatingFile struct {
+	mu         sync.Mutex
+	Path       string
+	MaxSize    int64
+	MaxAge     time.Duration
+	MaxBackups int
+	file       *os.File
+	size       int64
+	openedAt   time.Time
+}
+
+func NewRotatingFile(path string, maxSizeMB int, maxAgeDays int, maxBackups int) (*RotatingFile, error) {
+	if maxSizeMB <= 0 {
+		maxSizeMB = DEFAULT_MAX_SIZE_MB
+	}
+
+	if maxAgeDays <= 0 {
+		maxAgeDays = DEFAULT_MAX_AGE_DAYS
+	}
+
+	if maxBackups < 0 {
+		maxBackups = DEFAULT_MAX_BACKUPS
+	}
+
+	r := &RotatingFile{
+		Path:       path,
+		MaxSize:    int64(maxSizeMB) * 1024 * 1024,
+		MaxAge:     time.Duration(maxAgeDays) * 24 * time.Hour,
+		MaxBackups: maxBackups,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	// A log left by a previous run that is already too old is rotated before writing to it
+	if r.size > 0 && time.Since(r.openedAt) > r.MaxAge {
+		if err := r.rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.size > 0 && (r.size+int64(len(p)) > r.MaxSize || time.Since(r.openedAt) > r.MaxAge) {
+		if err := r.rotate(); err != nil {
+			// Losing the rotation is better than losing the record
+			fmt.Fprintf(os.Stderr, "could not rotate log file, reason: %v\n", err)
+		}
+		if r.file == nil {
+			return 0, fmt.Errorf("log file could not be opened after rotating it")
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Reopen closes the file and opens the path again, it's used when the file has been
+// moved by an external tool like logrotate
+func (r *RotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	return r.open()
+}
+
+// Rotate moves the current file to a backup and starts a new one
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rotate()
+}
+
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// open appends to the file, its age is taken from the modification time of an existing
+// file so logs kept across restarts are still rotated on time
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	r.file = f
+	r.size = info.Size()
+	r.openedAt = time.Now()
+	if r.size > 0 {
+		r.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+func (r *RotatingFile) rotate() error {
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+
+	backup := fmt.Sprintf("%s.%s", r.Path, time.Now().Format(BACKUP_TIME_FORMAT))
+
+	renameErr := os.Rename(r.Path, backup)
+	if err := r.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		// The file keeps growing, the rotation is tried again after another MaxSize or MaxAge
+		// instead of on every write
+		r.size = 0
+		r.openedAt = time.Now()
+		return renameErr
+	}
+
+	if err := compress(backup); err != nil {
+		return err
+	}
+	return r.removeOldBackups()
+}
+
+// Backups returns the compressed backups of the log file, newest first
+func (r *RotatingFile) Backups() ([]string, error) {
+	matches, err := filepath.Glob(r.Path + ".*.gz")
+	if err != nil {
+		return nil, err
+	}
+	// The timestamp in the name sorts them in order
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	return matches, nil
+}
+
+func (r *RotatingFile) removeOldBackups() error {
+	backups, err := r.Backups()
+	if err != nil {
+		return err
+	}
+
+	for i, b := range backups {
+		if i < r.MaxBackups {
+			continue
+		}
+		if err := os.Remove(b); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	gzPath := path + ".gz"
+	dst, err := os.OpenFile(gzPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	zw.Name = strings.TrimSuffix(filepath.Base(gzPath), ".gz")
+	if _, err := io.Copy(zw, src); err != nil {
+		zw.Close()
+		dst.Close()
+		os.Remove(gzPath)
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(gzPath)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(gzPath)
+		return err
+	}
+
+	src.Close()
+	return os.Remove(path)
+}
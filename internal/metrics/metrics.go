@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const NAMESPACE = "openuem_agent"
+
+var (
+	ReportCollectorDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "report_collector_duration_seconds",
+		Help:      "Time spent by each collector while running a report",
+		Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"collector"})
+
+	ReportSendFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "report_send_failures_total",
+		Help:      "Reports that could not be sent to the NATS server",
+	})
+
+	NATSReconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "nats_reconnects_total",
+		Help:      "Reconnections to the NATS server",
+	})
+
+	Deployments = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "deployments_total",
+		Help:      "Package deployments by action and result",
+	}, []string{"action", "result"})
+
+	ProfileTaskFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "profile_task_failures_total",
+		Help:      "Profile tasks that have failed",
+	})
+
+	SFTPSessions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "sftp_sessions_total",
+		Help:      "SFTP sessions started",
+	})
+
+	VNCSessions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "vnc_sessions_total",
+		Help:      "Remote desktop sessions started",
+	})
+
+	HandlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "handler_duration_seconds",
+		Help:      "Time spent handling NATS messages by handler and result",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"handler", "result"})
+
+	PendingACKs = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: NAMESPACE,
+		Name:      "pending_acks",
+		Help:      "Deployment results waiting to be acknowledged by the worker",
+	})
+
+	CertificateExpiry = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: NAMESPACE,
+		Name:      "certificate_expiry_timestamp_seconds",
+		Help:      "When each of the agent's certificates expires",
+	}, []string{"certificate"})
+)
+
+func ObserveCollector(collector string, start time.Time) {
+	ReportCollectorDuration.WithLabelValues(collector).Observe(time.Since(start).Seconds())
+}
+
+func ObserveHandler(handler string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "failed"
+	}
+	HandlerDuration.WithLabelValues(handler, result).Observe(time.Since(start).Seconds())
+}
+
+func DeploymentResult(action string, failed bool) {
+	result := "success"
+	if failed {
+		result = "failed"
+	}
+	Deployments.WithLabelValues(action, result).Inc()
+}
+
+// Serve exposes the metrics in the /metrics path of the address
+func Serve(address string) (*http.Server, error) {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server stopped", "error", err)
+		}
+	}()
+
+	return server, nil
+}
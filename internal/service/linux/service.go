@@ -3,7 +3,7 @@
 package main
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
@@ -35,7 +35,7 @@ func (s *OpenUEMService) Execute() {
 	<-done
 
 	// Stop agent
-	log.Println("[INFO]: service has received the stop or shutdown command")
-	s.Logger.Close()
+	slog.Info("service has received the stop or shutdown command")
 	a.Stop()
+	s.Logger.Close()
 }
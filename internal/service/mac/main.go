@@ -3,12 +3,30 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
+	"github.com/open-uem/openuem-agent/internal/agent"
+	"github.com/open-uem/openuem-agent/internal/cli"
 	"github.com/open-uem/openuem-agent/internal/logger"
 )
 
 func main() {
-	// Instantiate logger
-	l := logger.New()
+	// Settings given as flags take precedence over the config file
+	args, err := agent.ParseFlagOverrides(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n\n", err)
+		cli.Run([]string{"help"})
+		os.Exit(2)
+	}
+
+	// Run a command instead of the service if requested
+	if len(args) > 0 {
+		os.Exit(cli.Run(args))
+	}
+
+	// Instantiate logger with the log folder and format from the config, environment or flags
+	l := logger.New(agent.ResolveLogSettings())
 
 	// Instantiate service
 	s := NewService(l)
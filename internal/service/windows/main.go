@@ -3,9 +3,12 @@
 package main
 
 import (
-	"log"
+	"fmt"
+	"os"
 	"runtime"
 
+	"github.com/open-uem/openuem-agent/internal/agent"
+	"github.com/open-uem/openuem-agent/internal/cli"
 	"github.com/open-uem/openuem-agent/internal/logger"
 	"golang.org/x/sys/windows/svc"
 )
@@ -15,15 +18,28 @@ func main() {
 	// the agent will use two CPUs at maximum
 	runtime.GOMAXPROCS(2)
 
-	// Instantiate logger
-	l := logger.New()
+	// Settings given as flags take precedence over the config file
+	args, err := agent.ParseFlagOverrides(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n\n", err)
+		cli.Run([]string{"help"})
+		os.Exit(2)
+	}
+
+	// Run a command instead of the service if requested
+	if len(args) > 0 {
+		os.Exit(cli.Run(args))
+	}
+
+	// Instantiate logger with the log folder and format from the config, environment or flags
+	l := logger.New(agent.ResolveLogSettings())
 
 	// Instantiate service
 	s := NewService(l)
 
 	// Run service
-	err := svc.Run("openuem-agent", s)
+	err = svc.Run("openuem-agent", s)
 	if err != nil {
-		log.Fatalf("could not run service: %v", err)
+		logger.Fatal("could not run service", "error", err)
 	}
 }
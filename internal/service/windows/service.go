@@ -3,7 +3,7 @@
 package main
 
 import (
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/open-uem/openuem-agent/internal/agent"
@@ -43,12 +43,13 @@ loop:
 				time.Sleep(100 * time.Millisecond)
 				changes <- c.CurrentStatus
 			case svc.Stop, svc.Shutdown:
-				log.Println("[INFO]: service has received the stop or shutdown command")
-				s.Logger.Close()
+				slog.Info("service has received the stop or shutdown command")
+				changes <- svc.Status{State: svc.StopPending, WaitHint: uint32(a.Config.ShutdownTimeout+10) * 1000}
 				a.Stop()
+				s.Logger.Close()
 				break loop
 			default:
-				log.Println("[WARN]: unexpected control request")
+				slog.Warn("unexpected control request")
 				return true, 1
 			}
 		}